@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ANY content representations selectable with GeneratorOptions.AnyStyle
+const (
+	AnyStyleInnerXML = "innerxml" // Raw inner XML kept in a string
+	AnyStyleElements = "elements" // Generic []AnyElement tree (name, attributes, children)
+	AnyStyleUnion    = "union"    // []AnyContent holding the declared type of each child
+)
+
+// anyElementType is the generic element tree used for ANY content
+const anyElementType = `// AnyElement is an arbitrary element found in ANY content
+type AnyElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr   ` + "`xml:\",any,attr\"`" + `
+	Children []AnyElement ` + "`xml:\",any\"`" + `
+	Text     string       ` + "`xml:\",chardata\"`" + `
+}
+`
+
+// anyContentField returns the struct fields representing ANY content
+func (g *StructGenerator) anyContentField() []goField {
+	if g.options.TinyGo {
+		// The tag is not written out; it tells the hand-rolled codecs to keep raw XML
+		return []goField{{Name: "Content", Type: "string", Tag: ",innerxml"}}
+	}
+	if g.options.NoXMLTags {
+		return []goField{{Name: "Content", Type: "string"}}
+	}
+	switch g.options.AnyStyle {
+	case AnyStyleElements:
+		return []goField{
+			{Name: "Content", Type: "[]AnyElement", Tag: ",any"},
+			{Name: "Text", Type: "string", Tag: ",chardata"},
+		}
+	case AnyStyleUnion:
+		return []goField{
+			{Name: "Content", Type: "[]AnyContent", Tag: ",any"},
+			{Name: "Text", Type: "string", Tag: ",chardata"},
+		}
+	default:
+		return []goField{{Name: "Content", Type: "string", Tag: ",innerxml"}}
+	}
+}
+
+// hasAnyContent reports whether any generated struct has an ANY content model
+func (g *StructGenerator) hasAnyContent() bool {
+	for _, name := range g.elementOrder {
+		if element, exists := g.elements[name]; exists && strings.TrimSpace(element.Content) == "ANY" {
+			return true
+		}
+	}
+	return false
+}
+
+// generateAnyTypes generates the supporting types for the selected ANY content style
+func (g *StructGenerator) generateAnyTypes() string {
+	if !g.hasAnyContent() || g.options.NoXMLTags || (g.options.AnyStyle != AnyStyleElements && g.options.AnyStyle != AnyStyleUnion) {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString("\n")
+	builder.WriteString(anyElementType)
+
+	if g.options.AnyStyle != AnyStyleUnion {
+		return builder.String()
+	}
+
+	builder.WriteString("\n// AnyContent holds one child of ANY content decoded into its declared type:\n")
+	builder.WriteString("// a struct pointer, *string for text-only elements or *AnyElement for undeclared elements\n")
+	builder.WriteString("type AnyContent struct {\n")
+	builder.WriteString("\tXMLName xml.Name\n")
+	builder.WriteString("\tValue any\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// UnmarshalXML decodes the element into the type declared for its name\n")
+	builder.WriteString("func (a *AnyContent) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {\n")
+	builder.WriteString("\ta.XMLName = start.Name\n")
+	builder.WriteString("\tswitch start.Name.Local {\n")
+	for _, name := range g.elementOrder {
+		if _, exists := g.elements[name]; !exists {
+			continue
+		}
+		// Wrappers lifted into their parent have no struct of their own
+		if g.isInlined(name) {
+			continue
+		}
+		valueType := "string"
+		if !g.isSimpleElement(name) {
+			valueType = g.toGoStructName(name)
+		}
+		builder.WriteString(fmt.Sprintf("\tcase %q:\n", localName(name)))
+		builder.WriteString(fmt.Sprintf("\t\tv := new(%s)\n", valueType))
+		builder.WriteString("\t\ta.Value = v\n")
+		builder.WriteString("\t\treturn d.DecodeElement(v, &start)\n")
+	}
+	builder.WriteString("\tdefault:\n")
+	builder.WriteString("\t\tv := new(AnyElement)\n")
+	builder.WriteString("\t\ta.Value = v\n")
+	builder.WriteString("\t\treturn d.DecodeElement(v, &start)\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// MarshalXML encodes the held value under the element name it was decoded from\n")
+	builder.WriteString("func (a AnyContent) MarshalXML(e *xml.Encoder, start xml.StartElement) error {\n")
+	builder.WriteString("\treturn e.EncodeElement(a.Value, xml.StartElement{Name: a.XMLName})\n")
+	builder.WriteString("}\n")
+
+	return builder.String()
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// attributeGroup is a set of attributes declared identically on the same elements, with
+// no shared entity needed, generated as one struct embedded in each of their structs
+type attributeGroup struct {
+	Name       string
+	Attributes []DTDAttribute // In the order the first element declares them
+	Elements   []string       // Elements declaring all of them, in declaration order
+}
+
+// attributeSignature identifies an attribute declaration regardless of where it appears
+func attributeSignature(attr DTDAttribute) string {
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%t\x00%t\x00%s\x00%s",
+		attr.Name, attr.Type, attr.DefaultValue, attr.Required, attr.Fixed, strings.Join(attr.Values, "|"), attr.Deprecated)
+}
+
+// findAttributeGroups partitions the attributes that eligible elements share verbatim
+// into groups: attributes declared on exactly the same set of at least minElements
+// elements form one group, when there are at least two of them
+func findAttributeGroups(elements map[string]*DTDElement, order []string, minElements int, eligible func(element *DTDElement, attr DTDAttribute) bool) []attributeGroup {
+	carriers := make(map[string][]string) // Attribute signature to the elements declaring it
+	for _, name := range order {
+		element, exists := elements[name]
+		if !exists {
+			continue
+		}
+		for _, attr := range element.Attributes {
+			if eligible(element, attr) {
+				signature := attributeSignature(attr)
+				carriers[signature] = append(carriers[signature], name)
+			}
+		}
+	}
+
+	// Walk the declarations again so groups and their attributes come out in DTD order
+	var groups []attributeGroup
+	index := make(map[string]int) // Carrier set to its group
+	for _, name := range order {
+		element, exists := elements[name]
+		if !exists {
+			continue
+		}
+		for _, attr := range element.Attributes {
+			if !eligible(element, attr) {
+				continue
+			}
+			carried := carriers[attributeSignature(attr)]
+			if len(carried) < minElements || carried[0] != name {
+				continue
+			}
+			key := strings.Join(carried, " ")
+			i, ok := index[key]
+			if !ok {
+				i = len(groups)
+				index[key] = i
+				groups = append(groups, attributeGroup{Elements: carried})
+			}
+			groups[i].Attributes = append(groups[i].Attributes, attr)
+		}
+	}
+
+	var shared []attributeGroup
+	for _, group := range groups {
+		if len(group.Attributes) >= 2 {
+			shared = append(shared, group)
+		}
+	}
+	return shared
+}
+
+// attributeGroups returns the attribute groups embedded with -attr-groups, named after
+// their first attributes. Attributes with enum types are left out, as those types are
+// named after their element, and so are those the generator config types, which it may
+// do for one element only.
+func (g *StructGenerator) attributeGroups() []attributeGroup {
+	g.groupsOnce.Do(func() {
+		if g.options.AttrGroups < 2 {
+			return
+		}
+		groups := findAttributeGroups(g.elements, g.elementOrder, g.options.AttrGroups, func(element *DTDElement, attr DTDAttribute) bool {
+			return !g.isSimpleElement(element.Name) && !g.isInlined(element.Name) && g.enumTypeName(element, attr) == "" &&
+				g.typeMapping(element.Name, attr.Name) == nil
+		})
+
+		taken := make(map[string]bool)
+		for _, name := range g.elementOrder {
+			taken[g.toGoStructName(name)] = true
+		}
+		for _, enum := range g.enumTypes() {
+			taken[enum.Name] = true
+		}
+		for i := range groups {
+			var base strings.Builder
+			for j, attr := range groups[i].Attributes {
+				if j == 3 {
+					break
+				}
+				base.WriteString(g.toGoFieldName(attr.Name))
+			}
+			base.WriteString("Attrs")
+			name := base.String()
+			for n := 2; taken[name]; n++ {
+				name = fmt.Sprintf("%s%d", base.String(), n)
+			}
+			taken[name] = true
+			groups[i].Name = name
+		}
+		g.groups = groups
+	})
+	return g.groups
+}
+
+// attributeGroupOf returns the group holding an attribute of an element, or nil
+func (g *StructGenerator) attributeGroupOf(element *DTDElement, attr DTDAttribute) *attributeGroup {
+	groups := g.attributeGroups()
+	signature := attributeSignature(attr)
+	for i := range groups {
+		for _, name := range groups[i].Elements {
+			if name != element.Name {
+				continue
+			}
+			for _, grouped := range groups[i].Attributes {
+				if attributeSignature(grouped) == signature {
+					return &groups[i]
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// generateAttributeGroups generates the structs of the attribute groups
+func (g *StructGenerator) generateAttributeGroups() string {
+	var builder strings.Builder
+	for _, group := range g.attributeGroups() {
+		names := make([]string, len(group.Attributes))
+		for i, attr := range group.Attributes {
+			names[i] = attr.Name
+		}
+		elements := make([]string, len(group.Elements))
+		for i, name := range group.Elements {
+			elements[i] = "<" + name + ">"
+		}
+
+		builder.WriteString(fmt.Sprintf("\n// %s holds the attributes %s, declared identically on %s\n",
+			group.Name, strings.Join(names, ", "), strings.Join(elements, ", ")))
+		builder.WriteString(fmt.Sprintf("type %s struct {\n", group.Name))
+		element := g.elements[group.Elements[0]]
+		for _, attr := range group.Attributes {
+			field := g.attributeField(element, attr)
+			if g.options.NoXMLTags {
+				field.Tag = ""
+			}
+			if field.Deprecated != "" {
+				builder.WriteString(fmt.Sprintf("\t// Deprecated: %s\n", field.Deprecated))
+			}
+			builder.WriteString(fmt.Sprintf("\t%s\n", field))
+		}
+		builder.WriteString("}\n")
+	}
+	return builder.String()
+}
+
+// attributeGroupReport describes the attribute groups for stderr
+func (g *StructGenerator) attributeGroupReport() []string {
+	var report []string
+	for _, group := range g.attributeGroups() {
+		names := make([]string, len(group.Attributes))
+		for i, attr := range group.Attributes {
+			names[i] = attr.Name
+		}
+		report = append(report, fmt.Sprintf("%s shares %s across %d elements, replacing %d fields",
+			group.Name, strings.Join(names, ", "), len(group.Elements), len(group.Elements)*len(names)))
+	}
+	return report
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// avroRecord is an Avro record schema
+type avroRecord struct {
+	Type      string      `json:"type"`
+	Name      string      `json:"name"`
+	Namespace string      `json:"namespace,omitempty"`
+	Doc       string      `json:"doc,omitempty"`
+	Fields    []avroField `json:"fields"`
+}
+
+// avroField is a single field of an Avro record
+type avroField struct {
+	Name    string          `json:"name"`
+	Type    any             `json:"type"`
+	Default json.RawMessage `json:"default,omitempty"`
+	Doc     string          `json:"doc,omitempty"`
+}
+
+// avroArray is an Avro array schema
+type avroArray struct {
+	Type  string `json:"type"`
+	Items any    `json:"items"`
+}
+
+var avroInvalidNameChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// AvroGenerator generates Avro (.avsc) schemas from DTD elements
+type AvroGenerator struct {
+	namespace    string
+	elements     map[string]*DTDElement
+	elementOrder []string
+	names        *StructGenerator // Reused for record naming so all backends agree
+	models       map[string]*ElementModel
+	defined      map[string]bool // Records already defined; later uses refer to them by name
+}
+
+// NewAvroGenerator creates a new Avro schema generator
+func NewAvroGenerator(namespace string, elements map[string]*DTDElement, elementOrder []string) *AvroGenerator {
+	return &AvroGenerator{
+		namespace:    namespace,
+		elements:     elements,
+		elementOrder: elementOrder,
+		names:        NewStructGenerator("", elements, elementOrder, GeneratorOptions{}),
+	}
+}
+
+// GenerateSchema generates the Avro schema. Elements that are not referenced by any other
+// element become top-level records; when there is more than one the schema is a union.
+func (g *AvroGenerator) GenerateSchema() (string, error) {
+	g.models = make(map[string]*ElementModel)
+	g.defined = make(map[string]bool)
+
+	models := BuildElementModels(g.elements, g.elementOrder)
+	referenced := make(map[string]bool)
+	for _, model := range models {
+		g.models[model.Name] = model
+		for _, child := range model.Children {
+			referenced[child.Name] = true
+		}
+	}
+
+	var roots []any
+	for _, model := range models {
+		if !referenced[model.Name] {
+			roots = append(roots, g.recordSchema(model))
+		}
+	}
+	// Recursive schemas may have no unreferenced element; fall back to the first one
+	if len(roots) == 0 && len(models) > 0 {
+		roots = append(roots, g.recordSchema(models[0]))
+	}
+
+	var schema any = roots
+	if len(roots) == 1 {
+		schema = roots[0]
+	}
+
+	var builder strings.Builder
+	encoder := json.NewEncoder(&builder)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(schema); err != nil {
+		return "", fmt.Errorf("failed to encode avro schema: %w", err)
+	}
+
+	return builder.String(), nil
+}
+
+// recordSchema returns the schema for an element: the full record on first use and the
+// record name afterwards, as Avro requires each named type to be defined exactly once
+func (g *AvroGenerator) recordSchema(model *ElementModel) any {
+	recordName := g.names.toGoStructName(model.Name)
+	if g.defined[model.Name] {
+		return recordName
+	}
+	g.defined[model.Name] = true
+
+	record := &avroRecord{
+		Type:      "record",
+		Name:      recordName,
+		Namespace: g.namespace,
+		Doc:       fmt.Sprintf("Represents the <%s> element", model.Name),
+		Fields:    []avroField{},
+	}
+
+	// Fields whose names collide, such as those of the attribute title and the child
+	// <title>, are numbered apart, as a record cannot hold two fields of one name
+	var reserved []string
+	if model.AnyContent {
+		reserved = append(reserved, "content")
+	}
+	if model.HasText {
+		reserved = append(reserved, "text")
+	}
+	names := model.memberNames(g.toAvroName, reserved...)
+
+	for i, attr := range model.Attributes {
+		field := avroField{Name: names[i], Doc: fmt.Sprintf("Attribute %s", attr.Name)}
+
+		switch {
+		case isListAttributeType(attr.Type):
+			field.Type = avroArray{Type: "array", Items: "string"}
+			field.Default = json.RawMessage("[]")
+		case attr.Required:
+			field.Type = "string"
+		case attr.DefaultValue != "":
+			field.Type = "string"
+			field.Default, _ = json.Marshal(attr.DefaultValue)
+		default:
+			field.Type = []any{"null", "string"}
+			field.Default = json.RawMessage("null")
+		}
+
+		record.Fields = append(record.Fields, field)
+	}
+
+	for i, child := range model.Children {
+		var itemType any = "string"
+		if childModel, exists := g.models[child.Name]; exists {
+			itemType = g.recordSchema(childModel)
+		}
+
+		field := avroField{Name: names[len(model.Attributes)+i], Doc: fmt.Sprintf("Child element <%s>", child.Name)}
+		if child.Repeated {
+			field.Type = avroArray{Type: "array", Items: itemType}
+			field.Default = json.RawMessage("[]")
+		} else {
+			field.Type = []any{"null", itemType}
+			field.Default = json.RawMessage("null")
+		}
+
+		record.Fields = append(record.Fields, field)
+	}
+
+	if model.AnyContent {
+		record.Fields = append(record.Fields, avroField{
+			Name:    "content",
+			Type:    []any{"null", "string"},
+			Default: json.RawMessage("null"),
+			Doc:     "Raw XML of the ANY content",
+		})
+	}
+
+	if model.HasText {
+		record.Fields = append(record.Fields, avroField{
+			Name:    "text",
+			Type:    []any{"null", "string"},
+			Default: json.RawMessage("null"),
+			Doc:     "Character data",
+		})
+	}
+
+	return record
+}
+
+// toAvroName converts a DTD name to a valid Avro field name
+func (g *AvroGenerator) toAvroName(name string) string {
+	avroName := avroInvalidNameChars.ReplaceAllString(name, "_")
+	if avroName == "" || (avroName[0] >= '0' && avroName[0] <= '9') {
+		avroName = "_" + avroName
+	}
+	return avroName
+}
@@ -0,0 +1,136 @@
+package main
+
+// canonicalRuntime produces Canonical XML 1.0 (without comments) from the output of
+// encoding/xml, so signed or hashed documents encode to the same bytes everywhere
+const canonicalRuntime = `
+// MarshalCanonical returns the XML encoding of v in Canonical XML 1.0 form without
+// comments: no XML declaration, namespace declarations and attributes in canonical order,
+// empty elements as start and end tag pairs, C14N escaping and LF line endings. Equal
+// values always encode to the same bytes, so the documents can be signed or hashed.
+func MarshalCanonical(v any) ([]byte, error) {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := Canonicalize(&buf, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteCanonical writes the canonical XML encoding of v to w
+func WriteCanonical(w io.Writer, v any) error {
+	data, err := MarshalCanonical(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Canonicalize copies the XML document read from r to w in canonical form. The DOCTYPE,
+// comments and the XML declaration are dropped.
+func Canonicalize(w io.Writer, r io.Reader) error {
+	d := xml.NewDecoder(r)
+	out := bufio.NewWriter(w)
+	var scopes []map[string]string // Namespace prefixes declared by the open elements
+	rootSeen := false
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			scope := make(map[string]string)
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" {
+					scope[attr.Name.Local] = attr.Value
+				} else if attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+					scope[""] = attr.Value
+				}
+			}
+			scopes = append(scopes, scope)
+			rootSeen = true
+
+			attrs := append([]xml.Attr(nil), t.Attr...)
+			sort.SliceStable(attrs, func(i, j int) bool {
+				return canonicalAttrKey(attrs[i], scopes) < canonicalAttrKey(attrs[j], scopes)
+			})
+			out.WriteString("<" + c14nName(t.Name))
+			for _, attr := range attrs {
+				out.WriteString(" " + c14nName(attr.Name) + "=\"" + canonicalAttrEscaper.Replace(attr.Value) + "\"")
+			}
+			out.WriteString(">")
+		case xml.EndElement:
+			if len(scopes) > 0 {
+				scopes = scopes[:len(scopes)-1]
+			}
+			out.WriteString("</" + c14nName(t.Name) + ">")
+		case xml.CharData:
+			if len(scopes) > 0 {
+				out.WriteString(canonicalTextEscaper.Replace(string(t)))
+			}
+		case xml.ProcInst:
+			if t.Target == "xml" {
+				continue
+			}
+			// Processing instructions outside the root element are separated from it by a newline
+			if len(scopes) == 0 && rootSeen {
+				out.WriteString("\n")
+			}
+			out.WriteString("<?" + t.Target)
+			if inst := strings.TrimLeft(string(t.Inst), " \t\r\n"); inst != "" {
+				out.WriteString(" " + inst)
+			}
+			out.WriteString("?>")
+			if len(scopes) == 0 && !rootSeen {
+				out.WriteString("\n")
+			}
+		}
+	}
+	return out.Flush()
+}
+
+// c14nName writes a name with its prefix, as it appeared in the document
+func c14nName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// canonicalAttrKey orders the default namespace declaration first, then the other
+// namespace declarations by prefix, then the attributes by namespace URI and local name
+func canonicalAttrKey(attr xml.Attr, scopes []map[string]string) string {
+	switch {
+	case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+		return "0"
+	case attr.Name.Space == "xmlns":
+		return "1" + attr.Name.Local
+	case attr.Name.Space == "":
+		return "2\x00" + attr.Name.Local
+	}
+	uri := attr.Name.Space
+	if attr.Name.Space == "xml" {
+		uri = "http://www.w3.org/XML/1998/namespace"
+	}
+	for i := len(scopes) - 1; i >= 0; i-- {
+		if declared, ok := scopes[i][attr.Name.Space]; ok {
+			uri = declared
+			break
+		}
+	}
+	return "2" + uri + "\x00" + attr.Name.Local
+}
+
+// canonicalTextEscaper escapes character data as Canonical XML requires
+var canonicalTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+
+// canonicalAttrEscaper escapes attribute values as Canonical XML requires
+var canonicalAttrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", "\"", "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+`
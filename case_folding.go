@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// caseFoldingRuntime rewrites the names read by an xml.Decoder to their DTD spelling, so
+// the generated UnmarshalXML methods accept names in any letter case
+const caseFoldingRuntime = `
+// canonicalName returns name with its local part spelled as in names, keyed by lowercase
+func canonicalName(name xml.Name, names map[string]string) xml.Name {
+	if canonical, ok := names[strings.ToLower(name.Local)]; ok {
+		name.Local = canonical
+	}
+	return name
+}
+
+// canonicalStart returns start with its element and attribute names spelled as in the DTD
+func canonicalStart(start xml.StartElement) xml.StartElement {
+	start.Name = canonicalName(start.Name, canonicalElements)
+	attrs := make([]xml.Attr, len(start.Attr))
+	for i, attr := range start.Attr {
+		attr.Name = canonicalName(attr.Name, canonicalAttributes[start.Name.Local])
+		attrs[i] = attr
+	}
+	start.Attr = attrs
+	return start
+}
+
+// caseFolder replays pending and then the rest of the element from d with canonical names
+type caseFolder struct {
+	d       *xml.Decoder
+	pending []xml.Token
+}
+
+func (f *caseFolder) Token() (xml.Token, error) {
+	if len(f.pending) > 0 {
+		tok := f.pending[0]
+		f.pending = f.pending[1:]
+		return tok, nil
+	}
+	tok, err := f.d.Token()
+	switch t := tok.(type) {
+	case xml.StartElement:
+		tok = canonicalStart(t)
+	case xml.EndElement:
+		t.Name = canonicalName(t.Name, canonicalElements)
+		tok = t
+	}
+	return tok, err
+}
+
+// decodeFolded decodes the element opened by start into v, matching names case-insensitively
+func decodeFolded(d *xml.Decoder, start xml.StartElement, v any) error {
+	start = canonicalStart(start)
+	return xml.NewTokenDecoder(&caseFolder{d: d, pending: []xml.Token{start}}).Decode(v)
+}
+`
+
+// caseFoldingInnerRuntime handles structs holding ANY content as ",innerxml", which
+// encoding/xml only fills when decoding from bytes, not from a token stream
+const caseFoldingInnerRuntime = `
+// decodeFoldedInner decodes the element opened by start into v like decodeFolded and
+// returns its content re-encoded from the tokens, with names left as written
+func decodeFoldedInner(d *xml.Decoder, start xml.StartElement, v any) (string, error) {
+	var content strings.Builder
+	e := xml.NewEncoder(&content)
+	depth := 0
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return "", err
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+		if depth < 0 {
+			break
+		}
+		if err := e.EncodeToken(tok); err != nil {
+			return "", err
+		}
+	}
+	if err := e.Flush(); err != nil {
+		return "", err
+	}
+
+	start = canonicalStart(start)
+	err := xml.NewTokenDecoder(&caseFolder{d: d, pending: []xml.Token{start, start.End()}}).Decode(v)
+	return content.String(), err
+}
+`
+
+// keepsInnerXML reports whether an element's struct holds its content as ",innerxml"
+func (g *StructGenerator) keepsInnerXML(name string) bool {
+	return strings.TrimSpace(g.elements[name].Content) == "ANY" && g.options.AnyStyle == AnyStyleInnerXML
+}
+
+// caseFoldingTable maps lowercased names to their spelling. Names that only differ in
+// case from another one are left out, as they must match exactly.
+func caseFoldingTable(names []string) map[string]string {
+	table := make(map[string]string)
+	ambiguous := make(map[string]bool)
+	for _, name := range names {
+		key := strings.ToLower(name)
+		if existing, ok := table[key]; ok && existing != name {
+			ambiguous[key] = true
+		}
+		table[key] = name
+	}
+	for key := range ambiguous {
+		delete(table, key)
+	}
+	return table
+}
+
+// writeFoldingTable writes a lowercased name to spelling map literal in key order
+func writeFoldingTable(builder *strings.Builder, table map[string]string, indent string) {
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		builder.WriteString(fmt.Sprintf("%s%q: %q,\n", indent, key, table[key]))
+	}
+}
+
+// generateCaseFolding generates UnmarshalXML methods that match element and attribute
+// names case-insensitively, with the name tables they use. Marshaling is unchanged and
+// writes the DTD spelling.
+func (g *StructGenerator) generateCaseFolding() string {
+	var builder strings.Builder
+
+	inner := false
+	for _, name := range g.elementOrder {
+		if _, exists := g.elements[name]; !exists || g.isSimpleElement(name) || g.isInlined(name) {
+			continue
+		}
+		structName := g.toGoStructName(name)
+		builder.WriteString(fmt.Sprintf("\n// UnmarshalXML decodes <%s>, matching element and attribute names case-insensitively\n", name))
+		builder.WriteString(fmt.Sprintf("func (v *%s) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {\n", structName))
+		builder.WriteString(g.plainDecl(name))
+		if g.fillsDefaults(name) {
+			builder.WriteString("\tstart = withAttrDefaults(canonicalStart(start))\n")
+		} else if g.checksFixed(name) {
+			builder.WriteString("\tstart = canonicalStart(start)\n")
+		}
+		if g.checksFixed(name) {
+			builder.WriteString(fixedCheckCall)
+		}
+		if g.keepsInnerXML(name) {
+			inner = true
+			builder.WriteString("\tcontent, err := decodeFoldedInner(d, start, (*plain)(v))\n")
+			builder.WriteString("\tv.Content = content\n")
+			builder.WriteString("\treturn err\n")
+		} else {
+			builder.WriteString(g.decodeReturn(name, "decodeFolded(d, start, "+g.decodeTarget(name)+")"))
+		}
+		builder.WriteString("}\n")
+	}
+
+	var names []string
+	for _, name := range g.elementOrder {
+		if _, exists := g.elements[name]; exists {
+			names = append(names, localName(name))
+		}
+		for _, child := range contentReferences(g.elements[name].Content) {
+			names = append(names, localName(child))
+		}
+	}
+	builder.WriteString("\n// canonicalElements maps lowercased element names to their DTD spelling\n")
+	builder.WriteString("var canonicalElements = map[string]string{\n")
+	writeFoldingTable(&builder, caseFoldingTable(names), "\t")
+	builder.WriteString("}\n")
+
+	builder.WriteString("\n// canonicalAttributes maps element names to their attributes' lowercased names and DTD spelling\n")
+	builder.WriteString("var canonicalAttributes = map[string]map[string]string{\n")
+	for _, name := range g.elementOrder {
+		element, exists := g.elements[name]
+		if !exists || len(element.Attributes) == 0 {
+			continue
+		}
+		var attributes []string
+		for _, attr := range element.Attributes {
+			attributes = append(attributes, localName(attr.Name))
+		}
+		builder.WriteString(fmt.Sprintf("\t%q: {\n", localName(name)))
+		writeFoldingTable(&builder, caseFoldingTable(attributes), "\t\t")
+		builder.WriteString("\t},\n")
+	}
+	builder.WriteString("}\n")
+
+	builder.WriteString(caseFoldingRuntime)
+	if inner {
+		builder.WriteString(caseFoldingInnerRuntime)
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Representations of exclusive choices between elements selectable with
+// GeneratorOptions.ChoiceStyle
+const (
+	ChoiceStyleFields    = "fields"    // A field per alternative, with a Choice method naming the one held
+	ChoiceStyleInterface = "interface" // One field of an interface type the alternatives' structs implement
+)
+
+// choiceInterface is an exclusive choice between elements generated as structs, held by
+// one field of an interface type with ChoiceStyleInterface
+type choiceInterface struct {
+	Group    choiceGroup
+	Index    int      // Index of the choice among the exclusive choices of the struct
+	Name     string   // Go interface type
+	Field    string   // Field of the struct holding the alternative
+	Marker   string   // Unexported method of the alternatives implementing the interface
+	Elements []string // Element of each alternative
+	Types    []string // Go struct type of each alternative
+}
+
+// choiceInterfaces returns the exclusive choices of an element's struct held by interface
+// fields: those whose alternatives are single elements generated as structs and held by
+// pointer, like (book | magazine | dvd). Other choices keep a field per alternative, as do
+// all choices of structs with a positional sequence.
+func (g *StructGenerator) choiceInterfaces(element *DTDElement) []choiceInterface {
+	if g.options.ChoiceStyle != ChoiceStyleInterface || g.positionalSequence(element) != nil {
+		return nil
+	}
+	groups := g.choiceGroups(element)
+	if len(groups) == 0 {
+		return nil
+	}
+
+	fieldTypes := make(map[string]string)
+	for _, field := range g.parseContentModel(element.Content) {
+		fieldTypes[field.Element] = field.Type
+	}
+	taken := make(map[string]bool)
+	for _, name := range g.elementOrder {
+		if _, exists := g.elements[name]; exists && !g.isSimpleElement(name) {
+			taken[g.toGoStructName(name)] = true
+		}
+	}
+	for _, enum := range g.enumTypes() {
+		taken[enum.Name] = true
+	}
+	fieldNames := make(map[string]bool)
+	for _, field := range g.structFields(element) {
+		fieldNames[field.Name] = true
+	}
+
+	var interfaces []choiceInterface
+	structName := g.toGoStructName(element.Name)
+	for i, group := range groups {
+		choice := choiceInterface{Group: group, Index: i}
+		for _, alternative := range group.Alternatives {
+			name := alternative[0]
+			if _, exists := g.elements[name]; !exists || len(alternative) > 1 || g.isSimpleElement(name) || g.isInlined(name) ||
+				fieldTypes[name] != "*"+g.toGoStructName(name) {
+				choice.Elements = nil
+				break
+			}
+			choice.Elements = append(choice.Elements, name)
+			choice.Types = append(choice.Types, g.toGoStructName(name))
+		}
+		if choice.Elements == nil {
+			continue
+		}
+
+		suffix := "Content"
+		if len(interfaces) > 0 {
+			suffix = fmt.Sprintf("Content%d", len(interfaces)+1)
+		}
+		choice.Name = structName + suffix
+		for taken[choice.Name] {
+			choice.Name += "Choice"
+		}
+		taken[choice.Name] = true
+		choice.Field = suffix
+		for fieldNames[choice.Field] {
+			choice.Field += "Choice"
+		}
+		fieldNames[choice.Field] = true
+		choice.Marker = "is" + choice.Name
+		interfaces = append(interfaces, choice)
+	}
+	return interfaces
+}
+
+// choiceInterfaceOf returns the interface choice of an element's struct holding the given
+// child element, or nil
+func choiceInterfaceOf(interfaces []choiceInterface, child string) *choiceInterface {
+	for i := range interfaces {
+		if containsString(interfaces[i].Elements, child) {
+			return &interfaces[i]
+		}
+	}
+	return nil
+}
+
+// holdsChoiceInterfaces reports whether an element's struct holds interface choices
+func (g *StructGenerator) holdsChoiceInterfaces(name string) bool {
+	element, exists := g.elements[name]
+	return exists && !g.isSimpleElement(name) && !g.isInlined(name) && len(g.choiceInterfaces(element)) > 0
+}
+
+// choiceDispatch returns the code making a method call, such as "redact(r)", on the
+// alternative an interface choice field holds, for the generated methods that walk the
+// elements inside a struct. It reports false for fields that are not alternatives of an
+// interface choice; the alternatives after the first yield no code.
+func (g *StructGenerator) choiceDispatch(interfaces []choiceInterface, field goField, call string) (string, bool) {
+	choice := choiceInterfaceOf(interfaces, field.Element)
+	if field.Element == "" || choice == nil {
+		return "", false
+	}
+	if field.Element != choice.Elements[0] {
+		return "", true
+	}
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("\tswitch c := v.%s.(type) {\n", choice.Field))
+	for _, typeName := range choice.Types {
+		builder.WriteString(fmt.Sprintf("\tcase *%s:\n", typeName))
+		builder.WriteString(fmt.Sprintf("\t\tc.%s\n", call))
+	}
+	builder.WriteString("\t}\n")
+	return builder.String(), true
+}
+
+// minOccurs returns how many alternatives of an interface choice a valid document holds
+// at least: none if the choice or one of its alternatives is optional, one otherwise
+func (c choiceInterface) minOccurs() int {
+	if c.Group.Particle.Indicator == '?' {
+		return 0
+	}
+	for _, alternative := range c.Group.Particle.Children {
+		if alternative.Indicator == '?' {
+			return 0
+		}
+	}
+	return 1
+}
+
+// alternativeList joins the Go types of the alternatives of an interface choice for doc
+// comments, as "*Book, *Magazine or *Dvd"
+func (c choiceInterface) alternativeList() string {
+	types := make([]string, len(c.Types))
+	for i, typeName := range c.Types {
+		types[i] = "*" + typeName
+	}
+	if len(types) == 1 {
+		return types[0]
+	}
+	return strings.Join(types[:len(types)-1], ", ") + " or " + types[len(types)-1]
+}
+
+// generateChoiceInterfaces generates the interface types of the interface choices with
+// the methods of the alternatives implementing them
+func (g *StructGenerator) generateChoiceInterfaces() string {
+	var builder strings.Builder
+
+	for _, name := range g.elementOrder {
+		if !g.holdsChoiceInterfaces(name) {
+			continue
+		}
+		element := g.elements[name]
+		for _, choice := range g.choiceInterfaces(element) {
+			builder.WriteString(fmt.Sprintf("\n// %s is the choice %s of <%s>, held as a %s\n", choice.Name, choice.Group.Particle, name, choice.alternativeList()))
+			builder.WriteString(fmt.Sprintf("type %s interface {\n", choice.Name))
+			builder.WriteString(fmt.Sprintf("\t%s()\n", choice.Marker))
+			builder.WriteString("}\n\n")
+			for _, typeName := range choice.Types {
+				builder.WriteString(fmt.Sprintf("func (*%s) %s() {}\n", typeName, choice.Marker))
+			}
+		}
+	}
+
+	return builder.String()
+}
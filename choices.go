@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// choiceGroup is a choice of a content model whose alternatives exclude each other in a
+// document, such as (circle | square) or ((street, city) | poBox)?
+type choiceGroup struct {
+	Particle     *ContentParticle
+	Alternatives [][]string // Element names of each alternative, in order of appearance
+}
+
+// exclusiveChoices returns the outermost choices of an element content model that occur
+// at most once, outside any repeated group, and whose element names occur nowhere else in
+// the model. At most one of their alternatives is present in a valid document, so the
+// fields of the others stay empty.
+func (m *ContentModel) exclusiveChoices() []choiceGroup {
+	if m.Kind != ContentChildren || m.Root == nil {
+		return nil
+	}
+	positions := make(map[string]int)
+	m.Root.countPositions(positions)
+
+	var groups []choiceGroup
+	var walk func(c *ContentParticle)
+	walk = func(c *ContentParticle) {
+		if c.Indicator == '*' || c.Indicator == '+' {
+			return
+		}
+		if c.Kind == ParticleChoice && len(c.Children) > 1 {
+			group := choiceGroup{Particle: c}
+			unique := true
+			for _, alternative := range c.Children {
+				names := (&ContentModel{Root: alternative}).ElementNames()
+				for _, name := range names {
+					unique = unique && positions[name] == 1
+				}
+				group.Alternatives = append(group.Alternatives, names)
+			}
+			if unique {
+				groups = append(groups, group)
+				return
+			}
+		}
+		for _, child := range c.Children {
+			walk(child)
+		}
+	}
+	walk(m.Root)
+	return groups
+}
+
+// choiceOf returns the exclusive choice of a content model an element name is an
+// alternative of, or nil
+func choiceOf(groups []choiceGroup, name string) *choiceGroup {
+	for i := range groups {
+		for _, alternative := range groups[i].Alternatives {
+			if containsString(alternative, name) {
+				return &groups[i]
+			}
+		}
+	}
+	return nil
+}
+
+// choiceGroups returns the exclusive choices of the element's struct. Structs lifting a
+// wrapper's fields with -inline-wrappers have none, as the lifted fields may be renamed.
+func (g *StructGenerator) choiceGroups(element *DTDElement) []choiceGroup {
+	if _, ok := g.inlinedChild(element); ok {
+		return nil
+	}
+	model, err := ParseContentModel(element.Content)
+	if err != nil {
+		return nil
+	}
+	return model.exclusiveChoices()
+}
+
+// choiceType describes the type naming the alternatives of a choice and the method
+// returning the one a struct holds
+type choiceType struct {
+	Name      string // Go type of the alternatives
+	Method    string // Method returning the alternative held
+	Constants []string
+	Group     choiceGroup
+}
+
+// choiceMethod returns the name of the method returning the alternative held of the i-th
+// exclusive choice of an element's struct, avoiding the names of its fields
+func (g *StructGenerator) choiceMethod(element *DTDElement, i int) string {
+	method := "Choice"
+	if i > 0 {
+		method = fmt.Sprintf("Choice%d", i+1)
+	}
+	for _, field := range g.structFields(element) {
+		if field.Name == method {
+			method += "Alternative"
+		}
+	}
+	return method
+}
+
+// choiceTypes names the choice types of an element's struct, avoiding the names of the
+// generated structs and enum types. Choices held by interface fields have none.
+func (g *StructGenerator) choiceTypes(element *DTDElement, taken map[string]bool) []choiceType {
+	var types []choiceType
+	structName := g.toGoStructName(element.Name)
+	interfaces := make(map[int]bool)
+	for _, choice := range g.choiceInterfaces(element) {
+		interfaces[choice.Index] = true
+	}
+	for i, group := range g.choiceGroups(element) {
+		if interfaces[i] {
+			continue
+		}
+		choice := choiceType{Name: structName + "Choice", Method: g.choiceMethod(element, i), Group: group}
+		if i > 0 {
+			choice.Name = fmt.Sprintf("%sChoice%d", structName, i+1)
+		}
+		for taken[choice.Name] {
+			choice.Name += "Alternative"
+		}
+		taken[choice.Name] = true
+		for j, alternative := range group.Alternatives {
+			constant := choice.Name + enumConstSuffix(g.identifierName(localName(alternative[0])))
+			if constant == choice.Name || taken[constant] {
+				constant = fmt.Sprintf("%sValue%d", choice.Name, j+1)
+			}
+			taken[constant] = true
+			choice.Constants = append(choice.Constants, constant)
+		}
+		types = append(types, choice)
+	}
+	return types
+}
+
+// generateChoices generates, for every exclusive choice of a struct's content, a type
+// naming its alternatives by their first element and a method returning the alternative
+// the struct holds
+func (g *StructGenerator) generateChoices() string {
+	var builder strings.Builder
+
+	taken := make(map[string]bool)
+	for _, name := range g.elementOrder {
+		if _, exists := g.elements[name]; exists && !g.isSimpleElement(name) {
+			taken[g.toGoStructName(name)] = true
+		}
+	}
+	for _, enum := range g.enumTypes() {
+		taken[enum.Name] = true
+	}
+	for _, name := range g.elementOrder {
+		if g.holdsChoiceInterfaces(name) {
+			for _, choice := range g.choiceInterfaces(g.elements[name]) {
+				taken[choice.Name] = true
+			}
+		}
+	}
+
+	for _, name := range g.elementOrder {
+		element, exists := g.elements[name]
+		if !exists || g.isSimpleElement(name) || g.isInlined(name) {
+			continue
+		}
+		fields := make(map[string]goField)
+		for _, field := range g.parseContentModel(element.Content) {
+			fields[field.Element] = field
+		}
+
+		structName := g.toGoStructName(name)
+		for _, choice := range g.choiceTypes(element, taken) {
+			builder.WriteString(fmt.Sprintf("\n// %s names the alternatives of the choice %s of <%s> by their first element\n", choice.Name, choice.Group.Particle, name))
+			builder.WriteString(fmt.Sprintf("type %s string\n\n", choice.Name))
+			builder.WriteString("const (\n")
+			for i, alternative := range choice.Group.Alternatives {
+				builder.WriteString(fmt.Sprintf("\t%s %s = %q\n", choice.Constants[i], choice.Name, alternative[0]))
+			}
+			builder.WriteString(")\n\n")
+
+			builder.WriteString(fmt.Sprintf("// %s returns the alternative of the choice %s the %s holds, or \"\" if it\n", choice.Method, choice.Group.Particle, structName))
+			builder.WriteString("// holds none. The alternatives exclude each other, so in a valid document at most one is set.\n")
+			builder.WriteString(fmt.Sprintf("func (v *%s) %s() %s {\n", structName, choice.Method, choice.Name))
+			builder.WriteString("\tswitch {\n")
+			for i, alternative := range choice.Group.Alternatives {
+				var conditions []string
+				for _, child := range alternative {
+					field := fields[child]
+					switch {
+					case field.Type == "Presence":
+						conditions = append(conditions, "bool(v."+field.Name+")")
+					case strings.HasPrefix(field.Type, "[]"):
+						conditions = append(conditions, "len(v."+field.Name+") > 0")
+					default:
+						conditions = append(conditions, "v."+field.Name+" != nil")
+					}
+				}
+				builder.WriteString(fmt.Sprintf("\tcase %s:\n", strings.Join(conditions, " || ")))
+				builder.WriteString(fmt.Sprintf("\t\treturn %s\n", choice.Constants[i]))
+			}
+			builder.WriteString("\t}\n")
+			builder.WriteString("\treturn \"\"\n")
+			builder.WriteString("}\n")
+		}
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,18 @@
+package main
+
+// CompatLatest is the newest compatibility level of the generated Go code, which the
+// generator uses unless GeneratorOptions.Compat pins an older one.
+//
+// For a given level, later versions of the generator keep making the same decisions on
+// which fields a struct gets, their Go types and the names of the types, fields, methods
+// and constants, so regenerating with a newer binary does not change the public API of
+// the generated package. A fix changing any of these goes behind a new level, checked as
+// g.options.Compat >= level, and older levels keep the old behavior. New options that
+// are off by default do not need a level. Levels:
+//
+//	1: the decisions of the first release to support -compat
+//	2: accented Latin letters in names are transliterated to ASCII in Go identifiers
+//	3: common initialisms in names, such as id and url, are upper case in type and field names
+//	4: children a content model requires exactly once are held by value rather than through
+//	   a pointer, as the default PointerPolicy becomes PointerPolicyOptionalOnly
+const CompatLatest = 4
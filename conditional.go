@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+)
+
+// conditionalScanner removes the <![INCLUDE[ ... ]]> and <![IGNORE[ ... ]]> markup from
+// the lines of a DTD and drops the text of ignored sections, carrying open sections over
+// to the following lines
+type conditionalScanner struct {
+	open []conditionalSection // Innermost last
+}
+
+// conditionalSection is a conditional section whose ]]> has not been seen yet
+type conditionalSection struct {
+	include bool
+	line    int // Line the section starts on
+}
+
+// ignoring reports whether the scanner is inside an ignored section
+func (c *conditionalScanner) ignoring() bool {
+	for _, section := range c.open {
+		if !section.include {
+			return true
+		}
+	}
+	return false
+}
+
+// strip returns the text of a line that the open conditional sections include. The
+// include callback decides the keyword of each section started on the line; sections
+// nested in an ignored one are ignored without consulting it.
+func (c *conditionalScanner) strip(line string, lineNumber int, include func(keyword string) bool) string {
+	var result strings.Builder
+	for line != "" {
+		start := strings.Index(line, "<![")
+		end := strings.Index(line, "]]>")
+		if end >= 0 && len(c.open) > 0 && (start < 0 || end < start) {
+			if !c.ignoring() {
+				result.WriteString(line[:end])
+			}
+			result.WriteString(" ")
+			c.open = c.open[:len(c.open)-1]
+			line = line[end+len("]]>"):]
+			continue
+		}
+		if start < 0 {
+			if !c.ignoring() {
+				result.WriteString(line)
+			}
+			break
+		}
+
+		if !c.ignoring() {
+			result.WriteString(line[:start])
+		}
+		result.WriteString(" ")
+		line = line[start+len("<!["):]
+		bracket := strings.Index(line, "[")
+		if bracket < 0 {
+			// The keyword must be on the line that opens the section
+			bracket = len(line)
+		}
+		section := conditionalSection{line: lineNumber}
+		if !c.ignoring() {
+			section.include = include(strings.TrimSpace(line[:bracket]))
+		}
+		c.open = append(c.open, section)
+		line = line[min(bracket+1, len(line)):]
+	}
+	return result.String()
+}
+
+// includesSection resolves the keyword of a conditional section, which may be a parameter
+// entity reference such as %draft; whose value is INCLUDE or IGNORE. Unknown keywords
+// are reported and their sections ignored.
+func (p *DTDParser) includesSection(keyword string, position Position) bool {
+	written := keyword
+	if match := entityReferencePattern.FindStringSubmatch(keyword); match != nil && match[0] == keyword {
+		entity, exists := p.entities[match[1]]
+		if !exists {
+			p.warnAt(position, "conditional section keyword %s refers to an undeclared parameter entity; ignoring the section", keyword)
+			return false
+		}
+		entity.References++
+		keyword = strings.TrimSpace(p.expandParameterEntities(entity.Value, map[string]bool{match[1]: true}))
+	}
+
+	switch keyword {
+	case "INCLUDE":
+		p.trace(position, "conditional section %s: including its declarations", written)
+		return true
+	case "IGNORE":
+		p.trace(position, "conditional section %s: skipping its declarations", written)
+		return false
+	}
+	p.warnAt(position, "conditional section keyword %q is neither INCLUDE nor IGNORE; ignoring the section", keyword)
+	return false
+}
@@ -0,0 +1,213 @@
+//go:build xmllint
+
+// The conformance tests compare dtd-to-go with libxml2's xmllint, the reference
+// implementation, so its semantics track it. They need xmllint installed and run with
+//
+//	go test -tags xmllint -run Xmllint .
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// mockSeeds is the number of random documents generated for every root of a DTD
+const mockSeeds = 5
+
+// lookXmllint returns the path of xmllint, failing the test when it is not installed, as
+// the xmllint build tag asks for it
+func lookXmllint(t *testing.T) string {
+	t.Helper()
+	path, err := exec.LookPath("xmllint")
+	if err != nil {
+		t.Fatalf("the xmllint build tag needs xmllint installed: %v", err)
+	}
+	return path
+}
+
+// xmllintValidate validates a document with xmllint against the DTD its DOCTYPE declares,
+// reporting whether it is valid and the errors xmllint printed
+func xmllintValidate(xmllint, document string) (bool, string) {
+	output, err := exec.Command(xmllint, "--noout", "--valid", document).CombinedOutput()
+	return err == nil, string(output)
+}
+
+// validateAccepts reports whether the validate subcommand finds a document valid against dtd
+func validateAccepts(dtd, document string) bool {
+	return runValidate([]string{"-input", dtd, document}) == 0
+}
+
+// TestValidateMatchesXmllint validates the documents in testdata/documents, and the invalid
+// ones in testdata/documents/invalid, with the validate subcommand and with xmllint --valid
+// against the DTDs their DOCTYPEs declare, and compares the verdicts
+func TestValidateMatchesXmllint(t *testing.T) {
+	xmllint := lookXmllint(t)
+	valid, _ := filepath.Glob("testdata/documents/*.xml")
+	invalid, _ := filepath.Glob("testdata/documents/invalid/*.xml")
+	if len(valid) == 0 || len(invalid) == 0 {
+		t.Fatal("no documents in testdata/documents and testdata/documents/invalid")
+	}
+	for _, document := range append(valid, invalid...) {
+		ours := validateAccepts(document, document)
+		reference, errors := xmllintValidate(xmllint, document)
+		if ours != reference {
+			t.Errorf("%s: validate says valid=%t, xmllint valid=%t:\n%s", document, ours, reference, errors)
+		}
+	}
+}
+
+// TestMockDocumentsMatchXmllint generates the mock server of every DTD in testdata/ that
+// xmllint finds valid itself, writes random documents of each of its roots with Document,
+// and checks that xmllint finds them valid and the validate subcommand agrees. DTDs that
+// are deliberately invalid, such as redeclared.dtd, or that only dtd-to-go reads, such as
+// windows.dtd with its backslash paths, are left out.
+func TestMockDocumentsMatchXmllint(t *testing.T) {
+	xmllint := lookXmllint(t)
+	goCommand, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("the go command is not installed")
+	}
+
+	work := t.TempDir()
+	module := filepath.Join(work, "module")
+	files := map[string]string{"go.mod": "module generated\n\ngo 1.24\n"}
+	dtds := make(map[string]string) // Mock package to the DTD it serves
+	var imports, calls strings.Builder
+	inputs, _ := filepath.Glob("testdata/*.dtd")
+	for _, input := range inputs {
+		if problems := xmllintDTDProblems(t, xmllint, input, work); problems != "" {
+			t.Logf("leaving out %s, which xmllint does not find valid:\n%s", input, problems)
+			continue
+		}
+		result, err := NewDTDParser(ParserOptions{}).ParseFile(input)
+		if err != nil || len(result.Elements) == 0 {
+			continue
+		}
+		pkg := strings.NewReplacer(".", "_", "-", "_").Replace(filepath.Base(input))
+		code, err := (&Generation{Result: result, Format: "mock", PackageName: pkg}).Generate()
+		if err != nil {
+			t.Errorf("generating the mock server of %s: %v", input, err)
+			continue
+		}
+		files[filepath.Join(pkg, "mock.go")] = string(code["schema.go"])
+		dtds[pkg] = input
+		fmt.Fprintf(&imports, "\t%q\n", "generated/"+pkg)
+		fmt.Fprintf(&calls, "\twrite(%q, %s.Roots, %s.Document)\n", pkg, pkg, pkg)
+	}
+	files[filepath.Join("documents", "main.go")] = fmt.Sprintf(`package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+
+%s)
+
+func main() {
+%s}
+
+// write writes %d documents of every root, skipping roots without finite valid content
+func write(pkg string, roots []string, document func(string, *rand.Rand) ([]byte, error)) {
+	for _, root := range roots {
+		for seed := int64(1); seed <= %d; seed++ {
+			data, err := document(root, rand.New(rand.NewSource(seed)))
+			if err != nil {
+				continue
+			}
+			name := fmt.Sprintf("%%s.%%s.%%d.xml", pkg, root, seed)
+			if err := os.WriteFile(filepath.Join(os.Args[1], name), data, 0o644); err != nil {
+				panic(err)
+			}
+		}
+	}
+}
+`, imports.String(), calls.String(), mockSeeds, mockSeeds)
+	for name, content := range files {
+		path := filepath.Join(module, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := filepath.Join(work, "out")
+	if err := os.Mkdir(out, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	command := exec.Command(goCommand, "run", "./documents", out)
+	command.Dir = module
+	if output, err := command.CombinedOutput(); err != nil {
+		t.Fatalf("generating documents: %v\n%s", err, output)
+	}
+
+	documents, _ := filepath.Glob(filepath.Join(out, "*.xml"))
+	if len(documents) == 0 {
+		t.Fatal("no mock documents generated")
+	}
+	for _, document := range documents {
+		// Name the DTD in a DOCTYPE, so xmllint normalizes attribute values by their
+		// declared types and applies the defaults, such as of xmlns: attributes, as it
+		// parses, as for documents in the wild
+		parts := strings.Split(filepath.Base(document), ".")
+		pkg, root := parts[0], strings.Join(parts[1:len(parts)-2], ".")
+		dtd, err := filepath.Abs(dtds[pkg])
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := os.ReadFile(document)
+		if err != nil {
+			t.Fatal(err)
+		}
+		declaration, body, _ := strings.Cut(string(content), "\n")
+		content = []byte(fmt.Sprintf("%s\n<!DOCTYPE %s SYSTEM %q>\n%s", declaration, root, dtd, body))
+		if err := os.WriteFile(document, content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		ours := validateAccepts(dtd, document)
+		reference, errors := xmllintValidate(xmllint, document)
+		switch {
+		case strings.Contains(errors, "namespace error"):
+			// namespaces.dtd uses the dc: prefix without binding it, as DTDs predating
+			// namespaces do, which xmllint rejects as it reads namespaces
+		case ours != reference:
+			t.Errorf("%s: validate says valid=%t, xmllint valid=%t:\n%s\n%s", filepath.Base(document), ours, reference, errors, content)
+		case reference:
+		case strings.Contains(errors, "references an unknown ID"):
+			// Document cannot make a required reference valid in a document without IDs
+		default:
+			t.Errorf("%s: xmllint rejects the mock document of %s:\n%s\n%s", filepath.Base(document), dtd, errors, content)
+		}
+	}
+}
+
+// xmllintDTDProblems validates a stub document against a DTD with xmllint and returns what
+// it reports about the DTD itself, such as redeclared elements or entities it cannot
+// resolve, leaving out that the stub's element is not declared
+func xmllintDTDProblems(t *testing.T, xmllint, dtd, work string) string {
+	t.Helper()
+	path, err := filepath.Abs(dtd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stub := filepath.Join(work, "stub.xml")
+	if err := os.WriteFile(stub, []byte(fmt.Sprintf("<?xml version=\"1.0\"?>\n<!DOCTYPE stub SYSTEM %q>\n<stub/>\n", path)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	output, _ := exec.Command(xmllint, "--noout", "--valid", stub).CombinedOutput()
+	var problems []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if (strings.Contains(line, " error : ") || strings.Contains(line, " warning : ")) && !strings.Contains(line, "element stub") {
+			problems = append(problems, line)
+		}
+	}
+	return strings.Join(problems, "\n")
+}
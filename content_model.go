@@ -0,0 +1,454 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// ContentKind classifies an element's content specification
+type ContentKind int
+
+const (
+	ContentEmpty    ContentKind = iota // EMPTY
+	ContentAny                         // ANY
+	ContentMixed                       // (#PCDATA | a | b)*
+	ContentChildren                    // Element content such as (a, (b | c)+)
+)
+
+// ParticleKind classifies a node of a content model
+type ParticleKind int
+
+const (
+	ParticleElement  ParticleKind = iota // A child element name
+	ParticleSequence                     // (a, b, c)
+	ParticleChoice                       // (a | b | c)
+)
+
+// ContentParticle is a node of a parsed content model
+type ContentParticle struct {
+	Kind      ParticleKind
+	Name      string             // Element name of a ParticleElement
+	Children  []*ContentParticle // Members of a sequence or choice
+	Indicator byte               // Occurrence indicator: 0, '?', '*' or '+'
+}
+
+// ContentModel is the parsed form of an element's content specification
+type ContentModel struct {
+	Kind ContentKind
+	Root *ContentParticle // Nil for EMPTY and ANY; the choice of allowed elements for mixed content
+}
+
+// Unbounded is the maximum occurrence count of children that may repeat without limit
+const Unbounded = -1
+
+// ParseContentModel parses a content specification such as "((a, b) | c)+". Unexpanded
+// parameter entity references are reported as errors.
+func ParseContentModel(content string) (*ContentModel, error) {
+	content = strings.TrimSpace(content)
+	switch content {
+	case "EMPTY":
+		return &ContentModel{Kind: ContentEmpty}, nil
+	case "ANY":
+		return &ContentModel{Kind: ContentAny}, nil
+	}
+
+	p := &contentParser{input: content}
+	model, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("content model %q: %w", content, err)
+	}
+	return model, nil
+}
+
+// contentParser is a recursive descent parser for content specifications
+type contentParser struct {
+	input string
+	pos   int
+}
+
+// parse parses the whole content specification
+func (p *contentParser) parse() (*ContentModel, error) {
+	p.skipSpace()
+	if !strings.HasPrefix(p.input[p.pos:], "(") {
+		// Tolerate a bare element name, which some DTDs use in place of (name)
+		particle, err := p.particle()
+		if err != nil {
+			return nil, err
+		}
+		return p.finish(&ContentModel{Kind: ContentChildren, Root: particle})
+	}
+
+	// Mixed content starts with #PCDATA right after the opening parenthesis
+	start := p.pos
+	p.pos++
+	p.skipSpace()
+	if strings.HasPrefix(p.input[p.pos:], "#PCDATA") {
+		p.pos += len("#PCDATA")
+		return p.mixed()
+	}
+	p.pos = start
+
+	particle, err := p.particle()
+	if err != nil {
+		return nil, err
+	}
+	return p.finish(&ContentModel{Kind: ContentChildren, Root: particle})
+}
+
+// mixed parses the rest of a mixed content model after "(#PCDATA"
+func (p *contentParser) mixed() (*ContentModel, error) {
+	root := &ContentParticle{Kind: ParticleChoice}
+	for {
+		p.skipSpace()
+		switch p.peek() {
+		case '|':
+			p.pos++
+			p.skipSpace()
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			root.Children = append(root.Children, &ContentParticle{Kind: ParticleElement, Name: name})
+		case ')':
+			p.pos++
+			if p.peek() == '*' {
+				p.pos++
+				root.Indicator = '*'
+			} else if len(root.Children) > 0 {
+				return nil, fmt.Errorf("mixed content with elements must end in )*")
+			}
+			return p.finish(&ContentModel{Kind: ContentMixed, Root: root})
+		default:
+			return nil, p.unexpected("'|' or ')'")
+		}
+	}
+}
+
+// particle parses a name or a parenthesized group with its occurrence indicator
+func (p *contentParser) particle() (*ContentParticle, error) {
+	p.skipSpace()
+
+	var particle *ContentParticle
+	if p.peek() == '(' {
+		p.pos++
+		group, err := p.group()
+		if err != nil {
+			return nil, err
+		}
+		particle = group
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		particle = &ContentParticle{Kind: ParticleElement, Name: name}
+	}
+
+	switch c := p.peek(); c {
+	case '?', '*', '+':
+		p.pos++
+		particle.Indicator = c
+	}
+	return particle, nil
+}
+
+// group parses the members of a sequence or choice up to the closing parenthesis
+func (p *contentParser) group() (*ContentParticle, error) {
+	first, err := p.particle()
+	if err != nil {
+		return nil, err
+	}
+	members := []*ContentParticle{first}
+
+	var separator byte
+	for {
+		p.skipSpace()
+		c := p.peek()
+		switch {
+		case c == ')':
+			p.pos++
+			if len(members) == 1 {
+				// A single-member group is a sequence of one, so (a)* keeps its own indicator
+				return &ContentParticle{Kind: ParticleSequence, Children: members}, nil
+			}
+			kind := ParticleSequence
+			if separator == '|' {
+				kind = ParticleChoice
+			}
+			return &ContentParticle{Kind: kind, Children: members}, nil
+		case c == ',' || c == '|':
+			if separator != 0 && c != separator {
+				return nil, fmt.Errorf("mixed ',' and '|' in one group at offset %d", p.pos)
+			}
+			separator = c
+			p.pos++
+			member, err := p.particle()
+			if err != nil {
+				return nil, err
+			}
+			members = append(members, member)
+		default:
+			return nil, p.unexpected("',', '|' or ')'")
+		}
+	}
+}
+
+// name parses an element name
+func (p *contentParser) name() (string, error) {
+	if p.peek() == '%' {
+		return "", fmt.Errorf("unexpanded parameter entity reference at offset %d", p.pos)
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) {
+		r := rune(p.input[p.pos])
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("_:.-", r) && r < 0x80 {
+			break
+		}
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.unexpected("an element name")
+	}
+	return p.input[start:p.pos], nil
+}
+
+// finish checks that nothing follows the content model
+func (p *contentParser) finish(model *ContentModel) (*ContentModel, error) {
+	p.skipSpace()
+	if p.pos < len(p.input) {
+		return nil, p.unexpected("end of content model")
+	}
+	return model, nil
+}
+
+// peek returns the next byte without consuming it, or 0 at the end of input
+func (p *contentParser) peek() byte {
+	if p.pos < len(p.input) {
+		return p.input[p.pos]
+	}
+	return 0
+}
+
+// skipSpace skips whitespace between tokens
+func (p *contentParser) skipSpace() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+// unexpected reports the token found where something else was expected
+func (p *contentParser) unexpected(expected string) error {
+	if p.pos >= len(p.input) {
+		return fmt.Errorf("expected %s, found end of input", expected)
+	}
+	return fmt.Errorf("expected %s at offset %d, found %q", expected, p.pos, p.input[p.pos])
+}
+
+// Occurrences returns how often the named child must and may appear in content
+// following the model. Max is Unbounded for children that can repeat without limit.
+func (m *ContentModel) Occurrences(name string) (min, max int) {
+	if m.Root == nil {
+		return 0, 0
+	}
+	return m.Root.occurrences(name)
+}
+
+// ElementNames returns the distinct element names the model references, in order of
+// first appearance
+func (m *ContentModel) ElementNames() []string {
+	if m.Root == nil {
+		return nil
+	}
+	var names []string
+	seen := make(map[string]bool)
+	var walk func(c *ContentParticle)
+	walk = func(c *ContentParticle) {
+		if c.Kind == ParticleElement && !seen[c.Name] {
+			seen[c.Name] = true
+			names = append(names, c.Name)
+		}
+		for _, child := range c.Children {
+			walk(child)
+		}
+	}
+	walk(m.Root)
+	return names
+}
+
+// occurrences computes the occurrence range of name within the particle
+func (c *ContentParticle) occurrences(name string) (min, max int) {
+	switch c.Kind {
+	case ParticleElement:
+		if c.Name == name {
+			min, max = 1, 1
+		}
+	case ParticleSequence:
+		for _, child := range c.Children {
+			childMin, childMax := child.occurrences(name)
+			min += childMin
+			max = addOccurrences(max, childMax)
+		}
+	case ParticleChoice:
+		for i, child := range c.Children {
+			childMin, childMax := child.occurrences(name)
+			if i == 0 || childMin < min {
+				min = childMin
+			}
+			if max != Unbounded && (childMax == Unbounded || childMax > max) {
+				max = childMax
+			}
+		}
+	}
+
+	switch c.Indicator {
+	case '?':
+		min = 0
+	case '*':
+		min = 0
+		if max != 0 {
+			max = Unbounded
+		}
+	case '+':
+		if max != 0 {
+			max = Unbounded
+		}
+	}
+	return min, max
+}
+
+// addOccurrences adds two maximum occurrence counts, either of which may be Unbounded
+func addOccurrences(a, b int) int {
+	if a == Unbounded || b == Unbounded {
+		return Unbounded
+	}
+	return a + b
+}
+
+// Regexp compiles the content model to a regular expression over the child element
+// names of an element, each followed by a comma ("a,b,b,"). ANY content matches anything.
+func (m *ContentModel) Regexp() string {
+	switch {
+	case m.Kind == ContentAny:
+		return `^(?:[^,]*,)*$`
+	case m.Root == nil || (m.Kind == ContentMixed && len(m.Root.Children) == 0):
+		return `^$`
+	default:
+		return "^" + m.Root.regexp() + "$"
+	}
+}
+
+// regexp compiles the particle to a regular expression fragment
+func (c *ContentParticle) regexp() string {
+	var expr string
+	switch c.Kind {
+	case ParticleElement:
+		expr = "(?:" + regexp.QuoteMeta(c.Name) + ",)"
+	case ParticleSequence:
+		var parts []string
+		for _, child := range c.Children {
+			parts = append(parts, child.regexp())
+		}
+		expr = "(?:" + strings.Join(parts, "") + ")"
+	case ParticleChoice:
+		var parts []string
+		for _, child := range c.Children {
+			parts = append(parts, child.regexp())
+		}
+		expr = "(?:" + strings.Join(parts, "|") + ")"
+	}
+
+	if c.Indicator != 0 {
+		expr += string(c.Indicator)
+	}
+	return expr
+}
+
+// String formats the content model as a DTD content specification
+func (m *ContentModel) String() string {
+	switch m.Kind {
+	case ContentEmpty:
+		return "EMPTY"
+	case ContentAny:
+		return "ANY"
+	case ContentMixed:
+		if m.Root == nil || len(m.Root.Children) == 0 {
+			return "(#PCDATA)"
+		}
+		names := []string{"#PCDATA"}
+		for _, child := range m.Root.Children {
+			names = append(names, child.Name)
+		}
+		return "(" + strings.Join(names, " | ") + ")*"
+	default:
+		if m.Root.Kind == ParticleElement {
+			return "(" + m.Root.String() + ")"
+		}
+		return m.Root.String()
+	}
+}
+
+// String formats the particle with its occurrence indicator
+func (c *ContentParticle) String() string {
+	var expr string
+	switch c.Kind {
+	case ParticleElement:
+		expr = c.Name
+	default:
+		separator := ", "
+		if c.Kind == ParticleChoice {
+			separator = " | "
+		}
+		parts := make([]string, len(c.Children))
+		for i, child := range c.Children {
+			parts[i] = child.String()
+		}
+		expr = "(" + strings.Join(parts, separator) + ")"
+	}
+
+	if c.Indicator != 0 {
+		expr += string(c.Indicator)
+	}
+	return expr
+}
+
+// without returns a copy of the model that only references the elements in keep.
+// Element content left without children becomes EMPTY.
+func (m *ContentModel) without(keep map[string]bool) *ContentModel {
+	if m.Root == nil {
+		return m
+	}
+	root := m.Root.without(keep)
+	if root == nil {
+		if m.Kind == ContentMixed {
+			return &ContentModel{Kind: ContentMixed, Root: &ContentParticle{Kind: ParticleChoice}}
+		}
+		return &ContentModel{Kind: ContentEmpty}
+	}
+	return &ContentModel{Kind: m.Kind, Root: root}
+}
+
+// without returns a copy of the particle without the elements not in keep, or nil when
+// nothing is left
+func (c *ContentParticle) without(keep map[string]bool) *ContentParticle {
+	if c.Kind == ParticleElement {
+		if !keep[c.Name] {
+			return nil
+		}
+		copied := *c
+		return &copied
+	}
+
+	var children []*ContentParticle
+	for _, child := range c.Children {
+		if reduced := child.without(keep); reduced != nil {
+			children = append(children, reduced)
+		}
+	}
+	if len(children) == 0 {
+		return nil
+	}
+	return &ContentParticle{Kind: c.Kind, Children: children, Indicator: c.Indicator}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contentPatternsRuntime looks up the content patterns by element name
+const contentPatternsRuntime = `
+// ContentPattern returns the regular expression MatchContent uses for an element. It
+// matches the child element names each followed by a comma, e.g. "agentID,address,".
+func ContentPattern(element string) string {
+	if pattern, ok := contentPatterns[element]; ok {
+		return pattern.String()
+	}
+	return ""
+}
+`
+
+// generateContentPatterns generates the content model of every element compiled to a
+// regular expression over its child element names, with MatchContent to apply them
+func (g *StructGenerator) generateContentPatterns() string {
+	var builder strings.Builder
+
+	builder.WriteString("\n// contentPatterns holds each element's content model as a regular expression\n")
+	builder.WriteString("var contentPatterns = map[string]*regexp.Regexp{\n")
+	for _, name := range g.elementOrder {
+		element, exists := g.elements[name]
+		if !exists {
+			continue
+		}
+		// Content models with unexpanded entity references cannot be checked
+		model, err := ParseContentModel(element.Content)
+		if err != nil {
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("\t%q: regexp.MustCompile(`%s`),\n", name, model.Regexp()))
+	}
+	builder.WriteString("}\n")
+
+	builder.WriteString("\n// MatchContent reports whether an element's child element names, in document order, are\n")
+	builder.WriteString("// allowed by its content model. Elements the DTD does not declare match anything.\n")
+	builder.WriteString("func MatchContent(element string, children []string) bool {\n")
+	builder.WriteString("\tpattern, ok := contentPatterns[element]\n")
+	builder.WriteString("\tif !ok {\n")
+	builder.WriteString("\t\treturn true\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\tvar sequence strings.Builder\n")
+	builder.WriteString("\tfor _, child := range children {\n")
+	builder.WriteString("\t\tsequence.WriteString(child)\n")
+	builder.WriteString("\t\tsequence.WriteByte(',')\n")
+	builder.WriteString("\t}\n")
+	if g.options.ViolationHooks {
+		builder.WriteString("\tmatched := pattern.MatchString(sequence.String())\n")
+		builder.WriteString("\tif !matched {\n")
+		builder.WriteString("\t\trecordViolation(element, ViolationContentModel)\n")
+		builder.WriteString("\t}\n")
+		builder.WriteString("\treturn matched\n")
+	} else {
+		builder.WriteString("\treturn pattern.MatchString(sequence.String())\n")
+	}
+	builder.WriteString("}\n")
+
+	builder.WriteString(contentPatternsRuntime)
+
+	return builder.String()
+}
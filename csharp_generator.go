@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CSharpGenerator generates XmlSerializer-annotated C# classes from DTD elements
+type CSharpGenerator struct {
+	namespace    string
+	elements     map[string]*DTDElement
+	elementOrder []string
+	names        *StructGenerator // Reused for class naming so all backends agree
+}
+
+// NewCSharpGenerator creates a new C# class generator
+func NewCSharpGenerator(namespace string, elements map[string]*DTDElement, elementOrder []string) *CSharpGenerator {
+	return &CSharpGenerator{
+		namespace:    namespace,
+		elements:     elements,
+		elementOrder: elementOrder,
+		names:        NewStructGenerator("", elements, elementOrder, GeneratorOptions{}),
+	}
+}
+
+// GenerateClasses generates C# source code for all elements
+func (g *CSharpGenerator) GenerateClasses() string {
+	var builder strings.Builder
+
+	builder.WriteString("// <auto-generated>\n")
+	builder.WriteString("// Generated from a DTD by dtd-to-go. Do not edit.\n")
+	builder.WriteString("// </auto-generated>\n\n")
+	builder.WriteString("using System.Collections.Generic;\n")
+	builder.WriteString("using System.ComponentModel;\n")
+	builder.WriteString("using System.Xml;\n")
+	builder.WriteString("using System.Xml.Serialization;\n\n")
+	builder.WriteString(fmt.Sprintf("namespace %s\n{\n", g.namespace))
+
+	for i, model := range BuildElementModels(g.elements, g.elementOrder) {
+		if i > 0 {
+			builder.WriteString("\n")
+		}
+		builder.WriteString(g.generateClass(model))
+	}
+
+	builder.WriteString("}\n")
+
+	return builder.String()
+}
+
+// generateClass generates a C# class for a single element
+func (g *CSharpGenerator) generateClass(model *ElementModel) string {
+	var builder strings.Builder
+
+	className := g.names.toGoStructName(model.Name)
+
+	builder.WriteString(fmt.Sprintf("    /// <summary>Represents the &lt;%s&gt; element.</summary>\n", model.Name))
+	builder.WriteString(fmt.Sprintf("    [XmlRoot(%q)]\n", model.Name))
+	builder.WriteString(fmt.Sprintf("    public class %s\n    {\n", className))
+
+	var members []string
+
+	// Properties whose names collide, such as those of the attribute title and the child
+	// <title>, are numbered apart as in the Go structs
+	var reserved []string
+	if model.AnyContent {
+		reserved = append(reserved, "Content", "ContentText")
+	}
+	if model.HasText {
+		reserved = append(reserved, "Text")
+	}
+	names := model.memberNames(func(name string) string { return g.toPropertyName(name, className) }, reserved...)
+
+	for i, attr := range model.Attributes {
+		propName := names[i]
+		annotation := fmt.Sprintf("[XmlAttribute(%q)]", attr.Name)
+
+		// XmlSerializer writes array-typed attributes as whitespace separated lists
+		if isListAttributeType(attr.Type) {
+			members = append(members, fmt.Sprintf("%s\n        public string[] %s { get; set; }", annotation, propName))
+			continue
+		}
+
+		if attr.DefaultValue != "" {
+			members = append(members, fmt.Sprintf("%s\n        [DefaultValue(%q)]\n        public string %s { get; set; } = %q;", annotation, attr.DefaultValue, propName, attr.DefaultValue))
+		} else {
+			members = append(members, fmt.Sprintf("%s\n        public string %s { get; set; }", annotation, propName))
+		}
+	}
+
+	for i, child := range model.Children {
+		elemType := "string"
+		if !isSimpleElement(g.elements, child.Name) {
+			elemType = g.names.toGoStructName(child.Name)
+		}
+		propName := names[len(model.Attributes)+i]
+		annotation := fmt.Sprintf("[XmlElement(%q)]", child.Name)
+
+		if child.Repeated {
+			members = append(members, fmt.Sprintf("%s\n        public List<%s> %s { get; set; } = new List<%s>();", annotation, elemType, propName, elemType))
+		} else {
+			members = append(members, fmt.Sprintf("%s\n        public %s %s { get; set; }", annotation, elemType, propName))
+		}
+	}
+
+	if model.AnyContent {
+		members = append(members, "[XmlAnyElement]\n        public XmlElement[] Content { get; set; }")
+		members = append(members, "[XmlText]\n        public string[] ContentText { get; set; }")
+	}
+
+	if model.HasText {
+		members = append(members, "[XmlText]\n        public string Text { get; set; }")
+	}
+
+	for i, member := range members {
+		if i > 0 {
+			builder.WriteString("\n")
+		}
+		builder.WriteString(fmt.Sprintf("        %s\n", member))
+	}
+
+	builder.WriteString("    }\n")
+
+	return builder.String()
+}
+
+// toPropertyName converts a DTD element/attribute name to a C# property name, avoiding
+// a clash with the enclosing class name (CS0542)
+func (g *CSharpGenerator) toPropertyName(name, className string) string {
+	propName := g.names.toGoFieldName(name)
+	if propName == className {
+		propName += "Value"
+	}
+	return propName
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// generateDecodeInto generates DecodeInto, which applies a document to an existing
+// document root as a partial update, and a detachLists method for every struct.
+// encoding/xml already leaves absent attributes and elements alone and decodes a child
+// element into the struct an existing pointer holds, but appends repeated children to
+// the existing slices. detachLists sets those slices aside before decoding, so a document
+// holding repeated children replaces the list while lists it leaves out are restored.
+func (g *StructGenerator) generateDecodeInto() string {
+	var builder strings.Builder
+
+	structs := make(map[string]bool) // Go types of the structs that can be updated
+	for _, name := range g.elementOrder {
+		if _, exists := g.elements[name]; exists && !g.isSimpleElement(name) && !g.isInlined(name) {
+			structs[g.toGoStructName(name)] = true
+		}
+	}
+
+	// Without any structs there is no document root to update, and DecodeInto would
+	// have no type to take
+	roots := g.documentRoots()
+	if len(roots) == 0 {
+		return ""
+	}
+	var types []string
+	for _, name := range roots {
+		types = append(types, "*"+g.toGoStructName(name))
+	}
+
+	builder.WriteString("\n// DecodeInto applies a document to an existing document root in place, as a partial\n")
+	builder.WriteString("// update: the attributes and elements the document holds overwrite those in dst and\n")
+	builder.WriteString("// the ones it leaves out keep their values. Elements that occur at most once are updated\n")
+	builder.WriteString("// field by field, while repeated elements the document holds replace the whole list, as\n")
+	builder.WriteString("// their cardinality in the DTD allows. On error dst may be partially updated.\n")
+	builder.WriteString(fmt.Sprintf("func DecodeInto[T interface{ %s }](r io.Reader, dst T, opts ...DecodeOption) error {\n", strings.Join(types, " | ")))
+	builder.WriteString("\tvar restore []func()\n")
+	builder.WriteString("\tvar root string\n")
+	builder.WriteString("\tswitch v := any(dst).(type) {\n")
+	for _, name := range roots {
+		builder.WriteString(fmt.Sprintf("\tcase *%s:\n", g.toGoStructName(name)))
+		builder.WriteString(fmt.Sprintf("\t\troot = %q\n", name))
+		builder.WriteString("\t\tv.detachLists(&restore)\n")
+	}
+	builder.WriteString("\t}\n")
+	builder.WriteString("\terr := decodeDocument(r, root, dst, opts)\n")
+	builder.WriteString("\tfor _, keep := range restore {\n")
+	builder.WriteString("\t\tkeep()\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\treturn err\n")
+	builder.WriteString("}\n")
+
+	for _, name := range g.elementOrder {
+		element, exists := g.elements[name]
+		if !exists || !structs[g.toGoStructName(name)] {
+			continue
+		}
+
+		structName := g.toGoStructName(name)
+		builder.WriteString(fmt.Sprintf("\n// detachLists clears the lists of repeated children of this <%s> and the elements inside\n", name))
+		builder.WriteString("// it, recording how to restore the lists a document leaves out\n")
+		builder.WriteString(fmt.Sprintf("func (v *%s) detachLists(restore *[]func()) {\n", structName))
+		interfaces := g.choiceInterfaces(element)
+		for i, field := range g.structFields(element) {
+			if i < len(element.Attributes) {
+				continue
+			}
+			if code, ok := g.choiceDispatch(interfaces, field, "detachLists(restore)"); ok {
+				builder.WriteString(code)
+				continue
+			}
+			switch {
+			case strings.HasPrefix(field.Type, "[]"):
+				builder.WriteString(fmt.Sprintf("\tif saved := v.%s; saved != nil {\n", field.Name))
+				builder.WriteString(fmt.Sprintf("\t\tv.%s = nil\n", field.Name))
+				builder.WriteString("\t\t*restore = append(*restore, func() {\n")
+				builder.WriteString(fmt.Sprintf("\t\t\tif v.%s == nil {\n", field.Name))
+				builder.WriteString(fmt.Sprintf("\t\t\t\tv.%s = saved\n", field.Name))
+				builder.WriteString("\t\t\t}\n")
+				builder.WriteString("\t\t})\n")
+				builder.WriteString("\t}\n")
+			case strings.HasPrefix(field.Type, "*") && structs[field.Type[1:]]:
+				builder.WriteString(fmt.Sprintf("\tif v.%s != nil {\n", field.Name))
+				builder.WriteString(fmt.Sprintf("\t\tv.%s.detachLists(restore)\n", field.Name))
+				builder.WriteString("\t}\n")
+			case structs[field.Type]:
+				builder.WriteString(fmt.Sprintf("\tv.%s.detachLists(restore)\n", field.Name))
+			}
+		}
+		builder.WriteString("}\n")
+	}
+
+	return builder.String()
+}
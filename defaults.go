@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// attrDefaultsRuntime adds the declared defaults of absent attributes to the start
+// elements decoded by the generated UnmarshalXML methods
+const attrDefaultsRuntime = `
+// withAttrDefaults returns start with the DTD default of every declared attribute it
+// lacks added, as a validating parser reports them
+func withAttrDefaults(start xml.StartElement) xml.StartElement {
+	defaults := attrDefaults[start.Name.Local]
+	if len(defaults) == 0 {
+		return start
+	}
+	attrs := append([]xml.Attr(nil), start.Attr...)
+	for _, def := range defaults {
+		present := false
+		for _, attr := range start.Attr {
+			if attr.Name == def.Name {
+				present = true
+				break
+			}
+		}
+		if !present {
+			attrs = append(attrs, def)
+		}
+	}
+	start.Attr = attrs
+	return start
+}
+`
+
+// defaultedAttributes returns the attributes of an element that have a default value
+// to fill in when they are absent
+func defaultedAttributes(element *DTDElement) []DTDAttribute {
+	var defaulted []DTDAttribute
+	for _, attr := range element.Attributes {
+		if attr.DefaultValue != "" && !attr.Required && !strings.HasPrefix(attr.DefaultValue, "#") {
+			defaulted = append(defaulted, attr)
+		}
+	}
+	return defaulted
+}
+
+// fillsDefaults reports whether an element's struct gets an UnmarshalXML method filling
+// in attribute defaults
+func (g *StructGenerator) fillsDefaults(name string) bool {
+	element, exists := g.elements[name]
+	return g.options.FillDefaults && exists && !g.isSimpleElement(name) && !g.isInlined(name) &&
+		len(defaultedAttributes(element)) > 0
+}
+
+// generateAttrDefaults generates the table of attribute defaults and, unless the case
+// folding UnmarshalXML methods apply them, an UnmarshalXML method for every struct with
+// defaulted attributes
+func (g *StructGenerator) generateAttrDefaults() string {
+	var builder strings.Builder
+
+	builder.WriteString("\n// attrDefaults holds the declared default values of attributes, by element\n")
+	builder.WriteString("var attrDefaults = map[string][]xml.Attr{\n")
+	for _, name := range g.elementOrder {
+		if !g.fillsDefaults(name) {
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("\t%q: {\n", localName(name)))
+		for _, attr := range defaultedAttributes(g.elements[name]) {
+			if space, local := g.xmlName(attr.Name); space != "" {
+				builder.WriteString(fmt.Sprintf("\t\t{Name: xml.Name{Space: %q, Local: %q}, Value: %q},\n", space, local, attr.DefaultValue))
+			} else {
+				builder.WriteString(fmt.Sprintf("\t\t{Name: xml.Name{Local: %q}, Value: %q},\n", local, attr.DefaultValue))
+			}
+		}
+		builder.WriteString("\t},\n")
+	}
+	builder.WriteString("}\n")
+	builder.WriteString(attrDefaultsRuntime)
+
+	if g.options.FoldCase {
+		return builder.String()
+	}
+	for _, name := range g.elementOrder {
+		if !g.fillsDefaults(name) {
+			continue
+		}
+		structName := g.toGoStructName(name)
+		if g.checksFixed(name) {
+			builder.WriteString(fmt.Sprintf("\n// UnmarshalXML decodes <%s>, filling in the DTD defaults of absent attributes and\n", name))
+			builder.WriteString("// rejecting #FIXED attributes with other values\n")
+		} else {
+			builder.WriteString(fmt.Sprintf("\n// UnmarshalXML decodes <%s>, filling in the DTD defaults of absent attributes\n", name))
+		}
+		builder.WriteString(fmt.Sprintf("func (v *%s) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {\n", structName))
+		builder.WriteString(g.plainDecl(name))
+		builder.WriteString("\tstart = withAttrDefaults(start)\n")
+		if g.checksFixed(name) {
+			builder.WriteString(fixedCheckCall)
+		}
+		builder.WriteString(g.decodeReturn(name, "d.DecodeElement("+g.decodeTarget(name)+", &start)"))
+		builder.WriteString("}\n")
+	}
+	return builder.String()
+}
+
+// constructorName returns the name of the constructor of an element's struct, NewX unless
+// another generated type has that name
+func (g *StructGenerator) constructorName(name string) string {
+	taken := make(map[string]bool)
+	for _, other := range g.elementOrder {
+		if _, exists := g.elements[other]; exists && !g.isSimpleElement(other) {
+			taken[g.toGoStructName(other)] = true
+		}
+	}
+	for _, enum := range g.enumTypes() {
+		taken[enum.Name] = true
+	}
+	constructor := "New" + g.toGoStructName(name)
+	for taken[constructor] {
+		constructor += "Defaults"
+	}
+	return constructor
+}
+
+// defaultAssignments returns the statements setting the defaulted attributes of an
+// element's struct to their DTD defaults in the variable v. Attributes of an enum type
+// whose default is not among their values, which the parser warns about, are left unset.
+func (g *StructGenerator) defaultAssignments(name, v string) string {
+	var builder strings.Builder
+	element := g.elements[name]
+	defaulted := make(map[string]bool)
+	for _, attr := range defaultedAttributes(element) {
+		defaulted[attr.Name] = true
+	}
+	fields := g.structFields(element)
+	for i, attr := range element.Attributes {
+		if !defaulted[attr.Name] {
+			continue
+		}
+		field := fields[i]
+		value := fmt.Sprintf("%q", attr.DefaultValue)
+		if mapping := g.typeMapping(name, attr.Name); mapping != nil {
+			value, _ = typedLiteral(mapping.Type, attr.DefaultValue) // Checked by CheckTypeMappings
+		}
+		if g.enumTypeName(element, attr) != "" {
+			enum := g.newEnumType(element, attr)
+			value = ""
+			for j, enumValue := range enum.Values {
+				if enumValue == strings.TrimSpace(attr.DefaultValue) {
+					value = enum.Constants[j]
+				}
+			}
+			if value == "" {
+				continue
+			}
+		}
+		switch {
+		case strings.HasPrefix(field.Type, "*"):
+			builder.WriteString(fmt.Sprintf("\tdefault%s := %s\n", field.Name, value))
+			builder.WriteString(fmt.Sprintf("\t%s.%s = &default%s\n", v, field.Name, field.Name))
+		case isListAttributeType(attr.Type):
+			var tokens []string
+			for _, token := range strings.Fields(attr.DefaultValue) {
+				tokens = append(tokens, fmt.Sprintf("%q", token))
+			}
+			builder.WriteString(fmt.Sprintf("\t%s.%s = %s{%s}\n", v, field.Name, field.Type, strings.Join(tokens, ", ")))
+		default:
+			builder.WriteString(fmt.Sprintf("\t%s.%s = %s\n", v, field.Name, value))
+		}
+	}
+	return builder.String()
+}
+
+// generateConstructors generates a constructor for every struct with defaulted attributes
+// that returns it with those attributes set to their DTD defaults, so documents built in
+// code encode as if the defaults had been filled in
+func (g *StructGenerator) generateConstructors() string {
+	var builder strings.Builder
+
+	for _, name := range g.elementOrder {
+		element, exists := g.elements[name]
+		if !exists || g.isSimpleElement(name) || g.isInlined(name) || len(defaultedAttributes(element)) == 0 {
+			continue
+		}
+		structName := g.toGoStructName(name)
+		constructor := g.constructorName(name)
+		builder.WriteString(fmt.Sprintf("\n// %s returns a new %s with its attributes set to their DTD defaults\n", constructor, structName))
+		builder.WriteString(fmt.Sprintf("func %s() *%s {\n", constructor, structName))
+		builder.WriteString(fmt.Sprintf("\tv := &%s{}\n", structName))
+		builder.WriteString(g.defaultAssignments(name, "v"))
+		builder.WriteString("\treturn v\n")
+		builder.WriteString("}\n")
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// deprecatedTag starts a comment that deprecates the declaration or attribute row it
+// precedes, or the row it trails: <!-- @deprecated use new-price -->
+const deprecatedTag = "@deprecated"
+
+// deprecationMarkerPattern matches the markers standing in for @deprecated comments in
+// assembled declarations
+var deprecationMarkerPattern = regexp.MustCompile(`@deprecated:(\d+)`)
+
+// declarationStartPattern splits a line opening a declaration after its name
+var declarationStartPattern = regexp.MustCompile(`^(<!\w+\s+%?\s*[^\s%]+)\s*(.*)$`)
+
+// deprecationMarkers records the reasons of the @deprecated comments among comments and
+// returns the markers to put in their place, so the tokenizer sees where they appeared.
+// Markers of comments trailing a row are returned separately.
+func (p *DTDParser) deprecationMarkers(comments []dtdComment) (string, string) {
+	var markers, trailing strings.Builder
+	for _, comment := range comments {
+		if !strings.HasPrefix(comment.Text, deprecatedTag) {
+			continue
+		}
+		reason := strings.Join(strings.Fields(strings.TrimPrefix(comment.Text, deprecatedTag)), " ")
+		if reason == "" {
+			reason = "no longer part of the DTD"
+		}
+		marker := fmt.Sprintf("%s:%d ", deprecatedTag, len(p.deprecations))
+		p.deprecations = append(p.deprecations, reason)
+		if comment.Trailing {
+			trailing.WriteString(marker)
+		} else {
+			markers.WriteString(marker)
+		}
+	}
+	return markers.String(), trailing.String()
+}
+
+// insertMarkers places markers before the first row of a line. On a line opening a
+// declaration that is after the declaration keyword and name, so markers in an
+// <!ATTLIST> always precede the attribute they apply to.
+func insertMarkers(line, markers string) string {
+	if markers == "" {
+		return line
+	}
+	if matches := declarationStartPattern.FindStringSubmatch(line); matches != nil {
+		return matches[1] + " " + markers + matches[2]
+	}
+	return markers + line
+}
+
+// deprecationMarker reports the reason behind a marker token
+func (p *DTDParser) deprecationMarker(token string) (string, bool) {
+	matches := deprecationMarkerPattern.FindStringSubmatch(token)
+	if matches == nil || matches[0] != token {
+		return "", false
+	}
+	index, err := strconv.Atoi(matches[1])
+	if err != nil || index >= len(p.deprecations) {
+		return "", false
+	}
+	return p.deprecations[index], true
+}
+
+// takeDeprecation removes the markers from a declaration and returns the reason of the
+// first one, which deprecates the whole declaration
+func (p *DTDParser) takeDeprecation(line string) (string, string) {
+	if !strings.Contains(line, deprecatedTag+":") {
+		return line, ""
+	}
+
+	var reason string
+	for _, marker := range deprecationMarkerPattern.FindAllString(line, -1) {
+		if found, ok := p.deprecationMarker(marker); ok && reason == "" {
+			reason = found
+		}
+	}
+	line = deprecationMarkerPattern.ReplaceAllString(line, "")
+	return strings.Join(strings.Fields(line), " "), reason
+}
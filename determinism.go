@@ -0,0 +1,137 @@
+package main
+
+// glushkov holds the positions of a content model (every occurrence of an element name)
+// with the first and follow sets of its position automaton, as in XML 1.0 Appendix E
+type glushkov struct {
+	names  []string // Element name at each position
+	first  []int
+	follow [][]int
+}
+
+// particleSets are the Glushkov sets of one particle
+type particleSets struct {
+	nullable    bool
+	first, last []int
+}
+
+// Ambiguity checks that the content model is deterministic (XML 1.0 Appendix E). For a
+// non-deterministic model it returns the shortest sequence of child names after which
+// the last name could match more than one particle, e.g. ["a"] for ((a, b) | (a, c)).
+func (m *ContentModel) Ambiguity() ([]string, bool) {
+	if m.Root == nil || m.Kind != ContentChildren {
+		return nil, false
+	}
+
+	g := &glushkov{}
+	root := g.build(m.Root)
+	g.first = root.first
+
+	// Walk the automaton breadth first so the reported prefix is as short as possible
+	type state struct {
+		position int // -1 is the start state
+		prefix   []string
+	}
+	visited := make(map[int]bool)
+	queue := []state{{position: -1}}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		next := g.first
+		if current.position >= 0 {
+			next = g.follow[current.position]
+		}
+
+		seen := make(map[string]bool)
+		for _, position := range next {
+			name := g.names[position]
+			if seen[name] {
+				return append(append([]string(nil), current.prefix...), name), true
+			}
+			seen[name] = true
+		}
+
+		for _, position := range next {
+			if !visited[position] {
+				visited[position] = true
+				prefix := append(append([]string(nil), current.prefix...), g.names[position])
+				queue = append(queue, state{position: position, prefix: prefix})
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// build assigns positions to the particle's element names and computes its sets,
+// extending the follow sets of the automaton
+func (g *glushkov) build(c *ContentParticle) particleSets {
+	var sets particleSets
+
+	switch c.Kind {
+	case ParticleElement:
+		position := len(g.names)
+		g.names = append(g.names, c.Name)
+		g.follow = append(g.follow, nil)
+		sets = particleSets{first: []int{position}, last: []int{position}}
+
+	case ParticleSequence:
+		sets.nullable = true
+		for _, child := range c.Children {
+			childSets := g.build(child)
+			// Whatever can end the sequence so far can be followed by the child
+			for _, position := range sets.last {
+				g.follow[position] = appendUnique(g.follow[position], childSets.first...)
+			}
+			if sets.nullable {
+				sets.first = appendUnique(sets.first, childSets.first...)
+			}
+			if childSets.nullable {
+				sets.last = appendUnique(sets.last, childSets.last...)
+			} else {
+				sets.last = childSets.last
+			}
+			sets.nullable = sets.nullable && childSets.nullable
+		}
+
+	case ParticleChoice:
+		for _, child := range c.Children {
+			childSets := g.build(child)
+			sets.first = appendUnique(sets.first, childSets.first...)
+			sets.last = appendUnique(sets.last, childSets.last...)
+			sets.nullable = sets.nullable || childSets.nullable
+		}
+	}
+
+	switch c.Indicator {
+	case '?':
+		sets.nullable = true
+	case '*', '+':
+		// A repeated particle can start over after any of its last positions
+		for _, position := range sets.last {
+			g.follow[position] = appendUnique(g.follow[position], sets.first...)
+		}
+		if c.Indicator == '*' {
+			sets.nullable = true
+		}
+	}
+
+	return sets
+}
+
+// appendUnique appends the positions not already in list
+func appendUnique(list []int, positions ...int) []int {
+	for _, position := range positions {
+		found := false
+		for _, existing := range list {
+			if existing == position {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, position)
+		}
+	}
+	return list
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// docFileName is the file the package documentation is written to, next to -output
+const docFileName = "doc.go"
+
+// docInfo is what the package documentation says about where the code came from
+type docInfo struct {
+	Source   string   // Base name of the DTD file
+	Entities int      // Number of entity declarations
+	Flags    []string // Generation options as given on the command line
+}
+
+// GenerateDoc generates a doc.go summarizing the schema behind the generated package,
+// so documentation sites do not show an empty package page
+func (g *StructGenerator) GenerateDoc(info docInfo) string {
+	var builder strings.Builder
+
+	structs, attributes := 0, 0
+	for _, name := range g.elementOrder {
+		element, exists := g.elements[name]
+		if !exists {
+			continue
+		}
+		attributes += len(element.Attributes)
+		if !g.isSimpleElement(name) && !g.isInlined(name) {
+			structs++
+		}
+	}
+
+	builder.WriteString(fmt.Sprintf("// Package %s holds the Go types for the XML documents described by %s,\n", g.packageName, info.Source))
+	builder.WriteString("// generated by dtd-to-go.\n")
+	builder.WriteString("//\n")
+	builder.WriteString("// Schema summary:\n")
+	builder.WriteString("//\n")
+
+	roots := g.documentRoots()
+	for i, root := range roots {
+		roots[i] = fmt.Sprintf("<%s> (%s)", root, g.toGoStructName(root))
+	}
+	if len(roots) == 1 {
+		builder.WriteString(fmt.Sprintf("//   - Root element: %s\n", roots[0]))
+	} else if len(roots) > 1 {
+		builder.WriteString(fmt.Sprintf("//   - Root elements: %s\n", strings.Join(roots, ", ")))
+	}
+
+	builder.WriteString(fmt.Sprintf("//   - Elements: %d (%d generated as structs)\n", len(g.elementOrder), structs))
+	builder.WriteString(fmt.Sprintf("//   - Attributes: %d\n", attributes))
+	builder.WriteString(fmt.Sprintf("//   - Entities: %d\n", info.Entities))
+	if len(info.Flags) > 0 {
+		builder.WriteString(fmt.Sprintf("//   - Generation options: %s\n", strings.Join(info.Flags, " ")))
+	}
+	if len(g.options.Roots) > 0 {
+		builder.WriteString(g.documentSections())
+	}
+	builder.WriteString(fmt.Sprintf("package %s\n", g.packageName))
+
+	return builder.String()
+}
+
+// documentSections describes the document types selected with -root, each with its Parse
+// and WriteXML helpers and the types it uses, and the types they share
+func (g *StructGenerator) documentSections() string {
+	var builder strings.Builder
+
+	builder.WriteString("//\n")
+	builder.WriteString("// # Documents\n")
+
+	uses := make(map[string]int)
+	for _, root := range g.options.Roots {
+		structName := g.toGoStructName(root)
+		text := fmt.Sprintf("<%s> documents are read with Parse%s and written with %s.WriteXML.", root, structName, structName)
+		if g.options.TinyGo {
+			text = fmt.Sprintf("<%s> documents are read with Parse%s and written with %s.WriteXML or AppendXML.", root, structName, structName)
+		}
+		var others []string
+		for _, typeName := range g.rootTypes(root) {
+			uses[typeName]++
+			if typeName != structName {
+				others = append(others, typeName)
+			}
+		}
+		if len(others) > 0 {
+			text += fmt.Sprintf(" They hold %s.", strings.Join(others, ", "))
+		}
+		builder.WriteString("//\n")
+		for _, line := range wrapComment("", text, 80) {
+			builder.WriteString(line + "\n")
+		}
+	}
+
+	var shared []string
+	for _, name := range g.elementOrder {
+		if _, exists := g.elements[name]; exists && uses[g.toGoStructName(name)] > 1 {
+			shared = append(shared, g.toGoStructName(name))
+		}
+	}
+	if len(shared) > 0 {
+		builder.WriteString("//\n")
+		for _, line := range wrapComment("", fmt.Sprintf("The documents share the types %s.", strings.Join(shared, ", ")), 80) {
+			builder.WriteString(line + "\n")
+		}
+	}
+
+	return builder.String()
+}
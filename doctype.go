@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// doctypeDeclaration is the <!DOCTYPE> of an XML document or wrapped DTD
+type doctypeDeclaration struct {
+	Root     string   // Name of the document element
+	SystemID string   // External subset, if the DOCTYPE references one
+	PublicID string   // Public identifier of the external subset
+	Position Position // Where the DOCTYPE starts
+}
+
+// doctypePattern matches the part of a DOCTYPE before its internal subset, such as
+// <!DOCTYPE catalog SYSTEM "catalog.dtd" or <!DOCTYPE catalog PUBLIC "-//Acme//Catalog//EN"
+// "catalog.dtd", with the same identifier groups as externalEntityPattern
+var doctypePattern = regexp.MustCompile(`^<!DOCTYPE\s+([\w\p{L}\p{M}.:\x{B7}-]+)(?:\s+(?:SYSTEM\s+("[^"]*"|'[^']*')|PUBLIC\s+("[^"]*"|'[^']*')\s+("[^"]*"|'[^']*')))?\s*$`)
+
+// parseDoctype parses the text of a DOCTYPE up to its internal subset. A DOCTYPE it
+// cannot make sense of is reported and treated as having no external subset.
+func (p *DTDParser) parseDoctype(header string, position Position) doctypeDeclaration {
+	doctype := doctypeDeclaration{Position: position}
+	matches := doctypePattern.FindStringSubmatch(strings.TrimSpace(header))
+	if matches == nil {
+		p.warnAt(position, "malformed DOCTYPE %s; ignoring its external subset", strings.Join(strings.Fields(header), " "))
+		return doctype
+	}
+	doctype.Root, doctype.SystemID = matches[1], unquote(matches[2])
+	if matches[3] != "" {
+		doctype.PublicID, doctype.SystemID = unquote(matches[3]), unquote(matches[4])
+	}
+	return doctype
+}
+
+// includeExternalSubset parses the external subset a DOCTYPE references, resolved
+// relative to the file holding the DOCTYPE, after its internal subset
+func (p *DTDParser) includeExternalSubset(doctype doctypeDeclaration, from string) error {
+	if strings.Contains(doctype.SystemID, "://") {
+		return &EntityError{Position: doctype.Position, Message: fmt.Sprintf("DOCTYPE refers to %s; only local files are resolved", doctype.SystemID)}
+	}
+	path := p.resolvePath(doctype.SystemID, from)
+	for _, open := range p.including {
+		if open == path {
+			return &EntityError{Position: doctype.Position, Message: fmt.Sprintf("DOCTYPE includes %s recursively", path)}
+		}
+	}
+	p.trace(doctype.Position, "including %s, the external subset of DOCTYPE %s", path, doctype.Root)
+	if err := p.parseFile(path); err != nil {
+		return &EntityError{Position: doctype.Position, Message: "failed to read the external subset of DOCTYPE " + doctype.Root, Err: err}
+	}
+	return nil
+}
@@ -3,16 +3,36 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"unicode/utf8"
 )
 
 // DTDElement represents an element definition in a DTD
 type DTDElement struct {
 	Name       string
+	Prefix     string // Namespace prefix of a qualified name such as xhtml:body, if any
+	Local      string // Name without its prefix
 	Content    string
 	Attributes []DTDAttribute
+	Position   Position // Where the element was declared
+	Deprecated string   // Reason from a <!-- @deprecated ... --> comment, if any
+}
+
+// DTDEntity represents a parameter or general entity declaration in a DTD
+type DTDEntity struct {
+	Name       string
+	Value      string
+	General    bool     // Declared without %, for use in documents and attribute values
+	SystemID   string   // File of an external entity (<!ENTITY % name SYSTEM "file.dtd">)
+	PublicID   string   // Public identifier of an external entity declared PUBLIC
+	Position   Position // Where the entity was declared
+	References int      // Number of times the entity was expanded in an ATTLIST or default value, or included
 }
 
 // DTDAttribute represents an attribute definition in a DTD
@@ -21,80 +41,637 @@ type DTDAttribute struct {
 	Type         string
 	DefaultValue string
 	Required     bool
+	Fixed        bool     // Declared #FIXED: DefaultValue is the only value the attribute may have
+	Values       []string // Allowed values of an enumerated type such as (yes | no), or the notations of a NOTATION attribute
+	Deprecated   string   // Reason from a <!-- @deprecated ... --> comment, if any
+	Position     Position // Where the attribute was declared
+}
+
+// Position identifies a declaration in a DTD file
+type Position struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// String formats the position as file:line
+func (p Position) String() string {
+	return fmt.Sprintf("%s:%d", p.File, p.Line)
+}
+
+// ParseWarning describes a questionable construct found while parsing
+type ParseWarning struct {
+	Position Position
+	Message  string
+}
+
+// String formats the warning with its position
+func (w ParseWarning) String() string {
+	return fmt.Sprintf("%s: %s", w.Position, w.Message)
 }
 
 // ParseResult contains the result of DTD parsing
 type ParseResult struct {
-	Elements map[string]*DTDElement
-	Order    []string
+	Elements  map[string]*DTDElement
+	Order     []string
+	Entities  map[string]*DTDEntity   // Parameter entities by name
+	General   map[string]*DTDEntity   // General entities by name
+	Notations map[string]*DTDNotation // Notations by name
+	Warnings  []ParseWarning
+	Files     []string // The DTD file and every file it includes, in the order they were read
+
+	// LintDisabled holds the lint rules silenced by dtd-to-go:disable comments, by the
+	// position of the declaration they apply to ("*" for all rules)
+	LintDisabled map[Position][]string
+}
+
+// ParserOptions controls how strictly DTDs are parsed
+type ParserOptions struct {
+	Strict        bool                   // Treat warnings such as conflicting attribute declarations as errors
+	OnDeclaration func(DeclarationEvent) // Called for every declaration as soon as it is parsed
+
+	// Streaming only reports element and attribute declarations to OnDeclaration instead
+	// of collecting them, so memory use does not grow with the size of the DTD. ParseFile
+	// then returns no elements, and redeclared elements and attributes are reported again
+	// rather than detected.
+	Streaming bool
+
+	// DuplicateElements decides which declaration of an element declared more than once
+	// is used: DuplicateFirst (the default), DuplicateLast or DuplicateError
+	DuplicateElements string
+
+	// FS is the file system DTDs and the files they include are read from, such as an
+	// embed.FS, a zip.Reader or an fstest.MapFS, with paths as fs.FS names them. Nil
+	// reads from the operating system.
+	FS fs.FS
+
+	// Trace, if set, receives a line with its position for every declaration recognized,
+	// entity expanded, file included, warning and construct skipped, such as comments,
+	// ignored conditional sections and redeclarations, for diagnosing missing output
+	Trace io.Writer
 }
 
+// Policies for elements declared more than once, selectable with
+// ParserOptions.DuplicateElements
+const (
+	DuplicateFirst = "first" // Keep the first declaration, as for entities and attributes
+	DuplicateLast  = "last"  // Let every declaration replace the previous one
+	DuplicateError = "error" // Fail on every redeclaration
+)
+
+// maxDeclarationSize bounds the text of one declaration, and so the memory spent on
+// assembling it, such as when the > of a declaration is missing
+const maxDeclarationSize = 1 << 20
+
+// elementPattern matches <!ELEMENT name content>, with hyphenated, dotted and namespace
+// prefixed element names
+var elementPattern = regexp.MustCompile(`<!ELEMENT\s+([\w\p{L}\p{M}.:\x{B7}-]+)\s+(.+?)>`)
+
 // DTDParser handles parsing of DTD files
 type DTDParser struct {
 	elements     map[string]*DTDElement
 	attributes   map[string][]DTDAttribute
-	elementOrder []string          // Track the order of element declarations
-	entities     map[string]string // Store parameter entity definitions
+	elementOrder []string              // Track the order of element declarations
+	entities     map[string]*DTDEntity // Store parameter entity definitions
+	general      map[string]*DTDEntity // General entity definitions
+	notations    map[string]*DTDNotation
+	options      ParserOptions
+	position     Position // Position of the declaration being parsed
+	column       int      // Column the declaration being parsed starts at
+	declaration  string   // Text of the declaration being parsed, for errors
+	warnings     []ParseWarning
+	errors       ParseErrors
+	deprecations []string // Reasons of the @deprecated comments, indexed by marker
+	including    []string // Files being parsed, outermost first, to detect include cycles
+	files        []string // Every file read, in the order they were opened
+	lintDisabled map[Position][]string
+	duplicates   map[string][]Position // Declarations of the elements declared more than once
 }
 
 // NewDTDParser creates a new DTD parser
-func NewDTDParser() *DTDParser {
+func NewDTDParser(options ParserOptions) *DTDParser {
 	return &DTDParser{
 		elements:     make(map[string]*DTDElement),
 		attributes:   make(map[string][]DTDAttribute),
 		elementOrder: make([]string, 0),
-		entities:     make(map[string]string),
+		entities:     make(map[string]*DTDEntity),
+		general:      make(map[string]*DTDEntity),
+		notations:    make(map[string]*DTDNotation),
+		lintDisabled: make(map[Position][]string),
+		duplicates:   make(map[string][]Position),
+		options:      options,
 	}
 }
 
-// ParseFile parses a DTD file and returns the elements with their order
+// ParseFile parses a DTD file and returns the elements with their order. External
+// parameter entities referenced by the file are parsed in place. Declarations that
+// cannot be parsed are returned together as ParseErrors. Files are read from
+// ParserOptions.FS when set.
 func (p *DTDParser) ParseFile(filename string) (*ParseResult, error) {
-	file, err := os.Open(filename)
+	if err := p.parseFile(filename); err != nil {
+		return nil, err
+	}
+	if len(p.errors) > 0 {
+		return nil, p.errors
+	}
+	p.checkNotations()
+	p.reportDuplicates()
+	p.traceUnused()
+
+	// Associate attributes with their elements
+	for elementName, attrs := range p.attributes {
+		if element, exists := p.elements[elementName]; exists {
+			element.Attributes = attrs
+		}
+	}
+
+	if p.options.Strict && len(p.warnings) > 0 {
+		messages := make([]string, len(p.warnings))
+		for i, warning := range p.warnings {
+			messages[i] = warning.String()
+		}
+		return nil, fmt.Errorf("strict mode: %s", strings.Join(messages, "; "))
+	}
+
+	return &ParseResult{
+		Elements:  p.elements,
+		Order:     p.elementOrder,
+		Entities:  p.entities,
+		General:   p.general,
+		Notations: p.notations,
+		Warnings:  p.warnings,
+		Files:     p.files,
+
+		LintDisabled: p.lintDisabled,
+	}, nil
+}
+
+// parseFile reads the declarations of one DTD file
+func (p *DTDParser) parseFile(filename string) error {
+	file, err := p.open(filename)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %v", err)
+		return fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
+	p.including = append(p.including, p.cleanPath(filename))
+	p.files = append(p.files, p.cleanPath(filename))
+	defer func() { p.including = p.including[:len(p.including)-1] }()
+
+	content, err := utf8Reader(file)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	scanner := bufio.NewScanner(content)
+	scanner.Buffer(nil, maxDeclarationSize)
+	scanner.Split(scanLines)
 	var currentLine strings.Builder
+	lineNumber := 0
+	inDoctype := false
+	var pendingDisabled []string // Lint rules disabled for the next declaration
+	var declarations declarationScanner
+	var conditions conditionalScanner
+	pendingMarkers := ""                // Deprecation markers waiting for the next declaration row
+	lastRow := 0                        // Offset of the last row appended to currentLine
+	start, startColumn := Position{}, 0 // Where the declaration in currentLine starts
+	var doctype doctypeDeclaration      // DOCTYPE whose internal subset is being parsed
+	oversized := false                  // Skipping the rest of a declaration that grew too long
 
+lines:
 	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+		lineNumber++
+		// Comments may trail a declaration row or sit between the rows of one
+		// declaration, so they are removed before rows are assembled
+		text, comments := declarations.scan(scanner.Text())
+		for _, comment := range comments {
+			p.trace(Position{File: filename, Line: lineNumber}, "skipped comment %s", traceShort(comment.Text))
+		}
+		text = conditions.strip(text, lineNumber, func(keyword string) bool {
+			return p.includesSection(keyword, Position{File: filename, Line: lineNumber})
+		})
+		markers, trailingMarkers := p.deprecationMarkers(comments)
+		if trailingMarkers != "" && strings.TrimSpace(withoutBoundaries(text)) == "" && currentLine.Len() > 0 {
+			// A comment trailing an earlier row that ended on this line still applies to that row
+			assembled := currentLine.String()
+			currentLine.Reset()
+			currentLine.WriteString(assembled[:lastRow] + insertMarkers(assembled[lastRow:], trailingMarkers))
+		} else {
+			markers = trailingMarkers + markers
+		}
 
-		// Skip comments and empty lines
-		if line == "" || strings.HasPrefix(line, "<!--") {
-			continue
+		// dtd-to-go:disable comments apply to the declaration they precede, trail or sit in
+		pendingDisabled = append(pendingDisabled, lintDirectives(comments)...)
+		if currentLine.Len() > 0 && len(pendingDisabled) > 0 {
+			p.disableLint(pendingDisabled)
+			pendingDisabled = nil
 		}
 
-		currentLine.WriteString(line)
-		currentLine.WriteString(" ")
+		// A line may hold several declarations or the end of one and the start of the next;
+		// each piece is a row of its own
+		searched := 0 // Offset in the raw line up to which rows have been located
+		for _, segment := range declarationSegments(text) {
+			line := strings.TrimSpace(segment.Text)
+			if oversized && !segment.Opens {
+				oversized = !segment.Closes
+				continue
+			}
+			oversized = false
 
-		// Check if we have a complete declaration
-		if strings.HasSuffix(line, ">") && (strings.Contains(currentLine.String(), "<!ELEMENT") ||
-			strings.Contains(currentLine.String(), "<!ATTLIST") ||
-			strings.Contains(currentLine.String(), "<!ENTITY")) {
+			// Skip XML and text declarations
+			if strings.HasPrefix(line, "<?") {
+				p.trace(Position{File: filename, Line: lineNumber}, "skipped processing instruction %s", traceShort(line))
+				continue
+			}
 
-			completeLine := strings.TrimSpace(currentLine.String())
-			p.parseLine(completeLine)
-			currentLine.Reset()
+			// Unwrap DTDs shipped inside a <!DOCTYPE name [ ... ]> wrapper, such as XML
+			// documents, by parsing the bracketed internal subset as if it were the whole file
+			if !inDoctype && (strings.HasPrefix(line, "<!DOCTYPE") || strings.HasPrefix(currentLine.String(), "<!DOCTYPE")) {
+				if open := strings.Index(line, "["); open >= 0 {
+					if currentLine.Len() == 0 {
+						start = Position{File: filename, Line: lineNumber}
+					}
+					doctype = p.parseDoctype(currentLine.String()+line[:open], start)
+					p.trace(start, "DOCTYPE %s: parsing its internal subset", doctype.Root)
+					currentLine.Reset()
+					inDoctype = true
+					line = strings.TrimSpace(line[open+1:])
+				}
+			}
+			// Anything after the subset is document content, not declarations
+			doctypeClosed := inDoctype && (line == "]" || strings.HasSuffix(line, "]>"))
+			if doctypeClosed {
+				line = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(line, ">"), "]"))
+			}
+
+			// Skip rows that held only comments or whitespace
+			if line == "" {
+				pendingMarkers += markers
+				markers = ""
+				if doctypeClosed {
+					p.trace(Position{File: filename, Line: lineNumber}, "end of the DOCTYPE internal subset; skipping the document content after it")
+					break lines
+				}
+				continue
+			}
+
+			// Pull in external parameter entities referenced between declarations
+			if names, ok := entityReferencesOnly(line); ok && currentLine.Len() == 0 {
+				pendingMarkers += markers
+				markers = ""
+				p.position = Position{File: filename, Line: lineNumber}
+				for _, name := range names {
+					if err := p.includeEntity(name, filename); err != nil {
+						return err
+					}
+				}
+				if doctypeClosed {
+					p.trace(Position{File: filename, Line: lineNumber}, "end of the DOCTYPE internal subset; skipping the document content after it")
+					break lines
+				}
+				continue
+			}
+
+			// A row opening a declaration means the one being assembled was never closed
+			if currentLine.Len() > 0 && segment.Opens {
+				p.failUnterminated(start, startColumn, currentLine.String())
+				currentLine.Reset()
+			}
+			row := line
+
+			line = insertMarkers(line, pendingMarkers+markers)
+			pendingMarkers, markers = "", ""
+
+			// Remember where the declaration being assembled starts
+			offset := rowOffset(scanner.Text(), searched, row)
+			searched = offset + 1
+			if currentLine.Len() == 0 {
+				start, startColumn = Position{File: filename, Line: lineNumber}, utf8.RuneCountInString(scanner.Text()[:offset])+1
+				p.position = start
+				p.disableLint(pendingDisabled)
+				pendingDisabled = nil
+			}
+
+			lastRow = currentLine.Len()
+			currentLine.WriteString(line)
+			currentLine.WriteString(" ")
+			if currentLine.Len() > maxDeclarationSize {
+				p.failAt(start, startColumn, "", "declaration is longer than %d bytes; is its > missing?", maxDeclarationSize)
+				currentLine.Reset()
+				oversized = !segment.Closes
+			} else if segment.Closes {
+				// The declaration is complete
+				completeLine := strings.TrimSpace(currentLine.String())
+				currentLine.Reset()
+				// A DOCTYPE without an internal subset, as XML documents referencing their
+				// DTD have, declares nothing itself; everything after it is document content
+				if strings.HasPrefix(completeLine, "<!DOCTYPE") {
+					doctype = p.parseDoctype(strings.TrimSuffix(completeLine, ">"), start)
+					p.trace(start, "DOCTYPE %s without an internal subset; skipping the document content after it", doctype.Root)
+					break lines
+				}
+				p.position, p.column, p.declaration = start, startColumn, completeLine
+				p.parseLine(completeLine)
+			}
+
+			if doctypeClosed {
+				p.trace(Position{File: filename, Line: lineNumber}, "end of the DOCTYPE internal subset; skipping the document content after it")
+				break lines
+			}
 		}
+		pendingMarkers += markers
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading file: %v", err)
+		return fmt.Errorf("error reading file: %w", err)
+	}
+	if currentLine.Len() > 0 {
+		p.failUnterminated(start, startColumn, currentLine.String())
+	}
+	// The external subset named by the DOCTYPE is read after the internal one, whose
+	// declarations therefore take precedence
+	if doctype.SystemID != "" {
+		if err := p.includeExternalSubset(doctype, filename); err != nil {
+			return err
+		}
+	}
+	for _, section := range conditions.open {
+		p.warnAt(Position{File: filename, Line: section.line}, "conditional section is not closed")
 	}
+	return nil
+}
 
-	// Associate attributes with their elements
-	for elementName, attrs := range p.attributes {
-		if element, exists := p.elements[elementName]; exists {
-			element.Attributes = attrs
+// includeEntity parses the declarations of an external parameter entity in place of its
+// %name; reference, resolving the system identifier relative to the referencing file
+func (p *DTDParser) includeEntity(name, from string) error {
+	entity, exists := p.entities[name]
+	if !exists {
+		p.warn("reference to undeclared parameter entity %%%s;", name)
+		return nil
+	}
+	entity.References++
+	if entity.SystemID == "" {
+		p.trace(p.position, "skipped reference %%%s; between declarations: internal parameter entities are only expanded inside declarations", name)
+		return nil
+	}
+
+	position := p.position
+	if strings.Contains(entity.SystemID, "://") {
+		return &EntityError{Position: position, Message: fmt.Sprintf("parameter entity %%%s; refers to %s; only local files are resolved", name, entity.SystemID)}
+	}
+	path := p.resolvePath(entity.SystemID, from)
+	for _, open := range p.including {
+		if open == path {
+			return &EntityError{Position: position, Message: fmt.Sprintf("parameter entity %%%s; includes %s recursively", name, path)}
 		}
 	}
+	p.trace(position, "including %s for parameter entity %%%s;", path, name)
+	if err := p.parseFile(path); err != nil {
+		return &EntityError{Position: position, Message: fmt.Sprintf("failed to include parameter entity %%%s;", name), Err: err}
+	}
+	return nil
+}
 
-	return &ParseResult{
-		Elements: p.elements,
-		Order:    p.elementOrder,
-	}, nil
+// maxEntityDepth bounds how deeply parameter entities may be nested in one another
+const maxEntityDepth = 32
+
+// expandParameterEntities replaces the references to internal parameter entities in a
+// declaration with their values, padded with a space on each side as XML 1.0 requires.
+// Entities referenced by the values are expanded in turn. References that cannot be
+// expanded are reported and kept as written; an expansion growing past the size of a
+// declaration is reported and the text returned unexpanded.
+func (p *DTDParser) expandParameterEntities(text string, expanding map[string]bool) string {
+	tooLong := false
+	expanded := p.expandNestedEntities(text, expanding, &tooLong)
+	if tooLong {
+		p.fail("expanding the parameter entities makes the declaration longer than %d bytes", maxDeclarationSize)
+		return text
+	}
+	return expanded
+}
+
+// expandNestedEntities expands the references in text, with the entities being expanded
+// around it in expanding. It gives up once the expansion grows too long.
+func (p *DTDParser) expandNestedEntities(text string, expanding map[string]bool, tooLong *bool) string {
+	expanded := entityReferencePattern.ReplaceAllStringFunc(text, func(reference string) string {
+		if *tooLong {
+			return reference
+		}
+		name := reference[1 : len(reference)-1]
+		entity, exists := p.entities[name]
+		switch {
+		case !exists:
+			p.warn("reference to undeclared parameter entity %s", reference)
+			return reference
+		case entity.SystemID != "":
+			p.warn("external parameter entity %s cannot be used inside a declaration", reference)
+			return reference
+		case expanding[name]:
+			p.warn("parameter entity %s references itself", reference)
+			return reference
+		case len(expanding) >= maxEntityDepth:
+			p.warn("parameter entity %s is nested more than %d entities deep; not expanding it", reference, maxEntityDepth)
+			return reference
+		}
+		entity.References++
+		p.trace(p.position, "expanded %s to %s", reference, traceShort(entity.Value))
+
+		nested := map[string]bool{name: true}
+		for open := range expanding {
+			nested[open] = true
+		}
+		return " " + p.expandNestedEntities(entity.Value, nested, tooLong) + " "
+	})
+	if len(expanded) > maxDeclarationSize {
+		*tooLong = true
+	}
+	return expanded
+}
+
+// open opens a DTD file on the parser's file system
+func (p *DTDParser) open(name string) (io.ReadCloser, error) {
+	if p.options.FS != nil {
+		return p.options.FS.Open(name)
+	}
+	return os.Open(name)
+}
+
+// cleanPath returns the shortest name of a file on the parser's file system
+func (p *DTDParser) cleanPath(name string) string {
+	if p.options.FS != nil {
+		return path.Clean(name)
+	}
+	return filepath.Clean(name)
+}
+
+// resolvePath resolves a system identifier on the parser's file system, relative to the
+// file referencing it. On an fs.FS absolute identifiers start from its root, and ones
+// leaving it fail to open.
+func (p *DTDParser) resolvePath(systemID, from string) string {
+	if p.options.FS == nil {
+		return localPath(systemID, from)
+	}
+	name := strings.ReplaceAll(systemID, `\`, "/")
+	if strings.HasPrefix(name, "/") {
+		return path.Clean(name[1:])
+	}
+	return path.Join(path.Dir(from), name)
+}
+
+// localPath resolves a system identifier naming a local file relative to the file
+// referencing it. System identifiers separate directories with /, but DTDs written on
+// Windows often use \, so either is accepted on every platform.
+func localPath(systemID, from string) string {
+	path := filepath.FromSlash(strings.ReplaceAll(systemID, `\`, "/"))
+	if filepath.IsAbs(path) || filepath.VolumeName(path) != "" {
+		return path
+	}
+	return filepath.Join(filepath.Dir(from), path)
+}
+
+// entityReferencesOnly returns the names referenced by a line holding nothing but
+// parameter entity references, e.g. "%common; %links;"
+func entityReferencesOnly(line string) ([]string, bool) {
+	if strings.TrimSpace(entityReferencePattern.ReplaceAllString(line, "")) != "" {
+		return nil, false
+	}
+	var names []string
+	for _, match := range entityReferencePattern.FindAllStringSubmatch(line, -1) {
+		names = append(names, match[1])
+	}
+	return names, len(names) > 0
+}
+
+// declarationScanner removes <!-- ... --> comments from the lines of a DTD and marks
+// where declarations start and end, carrying unterminated comments, declarations and
+// quoted literals over to the following lines. Declarations are thus delimited by their
+// markup rather than by line breaks: a "<!--" or ">" inside a quoted attribute default
+// neither starts a comment nor ends the declaration.
+type declarationScanner struct {
+	inComment     bool
+	trailing      bool // The open comment started after declaration text on its line
+	text          strings.Builder
+	inDeclaration bool // Inside <! ... >, outside comments
+	doctype       bool // The open declaration is a <!DOCTYPE, whose internal subset ends it
+	quote         byte // Quote character of the open literal inside a declaration, if any
+}
+
+// Boundaries the declarationScanner inserts into the text of a line
+const (
+	declarationOpens  = "\x01" // Before the <! of a declaration
+	declarationCloses = "\x00" // After the > of a declaration
+)
+
+// dtdComment is a comment removed by the declarationScanner
+type dtdComment struct {
+	Text     string
+	Trailing bool // Started after declaration text on its first line
+}
+
+// scan removes the comments from a line, marks the declaration boundaries on it and
+// returns the comments completed on it
+func (c *declarationScanner) scan(line string) (string, []dtdComment) {
+	var result strings.Builder
+	var comments []dtdComment
+	for i := 0; i < len(line); {
+		rest := line[i:]
+		switch {
+		case c.inComment:
+			end := strings.Index(rest, "-->")
+			if end < 0 {
+				c.text.WriteString(rest)
+				c.text.WriteString(" ")
+				return result.String(), comments
+			}
+			c.text.WriteString(rest[:end])
+			comments = append(comments, dtdComment{Text: strings.TrimSpace(c.text.String()), Trailing: c.trailing})
+			c.text.Reset()
+			c.inComment = false
+			result.WriteString(" ")
+			i += end + len("-->")
+		case c.quote != 0:
+			if line[i] == c.quote {
+				c.quote = 0
+			}
+			result.WriteByte(line[i])
+			i++
+		case strings.HasPrefix(rest, "<!--"):
+			c.inComment = true
+			c.trailing = strings.TrimSpace(withoutBoundaries(result.String())) != ""
+			i += len("<!--")
+		case c.inDeclaration && !strings.HasPrefix(rest, "<!"):
+			switch line[i] {
+			case '"', '\'':
+				c.quote = line[i]
+			case '[':
+				c.inDeclaration = !c.doctype
+			}
+			result.WriteByte(line[i])
+			if line[i] == '>' {
+				c.inDeclaration = false
+				result.WriteString(declarationCloses)
+			}
+			i++
+		case strings.HasPrefix(rest, "<!") && !strings.HasPrefix(rest, "<!["):
+			// Also ends an open declaration that is missing its >
+			c.inDeclaration = true
+			c.doctype = strings.HasPrefix(rest, "<!DOCTYPE")
+			result.WriteString(declarationOpens + "<!")
+			i += len("<!")
+		default:
+			result.WriteByte(line[i])
+			i++
+		}
+	}
+	return result.String(), comments
+}
+
+// declarationSegment is a piece of a scanned line between declaration boundaries
+type declarationSegment struct {
+	Text   string
+	Opens  bool // Starts with the <! of a declaration
+	Closes bool // Ends with the > of a declaration
+}
+
+// declarationSegments splits a scanned line at the declaration boundaries
+func declarationSegments(text string) []declarationSegment {
+	var segments []declarationSegment
+	current := declarationSegment{}
+	for text != "" {
+		next := strings.IndexAny(text, declarationOpens+declarationCloses)
+		if next < 0 {
+			current.Text = text
+			break
+		}
+		current.Text = text[:next]
+		if text[next:next+1] == declarationCloses {
+			current.Closes = true
+			segments = append(segments, current)
+			current = declarationSegment{}
+		} else {
+			segments = append(segments, current)
+			current = declarationSegment{Opens: true}
+		}
+		text = text[next+1:]
+	}
+	return append(segments, current)
+}
+
+// withoutBoundaries removes the declaration boundaries from scanned text
+func withoutBoundaries(text string) string {
+	return strings.NewReplacer(declarationOpens, "", declarationCloses, "").Replace(text)
+}
+
+// warn records a warning at the position of the current declaration
+func (p *DTDParser) warn(format string, args ...any) {
+	p.warnAt(p.position, format, args...)
+}
+
+// warnAt records a warning at a given position
+func (p *DTDParser) warnAt(position Position, format string, args ...any) {
+	p.warnings = append(p.warnings, ParseWarning{
+		Position: position,
+		Message:  fmt.Sprintf(format, args...),
+	})
+	p.trace(position, "warning: %s", fmt.Sprintf(format, args...))
 }
 
 // parseLine parses a single complete DTD line
@@ -102,89 +679,167 @@ func (p *DTDParser) parseLine(line string) {
 	line = strings.TrimSpace(line)
 
 	if strings.HasPrefix(line, "<!ENTITY") {
+		line, _ = p.takeDeprecation(line)
 		p.parseEntity(line)
 	} else if strings.HasPrefix(line, "<!ELEMENT") {
-		p.parseElement(line)
+		line, deprecated := p.takeDeprecation(line)
+		p.parseElement(line, deprecated)
 	} else if strings.HasPrefix(line, "<!ATTLIST") {
 		p.parseAttributeList(line)
+	} else if strings.HasPrefix(line, "<!NOTATION") {
+		line, _ = p.takeDeprecation(line)
+		p.parseNotation(line)
+	} else if strings.HasPrefix(line, "<!") {
+		p.fail("unknown declaration")
+	} else {
+		p.fail("text outside a declaration")
 	}
 }
 
-// parseEntity parses an ENTITY declaration
+// parseEntity parses an ENTITY declaration. As in XML 1.0, the first declaration of an
+// entity is binding, so a driver file can set switches such as %draft; before including
+// the DTD that declares their defaults.
 func (p *DTDParser) parseEntity(line string) {
 	// Handle parameter entities like <!ENTITY % status_sellable "...">
-	re := regexp.MustCompile(`<!ENTITY\s+%\s+(\w+)\s+"(.+?)">`)
+	re := regexp.MustCompile(`<!ENTITY\s+%\s+([\w\p{L}\p{M}.:\x{B7}-]+)\s+"(.+?)">`)
 	matches := re.FindStringSubmatch(line)
 
 	if len(matches) >= 3 {
 		entityName := matches[1]
 		entityValue := matches[2]
-		p.entities[entityName] = entityValue
+		p.checkName("entity", entityName)
+		if previous, exists := p.entities[entityName]; exists {
+			p.trace(p.position, "skipped redeclaration of parameter entity %%%s;: the first declaration at %s is binding", entityName, previous.Position)
+			return
+		}
+		p.trace(p.position, "declared parameter entity %%%s; as %s", entityName, traceShort(entityValue))
+		p.entities[entityName] = &DTDEntity{Name: entityName, Value: entityValue, Position: p.position}
+		p.emit("entity", entityName, entityEvent{Value: entityValue})
+		return
+	}
+
+	// External parameter entities like <!ENTITY % common SYSTEM "common.dtd">, or with
+	// PUBLIC "-//Acme//Common//EN" "common.dtd"
+	matches = externalEntityPattern.FindStringSubmatch(line)
+	if matches != nil {
+		entityName := matches[1]
+		p.checkName("entity", entityName)
+		if previous, exists := p.entities[entityName]; exists {
+			p.trace(p.position, "skipped redeclaration of parameter entity %%%s;: the first declaration at %s is binding", entityName, previous.Position)
+			return
+		}
+		entity := &DTDEntity{Name: entityName, SystemID: unquote(matches[2]), Position: p.position}
+		if matches[3] != "" {
+			entity.PublicID, entity.SystemID = unquote(matches[3]), unquote(matches[4])
+		}
+		p.trace(p.position, "declared external parameter entity %%%s; for %s", entityName, entity.SystemID)
+		p.entities[entityName] = entity
+		p.emit("entity", entityName, entityEvent{SystemID: entity.SystemID, PublicID: entity.PublicID})
+		return
 	}
+
+	p.parseGeneralEntity(line)
+}
+
+// externalEntityPattern matches external parameter entity declarations, with the system
+// identifier of a SYSTEM entity in group 2 or the public and system identifiers of a
+// PUBLIC entity in groups 3 and 4
+var externalEntityPattern = regexp.MustCompile(`<!ENTITY\s+%\s+([\w\p{L}\p{M}.:\x{B7}-]+)\s+(?:SYSTEM\s+("[^"]*"|'[^']*')|PUBLIC\s+("[^"]*"|'[^']*')\s+("[^"]*"|'[^']*'))\s*>`)
+
+// unquote removes the quotes around a literal
+func unquote(literal string) string {
+	if len(literal) >= 2 {
+		return literal[1 : len(literal)-1]
+	}
+	return literal
 }
 
 // parseElement parses an ELEMENT declaration
-func (p *DTDParser) parseElement(line string) {
-	// Regular expression to match <!ELEMENT name content>
-	// Updated to handle hyphenated element names
-	re := regexp.MustCompile(`<!ELEMENT\s+([\w-]+)\s+(.+?)>`)
-	matches := re.FindStringSubmatch(line)
+func (p *DTDParser) parseElement(line, deprecated string) {
+	// Content models such as (%address.model;, price) are parsed with their entities
+	// replaced, without the padding around the replacement text
+	if expanded := p.expandParameterEntities(line, nil); expanded != line {
+		line = strings.Join(strings.Fields(expanded), " ")
+	}
+
+	matches := elementPattern.FindStringSubmatch(line)
+	if matches == nil {
+		p.fail("malformed element declaration")
+		return
+	}
+	if strings.TrimSpace(line[len(matches[0]):]) != "" {
+		p.fail("unexpected text after the element declaration")
+	}
 
 	if len(matches) >= 3 {
 		name := matches[1]
 		content := strings.TrimSpace(matches[2])
+		prefix, local := splitQualifiedName(name)
+		p.checkName("element", name)
+		p.checkContentModel(content)
+
+		// Streaming parsers only report the declaration
+		if p.options.Streaming {
+			p.trace(p.position, "declared element <%s> with content %s", name, content)
+			p.emit("element", name, elementEvent{Content: content, Deprecated: deprecated})
+			return
+		}
 
-		// Only add to order if this is the first time we see this element
-		if _, exists := p.elements[name]; !exists {
-			p.elementOrder = append(p.elementOrder, name)
+		// As with entities and attributes the first declaration is kept by default, so an
+		// internal subset can override its external subset
+		if previous, exists := p.elements[name]; exists {
+			if p.options.DuplicateElements == DuplicateError {
+				p.fail("element <%s> is already declared at %s", name, previous.Position)
+				return
+			}
+			if len(p.duplicates[name]) == 0 {
+				p.duplicates[name] = []Position{previous.Position}
+			}
+			p.duplicates[name] = append(p.duplicates[name], p.position)
+			if p.options.DuplicateElements != DuplicateLast {
+				p.trace(p.position, "skipped redeclaration of element <%s>: keeping the first declaration at %s", name, previous.Position)
+				return
+			}
+			p.trace(p.position, "element <%s> redeclared with content %s: replacing the declaration at %s", name, content, previous.Position)
+			// The element keeps its place in the order of the first declaration
+			*previous = DTDElement{Name: name, Prefix: prefix, Local: local, Content: content, Position: p.position, Deprecated: deprecated}
+			p.emit("element", name, elementEvent{Content: content, Deprecated: deprecated})
+			return
 		}
+		p.elementOrder = append(p.elementOrder, name)
+		p.trace(p.position, "declared element <%s> with content %s", name, content)
 
 		p.elements[name] = &DTDElement{
-			Name:    name,
-			Content: content,
+			Name:       name,
+			Prefix:     prefix,
+			Local:      local,
+			Content:    content,
+			Position:   p.position,
+			Deprecated: deprecated,
 		}
+		p.emit("element", name, elementEvent{Content: content, Deprecated: deprecated})
 	}
 }
 
-// parseEntityValue parses an entity value and adds attributes
-func (p *DTDParser) parseEntityValue(elementName, entityValue string, attributes *[]DTDAttribute) {
-	// Split the entity value into parts
-	parts := strings.Fields(entityValue)
-	if len(parts) < 3 {
-		return
+// reportDuplicates warns once about every element declared more than once, listing its
+// declarations, at the declaration in use
+func (p *DTDParser) reportDuplicates() {
+	kept := "first"
+	if p.options.DuplicateElements == DuplicateLast {
+		kept = "last"
 	}
-
-	// Extract attribute name, type, and requirement
-	// Format: "status ( current | withdrawn | offmarket | sold | deleted ) #REQUIRED"
-	attrName := parts[0]
-
-	// Find the closing parenthesis to get the complete type definition
-	typeEnd := -1
-	for i, part := range parts {
-		if strings.Contains(part, ")") {
-			typeEnd = i
-			break
+	for _, name := range p.elementOrder {
+		positions := p.duplicates[name]
+		if len(positions) == 0 {
+			continue
 		}
+		declarations := make([]string, len(positions))
+		for i, position := range positions {
+			declarations[i] = position.String()
+		}
+		p.warnAt(p.elements[name].Position, "element <%s> declared %d times (%s); using the %s declaration",
+			name, len(positions), strings.Join(declarations, ", "), kept)
 	}
-
-	var defaultInfo string
-	if typeEnd+1 < len(parts) {
-		defaultInfo = parts[typeEnd+1]
-	}
-
-	attr := DTDAttribute{
-		Name: attrName,
-		Type: "string", // Simplify enumerated types to string
-	}
-
-	// Check if required or has default value
-	if defaultInfo == "#REQUIRED" {
-		attr.Required = true
-	} else if defaultInfo != "#IMPLIED" {
-		attr.DefaultValue = strings.Trim(defaultInfo, `"`)
-	}
-
-	*attributes = append(*attributes, attr)
 }
 
 // parseAttributeList parses an ATTLIST declaration
@@ -192,33 +847,46 @@ func (p *DTDParser) parseAttributeList(line string) {
 	// Remove <!ATTLIST and >
 	content := strings.TrimPrefix(line, "<!ATTLIST")
 	content = strings.TrimSuffix(content, ">")
-	content = strings.TrimSpace(content)
+	content = strings.TrimSpace(p.expandParameterEntities(content, nil))
 
-	parts := strings.Fields(content)
+	parts := attlistFields(content)
 	if len(parts) < 1 {
+		p.fail("attribute list declaration names no element")
 		return
 	}
 
 	elementName := parts[0]
 	parts = parts[1:]
+	p.checkName("element", elementName)
 
 	var attributes []DTDAttribute
 
+	// A deprecation marker applies to the next attribute parsed after it
+	deprecated, deprecatedFrom := "", 0
+	markDeprecated := func() {
+		if deprecated != "" && len(attributes) > deprecatedFrom {
+			attributes[deprecatedFrom].Deprecated = deprecated
+			deprecated = ""
+		}
+	}
+
 	// Parse attributes (simplified parsing for complex DTD constructs)
+	incomplete := false // The trailing parts were reported as not forming an attribute
 	for i := 0; i < len(parts); {
+		markDeprecated()
 		if i >= len(parts) {
 			break
 		}
 
-		// Handle entity references like %status_sellable;
-		if strings.HasPrefix(parts[i], "%") && strings.HasSuffix(parts[i], ";") {
-			entityName := strings.TrimPrefix(parts[i], "%")
-			entityName = strings.TrimSuffix(entityName, ";")
+		if reason, ok := p.deprecationMarker(parts[i]); ok {
+			deprecated, deprecatedFrom = reason, len(attributes)
+			i++
+			continue
+		}
 
-			if entityValue, exists := p.entities[entityName]; exists {
-				// Recursively parse the entity value
-				p.parseEntityValue(elementName, entityValue, &attributes)
-			}
+		// Entity references like %status_sellable; were expanded above; the ones left
+		// could not be and have been reported
+		if strings.HasPrefix(parts[i], "%") && strings.HasSuffix(parts[i], ";") {
 			i++
 			continue
 		}
@@ -229,10 +897,17 @@ func (p *DTDParser) parseAttributeList(line string) {
 			attrType := parts[i+1]
 			defaultInfo := parts[i+2]
 
+			// NOTATION (png | gif) is parsed like an enumeration starting after the keyword
+			typeStart, enumType := i+1, "string" // Simplify enumerated types to string
+			if attrType == notationAttributeType && strings.HasPrefix(defaultInfo, "(") {
+				typeStart, enumType = i+2, notationAttributeType
+				attrType = defaultInfo
+			}
+
 			// Skip attributes with complex type definitions (parentheses)
 			if strings.Contains(attrType, "(") {
 				// Find the end of the parenthetical expression
-				j := i + 1
+				j := typeStart
 				parenCount := 0
 				for j < len(parts) {
 					for _, char := range parts[j] {
@@ -250,48 +925,141 @@ func (p *DTDParser) parseAttributeList(line string) {
 
 				if j+1 < len(parts) {
 					defaultInfo = parts[j+1]
+					p.checkAttributeDefinition(elementName, attrName, enumType, strings.Join(parts[typeStart:j+1], " "), defaultInfo)
 
 					attr := DTDAttribute{
-						Name: attrName,
-						Type: "string", // Simplify enumerated types to string
-					}
-
-					// Check if required or has default value
-					if defaultInfo == "#REQUIRED" {
-						attr.Required = true
-					} else if defaultInfo != "#IMPLIED" {
-						attr.DefaultValue = strings.Trim(defaultInfo, `"`)
+						Name:     attrName,
+						Type:     enumType,
+						Values:   enumerationValues(strings.Join(parts[typeStart:j+1], " ")),
+						Position: p.position,
 					}
-
+					i = p.attributeDefault(elementName, &attr, parts, j+1)
 					attributes = append(attributes, attr)
+				} else {
+					p.fail("attribute %q of <%s> has no default declaration", attrName, elementName)
+					i = j + 2
 				}
-
-				i = j + 2
 			} else {
+				p.checkAttributeDefinition(elementName, attrName, attrType, "", defaultInfo)
 				attr := DTDAttribute{
-					Name: attrName,
-					Type: attrType,
+					Name:     attrName,
+					Type:     attrType,
+					Position: p.position,
 				}
-
-				// Check if required or has default value
-				if defaultInfo == "#REQUIRED" {
-					attr.Required = true
-				} else if defaultInfo != "#IMPLIED" {
-					attr.DefaultValue = strings.Trim(defaultInfo, `"`)
-				}
-
+				i = p.attributeDefault(elementName, &attr, parts, i+2)
 				attributes = append(attributes, attr)
-				i += 3
 			}
 		} else {
+			if !incomplete {
+				p.fail("incomplete attribute definition %q for <%s>", strings.Join(parts[i:], " "), elementName)
+				incomplete = true
+			}
 			i++
 		}
 	}
 
-	// Append to existing attributes instead of overwriting
-	if existingAttrs, exists := p.attributes[elementName]; exists {
-		p.attributes[elementName] = append(existingAttrs, attributes...)
-	} else {
-		p.attributes[elementName] = attributes
+	markDeprecated()
+
+	for _, attr := range attributes {
+		p.trace(p.position, "declared attribute %s of <%s> as %s", attr.Name, elementName, describeAttribute(attr))
+	}
+	p.emit("attlist", elementName, newAttlistEvent(attributes))
+	if p.options.Streaming {
+		return
+	}
+
+	// Append to existing attributes instead of overwriting. As in XML 1.0 the first
+	// declaration of an attribute is binding; later ones are dropped.
+	for _, attr := range attributes {
+		p.attributes[elementName] = p.mergeAttribute(elementName, p.attributes[elementName], attr)
+	}
+}
+
+// attributeDefault applies the default declaration at parts[i] to attr and returns the
+// index of the part after it: #REQUIRED, #IMPLIED, #FIXED with the value following it,
+// or a default value
+func (p *DTDParser) attributeDefault(elementName string, attr *DTDAttribute, parts []string, i int) int {
+	switch parts[i] {
+	case "#REQUIRED":
+		attr.Required = true
+	case "#IMPLIED":
+	case "#FIXED":
+		if i+1 >= len(parts) {
+			p.fail("#FIXED attribute %q of <%s> has no value", attr.Name, elementName)
+			return i + 1
+		}
+		i++
+		if p.options.Strict && !isQuotedLiteral(parts[i]) {
+			p.fail("value of #FIXED attribute %s of <%s> must be a quoted literal, not %s", attr.Name, elementName, parts[i])
+		}
+		attr.Fixed = true
+		attr.DefaultValue = p.defaultValue(parts[i])
+		if len(attr.Values) > 0 && !containsString(attr.Values, attr.DefaultValue) {
+			p.warn("#FIXED value %q of attribute %s of <%s> is not one of its values %s", attr.DefaultValue, attr.Name, elementName, attr.DeclaredType())
+		}
+	default:
+		attr.DefaultValue = p.defaultValue(parts[i])
+	}
+	return i + 1
+}
+
+// enumerationValues returns the literals of an enumerated attribute type like (a | b | c)
+func enumerationValues(typeDef string) []string {
+	typeDef = strings.TrimSpace(typeDef)
+	typeDef = strings.TrimSuffix(strings.TrimPrefix(typeDef, "("), ")")
+
+	var values []string
+	for _, value := range strings.Split(typeDef, "|") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// mergeAttribute adds attr to the attributes already declared for an element, warning
+// when it redeclares an existing attribute with a different type or default
+func (p *DTDParser) mergeAttribute(elementName string, existing []DTDAttribute, attr DTDAttribute) []DTDAttribute {
+	for _, first := range existing {
+		if first.Name != attr.Name {
+			continue
+		}
+
+		if first.DeclaredType() != attr.DeclaredType() || first.DefaultValue != attr.DefaultValue || first.Required != attr.Required || first.Fixed != attr.Fixed {
+			p.warn("attribute %q of <%s> redeclared as %s; keeping the first declaration at %s (%s)",
+				attr.Name, elementName, describeAttribute(attr), first.Position, describeAttribute(first))
+		} else {
+			p.trace(p.position, "skipped redeclaration of attribute %s of <%s>: keeping the first declaration at %s", attr.Name, elementName, first.Position)
+		}
+		return existing
+	}
+
+	return append(existing, attr)
+}
+
+// DeclaredType returns the attribute type as declared: Type, or for enumerated and
+// NOTATION attributes the enumeration of their values such as (yes | no)
+func (attr DTDAttribute) DeclaredType() string {
+	switch {
+	case attr.Type == notationAttributeType:
+		return notationAttributeType + " (" + strings.Join(attr.Values, " | ") + ")"
+	case len(attr.Values) > 0:
+		return "(" + strings.Join(attr.Values, " | ") + ")"
+	default:
+		return attr.Type
+	}
+}
+
+// describeAttribute summarizes an attribute's type and default for diagnostics
+func describeAttribute(attr DTDAttribute) string {
+	switch {
+	case attr.Required:
+		return attr.DeclaredType() + " #REQUIRED"
+	case attr.Fixed:
+		return fmt.Sprintf("%s #FIXED %q", attr.DeclaredType(), attr.DefaultValue)
+	case attr.DefaultValue != "":
+		return fmt.Sprintf("%s %q", attr.DeclaredType(), attr.DefaultValue)
+	default:
+		return attr.DeclaredType() + " #IMPLIED"
 	}
 }
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ChildRef describes a child element referenced from an element's content model
+type ChildRef struct {
+	Name     string
+	Repeated bool
+	Min, Max int    // Occurrences allowed by the content model; Max may be Unbounded
+	Reason   string // Why Repeated was decided, for -explain-decisions
+}
+
+// ElementModel is the backend-neutral view of a DTD element shared by all output formats
+type ElementModel struct {
+	Name       string
+	Attributes []DTDAttribute
+	Children   []ChildRef
+	HasText    bool
+	AnyContent bool
+}
+
+// memberNames returns the names name forms for the attributes and then the children of
+// the element, as the members of a class or record in another format, numbered apart with
+// uniqueNames. reserved are the members the format adds after them, such as text for the
+// character data.
+func (m *ElementModel) memberNames(name func(string) string, reserved ...string) []string {
+	var names []string
+	for _, attr := range m.Attributes {
+		names = append(names, name(attr.Name))
+	}
+	for _, child := range m.Children {
+		names = append(names, name(child.Name))
+	}
+	return uniqueNames(names, reserved...)
+}
+
+// BuildElementModels builds the shared model for every element that needs its own type
+func BuildElementModels(elements map[string]*DTDElement, elementOrder []string) []*ElementModel {
+	var models []*ElementModel
+
+	for _, name := range elementOrder {
+		element, exists := elements[name]
+		if !exists || isSimpleElement(elements, name) {
+			continue
+		}
+
+		content := strings.TrimSpace(element.Content)
+		models = append(models, &ElementModel{
+			Name:       element.Name,
+			Attributes: element.Attributes,
+			Children:   contentChildren(content),
+			HasText:    strings.Contains(content, "#PCDATA"),
+			AnyContent: content == "ANY",
+		})
+	}
+
+	return models
+}
+
+// contentChildren returns the child elements of an element content model with how
+// often each may occur. Mixed, EMPTY and ANY content and models that do not parse, such
+// as ones with unexpanded parameter entity references, have no child elements.
+func contentChildren(content string) []ChildRef {
+	model, err := ParseContentModel(content)
+	if err != nil || model.Kind != ContentChildren {
+		return nil
+	}
+
+	var children []ChildRef
+	choices := model.exclusiveChoices()
+	for _, name := range model.ElementNames() {
+		min, max := model.Occurrences(name)
+
+		repeated, reason := true, ""
+		switch {
+		case max == Unbounded:
+			reason = "slice because it may repeat without limit"
+		case max > 1:
+			reason = fmt.Sprintf("slice because it may occur %d times", max)
+		case min == 0:
+			repeated, reason = false, "pointer because it is optional"
+		default:
+			repeated, reason = false, "pointer because it occurs once"
+		}
+		if choice := choiceOf(choices, name); choice != nil {
+			reason += fmt.Sprintf(", in an alternative of the choice %s", choice.Particle)
+		}
+
+		children = append(children, ChildRef{Name: name, Repeated: repeated, Min: min, Max: max, Reason: reason})
+	}
+	return children
+}
+
+// isSimpleElement determines if an element should be treated as a simple string field
+func isSimpleElement(elements map[string]*DTDElement, elementName string) bool {
+	element, exists := elements[elementName]
+	if !exists {
+		return true // Unknown elements treated as simple
+	}
+
+	content := strings.TrimSpace(element.Content)
+
+	// EMPTY elements get a type of their own, holding their attributes if they have any,
+	// so they are told apart from empty text when marshaling
+	if content == "EMPTY" {
+		return false
+	}
+
+	// Text-only and mixed content without attributes. Attributes need a struct to hold them
+	// next to the text, as in <price currency="EUR">9.99</price>.
+	return len(element.Attributes) == 0 && strings.Contains(content, "#PCDATA")
+}
+
+// isListAttributeType reports whether a DTD attribute type holds a whitespace separated list
+func isListAttributeType(dtdType string) bool {
+	switch strings.ToUpper(dtdType) {
+	case "IDREFS", "NMTOKENS", "ENTITIES":
+		return true
+	}
+	return false
+}
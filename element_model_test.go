@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+)
+
+// formatMembers return the names of the members of every class or record, by its name, in
+// the code of the formats built on ElementModel
+var formatMembers = map[string]func(code string) (map[string][]string, error){
+	"avro":    avroMembers,
+	"csharp":  classMembers(`public class (\w+)`, `(?m)^        public [\w<>\[\]]+ (\w+) \{`),
+	"parquet": classMembers(`(?m)^message (\w+) \{`, `(?m)^  \w+ \w+ (\w+)`),
+	"java":    classMembers(`public static class (\w+)`, `(?m)^        public [\w<>]+ (\w+)[ ;]`),
+	"python":  classMembers(`(?m)^class (\w+):`, `(?m)^    (\w+): `),
+}
+
+// classMembers returns the members of classes that start at a match of class, naming the
+// class, and hold the members matching member up to the next class
+func classMembers(class, member string) func(string) (map[string][]string, error) {
+	classPattern, memberPattern := regexp.MustCompile(class), regexp.MustCompile(member)
+	return func(code string) (map[string][]string, error) {
+		members := make(map[string][]string)
+		starts := classPattern.FindAllStringSubmatchIndex(code, -1)
+		for i, start := range starts {
+			end := len(code)
+			if i+1 < len(starts) {
+				end = starts[i+1][0]
+			}
+			name := code[start[2]:start[3]]
+			members[name] = []string{}
+			for _, match := range memberPattern.FindAllStringSubmatch(code[start[1]:end], -1) {
+				members[name] = append(members[name], match[1])
+			}
+		}
+		return members, nil
+	}
+}
+
+// avroMembers returns the fields of the records of an Avro schema, which are nested in the
+// fields of the records using them
+func avroMembers(code string) (map[string][]string, error) {
+	var schema any
+	if err := json.Unmarshal([]byte(code), &schema); err != nil {
+		return nil, err
+	}
+	members := make(map[string][]string)
+	var walk func(any)
+	walk = func(value any) {
+		switch value := value.(type) {
+		case []any:
+			for _, item := range value {
+				walk(item)
+			}
+		case map[string]any:
+			if value["type"] == "record" {
+				name, _ := value["name"].(string)
+				members[name] = []string{}
+				fields, _ := value["fields"].([]any)
+				for _, field := range fields {
+					if field, ok := field.(map[string]any); ok {
+						fieldName, _ := field["name"].(string)
+						members[name] = append(members[name], fieldName)
+					}
+				}
+			}
+			for _, item := range value {
+				walk(item)
+			}
+		}
+	}
+	walk(schema)
+	return members, nil
+}
+
+// TestMemberNamesNumberedApart generates collisions.dtd, whose attributes and children
+// form the same names, in every format and checks that no class or record has two
+// members of the same name, which would not compile or would lose data
+func TestMemberNamesNumberedApart(t *testing.T) {
+	result, err := NewDTDParser(ParserOptions{}).ParseFile("testdata/collisions.dtd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, format := range sortedKeys(formatMembers) {
+		files, err := (&Generation{Result: result, Format: format, PackageName: "schema"}).Generate()
+		if err != nil {
+			t.Errorf("generating %s: %v", format, err)
+			continue
+		}
+		code := string(files[(&Generation{Format: format}).outputName()])
+		members, err := formatMembers[format](code)
+		if err != nil || len(members) == 0 {
+			t.Errorf("no classes in the %s code (%v):\n%s", format, err, code)
+		}
+		for _, class := range sortedKeys(members) {
+			seen := make(map[string]bool)
+			for _, member := range members[class] {
+				if seen[member] {
+					t.Errorf("%s: %s has two members named %s", format, class, member)
+				}
+				seen[member] = true
+			}
+		}
+	}
+}
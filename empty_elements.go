@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Representations of EMPTY elements without attributes selectable with
+// GeneratorOptions.EmptyStyle. EMPTY elements with attributes always get a struct.
+const (
+	EmptyStyleStruct = "struct" // Empty marker struct types, held by pointer or slice
+	EmptyStyleBool   = "bool"   // Presence fields, a bool that is true when the element occurs
+)
+
+// isEmptyElement reports whether an element is declared EMPTY
+func (g *StructGenerator) isEmptyElement(name string) bool {
+	element, exists := g.elements[name]
+	return exists && strings.TrimSpace(element.Content) == "EMPTY"
+}
+
+// isPresenceElement reports whether an element is held as a Presence field rather than a
+// struct of its own: an EMPTY element without attributes, with EmptyStyleBool
+func (g *StructGenerator) isPresenceElement(name string) bool {
+	return g.options.EmptyStyle == EmptyStyleBool && g.isEmptyElement(name) && len(g.elements[name].Attributes) == 0
+}
+
+// usesPresence reports whether any field is a Presence flag
+func (g *StructGenerator) usesPresence() bool {
+	for _, name := range g.elementOrder {
+		if g.isPresenceElement(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// usesSelfClosingMarshal reports whether Marshal and MarshalIndent are generated, to
+// write the elements declared EMPTY as self-closing tags
+func (g *StructGenerator) usesSelfClosingMarshal() bool {
+	if g.options.NoXMLTags {
+		return false
+	}
+	for _, name := range g.elementOrder {
+		if g.isEmptyElement(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// presenceType is the type of Presence fields with encoding/xml
+const presenceType = `
+// Presence records whether an element declared EMPTY occurs: true marshals to the element
+// and false to nothing
+type Presence bool
+
+// UnmarshalXML records the element as present
+func (p *Presence) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	*p = true
+	return d.Skip()
+}
+
+// MarshalXML writes the element if it is present
+func (p Presence) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if !p {
+		return nil
+	}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+`
+
+// plainPresenceType is the type of Presence fields without encoding/xml
+const plainPresenceType = `
+// Presence records whether an element declared EMPTY occurs
+type Presence bool
+`
+
+// selfClosingMarshal writes encoding/xml output with the elements declared EMPTY as
+// self-closing tags, which encoding/xml cannot produce itself
+const selfClosingMarshal = `
+// Marshal returns the XML encoding of v like xml.Marshal, writing the elements declared
+// EMPTY as self-closing tags such as <br/> rather than <br></br>
+func Marshal(v any) ([]byte, error) {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return selfCloseEmpty(data), nil
+}
+
+// MarshalIndent is like Marshal but indents the output like xml.MarshalIndent
+func MarshalIndent(v any, prefix, indent string) ([]byte, error) {
+	data, err := xml.MarshalIndent(v, prefix, indent)
+	if err != nil {
+		return nil, err
+	}
+	return selfCloseEmpty(data), nil
+}
+
+// selfCloseEmpty replaces the start and end tag pairs of the elements declared EMPTY in
+// encoding/xml output by self-closing tags. encoding/xml escapes < and > in text and
+// attribute values, so the last < before a "></" starts the start tag.
+func selfCloseEmpty(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	for {
+		i := bytes.Index(data, []byte("></"))
+		if i < 0 {
+			return append(out, data...)
+		}
+		if start := bytes.LastIndexByte(data[:i], '<'); start >= 0 {
+			name := data[start+1 : i]
+			if j := bytes.IndexAny(name, " \t\r\n"); j >= 0 {
+				name = name[:j]
+			}
+			end := i + 3 + len(name)
+			if emptyElements[string(name)] && bytes.HasPrefix(data[i+3:], name) && end < len(data) && data[end] == '>' {
+				out = append(out, data[:i]...)
+				out = append(out, "/>"...)
+				data = data[end+1:]
+				continue
+			}
+		}
+		out = append(out, data[:i+1]...)
+		data = data[i+1:]
+	}
+}
+`
+
+// generateEmptyElements generates the Presence type and the Marshal helpers writing the
+// elements declared EMPTY as self-closing tags, as far as they are used
+func (g *StructGenerator) generateEmptyElements() string {
+	var builder strings.Builder
+
+	if g.usesPresence() {
+		if g.options.NoXMLTags {
+			builder.WriteString(plainPresenceType)
+		} else {
+			builder.WriteString(presenceType)
+		}
+	}
+
+	if g.usesSelfClosingMarshal() {
+		builder.WriteString("\n// emptyElements are the elements declared EMPTY, which Marshal writes as self-closing tags\n")
+		builder.WriteString("var emptyElements = map[string]bool{\n")
+		for _, name := range g.elementOrder {
+			if g.isEmptyElement(name) {
+				builder.WriteString(fmt.Sprintf("\t%q: true,\n", name))
+			}
+		}
+		builder.WriteString("}\n")
+		builder.WriteString(selfClosingMarshal)
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// textDeclarationPattern finds the encoding named by the <?xml ... ?> text declaration
+// at the top of a DTD
+var textDeclarationPattern = regexp.MustCompile(`^<\?xml\s[^?]*?encoding\s*=\s*["']([A-Za-z][\w.-]*)["']`)
+
+// textDeclarationLength bounds how far into a file the text declaration is looked for
+const textDeclarationLength = 256
+
+// utf8Reader returns the content of a DTD file as UTF-8. As described in XML 1.0
+// Appendix F, the encoding is taken from a byte order mark, from the first characters of
+// a UTF-16 text declaration without one, or else from the encoding the text declaration
+// names; UTF-8 is assumed without any of these.
+func utf8Reader(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	head, _ := buffered.Peek(4)
+	switch {
+	case bytes.HasPrefix(head, []byte{0x00, 0x00, 0xFE, 0xFF}), bytes.HasPrefix(head, []byte{0xFF, 0xFE, 0x00, 0x00}):
+		return nil, fmt.Errorf("UTF-32 encoded files are not supported")
+	case bytes.HasPrefix(head, []byte{0xEF, 0xBB, 0xBF}):
+		buffered.Discard(3)
+		return buffered, nil
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}):
+		buffered.Discard(2)
+		return &utf16Reader{r: buffered, order: binary.LittleEndian}, nil
+	case bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		buffered.Discard(2)
+		return &utf16Reader{r: buffered, order: binary.BigEndian}, nil
+	case bytes.HasPrefix(head, []byte{'<', 0x00, '?', 0x00}):
+		return &utf16Reader{r: buffered, order: binary.LittleEndian}, nil
+	case bytes.HasPrefix(head, []byte{0x00, '<', 0x00, '?'}):
+		return &utf16Reader{r: buffered, order: binary.BigEndian}, nil
+	}
+
+	// Peek returns what there is of shorter files
+	declaration, _ := buffered.Peek(textDeclarationLength)
+	encoding := ""
+	if matches := textDeclarationPattern.FindSubmatch(declaration); matches != nil {
+		encoding = string(matches[1])
+	}
+	switch strings.ToUpper(encoding) {
+	case "", "UTF-8", "UTF8", "US-ASCII", "ASCII":
+		return buffered, nil
+	case "ISO-8859-1", "ISO8859-1", "ISO_8859-1", "LATIN1", "L1":
+		return &latin1Reader{r: buffered}, nil
+	case "UTF-16", "UTF-16LE", "UTF-16BE":
+		return nil, fmt.Errorf("text declaration names %s, but the file is not UTF-16 encoded", encoding)
+	default:
+		return nil, fmt.Errorf("unsupported encoding %s in the text declaration; convert the file to UTF-8", encoding)
+	}
+}
+
+// utf16Reader transcodes UTF-16 text to UTF-8
+type utf16Reader struct {
+	r       *bufio.Reader
+	order   binary.ByteOrder
+	pending []byte // Transcoded text not yet read
+}
+
+// Read fills p with transcoded text
+func (u *utf16Reader) Read(p []byte) (int, error) {
+	for len(u.pending) < len(p) {
+		unit, err := u.unit()
+		if err == io.EOF && len(u.pending) > 0 {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		char := rune(unit)
+		if utf16.IsSurrogate(char) {
+			low, err := u.unit()
+			if err != nil {
+				return 0, fmt.Errorf("UTF-16 text ends inside a surrogate pair")
+			}
+			char = utf16.DecodeRune(char, rune(low))
+		}
+		u.pending = utf8.AppendRune(u.pending, char)
+	}
+	n := copy(p, u.pending)
+	u.pending = u.pending[:copy(u.pending, u.pending[n:])]
+	return n, nil
+}
+
+// unit reads one UTF-16 code unit
+func (u *utf16Reader) unit() (uint16, error) {
+	var b [2]byte
+	if _, err := io.ReadFull(u.r, b[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, fmt.Errorf("UTF-16 text ends inside a character")
+		}
+		return 0, err
+	}
+	return u.order.Uint16(b[:]), nil
+}
+
+// latin1Reader transcodes ISO-8859-1 text, whose bytes are the first 256 code points, to
+// UTF-8
+type latin1Reader struct {
+	r       *bufio.Reader
+	pending []byte // Transcoded text not yet read
+}
+
+// Read fills p with transcoded text
+func (l *latin1Reader) Read(p []byte) (int, error) {
+	for len(l.pending) < len(p) {
+		b, err := l.r.ReadByte()
+		if err == io.EOF && len(l.pending) > 0 {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		l.pending = utf8.AppendRune(l.pending, rune(b))
+	}
+	n := copy(p, l.pending)
+	l.pending = l.pending[:copy(l.pending, l.pending[n:])]
+	return n, nil
+}
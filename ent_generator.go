@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// entReservedNames are the field and edge names ent reserves for the generated entities
+var entReservedNames = map[string]bool{
+	"id":     true,
+	"config": true,
+	"edges":  true,
+}
+
+// EntGenerator generates ent schema definitions from DTD elements, so decoded documents
+// can be persisted with ent. Attributes, text and plain string children become fields and
+// children generated as structs become edges.
+type EntGenerator struct {
+	packageName  string
+	elements     map[string]*DTDElement
+	elementOrder []string
+	names        *StructGenerator // Reused for schema naming so all backends agree
+}
+
+// entSchema is the ent schema of one element
+type entSchema struct {
+	Name    string
+	Element string
+	Fields  []string // Field builder expressions such as field.String("isbn")
+	Edges   []string // Edge builder expressions such as edge.To("chapter", Chapter.Type)
+}
+
+// NewEntGenerator creates a new ent schema generator
+func NewEntGenerator(packageName string, elements map[string]*DTDElement, elementOrder []string) *EntGenerator {
+	return &EntGenerator{
+		packageName:  packageName,
+		elements:     elements,
+		elementOrder: elementOrder,
+		names:        NewStructGenerator("", elements, elementOrder, GeneratorOptions{}),
+	}
+}
+
+// GenerateSchemas generates the Go source of the ent schemas of all elements
+func (g *EntGenerator) GenerateSchemas() string {
+	var schemas []entSchema
+	usesFields, usesEdges := false, false
+	for _, model := range BuildElementModels(g.elements, g.elementOrder) {
+		schema := g.schemaFor(model)
+		usesFields = usesFields || len(schema.Fields) > 0
+		usesEdges = usesEdges || len(schema.Edges) > 0
+		schemas = append(schemas, schema)
+	}
+
+	var builder strings.Builder
+	builder.WriteString("// Code generated from a DTD by dtd-to-go. DO NOT EDIT.\n\n")
+	builder.WriteString(fmt.Sprintf("package %s\n\n", g.packageName))
+	builder.WriteString("import (\n")
+	builder.WriteString("\t\"entgo.io/ent\"\n")
+	if usesEdges {
+		builder.WriteString("\t\"entgo.io/ent/schema/edge\"\n")
+	}
+	if usesFields {
+		builder.WriteString("\t\"entgo.io/ent/schema/field\"\n")
+	}
+	builder.WriteString(")\n")
+
+	for _, schema := range schemas {
+		builder.WriteString(fmt.Sprintf("\n// %s holds the schema of the <%s> element\n", schema.Name, schema.Element))
+		builder.WriteString(fmt.Sprintf("type %s struct {\n\tent.Schema\n}\n", schema.Name))
+		if len(schema.Fields) > 0 {
+			builder.WriteString(fmt.Sprintf("\n// Fields of the %s: its attributes, text and plain string children\n", schema.Name))
+			builder.WriteString(fmt.Sprintf("func (%s) Fields() []ent.Field {\n", schema.Name))
+			builder.WriteString("\treturn []ent.Field{\n")
+			for _, f := range schema.Fields {
+				builder.WriteString(fmt.Sprintf("\t\t%s,\n", f))
+			}
+			builder.WriteString("\t}\n}\n")
+		}
+		if len(schema.Edges) > 0 {
+			builder.WriteString(fmt.Sprintf("\n// Edges of the %s: its children generated as schemas of their own\n", schema.Name))
+			builder.WriteString(fmt.Sprintf("func (%s) Edges() []ent.Edge {\n", schema.Name))
+			builder.WriteString("\treturn []ent.Edge{\n")
+			for _, e := range schema.Edges {
+				builder.WriteString(fmt.Sprintf("\t\t%s,\n", e))
+			}
+			builder.WriteString("\t}\n}\n")
+		}
+	}
+
+	return builder.String()
+}
+
+// schemaFor builds the ent schema of one element
+func (g *EntGenerator) schemaFor(model *ElementModel) entSchema {
+	schema := entSchema{Name: g.names.toGoStructName(model.Name), Element: model.Name}
+	taken := make(map[string]bool)
+
+	for _, attr := range model.Attributes {
+		name := g.uniqueName(g.toEntName(attr.Name), "_attr", taken)
+		var expr string
+		switch {
+		case isListAttributeType(attr.Type):
+			expr = fmt.Sprintf("field.Strings(%q)", name)
+		case len(attr.Values) > 0:
+			expr = fmt.Sprintf("field.Enum(%q).NamedValues(%s)", name, g.namedValues(attr.Values))
+		default:
+			expr = fmt.Sprintf("field.String(%q)", name)
+		}
+		if attr.DefaultValue != "" && !isListAttributeType(attr.Type) {
+			expr += fmt.Sprintf(".Default(%q)", attr.DefaultValue)
+		}
+		if !attr.Required {
+			expr += ".Optional()"
+		}
+		schema.Fields = append(schema.Fields, expr+g.comment(attr.Name, attr.Deprecated))
+	}
+
+	for _, child := range model.Children {
+		if isSimpleElement(g.elements, child.Name) {
+			name := g.uniqueName(g.toEntName(child.Name), "_element", taken)
+			expr := fmt.Sprintf("field.String(%q)", name)
+			if child.Repeated {
+				expr = fmt.Sprintf("field.Strings(%q)", name)
+			}
+			if child.Repeated || child.Min == 0 {
+				expr += ".Optional()"
+			}
+			schema.Fields = append(schema.Fields, expr+g.comment("<"+child.Name+">", g.deprecation(child.Name)))
+			continue
+		}
+		if _, exists := g.elements[child.Name]; !exists {
+			continue // Undeclared elements have no schema to point to
+		}
+		name := g.uniqueName(g.toEntName(child.Name), "_element", taken)
+		expr := fmt.Sprintf("edge.To(%q, %s.Type)", name, g.names.toGoStructName(child.Name))
+		if !child.Repeated {
+			expr += ".Unique()"
+		}
+		schema.Edges = append(schema.Edges, expr+g.comment("<"+child.Name+">", g.deprecation(child.Name)))
+	}
+
+	if model.AnyContent {
+		name := g.uniqueName("content", "_text", taken)
+		schema.Fields = append(schema.Fields, fmt.Sprintf("field.Text(%q).Optional().Comment(\"Inner XML of the ANY content\")", name))
+	}
+	if model.HasText {
+		name := g.uniqueName("text", "_text", taken)
+		schema.Fields = append(schema.Fields, fmt.Sprintf("field.Text(%q).Optional()", name))
+	}
+
+	return schema
+}
+
+// comment returns the Comment call naming the attribute or element a field or edge holds,
+// with the reason it is deprecated if it is
+func (g *EntGenerator) comment(xmlName, deprecated string) string {
+	if deprecated != "" {
+		xmlName += "; deprecated: " + deprecated
+	}
+	return fmt.Sprintf(".Comment(%q)", xmlName)
+}
+
+// deprecation returns the reason an element is deprecated, if it is
+func (g *EntGenerator) deprecation(name string) string {
+	if element, exists := g.elements[name]; exists {
+		return element.Deprecated
+	}
+	return ""
+}
+
+// namedValues returns the arguments of an ent NamedValues call for enumeration values,
+// naming each like the Go enum constants
+func (g *EntGenerator) namedValues(values []string) string {
+	taken := make(map[string]bool)
+	args := make([]string, len(values))
+	for i, value := range values {
+		name := enumConstSuffix(value)
+		if name == "" || taken[name] {
+			name = fmt.Sprintf("Value%d", i+1)
+		}
+		taken[name] = true
+		args[i] = fmt.Sprintf("%q, %q", name, value)
+	}
+	return strings.Join(args, ", ")
+}
+
+// uniqueName returns name, or name with suffix when it is reserved by ent or already used
+// by another field or edge of the schema, and marks it as taken
+func (g *EntGenerator) uniqueName(name, suffix string, taken map[string]bool) string {
+	if entReservedNames[name] || taken[name] {
+		name += suffix
+	}
+	base := name
+	for n := 2; taken[name]; n++ {
+		name = fmt.Sprintf("%s%d", base, n)
+	}
+	taken[name] = true
+	return name
+}
+
+// toEntName converts a DTD element or attribute name to the snake_case name ent expects
+// for fields and edges, such as mod_time for modTime and xml_lang for xml:lang
+func (g *EntGenerator) toEntName(name string) string {
+	var result strings.Builder
+
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case r == '-' || r == '.' || r == ':':
+			result.WriteRune('_')
+		case unicode.IsUpper(r):
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])) {
+				result.WriteRune('_')
+			}
+			result.WriteRune(unicode.ToLower(r))
+		default:
+			result.WriteRune(r)
+		}
+	}
+	if result.Len() == 0 {
+		return "field"
+	}
+	return result.String()
+}
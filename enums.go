@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Enumerated attribute representations selectable with GeneratorOptions.EnumStyle
+const (
+	EnumStyleString = "string" // Plain string fields
+	EnumStyleInt    = "int"    // iota-based integer types with parse/format tables
+	EnumStyleTyped  = "typed"  // String types with a constant per value and IsValid
+)
+
+// Representations of optional (non-#REQUIRED) integer enums selectable with
+// GeneratorOptions.OptionalEnums
+const (
+	OptionalEnumZero    = "zero"    // The zero value, which has no constant, means absent
+	OptionalEnumUnset   = "unset"   // An explicit XUnset constant is the zero value
+	OptionalEnumPointer = "pointer" // *X fields, nil when absent
+)
+
+// enumType describes the type generated for an enumerated attribute
+type enumType struct {
+	Name      string
+	Element   string
+	Attribute string
+	Values    []string
+	Constants []string // Go constant name of each value
+	Unset     string   // Name of the zero constant of an optional attribute with OptionalEnumUnset
+	Notation  bool     // Generated for a NOTATION attribute
+	Strings   bool     // A string type with EnumStyleTyped rather than an integer one
+}
+
+// enumTypes returns the enum types needed by the generated structs, in declaration order
+func (g *StructGenerator) enumTypes() []enumType {
+	var enums []enumType
+	for _, name := range g.elementOrder {
+		element, exists := g.elements[name]
+		if !exists || g.isSimpleElement(name) {
+			continue
+		}
+		for _, attr := range element.Attributes {
+			if g.enumTypeName(element, attr) != "" {
+				enums = append(enums, g.newEnumType(element, attr))
+			}
+		}
+	}
+	return enums
+}
+
+// newEnumType names the enum type of an attribute and its constants
+func (g *StructGenerator) newEnumType(element *DTDElement, attr DTDAttribute) enumType {
+	enum := enumType{
+		Name:      g.enumTypeName(element, attr),
+		Element:   element.Name,
+		Attribute: attr.Name,
+		Values:    attr.Values,
+		Notation:  attr.Type == notationAttributeType,
+	}
+	enum.Strings = g.options.EnumStyle == EnumStyleTyped && !enum.Notation
+
+	// The values slice is named like a constant for a "values" literal would be
+	taken := map[string]bool{enum.Name + "Values": true}
+	if g.options.OptionalEnums == OptionalEnumUnset && !attr.Required {
+		enum.Unset = enum.Name + "Unset"
+		taken[enum.Unset] = true
+	}
+	for i, value := range attr.Values {
+		constant := enum.Name + enumConstSuffix(g.identifierName(value))
+		if constant == enum.Name || taken[constant] {
+			constant = fmt.Sprintf("%sValue%d", enum.Name, i+1)
+		}
+		taken[constant] = true
+		enum.Constants = append(enum.Constants, constant)
+	}
+	return enum
+}
+
+// enumTypeName returns the Go type of an enumerated attribute, or "" when the attribute
+// is generated as a plain string. NOTATION attributes always get an integer enum type, as
+// their values name declared notations rather than free text.
+func (g *StructGenerator) enumTypeName(element *DTDElement, attr DTDAttribute) string {
+	if len(attr.Values) == 0 || (g.options.EnumStyle == EnumStyleString && attr.Type != notationAttributeType) {
+		return ""
+	}
+	return g.toGoStructName(element.Name) + g.toGoFieldName(attr.Name)
+}
+
+// isStringEnum reports whether an enumerated attribute gets a string enum type, with
+// EnumStyleTyped, rather than an integer one or a plain string
+func (g *StructGenerator) isStringEnum(element *DTDElement, attr DTDAttribute) bool {
+	return g.enumTypeName(element, attr) != "" && g.options.EnumStyle == EnumStyleTyped && attr.Type != notationAttributeType
+}
+
+// usesIntEnums reports whether any enum type is an integer one, whose parse and format
+// functions need fmt
+func (g *StructGenerator) usesIntEnums() bool {
+	for _, enum := range g.enumTypes() {
+		if !enum.Strings {
+			return true
+		}
+	}
+	return false
+}
+
+// enumFieldType returns the Go type of the struct field holding an enumerated attribute,
+// or "" when the attribute is generated as a plain string
+func (g *StructGenerator) enumFieldType(element *DTDElement, attr DTDAttribute) string {
+	name := g.enumTypeName(element, attr)
+	if name != "" && g.options.OptionalEnums == OptionalEnumPointer && !attr.Required {
+		return "*" + name
+	}
+	return name
+}
+
+// enumConstSuffix turns an enumeration literal such as "off-market" into an identifier
+// suffix such as "OffMarket"
+func enumConstSuffix(value string) string {
+	words := strings.FieldsFunc(value, func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+	})
+
+	var result strings.Builder
+	for _, word := range words {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		result.WriteString(string(runes))
+	}
+	return result.String()
+}
+
+// generateEnumTypes generates the integer enum types with their parse/format tables and
+// XML attribute marshaling, where the zero value means the attribute is absent, and the
+// string enum types
+func (g *StructGenerator) generateEnumTypes() string {
+	var builder strings.Builder
+
+	for _, enum := range g.enumTypes() {
+		if enum.Strings {
+			builder.WriteString(g.stringEnumType(enum))
+			continue
+		}
+		names := lowerFirst(enum.Name) + "Names"
+
+		if enum.Notation {
+			builder.WriteString(fmt.Sprintf("\n// %s enumerates the notations the %s attribute of <%s> may name\n", enum.Name, enum.Attribute, enum.Element))
+		} else {
+			builder.WriteString(fmt.Sprintf("\n// %s enumerates the values of the %s attribute of <%s>\n", enum.Name, enum.Attribute, enum.Element))
+		}
+		builder.WriteString(fmt.Sprintf("type %s int\n\n", enum.Name))
+
+		builder.WriteString("const (\n")
+		if enum.Unset != "" {
+			builder.WriteString(fmt.Sprintf("\t%s %s = iota // Attribute absent\n", enum.Unset, enum.Name))
+		}
+		for i, constant := range enum.Constants {
+			if i == 0 && enum.Unset == "" {
+				builder.WriteString(fmt.Sprintf("\t%s %s = iota + 1 // %q\n", constant, enum.Name, enum.Values[i]))
+			} else {
+				builder.WriteString(fmt.Sprintf("\t%s // %q\n", constant, enum.Values[i]))
+			}
+		}
+		builder.WriteString(")\n\n")
+
+		builder.WriteString(fmt.Sprintf("// %sValues lists the values of %s in declaration order, e.g. for form options\n", enum.Name, enum.Name))
+		builder.WriteString(fmt.Sprintf("var %sValues = []%s{%s}\n\n", enum.Name, enum.Name, strings.Join(enum.Constants, ", ")))
+
+		builder.WriteString(fmt.Sprintf("// %s is the format table of %s, indexed by value\n", names, enum.Name))
+		builder.WriteString(fmt.Sprintf("var %s = [...]string{\"\"", names))
+		for _, value := range enum.Values {
+			builder.WriteString(fmt.Sprintf(", %q", value))
+		}
+		builder.WriteString("}\n\n")
+
+		builder.WriteString(fmt.Sprintf("// Parse%s returns the %s for an attribute value\n", enum.Name, enum.Name))
+		builder.WriteString(fmt.Sprintf("func Parse%s(s string) (%s, error) {\n", enum.Name, enum.Name))
+		builder.WriteString("\tswitch s {\n")
+		for i, value := range enum.Values {
+			builder.WriteString(fmt.Sprintf("\tcase %q:\n\t\treturn %s, nil\n", value, enum.Constants[i]))
+		}
+		builder.WriteString("\t}\n")
+		if g.options.TinyGo {
+			builder.WriteString(fmt.Sprintf("\treturn 0, errors.New(\"invalid %s value \" + strconv.Quote(s))\n", enum.Attribute))
+		} else {
+			builder.WriteString(fmt.Sprintf("\treturn 0, fmt.Errorf(\"invalid %s value %%q\", s)\n", enum.Attribute))
+		}
+		builder.WriteString("}\n\n")
+
+		builder.WriteString("// String returns the attribute value\n")
+		builder.WriteString(fmt.Sprintf("func (v %s) String() string {\n", enum.Name))
+		builder.WriteString(fmt.Sprintf("\tif v > 0 && int(v) < len(%s) {\n", names))
+		builder.WriteString(fmt.Sprintf("\t\treturn %s[v]\n", names))
+		builder.WriteString("\t}\n")
+		if g.options.TinyGo {
+			builder.WriteString(fmt.Sprintf("\treturn \"%s(\" + strconv.Itoa(int(v)) + \")\"\n", enum.Name))
+		} else {
+			builder.WriteString(fmt.Sprintf("\treturn fmt.Sprintf(\"%s(%%d)\", int(v))\n", enum.Name))
+		}
+		builder.WriteString("}\n")
+
+		if g.options.NoXMLTags {
+			continue
+		}
+		builder.WriteString("\n")
+
+		builder.WriteString("// MarshalXMLAttr writes the attribute value, leaving the attribute out when unset\n")
+		builder.WriteString(fmt.Sprintf("func (v %s) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {\n", enum.Name))
+		builder.WriteString("\tif v == 0 {\n")
+		builder.WriteString("\t\treturn xml.Attr{}, nil\n")
+		builder.WriteString("\t}\n")
+		builder.WriteString(fmt.Sprintf("\tif int(v) >= len(%s) {\n", names))
+		builder.WriteString(fmt.Sprintf("\t\treturn xml.Attr{}, fmt.Errorf(\"invalid %s value %%d\", int(v))\n", enum.Attribute))
+		builder.WriteString("\t}\n")
+		builder.WriteString(fmt.Sprintf("\treturn xml.Attr{Name: name, Value: %s[v]}, nil\n", names))
+		builder.WriteString("}\n\n")
+
+		builder.WriteString("// UnmarshalXMLAttr parses the attribute value\n")
+		builder.WriteString(fmt.Sprintf("func (v *%s) UnmarshalXMLAttr(attr xml.Attr) error {\n", enum.Name))
+		builder.WriteString(fmt.Sprintf("\tparsed, err := Parse%s(attr.Value)\n", enum.Name))
+		builder.WriteString("\tif err != nil {\n")
+		if g.options.ViolationHooks {
+			builder.WriteString(fmt.Sprintf("\t\trecordViolation(%q, ViolationEnumeration)\n", enum.Element))
+		}
+		builder.WriteString("\t\treturn err\n")
+		builder.WriteString("\t}\n")
+		builder.WriteString("\t*v = parsed\n")
+		builder.WriteString("\treturn nil\n")
+		builder.WriteString("}\n")
+	}
+
+	return builder.String()
+}
+
+// stringEnumType generates a string enum type with its constants, the list of its values
+// and IsValid. encoding/xml reads and writes it like a plain string, so the empty value
+// means the attribute is absent.
+func (g *StructGenerator) stringEnumType(enum enumType) string {
+	var builder strings.Builder
+
+	builder.WriteString(fmt.Sprintf("\n// %s enumerates the values of the %s attribute of <%s>\n", enum.Name, enum.Attribute, enum.Element))
+	builder.WriteString(fmt.Sprintf("type %s string\n\n", enum.Name))
+
+	builder.WriteString("const (\n")
+	for i, constant := range enum.Constants {
+		builder.WriteString(fmt.Sprintf("\t%s %s = %q\n", constant, enum.Name, enum.Values[i]))
+	}
+	builder.WriteString(")\n\n")
+
+	builder.WriteString(fmt.Sprintf("// %sValues lists the values of %s in declaration order, e.g. for form options\n", enum.Name, enum.Name))
+	builder.WriteString(fmt.Sprintf("var %sValues = []%s{%s}\n\n", enum.Name, enum.Name, strings.Join(enum.Constants, ", ")))
+
+	builder.WriteString("// IsValid reports whether the value is one the DTD declares\n")
+	builder.WriteString(fmt.Sprintf("func (v %s) IsValid() bool {\n", enum.Name))
+	builder.WriteString("\tswitch v {\n")
+	builder.WriteString(fmt.Sprintf("\tcase %s:\n", strings.Join(enum.Constants, ", ")))
+	builder.WriteString("\t\treturn true\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\treturn false\n")
+	builder.WriteString("}\n")
+
+	return builder.String()
+}
+
+// lowerFirst lowercases the first letter of an identifier to make it unexported
+func lowerFirst(name string) string {
+	runes := []rune(name)
+	if len(runes) > 0 {
+		runes[0] = unicode.ToLower(runes[0])
+	}
+	return string(runes)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors wrapped by the errors of the parser and generator, for callers to test with
+// errors.Is instead of matching messages
+var (
+	// ErrUnresolvedEntity is wrapped by an EntityError for an external parameter entity or
+	// DOCTYPE external subset that cannot be included
+	ErrUnresolvedEntity = errors.New("unresolved entity")
+	// ErrUndeclaredElement is wrapped by a GenerateError for an element that is named by a
+	// flag, config or sample document but not declared in the DTD
+	ErrUndeclaredElement = errors.New("not declared in the DTD")
+	// ErrNotStruct is wrapped by a GenerateError for an element that must be generated as
+	// a struct of its own but is a plain string or lifted into its parent
+	ErrNotStruct = errors.New("not generated as a struct")
+)
+
+// EntityError is returned by ParseFile when an external parameter entity or the external
+// subset of a DOCTYPE cannot be included: it refers to a remote file, includes itself, or
+// reading it failed
+type EntityError struct {
+	Position Position // Where the entity is referenced
+	Message  string   // What failed, naming the entity
+	Err      error    // Error reading the entity, if that failed
+}
+
+// Error formats the error as file:line: message, followed by the error reading the entity
+func (e *EntityError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("%s: %s", e.Position, e.Message)
+	}
+	return fmt.Sprintf("%s: %s: %v", e.Position, e.Message, e.Err)
+}
+
+// Unwrap returns ErrUnresolvedEntity and the error reading the entity, if any
+func (e *EntityError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{ErrUnresolvedEntity}
+	}
+	return []error{ErrUnresolvedEntity, e.Err}
+}
+
+// GenerateError is returned when code cannot be generated for an element, such as an
+// element selected by -root or -split that is not declared
+type GenerateError struct {
+	Element string // Name of the element
+	Err     error  // Such as ErrUndeclaredElement or ErrNotStruct
+}
+
+// Error formats the error as element <name>: cause
+func (e *GenerateError) Error() string {
+	return fmt.Sprintf("element <%s>: %v", e.Element, e.Err)
+}
+
+// Unwrap returns the cause of the error
+func (e *GenerateError) Unwrap() error {
+	return e.Err
+}
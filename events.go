@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// DeclarationEvent describes one declaration reported while a DTD is parsed
+type DeclarationEvent struct {
+	Type     string   `json:"type"` // "element", "attlist", "entity" or "notation"
+	Name     string   `json:"name"` // Element name, or entity or notation name
+	Payload  any      `json:"payload"`
+	Position Position `json:"position"`
+}
+
+// elementEvent is the payload of an "element" event
+type elementEvent struct {
+	Content    string `json:"content"`
+	Deprecated string `json:"deprecated,omitempty"`
+}
+
+// attlistEvent is the payload of an "attlist" event
+type attlistEvent struct {
+	Attributes []attributeEvent `json:"attributes"`
+}
+
+// attributeEvent describes one attribute of an "attlist" event
+type attributeEvent struct {
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`
+	Default    string   `json:"default,omitempty"`
+	Required   bool     `json:"required"`
+	Fixed      bool     `json:"fixed,omitempty"` // Default is the only value allowed
+	Values     []string `json:"values,omitempty"`
+	Deprecated string   `json:"deprecated,omitempty"`
+}
+
+// entityEvent is the payload of an "entity" event
+type entityEvent struct {
+	Value    string `json:"value"`
+	SystemID string `json:"systemId,omitempty"` // External entities only
+	PublicID string `json:"publicId,omitempty"`
+	General  bool   `json:"general,omitempty"` // Declared without %
+}
+
+// notationEvent is the payload of a "notation" event
+type notationEvent struct {
+	SystemID string `json:"systemId,omitempty"`
+	PublicID string `json:"publicId,omitempty"`
+}
+
+// newAttlistEvent builds the payload for the attributes of one ATTLIST declaration
+func newAttlistEvent(attributes []DTDAttribute) attlistEvent {
+	event := attlistEvent{Attributes: make([]attributeEvent, 0, len(attributes))}
+	for _, attr := range attributes {
+		event.Attributes = append(event.Attributes, attributeEvent{
+			Name:       attr.Name,
+			Type:       attr.Type,
+			Default:    attr.DefaultValue,
+			Required:   attr.Required,
+			Fixed:      attr.Fixed,
+			Values:     attr.Values,
+			Deprecated: attr.Deprecated,
+		})
+	}
+	return event
+}
+
+// emit reports a parsed declaration to the OnDeclaration callback
+func (p *DTDParser) emit(eventType, name string, payload any) {
+	if p.options.OnDeclaration == nil {
+		return
+	}
+	p.options.OnDeclaration(DeclarationEvent{
+		Type:     eventType,
+		Name:     name,
+		Payload:  payload,
+		Position: p.position,
+	})
+}
+
+// runEvents implements -format events: every declaration is written as one JSON line
+// the moment it is parsed, so consumers never wait for the whole model. With streaming
+// the model is not collected at all.
+func runEvents(inputFile, outputFile string, options ParserOptions) error {
+	var out io.Writer = os.Stdout
+	if outputFile != "" {
+		file, err := os.Create(outputFile)
+		if err != nil {
+			return fmt.Errorf("failed to create file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	encoder := json.NewEncoder(out)
+	var writeErr error
+	options.OnDeclaration = func(event DeclarationEvent) {
+		if writeErr == nil {
+			writeErr = encoder.Encode(event)
+		}
+	}
+	parser := NewDTDParser(options)
+
+	result, err := parser.ParseFile(inputFile)
+	if err != nil {
+		return err
+	}
+	if writeErr != nil {
+		return fmt.Errorf("failed to write event: %w", writeErr)
+	}
+
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// fixedAttribute is an attribute declared #FIXED together with the struct field holding it
+type fixedAttribute struct {
+	Attr     DTDAttribute
+	Field    goField
+	Value    string // The fixed value, with whitespace collapsed unless the attribute is CDATA
+	Constant string // Go constant holding the fixed value
+	Enum     string // Enum type of the field, if it has one
+	Strings  bool   // The enum type is a string one, with EnumStyleTyped
+}
+
+// fixedAttributes returns the #FIXED attributes of an element's struct. Attributes of an
+// enum type whose fixed value is not among their values, which the parser warns
+// about, have no constant to hold it and are left out.
+func (g *StructGenerator) fixedAttributes(element *DTDElement) []fixedAttribute {
+	var fixed []fixedAttribute
+	fields := g.structFields(element)
+	for i, attr := range element.Attributes {
+		if !attr.Fixed {
+			continue
+		}
+		field := fields[i]
+		enum := g.enumTypeName(element, attr)
+		if enum != "" && !containsString(attr.Values, strings.TrimSpace(attr.DefaultValue)) {
+			continue
+		}
+		value := attr.DefaultValue
+		if isTokenizedAttributeType(attr.Type) {
+			value = strings.Join(strings.Fields(value), " ")
+		}
+		fixed = append(fixed, fixedAttribute{
+			Attr:     attr,
+			Field:    field,
+			Value:    value,
+			Constant: "Fixed" + g.toGoStructName(element.Name) + field.Name,
+			Enum:     enum,
+			Strings:  g.isStringEnum(element, attr),
+		})
+	}
+	return fixed
+}
+
+// fixedStructs returns the elements whose structs hold #FIXED attributes, in declaration order
+func (g *StructGenerator) fixedStructs() []string {
+	var names []string
+	for _, name := range g.elementOrder {
+		element, exists := g.elements[name]
+		if exists && !g.isSimpleElement(name) && !g.isInlined(name) && len(g.fixedAttributes(element)) > 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// checksFixed reports whether an element's struct gets its #FIXED attributes checked when
+// decoding
+func (g *StructGenerator) checksFixed(name string) bool {
+	element, exists := g.elements[name]
+	return g.options.ValidateFixed && exists && !g.isSimpleElement(name) && !g.isInlined(name) &&
+		len(g.fixedAttributes(element)) > 0
+}
+
+// stringValue returns the Go expression of a #FIXED attribute's value as a string, for the
+// hand-rolled encoders
+func (f fixedAttribute) stringValue() string {
+	if f.Strings {
+		return "string(" + f.Constant + ")"
+	}
+	if f.Enum != "" {
+		return f.Constant + ".String()"
+	}
+	return f.Constant
+}
+
+// fixedCheckRuntime rejects decoded start elements giving #FIXED attributes other values
+const fixedCheckRuntime = `
+// fixedAttr is an attribute declared #FIXED with the only value it may have
+type fixedAttr struct {
+	Name      xml.Name
+	Value     string
+	Tokenized bool // Compared with whitespace collapsed, as for declared types other than CDATA
+}
+
+// checkFixed returns an error if start gives a #FIXED attribute a value other than its
+// fixed one. Absent attributes take the fixed value, so they pass.
+func checkFixed(start xml.StartElement) error {
+	for _, fixed := range fixedAttrs[start.Name.Local] {
+		for _, attr := range start.Attr {
+			if attr.Name != fixed.Name {
+				continue
+			}
+			value := attr.Value
+			if fixed.Tokenized {
+				value = strings.Join(strings.Fields(value), " ")
+			}
+			if value != fixed.Value {
+				recordViolation(start.Name.Local, ViolationFixed)
+				return fmt.Errorf("attribute %s of <%s> is %q, but it is #FIXED %q", fixed.Name.Local, start.Name.Local, attr.Value, fixed.Value)
+			}
+		}
+	}
+	return nil
+}
+`
+
+// generateFixedAttributes generates a constant for every #FIXED attribute and a MarshalXML
+// method writing the fixed values whatever the fields hold, and with ValidateFixed the
+// checks rejecting documents that give them other values
+func (g *StructGenerator) generateFixedAttributes() string {
+	var builder strings.Builder
+
+	names := g.fixedStructs()
+	builder.WriteString("\n// The values of the attributes declared #FIXED, which they always have\n")
+	builder.WriteString("const (\n")
+	for _, name := range names {
+		for _, fixed := range g.fixedAttributes(g.elements[name]) {
+			if fixed.Enum != "" {
+				enum := g.newEnumType(g.elements[name], fixed.Attr)
+				for i, value := range enum.Values {
+					if value == fixed.Value {
+						builder.WriteString(fmt.Sprintf("\t%s = %s // %s of <%s>\n", fixed.Constant, enum.Constants[i], fixed.Attr.Name, name))
+						break
+					}
+				}
+				continue
+			}
+			builder.WriteString(fmt.Sprintf("\t%s = %q // %s of <%s>\n", fixed.Constant, fixed.Value, fixed.Attr.Name, name))
+		}
+	}
+	builder.WriteString(")\n")
+
+	if g.options.NoXMLTags {
+		return builder.String()
+	}
+
+	for _, name := range names {
+		if g.hasXMLForm(name) {
+			continue // Its MarshalXML method sets the fixed values in the encoding/xml form
+		}
+		structName := g.toGoStructName(name)
+		builder.WriteString(fmt.Sprintf("\n// MarshalXML encodes <%s> with its #FIXED attributes set to their fixed values\n", name))
+		builder.WriteString(fmt.Sprintf("func (v %s) MarshalXML(e *xml.Encoder, start xml.StartElement) error {\n", structName))
+		builder.WriteString(fmt.Sprintf("\ttype plain %s // Without this method, so encoding does not recurse\n", structName))
+		builder.WriteString("\tp := plain(v)\n")
+		builder.WriteString(g.fixedAssignments(name, "p"))
+		builder.WriteString("\treturn e.Encode(p) // Named by the XMLName field, as start is not for document roots\n")
+		builder.WriteString("}\n")
+	}
+
+	if !g.options.ValidateFixed {
+		return builder.String()
+	}
+
+	builder.WriteString("\n// fixedAttrs holds the attributes declared #FIXED, by element\n")
+	builder.WriteString("var fixedAttrs = map[string][]fixedAttr{\n")
+	for _, name := range names {
+		builder.WriteString(fmt.Sprintf("\t%q: {\n", localName(name)))
+		for _, fixed := range g.fixedAttributes(g.elements[name]) {
+			name := fmt.Sprintf("xml.Name{Local: %q}", fixed.Attr.Name)
+			if space, local := g.xmlName(fixed.Attr.Name); space != "" {
+				name = fmt.Sprintf("xml.Name{Space: %q, Local: %q}", space, local)
+			}
+			builder.WriteString(fmt.Sprintf("\t\t{Name: %s, Value: %q, Tokenized: %t},\n", name, fixed.Value, isTokenizedAttributeType(fixed.Attr.Type)))
+		}
+		builder.WriteString("\t},\n")
+	}
+	builder.WriteString("}\n")
+	if g.options.ViolationHooks {
+		builder.WriteString(fixedCheckRuntime)
+	} else {
+		builder.WriteString(strings.Replace(fixedCheckRuntime, "\t\t\t\trecordViolation(start.Name.Local, ViolationFixed)\n", "", 1))
+	}
+
+	// The case folding and default filling UnmarshalXML methods check the attributes themselves
+	for _, name := range names {
+		if g.options.FoldCase || g.fillsDefaults(name) {
+			continue
+		}
+		structName := g.toGoStructName(name)
+		builder.WriteString(fmt.Sprintf("\n// UnmarshalXML decodes <%s>, rejecting #FIXED attributes with other values\n", name))
+		builder.WriteString(fmt.Sprintf("func (v *%s) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {\n", structName))
+		builder.WriteString(g.plainDecl(name))
+		builder.WriteString(fixedCheckCall)
+		builder.WriteString(g.decodeReturn(name, "d.DecodeElement("+g.decodeTarget(name)+", &start)"))
+		builder.WriteString("}\n")
+	}
+
+	return builder.String()
+}
+
+// fixedAssignments returns the statements setting the #FIXED attributes of an element's
+// struct to their fixed values in the variable v, before it is encoded
+func (g *StructGenerator) fixedAssignments(name, v string) string {
+	var builder strings.Builder
+	for _, fixed := range g.fixedAttributes(g.elements[name]) {
+		switch {
+		case strings.HasPrefix(fixed.Field.Type, "*"):
+			builder.WriteString(fmt.Sprintf("\tfixed%s := %s\n", fixed.Field.Name, fixed.Constant))
+			builder.WriteString(fmt.Sprintf("\t%s.%s = &fixed%s\n", v, fixed.Field.Name, fixed.Field.Name))
+		case isListAttributeType(fixed.Attr.Type):
+			builder.WriteString(fmt.Sprintf("\t%s.%s = strings.Fields(%s)\n", v, fixed.Field.Name, fixed.Constant))
+		default:
+			builder.WriteString(fmt.Sprintf("\t%s.%s = %s\n", v, fixed.Field.Name, fixed.Constant))
+		}
+	}
+	return builder.String()
+}
+
+// fixedCheckCall checks the #FIXED attributes of start in a generated UnmarshalXML method
+const fixedCheckCall = "\tif err := checkFixed(start); err != nil {\n\t\treturn err\n\t}\n"
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// formatCode formats the generated Go code like gofmt, aligning struct fields, tags and
+// trailing comments in columns, after wrapping comments at GeneratorOptions.MaxLineLength
+// columns. Code that does not parse is returned unchanged for checkCode to report.
+func (g *StructGenerator) formatCode(code string) string {
+	if g.options.MaxLineLength > 0 {
+		code = wrapComments(code, g.options.MaxLineLength)
+	}
+	formatted, err := format.Source([]byte(code))
+	if err != nil {
+		return code
+	}
+	code = string(formatted)
+	if g.options.MaxLineLength > 0 {
+		// Aligning pads the code before trailing comments, which may push them past the limit
+		if wrapped := wrapComments(code, g.options.MaxLineLength); wrapped != code {
+			if formatted, err := format.Source([]byte(wrapped)); err == nil {
+				code = string(formatted)
+			}
+		}
+	}
+	return code
+}
+
+// checkCode reports generated Go code that would not compile: code that does not parse,
+// and code the type checker rejects, such as an identifier declared twice or an import
+// left unused. Imported packages are read from the export data of the go command, so a
+// package that cannot be found, such as a third-party one outside any module, or every
+// package without a Go installation, is not checked; the uses of it are not either.
+// Positions are reported in filename.
+func checkCode(filename, code string) error {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, code, parser.AllErrors)
+	if err != nil {
+		return fmt.Errorf("generated code does not parse: %w", err)
+	}
+
+	var errs []error
+	config := types.Config{
+		Importer: importer.ForCompiler(fset, "gc", nil),
+		Error: func(err error) {
+			if typeErr, ok := err.(types.Error); ok && strings.HasPrefix(typeErr.Msg, "could not import ") {
+				return
+			}
+			errs = append(errs, err)
+		},
+	}
+	config.Check(file.Name.Name, fset, []*ast.File{file}, nil)
+	if len(errs) > 0 {
+		return fmt.Errorf("generated code does not compile: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// wrapComments rewraps the // comments of Go source so lines stay within width columns
+// (counting a tab as 4): comments on lines of their own are wrapped at word boundaries,
+// and trailing comments making a line too long are moved above it. Code, including struct
+// tags, is never broken, nor are comment lines holding indented code or directives.
+func wrapComments(code string, width int) string {
+	src := []byte(code)
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	var s scanner.Scanner
+	s.Init(file, src, nil, scanner.ScanComments)
+
+	// Line comments by the line they are on, with the column they start at
+	comments := make(map[int]int)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.COMMENT && strings.HasPrefix(lit, "//") {
+			position := file.Position(pos)
+			comments[position.Line] = position.Column - 1
+		}
+	}
+
+	lines := strings.Split(code, "\n")
+	var out []string
+	for i, line := range lines {
+		column, ok := comments[i+1]
+		if !ok || displayWidth(line) <= width {
+			out = append(out, line)
+			continue
+		}
+		code, comment := line[:column], strings.TrimPrefix(line[column:], "//")
+		indent := code[:len(code)-len(strings.TrimLeft(code, " \t"))]
+		if strings.TrimSpace(code) != "" {
+			// A trailing comment goes above the code it annotates
+			out = append(out, wrapComment(indent, strings.TrimSpace(comment), width)...)
+			out = append(out, strings.TrimRight(code, " \t"))
+			continue
+		}
+		if strings.HasPrefix(comment, "\t") || strings.HasPrefix(comment, "  ") || !strings.HasPrefix(comment, " ") {
+			// Indented code in a doc comment, or a directive such as //go:generate
+			out = append(out, line)
+			continue
+		}
+		out = append(out, wrapComment(indent, strings.TrimSpace(comment), width)...)
+	}
+	return strings.Join(out, "\n")
+}
+
+// wrapComment breaks comment text into // lines indented by indent, fitting width where
+// the words allow
+func wrapComment(indent, text string, width int) []string {
+	var lines []string
+	line := indent + "//"
+	for _, word := range strings.Fields(text) {
+		if line != indent+"//" && displayWidth(line+" "+word) > width {
+			lines = append(lines, line)
+			line = indent + "//"
+		}
+		line += " " + word
+	}
+	return append(lines, line)
+}
+
+// displayWidth returns the width of a line in columns, counting a tab as 4 as gofmt
+// output is commonly displayed
+func displayWidth(line string) int {
+	return len([]rune(line)) + 3*strings.Count(line, "\t")
+}
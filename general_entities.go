@@ -0,0 +1,143 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// generalEntityPattern matches internal general entity declarations like
+// <!ENTITY copyright "© ACME">
+var generalEntityPattern = regexp.MustCompile(`<!ENTITY\s+([\w\p{L}\p{M}.:\x{B7}-]+)\s+("[^"]*"|'[^']*')\s*>`)
+
+// externalGeneralEntityPattern matches external general entity declarations, parsed or
+// unparsed (NDATA), with the same groups as externalEntityPattern
+var externalGeneralEntityPattern = regexp.MustCompile(`<!ENTITY\s+([\w\p{L}\p{M}.:\x{B7}-]+)\s+(?:SYSTEM\s+("[^"]*"|'[^']*')|PUBLIC\s+("[^"]*"|'[^']*')\s+("[^"]*"|'[^']*'))(?:\s+NDATA\s+[\w\p{L}\p{M}.:\x{B7}-]+)?\s*>`)
+
+// generalReferencePattern matches entity and character references such as &copyright;,
+// &#169; and &#xA9;
+var generalReferencePattern = regexp.MustCompile(`&(#x[0-9a-fA-F]+|#[0-9]+|[\w\p{L}\p{M}.:\x{B7}-]+);`)
+
+// predefinedEntities are the general entities every XML processor recognizes
+var predefinedEntities = map[string]string{
+	"lt":   "<",
+	"gt":   ">",
+	"amp":  "&",
+	"apos": "'",
+	"quot": `"`,
+}
+
+// parseGeneralEntity parses a general entity declaration; the first declaration of a
+// name is binding
+func (p *DTDParser) parseGeneralEntity(line string) {
+	entity := &DTDEntity{Position: p.position, General: true}
+	if matches := generalEntityPattern.FindStringSubmatch(line); matches != nil {
+		entity.Name, entity.Value = matches[1], unquote(matches[2])
+	} else if matches := externalGeneralEntityPattern.FindStringSubmatch(line); matches != nil {
+		entity.Name, entity.SystemID = matches[1], unquote(matches[2])
+		if matches[3] != "" {
+			entity.PublicID, entity.SystemID = unquote(matches[3]), unquote(matches[4])
+		}
+	} else {
+		p.fail("malformed entity declaration")
+		return
+	}
+
+	p.checkName("entity", entity.Name)
+	if previous, exists := p.general[entity.Name]; exists {
+		p.trace(p.position, "skipped redeclaration of general entity &%s;: the first declaration at %s is binding", entity.Name, previous.Position)
+		return
+	}
+	if entity.SystemID != "" {
+		p.trace(p.position, "declared external general entity &%s; for %s", entity.Name, entity.SystemID)
+	} else {
+		p.trace(p.position, "declared general entity &%s; as %s", entity.Name, traceShort(entity.Value))
+	}
+	p.general[entity.Name] = entity
+	p.emit("entity", entity.Name, entityEvent{Value: entity.Value, SystemID: entity.SystemID, PublicID: entity.PublicID, General: true})
+}
+
+// defaultValue returns the value of an attribute default literal, unquoted and with its
+// entity and character references expanded
+func (p *DTDParser) defaultValue(literal string) string {
+	if len(literal) >= 2 && (literal[0] == '"' || literal[0] == '\'') && literal[len(literal)-1] == literal[0] {
+		literal = unquote(literal)
+	}
+	return p.expandReferences(literal, nil)
+}
+
+// expandReferences replaces the entity and character references in an attribute value.
+// References that cannot be expanded are reported and kept as written.
+func (p *DTDParser) expandReferences(value string, expanding map[string]bool) string {
+	return generalReferencePattern.ReplaceAllStringFunc(value, func(reference string) string {
+		name := reference[1 : len(reference)-1]
+		if strings.HasPrefix(name, "#") {
+			base, digits := 10, name[1:]
+			if strings.HasPrefix(digits, "x") {
+				base, digits = 16, digits[1:]
+			}
+			code, err := strconv.ParseInt(digits, base, 32)
+			if err != nil || !utf8.ValidRune(rune(code)) {
+				p.warn("invalid character reference %s", reference)
+				return reference
+			}
+			return string(rune(code))
+		}
+		if value, ok := predefinedEntities[name]; ok {
+			return value
+		}
+
+		entity, exists := p.general[name]
+		switch {
+		case !exists:
+			p.warn("reference to undeclared general entity %s", reference)
+			return reference
+		case entity.SystemID != "":
+			p.warn("external general entity %s cannot be used in an attribute value", reference)
+			return reference
+		case expanding[name]:
+			p.warn("general entity %s references itself", reference)
+			return reference
+		}
+		entity.References++
+		p.trace(p.position, "expanded %s to %s", reference, traceShort(entity.Value))
+
+		nested := map[string]bool{name: true}
+		for open := range expanding {
+			nested[open] = true
+		}
+		return p.expandReferences(entity.Value, nested)
+	})
+}
+
+// attlistFields splits the body of an ATTLIST declaration on whitespace like
+// strings.Fields, but keeps quoted default values such as "© ACME" in one field
+func attlistFields(content string) []string {
+	var fields []string
+	var field strings.Builder
+	var quote rune
+	for _, c := range content {
+		switch {
+		case quote != 0:
+			field.WriteRune(c)
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			field.WriteRune(c)
+			quote = c
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if field.Len() > 0 {
+				fields = append(fields, field.String())
+				field.Reset()
+			}
+		default:
+			field.WriteRune(c)
+		}
+	}
+	if field.Len() > 0 {
+		fields = append(fields, field.String())
+	}
+	return fields
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// generatedVariants are the options TestGeneratedCodeBuilds generates every testdata schema
+// with, a package each. They cover the code paths of the main flags together;
+// scripts/check-generated.sh goes through many more flag combinations one by one.
+var generatedVariants = map[string]GeneratorOptions{
+	"default":    {},
+	"tinygo":     {TinyGo: true, EnumStyle: EnumStyleInt, OptionalEnums: OptionalEnumPointer, EmptyStyle: EmptyStyleBool},
+	"validation": {EnumStyle: EnumStyleInt, OptionalEnums: OptionalEnumPointer, Validation: true, Constructors: true, Occurrences: true, ContentRegexp: true, ParentsIndex: true},
+	"styles":     {ChoiceStyle: ChoiceStyleInterface, GroupStyle: GroupStyleNamed, MixedStyle: MixedStyleNodes, Validation: true},
+	"decoding":   {SequenceStyle: SequenceStylePositional, FoldCase: true, FillDefaults: true, ValidateFixed: true, DecodeInto: true, IDIndex: true},
+	"pointers":   {PointerPolicy: PointerPolicyNone, EnumStyle: EnumStyleTyped, AnyStyle: AnyStyleUnion, Explain: true},
+	"compat1":    {Compat: 1, InlineWrappers: true, AttrGroups: 2, Canonical: true},
+}
+
+// TestGeneratedCodeBuilds generates the Go code of every DTD in testdata/ and XML document
+// in testdata/documents/ into a throwaway module, with each of generatedVariants and with
+// the generator config next to a DTD, and runs go vet and go build -race on it, so broken
+// output is caught here rather than by users downstream
+func TestGeneratedCodeBuilds(t *testing.T) {
+	goCommand := lookGo(t)
+	module := t.TempDir()
+	files := map[string][]byte{"go.mod": []byte(generatedModule)}
+	dtds, _ := filepath.Glob("testdata/*.dtd")
+	documents, _ := filepath.Glob("testdata/documents/*.xml")
+	for _, input := range append(dtds, documents...) {
+		result, err := NewDTDParser(ParserOptions{}).ParseFile(input)
+		if err != nil {
+			t.Errorf("parsing %s: %v", input, err)
+			continue
+		}
+		if len(result.Elements) == 0 {
+			continue
+		}
+
+		variants := make(map[string]GeneratorOptions, len(generatedVariants)+1)
+		for name, options := range generatedVariants {
+			variants[name] = options
+		}
+		configs, _ := filepath.Glob(strings.TrimSuffix(input, ".dtd") + ".*.json")
+		for _, file := range configs {
+			config, err := LoadGeneratorConfig(file)
+			if err != nil {
+				t.Errorf("%s: %v", input, err)
+				continue
+			}
+			// vendors.config.json is the config variant of vendors.dtd
+			variants[strings.TrimPrefix(filepath.Ext(strings.TrimSuffix(file, ".json")), ".")] = GeneratorOptions{
+				Redactions:    config.Redaction,
+				Transliterate: config.Transliteration,
+				Initialisms:   config.Initialisms,
+				Types:         config.Types,
+			}
+		}
+
+		for _, name := range sortedKeys(variants) {
+			pkg := strings.NewReplacer(".", "_", "-", "_").Replace(filepath.Base(input)) + "_" + name
+			run := &Generation{Result: result, Format: "go", PackageName: pkg, Output: "generated.go", Options: variants[name]}
+			generated, err := run.Generate()
+			if err != nil {
+				t.Errorf("generating %s with %s: %v", input, name, err)
+				continue
+			}
+			for file, content := range generated {
+				files[filepath.Join(pkg, file)] = content
+			}
+		}
+	}
+	writeFiles(t, module, files)
+
+	for _, args := range [][]string{{"vet", "./..."}, {"build", "-race", "./..."}} {
+		command := exec.Command(goCommand, args...)
+		command.Dir = module
+		if output, err := command.CombinedOutput(); err != nil {
+			t.Errorf("go %s: %v\n%s", strings.Join(args, " "), err, output)
+		}
+	}
+}
+
+// generatedModule is the go.mod of the throwaway modules the tests build generated code in
+const generatedModule = "module generated\n\ngo 1.24\n"
+
+// lookGo returns the path of the go command, skipping tests that build generated code
+// under -short or without a Go installation
+func lookGo(t *testing.T) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("builds the generated code with the go command")
+	}
+	path, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("the go command is not installed")
+	}
+	return path
+}
+
+// writeFiles writes files, by slash separated path, into dir
+func writeFiles(t *testing.T, dir string, files map[string][]byte) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
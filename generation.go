@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// Generation is one run of the generator, producing the generated code and the files that
+// go with it, such as doc.go and the manifest, in memory. The command line writes them to
+// disk; the serve mode and tests take them from Generate without any
+// file system side effects.
+type Generation struct {
+	Result      *ParseResult
+	Format      string           // Output format, such as "go" or "java"
+	PackageName string           // Package of the generated code
+	Output      string           // Name of the generated code, which doc.go goes next to; "" names it after the format
+	Options     GeneratorOptions // Options of the go format
+	Only        []string         // Elements whose types replace their declarations in Existing, keeping the rest (go format)
+	Existing    string           // Previous content of Output, for Only
+	Doc         bool             // Also generate doc.go summarizing the schema (go format)
+	Manifest    string           // Also generate a manifest of the files at this name
+	Input       string           // DTD file Result was parsed from, for doc.go and the manifest
+	Sources     fs.FS            // File system Input was read from, nil for the operating system
+	Flags       []string         // Generation options as given on the command line, for doc.go and the manifest
+
+	// Diagnostics are the reports of the last Generate for the user, such as renamed names
+	// and lossy conversions, one "kind: detail" line each, which the command line prints to
+	// standard error
+	Diagnostics []string
+}
+
+// generatedFile is a file a Generation produces
+type generatedFile struct {
+	Name    string
+	Title   string // Human readable description, such as "Generated Go Structs"
+	Content string
+}
+
+// Generate generates the code and the files requested with it, by name
+func (run *Generation) Generate() (map[string][]byte, error) {
+	files, err := run.generate()
+	if err != nil {
+		return nil, err
+	}
+	contents := make(map[string][]byte, len(files))
+	for _, file := range files {
+		contents[file.Name] = []byte(file.Content)
+	}
+	return contents, nil
+}
+
+// generate generates the files of the run, the code first
+func (run *Generation) generate() ([]generatedFile, error) {
+	if run.Doc && (run.Format != "go" || filepath.Base(run.outputName()) == docFileName) {
+		return nil, fmt.Errorf("doc.go needs go format and an output other than %s", docFileName)
+	}
+	if len(run.Only) > 0 && run.Format != "go" {
+		return nil, fmt.Errorf("updating only some elements needs go format")
+	}
+
+	code, title, diagnostics, err := generateCode(run.Format, run.PackageName, run.Output, run.Options, run.Result)
+	if err != nil {
+		return nil, err
+	}
+	run.Diagnostics = diagnostics
+
+	// Splice the selected structs into the existing output, keeping the rest of the file
+	if len(run.Only) > 0 {
+		typeNames, err := run.generator().structNamesOf(run.Only)
+		if err != nil {
+			return nil, fmt.Errorf("selecting elements to update: %w", err)
+		}
+		code, err = replaceTypeDecls(run.Existing, code, typeNames)
+		if err != nil {
+			return nil, fmt.Errorf("updating output: %w", err)
+		}
+		title = fmt.Sprintf("%s for %s", title, strings.Join(run.Only, ", "))
+	}
+	files := []generatedFile{{Name: run.outputName(), Title: "Generated " + title, Content: code}}
+
+	if run.Doc {
+		info := docInfo{Source: filepath.Base(run.Input), Entities: len(run.Result.Entities), Flags: run.Flags}
+		docPath := filepath.Join(filepath.Dir(run.outputName()), docFileName)
+		files = append(files, generatedFile{Name: docPath, Title: "Package documentation", Content: run.generator().GenerateDoc(info)})
+	}
+
+	if run.Manifest != "" {
+		var written []manifestFile
+		for _, file := range files {
+			written = append(written, manifestFile{Path: file.Name, SHA256: sha256Hex([]byte(file.Content))})
+		}
+		manifest, err := newGenerationManifest(run, written)
+		if err != nil {
+			return nil, err
+		}
+		content, err := manifest.encode()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, generatedFile{Name: run.Manifest, Title: "Manifest", Content: content})
+	}
+
+	return files, nil
+}
+
+// generator returns the Go generator of the run
+func (run *Generation) generator() *StructGenerator {
+	return NewStructGenerator(run.PackageName, run.Result.Elements, run.Result.Order, run.Options)
+}
+
+// outputName returns the name of the generated code: Output, or a name after the format
+func (run *Generation) outputName() string {
+	if run.Output != "" {
+		return run.Output
+	}
+	switch run.Format {
+	case "python":
+		return "schema.py"
+	case "java":
+		return "Schema.java" // The class generateCode names Schema without an output file
+	case "csharp":
+		return "Schema.cs"
+	case "avro":
+		return "schema.avsc"
+	case "parquet":
+		return "schema.parquet.txt"
+	case "json":
+		return "model.json"
+	}
+	return "schema.go"
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// genericDecoderRuntime is the schema-independent part of the DecodeGeneric helper
+const genericDecoderRuntime = `// genericElement describes how DecodeGeneric types the content of one element
+type genericElement struct {
+	Leaf     bool            // Decoded as its text content
+	Attrs    map[string]bool // Attribute name -> whitespace separated list
+	Children map[string]bool // Child element name -> repeated
+	Text     bool            // Character data is kept under "#text"
+	Any      bool            // Raw inner XML is kept under "#content"
+}
+
+// DecodeGeneric decodes a document into nested maps and slices using the DTD schema.
+// Attributes are stored under "@name", repeated children as []any, list attributes
+// as []string and text-only elements as plain strings.
+func DecodeGeneric(r io.Reader) (map[string]any, error) {
+	d := xml.NewDecoder(r)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			value, err := decodeGenericElement(d, start)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{start.Name.Local: value}, nil
+		}
+	}
+}
+
+// decodeGenericElement decodes the element opened by start
+func decodeGenericElement(d *xml.Decoder, start xml.StartElement) (any, error) {
+	schema, known := genericSchema[start.Name.Local]
+	if !known || schema.Leaf {
+		var text string
+		if err := d.DecodeElement(&text, &start); err != nil {
+			return nil, err
+		}
+		return text, nil
+	}
+
+	result := make(map[string]any)
+	for _, attr := range start.Attr {
+		if schema.Attrs[attr.Name.Local] {
+			result["@"+attr.Name.Local] = strings.Fields(attr.Value)
+		} else {
+			result["@"+attr.Name.Local] = attr.Value
+		}
+	}
+
+	if schema.Any {
+		var raw struct {
+			Content string ` + "`xml:\",innerxml\"`" + `
+		}
+		if err := d.DecodeElement(&raw, &start); err != nil {
+			return nil, err
+		}
+		result["#content"] = raw.Content
+		return result, nil
+	}
+
+	var text strings.Builder
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			value, err := decodeGenericElement(d, t)
+			if err != nil {
+				return nil, err
+			}
+			name := t.Name.Local
+			if schema.Children[name] {
+				list, _ := result[name].([]any)
+				result[name] = append(list, value)
+			} else {
+				result[name] = value
+			}
+		case xml.CharData:
+			if schema.Text {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if schema.Text {
+				result["#text"] = text.String()
+			}
+			return result, nil
+		}
+	}
+}
+`
+
+// generateGenericDecoder generates the DecodeGeneric helper and the schema table driving it
+func (g *StructGenerator) generateGenericDecoder() string {
+	var builder strings.Builder
+
+	builder.WriteString("\n// genericSchema is the element table used by DecodeGeneric\n")
+	builder.WriteString("var genericSchema = map[string]genericElement{\n")
+
+	for _, name := range g.elementOrder {
+		element, exists := g.elements[name]
+		if !exists {
+			continue
+		}
+
+		content := strings.TrimSpace(element.Content)
+		if len(element.Attributes) == 0 && g.isSimpleElement(name) {
+			builder.WriteString(fmt.Sprintf("\t%q: {Leaf: true},\n", localName(name)))
+			continue
+		}
+
+		var entries []string
+
+		if len(element.Attributes) > 0 {
+			var attrs []string
+			for _, attr := range element.Attributes {
+				attrs = append(attrs, fmt.Sprintf("%q: %t", localName(attr.Name), isListAttributeType(attr.Type)))
+			}
+			entries = append(entries, fmt.Sprintf("Attrs: map[string]bool{%s}", strings.Join(attrs, ", ")))
+		}
+
+		if children := contentChildren(content); len(children) > 0 {
+			var refs []string
+			for _, child := range children {
+				refs = append(refs, fmt.Sprintf("%q: %t", localName(child.Name), child.Repeated))
+			}
+			entries = append(entries, fmt.Sprintf("Children: map[string]bool{%s}", strings.Join(refs, ", ")))
+		}
+
+		if g.canContainText(content) {
+			entries = append(entries, "Text: true")
+		}
+		if content == "ANY" {
+			entries = append(entries, "Any: true")
+		}
+
+		builder.WriteString(fmt.Sprintf("\t%q: {%s},\n", localName(name), strings.Join(entries, ", ")))
+	}
+
+	builder.WriteString("}\n\n")
+	builder.WriteString(genericDecoderRuntime)
+
+	return builder.String()
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var contentNamePattern = regexp.MustCompile(`[\p{L}_:][\w\p{L}\p{M}.:\x{B7}-]*`)
+
+// Graph is the element usage graph of a DTD: an edge runs from each element to every
+// element its content model references. Elements that are referenced but never declared
+// are included as nodes without children.
+type Graph struct {
+	nodes    []string
+	children map[string][]string
+	parents  map[string][]string
+}
+
+// Graph builds the element usage graph of the parsed DTD
+func (r *ParseResult) Graph() *Graph {
+	g := &Graph{
+		children: make(map[string][]string),
+		parents:  make(map[string][]string),
+	}
+
+	known := make(map[string]bool)
+	addNode := func(name string) {
+		if !known[name] {
+			known[name] = true
+			g.nodes = append(g.nodes, name)
+		}
+	}
+
+	for _, name := range r.Order {
+		addNode(name)
+	}
+
+	for _, name := range r.Order {
+		element, exists := r.Elements[name]
+		if !exists {
+			continue
+		}
+		for _, child := range contentReferences(element.Content) {
+			addNode(child)
+			g.children[name] = append(g.children[name], child)
+			g.parents[child] = append(g.parents[child], name)
+		}
+	}
+
+	return g
+}
+
+// contentReferences returns the distinct element names referenced by a content model,
+// including the children of mixed content
+func contentReferences(content string) []string {
+	content = strings.TrimSpace(content)
+	if content == "EMPTY" || content == "ANY" {
+		return nil
+	}
+
+	// Drop #PCDATA and unexpanded parameter entity references before collecting names
+	content = strings.ReplaceAll(content, "#PCDATA", "")
+	content = regexp.MustCompile(`%[\w\p{L}\p{M}.:\x{B7}-]+;`).ReplaceAllString(content, "")
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range contentNamePattern.FindAllString(content, -1) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Nodes returns all elements in declaration order, followed by undeclared references
+func (g *Graph) Nodes() []string {
+	return append([]string(nil), g.nodes...)
+}
+
+// Children returns the elements referenced by name's content model
+func (g *Graph) Children(name string) []string {
+	return append([]string(nil), g.children[name]...)
+}
+
+// Parents returns the elements whose content models reference name
+func (g *Graph) Parents(name string) []string {
+	return append([]string(nil), g.parents[name]...)
+}
+
+// Roots returns the elements not referenced by any other element
+func (g *Graph) Roots() []string {
+	var roots []string
+	for _, name := range g.nodes {
+		isRoot := true
+		for _, parent := range g.parents[name] {
+			if parent != name {
+				isRoot = false
+				break
+			}
+		}
+		if isRoot {
+			roots = append(roots, name)
+		}
+	}
+	return roots
+}
+
+// Leaves returns the elements that reference no other element
+func (g *Graph) Leaves() []string {
+	var leaves []string
+	for _, name := range g.nodes {
+		if len(g.children[name]) == 0 {
+			leaves = append(leaves, name)
+		}
+	}
+	return leaves
+}
+
+// TopoSort orders the elements so that parents come before their children. Ties keep
+// declaration order. It fails if the graph contains cycles.
+func (g *Graph) TopoSort() ([]string, error) {
+	inDegree := make(map[string]int)
+	for _, name := range g.nodes {
+		for _, child := range g.children[name] {
+			inDegree[child]++
+		}
+	}
+
+	var queue, sorted []string
+	for _, name := range g.nodes {
+		if inDegree[name] == 0 {
+			queue = append(queue, name)
+		}
+	}
+
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		sorted = append(sorted, name)
+		for _, child := range g.children[name] {
+			inDegree[child]--
+			if inDegree[child] == 0 {
+				queue = append(queue, child)
+			}
+		}
+	}
+
+	if len(sorted) != len(g.nodes) {
+		var cycles []string
+		for _, cycle := range g.Cycles() {
+			cycles = append(cycles, strings.Join(cycle, " -> "))
+		}
+		return nil, fmt.Errorf("element graph contains cycles: %s", strings.Join(cycles, "; "))
+	}
+
+	return sorted, nil
+}
+
+// Cycles returns the recursive element groups (strongly connected components with more
+// than one element, or a single self-referencing element), each sorted by name
+func (g *Graph) Cycles() [][]string {
+	index := make(map[string]int)
+	lowLink := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var cycles [][]string
+	counter := 0
+
+	var visit func(name string)
+	visit = func(name string) {
+		index[name] = counter
+		lowLink[name] = counter
+		counter++
+		stack = append(stack, name)
+		onStack[name] = true
+
+		selfLoop := false
+		for _, child := range g.children[name] {
+			if child == name {
+				selfLoop = true
+			}
+			if _, visited := index[child]; !visited {
+				visit(child)
+				lowLink[name] = min(lowLink[name], lowLink[child])
+			} else if onStack[child] {
+				lowLink[name] = min(lowLink[name], index[child])
+			}
+		}
+
+		if lowLink[name] != index[name] {
+			return
+		}
+
+		var component []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == name {
+				break
+			}
+		}
+
+		if len(component) > 1 || selfLoop {
+			sort.Strings(component)
+			cycles = append(cycles, component)
+		}
+	}
+
+	for _, name := range g.nodes {
+		if _, visited := index[name]; !visited {
+			visit(name)
+		}
+	}
+
+	return cycles
+}
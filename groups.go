@@ -0,0 +1,421 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Representations of repeated groups of elements selectable with GeneratorOptions.GroupStyle
+const (
+	GroupStyleFlat      = "flat"      // The elements of the group become fields of the struct, a slice each
+	GroupStyleAnonymous = "anonymous" // A slice of an anonymous struct holding one repetition of the group
+	GroupStyleNamed     = "named"     // A slice of a named struct type holding one repetition of the group
+)
+
+// contentGroup is a repeated group of a content model that keeps its grouping with
+// GroupStyleAnonymous or GroupStyleNamed, such as (author | editor)+ in
+// (title, (author | editor)+, price): one field holds a struct per repetition
+type contentGroup struct {
+	Particle  *ContentParticle
+	Field     goField             // Field of the struct holding the repetitions
+	TypeName  string              // Struct type of a repetition with GroupStyleNamed
+	Fields    []goField           // Fields of a repetition, one per element of the group
+	Preceding map[string][]string // Elements that may come right before each element in one repetition
+}
+
+// repeatedGroups returns the outermost groups of an element content model that repeat,
+// such as (author | editor)+, holding more than one element name, none of which occurs
+// elsewhere in the model
+func (m *ContentModel) repeatedGroups() []*ContentParticle {
+	if m.Kind != ContentChildren || m.Root == nil {
+		return nil
+	}
+	positions := make(map[string]int)
+	m.Root.countPositions(positions)
+
+	var groups []*ContentParticle
+	var walk func(c *ContentParticle)
+	walk = func(c *ContentParticle) {
+		if c.Kind == ParticleElement {
+			return
+		}
+		if c.Indicator == '*' || c.Indicator == '+' {
+			names := (&ContentModel{Root: c}).ElementNames()
+			unique := len(names) > 1
+			for _, name := range names {
+				unique = unique && positions[name] == 1
+			}
+			if unique {
+				groups = append(groups, c)
+			}
+			return
+		}
+		for _, child := range c.Children {
+			walk(child)
+		}
+	}
+	walk(m.Root)
+	return groups
+}
+
+// groupOccurrences returns how often the repetitions of a group of the model may occur
+func (m *ContentModel) groupOccurrences(group *ContentParticle) (min, max int) {
+	const placeholder = "#group" // Not a valid element name, so it names nothing else
+	var clone func(c *ContentParticle) *ContentParticle
+	clone = func(c *ContentParticle) *ContentParticle {
+		if c == group {
+			return &ContentParticle{Kind: ParticleElement, Name: placeholder, Indicator: c.Indicator}
+		}
+		copied := *c
+		copied.Children = nil
+		for _, child := range c.Children {
+			copied.Children = append(copied.Children, clone(child))
+		}
+		return &copied
+	}
+	return (&ContentModel{Kind: m.Kind, Root: clone(m.Root)}).Occurrences(placeholder)
+}
+
+// preceding returns, for every element of the particle, the elements that may come right
+// before it. The particle's own occurrence indicator is left out, so for a group these
+// are the elements preceding each other within one repetition.
+func (c *ContentParticle) preceding() map[string][]string {
+	follow := make(map[string]map[string]bool)
+	body := *c
+	body.Indicator = 0
+	body.positions(follow)
+
+	preceding := make(map[string][]string)
+	for _, name := range (&ContentModel{Root: c}).ElementNames() {
+		for _, before := range (&ContentModel{Root: c}).ElementNames() {
+			if follow[before][name] {
+				preceding[name] = append(preceding[name], before)
+			}
+		}
+	}
+	return preceding
+}
+
+// positions computes the elements a particle may start and end with and whether it may
+// be empty, recording in follow which elements may come right after each other. The
+// element names must be distinct, as in the groups of repeatedGroups.
+func (c *ContentParticle) positions(follow map[string]map[string]bool) (first, last []string, nullable bool) {
+	switch c.Kind {
+	case ParticleElement:
+		first, last = []string{c.Name}, []string{c.Name}
+	case ParticleChoice:
+		for _, child := range c.Children {
+			childFirst, childLast, childNullable := child.positions(follow)
+			first = append(first, childFirst...)
+			last = append(last, childLast...)
+			nullable = nullable || childNullable
+		}
+	case ParticleSequence:
+		nullable = true
+		for _, child := range c.Children {
+			childFirst, childLast, childNullable := child.positions(follow)
+			for _, before := range last {
+				for _, after := range childFirst {
+					if follow[before] == nil {
+						follow[before] = make(map[string]bool)
+					}
+					follow[before][after] = true
+				}
+			}
+			if nullable {
+				first = append(first, childFirst...)
+			}
+			if childNullable {
+				last = append(last, childLast...)
+			} else {
+				last = append([]string(nil), childLast...)
+			}
+			nullable = nullable && childNullable
+		}
+	}
+
+	if c.Indicator == '*' || c.Indicator == '+' {
+		for _, before := range last {
+			for _, after := range first {
+				if follow[before] == nil {
+					follow[before] = make(map[string]bool)
+				}
+				follow[before][after] = true
+			}
+		}
+	}
+	return first, last, nullable || c.Indicator == '?' || c.Indicator == '*'
+}
+
+// contentGroups returns the repeated groups of an element's struct that keep their
+// grouping. Structs lifting a wrapper's fields with -inline-wrappers have none, and so do
+// structs with a positional sequence, which decode all their elements in document order.
+func (g *StructGenerator) contentGroups(element *DTDElement) []contentGroup {
+	if g.options.GroupStyle != GroupStyleAnonymous && g.options.GroupStyle != GroupStyleNamed || g.positionalSequence(element) != nil {
+		return nil
+	}
+	if _, ok := g.inlinedChild(element); ok {
+		return nil
+	}
+	model, err := ParseContentModel(element.Content)
+	if err != nil {
+		return nil
+	}
+	particles := model.repeatedGroups()
+	if len(particles) == 0 {
+		return nil
+	}
+
+	grouped := make(map[string]bool)
+	for _, particle := range particles {
+		for _, name := range (&ContentModel{Root: particle}).ElementNames() {
+			grouped[name] = true
+		}
+	}
+	taken := map[string]bool{"Text": true}
+	for _, attr := range element.Attributes {
+		taken[g.toGoFieldName(attr.Name)] = true
+	}
+	for _, field := range g.parseContentModel(element.Content) {
+		if !grouped[field.Element] {
+			taken[field.Name] = true
+		}
+	}
+	types := make(map[string]bool)
+	for _, name := range g.elementOrder {
+		if _, exists := g.elements[name]; exists && !g.isSimpleElement(name) {
+			types[g.toGoStructName(name)] = true
+		}
+	}
+	for _, enum := range g.enumTypes() {
+		types[enum.Name] = true
+	}
+
+	var groups []contentGroup
+	structName := g.toGoStructName(element.Name)
+	for _, particle := range particles {
+		body := *particle
+		body.Indicator = 0
+		group := contentGroup{
+			Particle:  particle,
+			Fields:    g.parseContentModel(body.String()),
+			Preceding: particle.preceding(),
+		}
+
+		first := g.toGoFieldName((&ContentModel{Root: particle}).ElementNames()[0])
+		fieldName := first + "Group"
+		for n := 2; taken[fieldName]; n++ {
+			fieldName = fmt.Sprintf("%sGroup%d", first, n)
+		}
+		taken[fieldName] = true
+
+		repetition := g.anonymousGroupType(group.Fields)
+		if g.options.GroupStyle == GroupStyleNamed {
+			group.TypeName = structName + fieldName
+			for types[group.TypeName] {
+				group.TypeName += "Repetition"
+			}
+			types[group.TypeName] = true
+			repetition = group.TypeName
+		}
+
+		min, max := model.groupOccurrences(particle)
+		group.Field = goField{
+			Name:   fieldName,
+			Type:   "[]" + repetition,
+			Tag:    "-",
+			Occurs: &occurrence{Min: min, Max: max},
+			Explain: fmt.Sprintf("from %s -> slice of a struct per repetition of %s because -group-style %s",
+				element.Content, particle, g.options.GroupStyle),
+		}
+		if g.options.NoXMLTags {
+			group.Field.Tag = ""
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// contentGroupOf returns the group of an element's struct holding the given child
+// element, or nil
+func contentGroupOf(groups []contentGroup, child string) *contentGroup {
+	for i := range groups {
+		for _, field := range groups[i].Fields {
+			if field.Element == child {
+				return &groups[i]
+			}
+		}
+	}
+	return nil
+}
+
+// groupField returns the group whose field of an element's struct is the given one, or nil
+func groupField(groups []contentGroup, field goField) *contentGroup {
+	for i := range groups {
+		if groups[i].Field.Name == field.Name && field.Element == "" {
+			return &groups[i]
+		}
+	}
+	return nil
+}
+
+// anonymousGroupType returns the anonymous struct type of a repetition of a group, as it
+// appears in the struct holding the group
+func (g *StructGenerator) anonymousGroupType(fields []goField) string {
+	var builder strings.Builder
+	builder.WriteString("struct {\n")
+	for _, field := range fields {
+		if g.options.NoXMLTags {
+			field.Tag = ""
+		}
+		if g.options.Explain && field.Explain != "" {
+			builder.WriteString(fmt.Sprintf("\t\t%s // %s\n", field, field.Explain))
+		} else {
+			builder.WriteString(fmt.Sprintf("\t\t%s\n", field))
+		}
+	}
+	builder.WriteString("\t}")
+	return builder.String()
+}
+
+// hasContentGroups reports whether an element's struct holds repeated groups that keep
+// their grouping
+func (g *StructGenerator) hasContentGroups(name string) bool {
+	element, exists := g.elements[name]
+	return exists && !g.isSimpleElement(name) && !g.isInlined(name) && len(g.contentGroups(element)) > 0
+}
+
+// groupChildName returns the unexported type decoding and encoding the elements of the
+// repeated groups of an element's struct one at a time, in document order
+func (g *StructGenerator) groupChildName(name string) string {
+	return lowerFirst(g.toGoStructName(name)) + "GroupChild"
+}
+
+// groupDispatch returns the code making a method call, such as "indexIDs(index)", on the
+// elements generated as structs in every repetition of a group, for the generated
+// methods that walk the elements inside a struct. It reports false for fields that do not
+// hold a group.
+func (g *StructGenerator) groupDispatch(groups []contentGroup, field goField, structs map[string]bool, call string) (string, bool) {
+	group := groupField(groups, field)
+	if group == nil {
+		return "", false
+	}
+	var builder strings.Builder
+	for _, inner := range group.Fields {
+		switch {
+		case strings.HasPrefix(inner.Type, "*") && structs[inner.Type[1:]]:
+			builder.WriteString(fmt.Sprintf("\t\tif v.%s[i].%s != nil {\n", field.Name, inner.Name))
+			builder.WriteString(fmt.Sprintf("\t\t\tv.%s[i].%s.%s\n", field.Name, inner.Name, call))
+			builder.WriteString("\t\t}\n")
+		case strings.HasPrefix(inner.Type, "[]") && structs[inner.Type[2:]]:
+			builder.WriteString(fmt.Sprintf("\t\tfor j := range v.%s[i].%s {\n", field.Name, inner.Name))
+			builder.WriteString(fmt.Sprintf("\t\t\tv.%s[i].%s[j].%s\n", field.Name, inner.Name, call))
+			builder.WriteString("\t\t}\n")
+		}
+	}
+	if builder.Len() == 0 {
+		return "", true
+	}
+	return fmt.Sprintf("\tfor i := range v.%s {\n%s\t}\n", field.Name, builder.String()), true
+}
+
+// groupRuntime holds the helper the generated setXMLForm methods start repetitions of
+// groups with
+const groupRuntime = `
+// appendRepetition appends an empty repetition of a group to s
+func appendRepetition[T any](s []T) []T {
+	var zero T
+	return append(s, zero)
+}
+`
+
+// generateContentGroups generates the named types of the repetitions of groups and, unless
+// NoXMLTags, the types decoding and encoding the elements of the groups one at a time
+func (g *StructGenerator) generateContentGroups() string {
+	var builder strings.Builder
+
+	for _, name := range g.elementOrder {
+		if !g.hasContentGroups(name) {
+			continue
+		}
+		for _, group := range g.contentGroups(g.elements[name]) {
+			if group.TypeName == "" {
+				continue
+			}
+			builder.WriteString(fmt.Sprintf("\n// %s is one repetition of the group %s of <%s>\n", group.TypeName, group.Particle, name))
+			builder.WriteString(fmt.Sprintf("type %s %s\n", group.TypeName, strings.ReplaceAll(g.anonymousGroupType(group.Fields), "\n\t", "\n")))
+		}
+	}
+
+	if g.options.NoXMLTags {
+		return builder.String()
+	}
+
+	used := false
+	for _, name := range g.elementOrder {
+		if !g.hasContentGroups(name) {
+			continue
+		}
+		used = true
+
+		// One field per element of the groups, holding a single occurrence
+		var fields []goField
+		for _, group := range g.contentGroups(g.elements[name]) {
+			for _, field := range group.Fields {
+				field.Type = "*" + strings.TrimLeft(field.Type, "*[]")
+				fields = append(fields, field)
+			}
+		}
+		doc := fmt.Sprintf("an element of a repeated group of <%s>, decoded in document order", name)
+		builder.WriteString(g.childType(g.groupChildName(name), doc, fields))
+	}
+	if used {
+		builder.WriteString(groupRuntime)
+	}
+
+	return builder.String()
+}
+
+// childType returns the type decoding and encoding one element of a struct at a time, so
+// the elements keep their document order: it has a pointer field per element, the given
+// fields with a pointer type, of which the one for the element it holds is set
+func (g *StructGenerator) childType(childName, doc string, fields []goField) string {
+	var builder strings.Builder
+
+	builder.WriteString(fmt.Sprintf("\n// %s is %s\n", childName, doc))
+	builder.WriteString(fmt.Sprintf("type %s struct {\n", childName))
+	for _, field := range fields {
+		builder.WriteString(fmt.Sprintf("\t%s %s\n", field.Name, field.Type))
+	}
+	builder.WriteString("}\n")
+
+	builder.WriteString("\n// UnmarshalXML decodes the element into the field for its name, skipping unknown elements\n")
+	builder.WriteString(fmt.Sprintf("func (c *%s) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {\n", childName))
+	builder.WriteString("\tswitch start.Name.Local {\n")
+	for _, field := range fields {
+		builder.WriteString(fmt.Sprintf("\tcase %q:\n", localName(field.Element)))
+		builder.WriteString(fmt.Sprintf("\t\tc.%s = new(%s)\n", field.Name, field.Type[1:]))
+		builder.WriteString(fmt.Sprintf("\t\treturn d.DecodeElement(c.%s, &start)\n", field.Name))
+	}
+	builder.WriteString("\t}\n")
+	builder.WriteString("\treturn d.Skip()\n")
+	builder.WriteString("}\n")
+
+	builder.WriteString("\n// MarshalXML encodes the element the child holds\n")
+	builder.WriteString(fmt.Sprintf("func (c %s) MarshalXML(e *xml.Encoder, start xml.StartElement) error {\n", childName))
+	builder.WriteString("\tswitch {\n")
+	for _, field := range fields {
+		space, local := g.xmlName(field.Element)
+		elementName := fmt.Sprintf("xml.Name{Local: %q}", local)
+		if space != "" {
+			elementName = fmt.Sprintf("xml.Name{Space: %q, Local: %q}", space, local)
+		}
+		builder.WriteString(fmt.Sprintf("\tcase c.%s != nil:\n", field.Name))
+		builder.WriteString(fmt.Sprintf("\t\treturn e.EncodeElement(c.%s, xml.StartElement{Name: %s})\n", field.Name, elementName))
+	}
+	builder.WriteString("\t}\n")
+	builder.WriteString("\treturn nil\n")
+	builder.WriteString("}\n")
+
+	return builder.String()
+}
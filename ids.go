@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// xmlNamespace is the namespace bound to the xml: prefix, which encoding/xml reports as
+// the Space of attributes such as xml:id and xml:lang
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+// xmlIDAttribute is always an ID attribute, whatever its declared type (xml:id 1.0)
+const xmlIDAttribute = "xml:id"
+
+// idIndexRuntime is the lookup table built by the generated IDs methods
+const idIndexRuntime = `
+// IDIndex maps the ID attribute values of a decoded document, such as xml:id, to the
+// elements carrying them, as pointers into the document
+type IDIndex map[string]any
+
+// Resolve returns the element an IDREF value refers to
+func (index IDIndex) Resolve(ref string) (any, bool) {
+	element, ok := index[strings.TrimSpace(ref)]
+	return element, ok
+}
+
+// ResolveAll returns the elements an IDREFS value refers to, in order, and the references
+// no element carries
+func (index IDIndex) ResolveAll(refs []string) ([]any, []string) {
+	var elements []any
+	var missing []string
+	for _, ref := range refs {
+		if element, ok := index.Resolve(ref); ok {
+			elements = append(elements, element)
+		} else {
+			missing = append(missing, ref)
+		}
+	}
+	return elements, missing
+}
+
+// add records the element carrying an ID; the first element wins, as IDs must be unique
+func (index IDIndex) add(id string, element any) {
+	id = strings.TrimSpace(id)
+	if _, exists := index[id]; id != "" && !exists {
+		index[id] = element
+	}
+}
+`
+
+// isIDAttribute reports whether an attribute identifies its element: attributes of type
+// ID, xml:id and the attributes listed in GeneratorOptions.IDAttributes as either name or
+// element@name
+func (g *StructGenerator) isIDAttribute(element *DTDElement, attr DTDAttribute) bool {
+	if strings.EqualFold(attr.Type, "ID") || attr.Name == xmlIDAttribute {
+		return true
+	}
+	for _, listed := range g.options.IDAttributes {
+		if listed == attr.Name || listed == element.Name+"@"+attr.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// generateIDIndex generates the IDIndex type, an indexIDs method for every struct and
+// IDs and FindByID methods on the document roots
+func (g *StructGenerator) generateIDIndex() string {
+	var builder strings.Builder
+
+	builder.WriteString(idIndexRuntime)
+
+	structs := make(map[string]bool) // Go types of the structs that can be indexed
+	for _, name := range g.elementOrder {
+		if _, exists := g.elements[name]; exists && !g.isSimpleElement(name) && !g.isInlined(name) {
+			structs[g.toGoStructName(name)] = true
+		}
+	}
+
+	for _, name := range g.elementOrder {
+		element, exists := g.elements[name]
+		if !exists || !structs[g.toGoStructName(name)] {
+			continue
+		}
+
+		structName := g.toGoStructName(name)
+		builder.WriteString(fmt.Sprintf("\n// indexIDs adds the IDs of this <%s> and the elements inside it to index\n", name))
+		builder.WriteString(fmt.Sprintf("func (v *%s) indexIDs(index IDIndex) {\n", structName))
+		for _, attr := range element.Attributes {
+			if g.isIDAttribute(element, attr) && g.getGoType(attr.Type) == "string" && g.enumTypeName(element, attr) == "" &&
+				g.typeMapping(name, attr.Name) == nil {
+				builder.WriteString(fmt.Sprintf("\tindex.add(v.%s, v)\n", g.attributeFieldName(element, attr)))
+			}
+		}
+		interfaces := g.choiceInterfaces(element)
+		groups := g.contentGroups(element)
+		for _, field := range g.structFields(element) {
+			if code, ok := g.mixedDispatch(element, field, structs, "indexIDs(index)"); ok {
+				builder.WriteString(code)
+				continue
+			}
+			if field.Occurs == nil {
+				continue
+			}
+			if code, ok := g.choiceDispatch(interfaces, field, "indexIDs(index)"); ok {
+				builder.WriteString(code)
+				continue
+			}
+			if code, ok := g.groupDispatch(groups, field, structs, "indexIDs(index)"); ok {
+				builder.WriteString(code)
+				continue
+			}
+			switch {
+			case strings.HasPrefix(field.Type, "*") && structs[field.Type[1:]]:
+				builder.WriteString(fmt.Sprintf("\tif v.%s != nil {\n", field.Name))
+				builder.WriteString(fmt.Sprintf("\t\tv.%s.indexIDs(index)\n", field.Name))
+				builder.WriteString("\t}\n")
+			case structs[field.Type]:
+				builder.WriteString(fmt.Sprintf("\tv.%s.indexIDs(index)\n", field.Name))
+			case strings.HasPrefix(field.Type, "[]") && structs[field.Type[2:]]:
+				builder.WriteString(fmt.Sprintf("\tfor i := range v.%s {\n", field.Name))
+				builder.WriteString(fmt.Sprintf("\t\tv.%s[i].indexIDs(index)\n", field.Name))
+				builder.WriteString("\t}\n")
+			}
+		}
+		builder.WriteString("}\n")
+	}
+
+	for _, name := range g.documentRoots() {
+		structName := g.toGoStructName(name)
+		builder.WriteString(fmt.Sprintf("\n// IDs indexes the elements of the <%s> document by their ID attributes\n", name))
+		builder.WriteString(fmt.Sprintf("func (v *%s) IDs() IDIndex {\n", structName))
+		builder.WriteString("\tindex := make(IDIndex)\n")
+		builder.WriteString("\tv.indexIDs(index)\n")
+		builder.WriteString("\treturn index\n")
+		builder.WriteString("}\n")
+
+		builder.WriteString(fmt.Sprintf("\n// FindByID returns the element of the <%s> document whose ID attribute is id. Use IDs\n", name))
+		builder.WriteString("// instead for repeated lookups.\n")
+		builder.WriteString(fmt.Sprintf("func (v *%s) FindByID(id string) (any, bool) {\n", structName))
+		builder.WriteString("\treturn v.IDs().Resolve(id)\n")
+		builder.WriteString("}\n")
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// importSet collects the packages generated code imports and picks the name each is
+// referred to by. Standard library packages keep their own names; third-party packages
+// whose names collide with an earlier import, or cannot be written as an identifier,
+// get an alias.
+type importSet struct {
+	paths map[string]bool
+	names map[string]string // Import path to the name used in generated code
+}
+
+// add records an import path
+func (s *importSet) add(path string) {
+	if s.paths == nil {
+		s.paths = make(map[string]bool)
+	}
+	s.paths[path] = true
+	s.names = nil
+}
+
+// keepUsed drops the imports code does not refer to, keeping the names picked for the
+// rest, as code generated for an option may not use every package it can. Code that does
+// not parse keeps them all.
+func (s *importSet) keepUsed(code string) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", "package p\n\n"+code, parser.SkipObjectResolution)
+	if err != nil {
+		return
+	}
+	used := make(map[string]bool)
+	ast.Inspect(file, func(node ast.Node) bool {
+		if selector, ok := node.(*ast.SelectorExpr); ok {
+			if ident, ok := selector.X.(*ast.Ident); ok {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+	for path := range s.paths {
+		if !used[s.name(path)] {
+			delete(s.paths, path)
+		}
+	}
+}
+
+// isStandardLibrary reports whether an import path belongs to the standard library,
+// whose first path element never contains a dot
+func isStandardLibrary(path string) bool {
+	return !strings.Contains(strings.Split(path, "/")[0], ".")
+}
+
+// groups returns the standard library and third-party import paths, each sorted
+func (s *importSet) groups() (std, external []string) {
+	for path := range s.paths {
+		if isStandardLibrary(path) {
+			std = append(std, path)
+		} else {
+			external = append(external, path)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(external)
+	return std, external
+}
+
+// name returns the identifier generated code uses to refer to an imported package
+func (s *importSet) name(path string) string {
+	if s.names == nil {
+		s.resolve()
+	}
+	if name, ok := s.names[path]; ok {
+		return name
+	}
+	return importName(path)
+}
+
+// resolve assigns names in a fixed order, standard library first, so the same set of
+// imports always produces the same aliases
+func (s *importSet) resolve() {
+	s.names = make(map[string]string)
+	taken := make(map[string]bool)
+
+	std, external := s.groups()
+	for _, path := range append(std, external...) {
+		elems := importElems(path)
+		name := importName(path)
+		for i := len(elems) - 2; taken[name] && i >= 0; i-- {
+			// Qualify with parent path elements: github.com/acme/xml becomes acmexml
+			name = identifier(elems[i]) + name
+		}
+		for n := 2; taken[name] || name == ""; n++ {
+			name = fmt.Sprintf("%s%d", importName(path), n)
+		}
+		taken[name] = true
+		s.names[path] = name
+	}
+}
+
+// render generates the import declaration, or "" when nothing is imported
+func (s *importSet) render() string {
+	std, external := s.groups()
+	if len(std)+len(external) == 0 {
+		return ""
+	}
+
+	spec := func(path string) string {
+		if name := s.name(path); name != lastImportElem(path) {
+			return fmt.Sprintf("%s %q", name, path)
+		}
+		return fmt.Sprintf("%q", path)
+	}
+
+	if len(std)+len(external) == 1 {
+		return fmt.Sprintf("import %s\n\n", spec(append(std, external...)[0]))
+	}
+
+	var builder strings.Builder
+	builder.WriteString("import (\n")
+	for _, path := range std {
+		builder.WriteString(fmt.Sprintf("\t%s\n", spec(path)))
+	}
+	if len(std) > 0 && len(external) > 0 {
+		builder.WriteString("\n")
+	}
+	for _, path := range external {
+		builder.WriteString(fmt.Sprintf("\t%s\n", spec(path)))
+	}
+	builder.WriteString(")\n\n")
+	return builder.String()
+}
+
+// importElems returns the elements of an import path without a major version suffix
+func importElems(path string) []string {
+	elems := strings.Split(path, "/")
+	if len(elems) > 1 && isMajorVersion(elems[len(elems)-1]) {
+		elems = elems[:len(elems)-1]
+	}
+	return elems
+}
+
+// lastImportElem returns the name a package is referred to by without an alias
+func lastImportElem(path string) string {
+	elems := strings.Split(path, "/")
+	return elems[len(elems)-1]
+}
+
+// importName returns the conventional name of an imported package, e.g. "yaml" for
+// gopkg.in/yaml.v3 and "uuid" for github.com/google/uuid/v2
+func importName(path string) string {
+	elems := importElems(path)
+	last := elems[len(elems)-1]
+	if i := strings.Index(last, ".v"); i > 0 && isMajorVersion(last[i+1:]) {
+		last = last[:i]
+	}
+	last = strings.TrimPrefix(last, "go-")
+	return identifier(last)
+}
+
+// identifier lowercases a path element and drops the characters identifiers cannot hold
+func identifier(elem string) string {
+	var name strings.Builder
+	for _, c := range strings.ToLower(elem) {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) {
+			name.WriteRune(c)
+		}
+	}
+	result := name.String()
+	if result != "" && (unicode.IsDigit([]rune(result)[0]) || token.IsKeyword(result)) {
+		result = "pkg" + result
+	}
+	return result
+}
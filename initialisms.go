@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// commonInitialisms are the words Go names keep in upper case, such as the ID of ListingID,
+// after the list of golint
+var commonInitialisms = []string{
+	"ACL", "API", "ASCII", "CPU", "CSS", "DNS", "EOF", "GUID", "HTML", "HTTP", "HTTPS", "ID",
+	"IP", "JSON", "LHS", "QPS", "RAM", "RHS", "RPC", "SLA", "SMTP", "SQL", "SSH", "TCP",
+	"TLS", "TTL", "UDP", "UI", "UID", "UUID", "URI", "URL", "UTF8", "VM", "XML", "XMPP",
+	"XSRF", "XSS",
+}
+
+// initialismCase returns a DTD name with the words that are initialisms in upper case, so
+// listing-id and image_url form the Go identifiers ListingID and ImageURL. Words are split
+// as by identifierWords and match an initialism in any case; the configured Initialisms
+// count from any compatibility level and the common ones from level 3 on.
+func (g *StructGenerator) initialismCase(name string) string {
+	if len(g.options.Initialisms) == 0 && g.options.Compat < 3 {
+		return name
+	}
+	initialisms := make(map[string]bool)
+	for _, initialism := range g.options.Initialisms {
+		initialisms[strings.ToUpper(initialism)] = true
+	}
+	if g.options.Compat >= 3 {
+		for _, initialism := range commonInitialisms {
+			initialisms[initialism] = true
+		}
+	}
+
+	var result strings.Builder
+	word := 0 // Start of the current word
+	for i, r := range name + "-" {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) {
+			continue
+		}
+		if upper := strings.ToUpper(name[word:i]); initialisms[upper] {
+			result.WriteString(upper)
+		} else {
+			result.WriteString(name[word:i])
+		}
+		if i < len(name) {
+			result.WriteRune(r)
+		}
+		word = i + utf8.RuneLen(r)
+	}
+	return result.String()
+}
+
+// checkInitialisms rejects configured initialisms that could not be words of Go
+// identifiers: empty ones, and ones other than ASCII letters and digits
+func checkInitialisms(initialisms []string) error {
+	for _, initialism := range initialisms {
+		if initialism == "" {
+			return fmt.Errorf("empty initialism")
+		}
+		for _, r := range initialism {
+			if r >= unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+				return fmt.Errorf("initialism %q: initialisms must be ASCII letters or digits", initialism)
+			}
+		}
+	}
+	return nil
+}
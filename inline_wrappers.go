@@ -0,0 +1,59 @@
+package main
+
+import "strings"
+
+// inlinedChild reports the child whose fields are lifted into element's struct. This
+// applies to <!ELEMENT a (b)> when b is only ever used inside a and carries nothing a
+// path tag (a>b>c) cannot express: no attributes, text or ANY content.
+func (g *StructGenerator) inlinedChild(element *DTDElement) (string, bool) {
+	if !g.options.InlineWrappers {
+		return "", false
+	}
+
+	content := strings.Join(strings.Fields(element.Content), "")
+	children := contentChildren(element.Content)
+	if len(children) != 1 || content != "("+children[0].Name+")" {
+		return "", false
+	}
+
+	name := children[0].Name
+	child, exists := g.elements[name]
+	if !exists || name == element.Name || g.isSimpleElement(name) {
+		return "", false
+	}
+
+	childContent := strings.TrimSpace(child.Content)
+	if len(child.Attributes) > 0 || g.canContainText(childContent) || childContent == "ANY" || childContent == "EMPTY" {
+		return "", false
+	}
+
+	if parents := g.parentsOf(name); len(parents) != 1 || parents[0] != element.Name {
+		return "", false
+	}
+
+	return name, true
+}
+
+// isInlined reports whether an element's fields are lifted into its only parent
+func (g *StructGenerator) isInlined(name string) bool {
+	if !g.options.InlineWrappers {
+		return false
+	}
+	for _, parent := range g.parentsOf(name) {
+		if element, exists := g.elements[parent]; exists {
+			if wrapped, ok := g.inlinedChild(element); ok && wrapped == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parentsOf returns the elements whose content models reference name
+func (g *StructGenerator) parentsOf(name string) []string {
+	g.graphOnce.Do(func() {
+		result := &ParseResult{Elements: g.elements, Order: g.elementOrder}
+		g.graph = result.Graph()
+	})
+	return g.graph.Parents(name)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// javaKeywords lists Java reserved words that cannot be used as field names
+var javaKeywords = map[string]bool{
+	"abstract": true, "assert": true, "boolean": true, "break": true, "byte": true, "case": true,
+	"catch": true, "char": true, "class": true, "const": true, "continue": true, "default": true,
+	"do": true, "double": true, "else": true, "enum": true, "extends": true, "final": true,
+	"finally": true, "float": true, "for": true, "goto": true, "if": true, "implements": true,
+	"import": true, "instanceof": true, "int": true, "interface": true, "long": true, "native": true,
+	"new": true, "package": true, "private": true, "protected": true, "public": true, "return": true,
+	"short": true, "static": true, "strictfp": true, "super": true, "switch": true, "synchronized": true,
+	"this": true, "throw": true, "throws": true, "transient": true, "try": true, "void": true,
+	"volatile": true, "while": true, "true": true, "false": true, "null": true,
+}
+
+// JavaGenerator generates JAXB-annotated Java classes from DTD elements
+type JavaGenerator struct {
+	packageName  string
+	className    string
+	elements     map[string]*DTDElement
+	elementOrder []string
+	names        *StructGenerator // Reused for class naming so all backends agree
+}
+
+// NewJavaGenerator creates a new Java class generator. All element classes are emitted
+// as public static nested classes of className, since a Java file may only hold one
+// public top-level class.
+func NewJavaGenerator(packageName, className string, elements map[string]*DTDElement, elementOrder []string) *JavaGenerator {
+	return &JavaGenerator{
+		packageName:  packageName,
+		className:    className,
+		elements:     elements,
+		elementOrder: elementOrder,
+		names:        NewStructGenerator("", elements, elementOrder, GeneratorOptions{}),
+	}
+}
+
+// GenerateClasses generates Java source code for all elements
+func (g *JavaGenerator) GenerateClasses() string {
+	var builder strings.Builder
+
+	if g.packageName != "" {
+		builder.WriteString(fmt.Sprintf("package %s;\n\n", g.packageName))
+	}
+	builder.WriteString("import java.util.ArrayList;\n")
+	builder.WriteString("import java.util.List;\n\n")
+	builder.WriteString("import jakarta.xml.bind.annotation.*;\n\n")
+
+	builder.WriteString("/** JAXB classes generated from a DTD by dtd-to-go. */\n")
+	builder.WriteString(fmt.Sprintf("public final class %s {\n", g.className))
+	builder.WriteString(fmt.Sprintf("    private %s() {}\n", g.className))
+
+	for _, model := range BuildElementModels(g.elements, g.elementOrder) {
+		builder.WriteString("\n")
+		builder.WriteString(g.generateClass(model))
+	}
+
+	builder.WriteString("}\n")
+
+	return builder.String()
+}
+
+// generateClass generates a nested Java class for a single element
+func (g *JavaGenerator) generateClass(model *ElementModel) string {
+	var builder strings.Builder
+
+	className := g.names.toGoStructName(model.Name)
+
+	builder.WriteString(fmt.Sprintf("    /** Represents the &lt;%s&gt; element. */\n", model.Name))
+	builder.WriteString(fmt.Sprintf("    @XmlRootElement(name = %q)\n", model.Name))
+	builder.WriteString("    @XmlAccessorType(XmlAccessType.FIELD)\n")
+	builder.WriteString(fmt.Sprintf("    public static class %s {\n", className))
+
+	var members []string
+
+	// Fields whose names collide, such as those of the attribute title and the child
+	// <title>, are numbered apart as in the Go structs
+	var reserved []string
+	if model.AnyContent {
+		reserved = append(reserved, "content")
+	}
+	if model.HasText {
+		reserved = append(reserved, "text")
+	}
+	names := model.memberNames(g.toJavaFieldName, reserved...)
+
+	for i, attr := range model.Attributes {
+		fieldName := names[i]
+		annotation := fmt.Sprintf("@XmlAttribute(name = %q", attr.Name)
+		if attr.Required {
+			annotation += ", required = true"
+		}
+		annotation += ")"
+
+		if isListAttributeType(attr.Type) {
+			members = append(members, fmt.Sprintf("%s\n        @XmlList\n        public List<String> %s = new ArrayList<>();", annotation, fieldName))
+			continue
+		}
+
+		if attr.DefaultValue != "" {
+			members = append(members, fmt.Sprintf("%s\n        public String %s = %q;", annotation, fieldName, attr.DefaultValue))
+		} else {
+			members = append(members, fmt.Sprintf("%s\n        public String %s;", annotation, fieldName))
+		}
+	}
+
+	for i, child := range model.Children {
+		elemType := "String"
+		if !isSimpleElement(g.elements, child.Name) {
+			elemType = g.names.toGoStructName(child.Name)
+		}
+		fieldName := names[len(model.Attributes)+i]
+		annotation := fmt.Sprintf("@XmlElement(name = %q)", child.Name)
+
+		if child.Repeated {
+			members = append(members, fmt.Sprintf("%s\n        public List<%s> %s = new ArrayList<>();", annotation, elemType, fieldName))
+		} else {
+			members = append(members, fmt.Sprintf("%s\n        public %s %s;", annotation, elemType, fieldName))
+		}
+	}
+
+	if model.AnyContent {
+		members = append(members, "@XmlMixed\n        @XmlAnyElement(lax = true)\n        public List<Object> content = new ArrayList<>();")
+	}
+
+	if model.HasText {
+		members = append(members, "@XmlValue\n        public String text;")
+	}
+
+	for i, member := range members {
+		if i > 0 {
+			builder.WriteString("\n")
+		}
+		builder.WriteString(fmt.Sprintf("        %s\n", member))
+	}
+
+	builder.WriteString("    }\n")
+
+	return builder.String()
+}
+
+// toJavaFieldName converts a DTD element/attribute name to a camelCase Java field name
+func (g *JavaGenerator) toJavaFieldName(name string) string {
+	runes := []rune(g.names.toGoFieldName(name))
+	if len(runes) == 0 {
+		return "field"
+	}
+
+	// Lower-case the leading run of capitals (ID -> id, URLValue -> urlValue)
+	for i := 0; i < len(runes) && unicode.IsUpper(runes[i]); i++ {
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+
+	fieldName := string(runes)
+	if javaKeywords[fieldName] {
+		fieldName += "_"
+	}
+
+	return fieldName
+}
+
+// isJavaIdentifier reports whether name can name a Java class: letters, digits, _ and $,
+// not starting with a digit, and not a reserved word
+func isJavaIdentifier(name string) bool {
+	if name == "" || javaKeywords[name] {
+		return false
+	}
+	for i, r := range name {
+		if !unicode.IsLetter(r) && r != '_' && r != '$' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// LintIssue is a problem found by a lint rule
+type LintIssue struct {
+	Position Position
+	Rule     string
+	Message  string
+}
+
+// String formats the issue with its position and rule
+func (i LintIssue) String() string {
+	return fmt.Sprintf("%s: %s [%s]", i.Position, i.Message, i.Rule)
+}
+
+// lintRule checks one element declaration
+type lintRule struct {
+	Name  string
+	Check func(result *ParseResult, element *DTDElement) []string
+}
+
+// lintRules are the checks run by Lint, in reporting order
+var lintRules = []lintRule{
+	{Name: "nondeterministic-content", Check: checkDeterministic},
+}
+
+// lintDirective starts a comment that silences lint rules for the declaration it precedes,
+// trails or sits in: <!-- dtd-to-go:disable nondeterministic-content -->. Without rule
+// names it silences every rule.
+const lintDirective = "dtd-to-go:disable"
+
+// lintDirectives returns the rules disabled by the dtd-to-go:disable comments among comments
+func lintDirectives(comments []dtdComment) []string {
+	var rules []string
+	for _, comment := range comments {
+		if !strings.HasPrefix(comment.Text, lintDirective) {
+			continue
+		}
+		names := strings.FieldsFunc(strings.TrimPrefix(comment.Text, lintDirective), func(c rune) bool {
+			return c == ',' || c == ' ' || c == '\t'
+		})
+		if len(names) == 0 {
+			names = []string{"*"}
+		}
+		rules = append(rules, names...)
+	}
+	return rules
+}
+
+// disableLint records rules disabled for the declaration at the current position
+func (p *DTDParser) disableLint(rules []string) {
+	if len(rules) > 0 {
+		p.lintDisabled[p.position] = append(p.lintDisabled[p.position], rules...)
+	}
+}
+
+// LintConfig turns lint rules off, entirely or for some elements, so known legacy quirks
+// do not drown out new problems. It is read from JSON:
+//
+//	{"disable": ["nondeterministic-content"], "exclude": {"nondeterministic-content": ["legacy-*"]}}
+type LintConfig struct {
+	Disable []string            `json:"disable"` // Rules not run at all
+	Exclude map[string][]string `json:"exclude"` // Rule to the elements it skips, as path.Match patterns
+}
+
+// LoadLintConfig reads a lint configuration file, rejecting unknown rule names so typos
+// do not silently keep a rule enabled
+func LoadLintConfig(filename string) (LintConfig, error) {
+	var config LintConfig
+	file, err := os.Open(filename)
+	if err != nil {
+		return config, fmt.Errorf("failed to open lint config: %w", err)
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return config, fmt.Errorf("failed to parse lint config %s: %w", filename, err)
+	}
+
+	rules := config.Disable
+	for rule, patterns := range config.Exclude {
+		rules = append(rules, rule)
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return config, fmt.Errorf("lint config %s: bad element pattern %q for %s: %w", filename, pattern, rule, err)
+			}
+		}
+	}
+	sort.Strings(rules)
+	for _, rule := range rules {
+		if !isLintRule(rule) {
+			return config, fmt.Errorf("lint config %s: unknown rule %q", filename, rule)
+		}
+	}
+	return config, nil
+}
+
+// isLintRule reports whether name is the name of a lint rule
+func isLintRule(name string) bool {
+	for _, rule := range lintRules {
+		if rule.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// suppresses reports whether the configuration or a dtd-to-go:disable comment silences
+// a rule for an element
+func (c LintConfig) suppresses(result *ParseResult, rule string, element *DTDElement) bool {
+	for _, disabled := range c.Disable {
+		if disabled == rule {
+			return true
+		}
+	}
+	for _, pattern := range c.Exclude[rule] {
+		if matched, _ := path.Match(pattern, element.Name); matched {
+			return true
+		}
+	}
+	for _, disabled := range result.LintDisabled[element.Position] {
+		if disabled == rule || disabled == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Lint runs every lint rule over the parsed DTD's element declarations, leaving out the
+// issues config or dtd-to-go:disable comments suppress
+func Lint(result *ParseResult, config LintConfig) []LintIssue {
+	var issues []LintIssue
+	for _, name := range result.Order {
+		element, exists := result.Elements[name]
+		if !exists {
+			continue
+		}
+		for _, rule := range lintRules {
+			if config.suppresses(result, rule.Name, element) {
+				continue
+			}
+			for _, message := range rule.Check(result, element) {
+				issues = append(issues, LintIssue{Position: element.Position, Rule: rule.Name, Message: message})
+			}
+		}
+	}
+	return issues
+}
+
+// checkDeterministic reports content models a validating parser must reject because
+// they are not deterministic, e.g. ((a, b) | (a, c))
+func checkDeterministic(result *ParseResult, element *DTDElement) []string {
+	model, err := ParseContentModel(element.Content)
+	if err != nil {
+		return nil
+	}
+	prefix, ambiguous := model.Ambiguity()
+	if !ambiguous {
+		return nil
+	}
+	return []string{fmt.Sprintf("content model %s of <%s> is not deterministic: in the prefix %q the last <%s> can match more than one particle",
+		element.Content, element.Name, strings.Join(prefix, ", "), prefix[len(prefix)-1])}
+}
+
+// runLint implements the "lint" subcommand. It exits with status 1 when issues are found.
+func runLint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	inputFile := flags.String("input", "", "Path to the DTD file to lint (required)")
+	configFile := flags.String("config", "", "Path to a JSON lint config disabling rules or excluding elements from them")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	if *inputFile == "" {
+		fmt.Fprintf(os.Stderr, "Usage: %s lint -input <dtd-file> [-config <json-file>]\n", os.Args[0])
+		return 1
+	}
+
+	var config LintConfig
+	if *configFile != "" {
+		var err error
+		if config, err = LoadLintConfig(*configFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+
+	result, err := NewDTDParser(ParserOptions{}).ParseFile(*inputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing DTD file: %v\n", err)
+		return 1
+	}
+
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	var unknown []string
+	for position, rules := range result.LintDisabled {
+		for _, rule := range rules {
+			if rule != "*" && !isLintRule(rule) {
+				unknown = append(unknown, fmt.Sprintf("%s: %s names unknown lint rule %q", position, lintDirective, rule))
+			}
+		}
+	}
+	sort.Strings(unknown)
+	for _, warning := range unknown {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+
+	issues := Lint(result, config)
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	if len(issues) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -1,92 +1,652 @@
+// The dtd-to-go command generates Go structs, and code in other languages, from DTD
+// files. See the README for its flags and subcommands.
+//
+// The parser and generator types, such as ParseResult, Graph, Generation, OutputFS and
+// the error types, are exported for the subcommands, the serve mode and the tests only:
+// package main cannot be imported, and they may change in any release.
 package main
 
 import (
 	"flag"
 	"fmt"
+	"go/parser"
+	"go/token"
 	"os"
 	"path/filepath"
 	"strings"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "registry":
+			os.Exit(runRegistry(os.Args[2:]))
+		case "lint":
+			os.Exit(runLint(os.Args[2:]))
+		case "validate":
+			os.Exit(runValidate(os.Args[2:]))
+		case "report":
+			os.Exit(runReport(os.Args[2:]))
+		case "serve":
+			os.Exit(runServe(os.Args[2:]))
+		case "model-schema":
+			fmt.Print(ModelSchema)
+			return
+		}
+	}
+
 	var (
-		inputFile   = flag.String("input", "", "Path to the DTD file to parse")
+		inputFile   = flag.String("input", "", "Path to the DTD file to parse, or an XML document whose DOCTYPE references one")
 		outputFile  = flag.String("output", "", "Path to output Go file (default: stdout)")
-		packageName = flag.String("package", "main", "Go package name for generated structs")
+		packageName = flag.String("package", "main", "Go package name for generated structs, or auto to infer it from the output directory")
+		format      = flag.String("format", "go", "Output format: go, python, java, csharp, avro, parquet, ent, mock, json or events")
+		generic     = flag.Bool("generic", false, "Also generate DecodeGeneric for schema-driven map decoding (go format)")
+		anyStyle    = flag.String("any-style", AnyStyleInnerXML, "Representation of ANY content: innerxml, elements or union (go format)")
+		enumStyle   = flag.String("enum-style", EnumStyleString, "Representation of enumerated attributes: string, int or typed (go format)")
+		emptyStyle  = flag.String("empty-style", EmptyStyleStruct, "Representation of EMPTY elements without attributes: struct or bool (go format)")
+		choiceStyle = flag.String("choice-style", ChoiceStyleFields, "Representation of exclusive choices between elements: fields or interface (go format)")
+		groupStyle  = flag.String("group-style", GroupStyleFlat, "Representation of repeated groups of elements: flat, anonymous or named (go format)")
+		pointers    = flag.String("pointer-policy", "", "Child elements occurring at most once held through pointers: all, optional-only or none (go format, default: optional-only, all before -compat 4)")
+		mixedStyle  = flag.String("mixed-style", MixedStyleText, "Representation of mixed content with child elements: text or nodes (go format)")
+		sequence    = flag.String("sequence-style", SequenceStyleMerged, "Representation of elements occurring at several places of a sequence: merged or positional (go format)")
+		compat      = flag.Int("compat", CompatLatest, "Compatibility level to pin the field planning and naming to, so newer versions generate the same API (go format)")
+		optEnums    = flag.String("optional-enums", OptionalEnumZero, "Representation of optional int enums: zero, unset or pointer (go format)")
+		occurrences = flag.Bool("occurrences", false, "Also generate Occurrences() min/max metadata for child element fields (go format)")
+		contentRe   = flag.Bool("content-regexp", false, "Also generate the content models as regular expressions with MatchContent (go format)")
+		explain     = flag.Bool("explain-decisions", false, "Annotate every generated field with the rule that produced it (go format)")
+		violations  = flag.Bool("violation-hooks", false, "Report enumeration and content model violations to an injectable ViolationRecorder (go format)")
+		foldCase    = flag.Bool("case-insensitive", false, "Match element and attribute names case-insensitively when decoding, marshaling the DTD spelling (go format)")
+		parents     = flag.Bool("parents-index", false, "Also generate ParentsOf, listing the elements allowed to contain each element (go format)")
+		noXMLTags   = flag.Bool("no-xml-tags", false, "Generate plain structs without xml tags, XMLName fields or encoding/xml (go format)")
+		tinyGo      = flag.Bool("tinygo", false, "Generate hand-rolled XML decoders and encoders instead of encoding/xml, for TinyGo and small binaries (go format)")
+		sample      = flag.String("sample", "", "Reduce the model to the elements and attributes used by this sample XML document")
+		only        = flag.String("only", "", "Regenerate only the structs of these comma separated elements in the existing -output file (go format)")
+		prune       = flag.Bool("prune", false, "Drop unused entities and attributes no generated type can hold, reporting what was pruned")
+		strict      = flag.Bool("strict", false, "Fail on conflicting declarations and declarations that break the XML 1.0 DTD grammar instead of warning")
+		duplicates  = flag.String("duplicate-elements", DuplicateFirst, "Which declaration of an element declared more than once is used: first, last or error")
+		stream      = flag.Bool("stream", false, "Report declarations without collecting the model, bounding memory use on very large DTDs (events format)")
+		debugParse  = flag.String("debug-parse", "", "Log every declaration recognized, entity expansion and skipped construct with its position to this file, or - for stderr")
+		inline      = flag.Bool("inline-wrappers", false, "Lift single-child wrapper elements into their only parent (go format)")
+		parse       = flag.Bool("parse-helpers", false, "Also generate ParseX helpers for the document roots (go format)")
+		decodeInto  = flag.Bool("decode-into", false, "Also generate DecodeInto, applying documents to existing document roots as partial updates (go format)")
+		charset     = flag.Bool("charset", false, "Let the ParseX helpers read non-UTF-8 documents via golang.org/x/net/html/charset (go format)")
+		docFile     = flag.Bool("doc", false, "Also write a doc.go with package documentation summarizing the schema next to -output (go format)")
+		manifest    = flag.String("manifest", "", "Also write a JSON manifest of the generated files, their hashes, the schema fingerprint, tool version and options to this path")
+		normalize   = flag.Bool("normalize-attrs", false, "Normalize attribute values by their declared type when decoding, as XML 1.0 requires (go format)")
+		idIndex     = flag.Bool("id-index", false, "Also generate IDIndex with IDs and FindByID on the document roots, indexing ID attributes such as xml:id (go format)")
+		idAttrs     = flag.String("id-attrs", "", "Comma separated further attributes (name or element@name) to index as IDs with -id-index (go format)")
+		defaults    = flag.Bool("fill-defaults", false, "Fill in the DTD default values of absent attributes when decoding, as a validating parser does (go format)")
+		newFuncs    = flag.Bool("constructors", false, "Also generate NewX constructors returning structs with their attributes set to their DTD defaults, so built documents encode them (go format)")
+		validators  = flag.Bool("validate-methods", false, "Also generate Validate methods checking #REQUIRED attributes, required and repeated children and choices, returning all violations (go format)")
+		fixedCheck  = flag.Bool("validate-fixed", false, "Reject documents giving #FIXED attributes other values when decoding (go format)")
+		canonical   = flag.Bool("c14n", false, "Also generate MarshalCanonical, WriteCanonical and Canonicalize producing Canonical XML for signing and hashing (go format)")
+		attrGroups  = flag.Int("attr-groups", 0, "Embed attributes declared identically on at least this many elements as one shared struct, reporting the groups (go format, 0 disables)")
+		otel        = flag.Bool("otel", false, "Let the ParseX helpers record spans and metrics through injected tracer/metrics interfaces (go format)")
+		split       = flag.String("split", "", "Also generate Split, cutting documents into standalone fragments at these comma separated repeated elements (go format)")
+		maxLine     = flag.Int("max-line-length", 0, "Wrap generated comments at this many columns, moving long trailing comments above their line (go format, 0 disables)")
+		roots       = flag.String("root", "", "Comma separated document roots to generate Parse helpers and WriteXML for, instead of the detected ones (go format)")
+		configFile  = flag.String("config", "", "JSON generator config with a redaction section listing the sensitive fields Redact blanks or hashes (go format)")
 	)
 	flag.Parse()
 
 	if *inputFile == "" {
-		fmt.Fprintf(os.Stderr, "Usage: %s -input <dtd-file> [-output <go-file>] [-package <package-name>]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s -input <dtd-file> [-output <go-file>] [-package <package-name>] [-format <format>]\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "\nOptions:\n")
-		fmt.Fprintf(os.Stderr, "  -input    Path to the DTD file to parse (required)\n")
+		fmt.Fprintf(os.Stderr, "  -input    Path to the DTD file to parse, or an XML document whose DOCTYPE references one (required)\n")
 		fmt.Fprintf(os.Stderr, "  -output   Path to output Go file (default: stdout)\n")
-		fmt.Fprintf(os.Stderr, "  -package  Go package name for generated structs (default: main)\n")
+		fmt.Fprintf(os.Stderr, "  -package  Go package name for generated structs, or auto to infer it from the output directory (default: main)\n")
+		fmt.Fprintf(os.Stderr, "  -format   Output format: go, python, java, csharp, avro, parquet, ent, mock, json or events (default: go)\n")
+		fmt.Fprintf(os.Stderr, "  -generic  Also generate DecodeGeneric for schema-driven map decoding (go format)\n")
+		fmt.Fprintf(os.Stderr, "  -any-style  Representation of ANY content: innerxml, elements or union (default: innerxml)\n")
+		fmt.Fprintf(os.Stderr, "  -enum-style  Representation of enumerated attributes: string, int or typed (default: string)\n")
+		fmt.Fprintf(os.Stderr, "  -optional-enums  Representation of optional int enums: zero, unset or pointer (default: zero)\n")
+		fmt.Fprintf(os.Stderr, "  -empty-style  Representation of EMPTY elements without attributes: struct or bool (default: struct)\n")
+		fmt.Fprintf(os.Stderr, "  -choice-style  Representation of exclusive choices between elements: fields or interface (default: fields)\n")
+		fmt.Fprintf(os.Stderr, "  -group-style   Representation of repeated groups of elements: flat, anonymous or named (default: flat)\n")
+		fmt.Fprintf(os.Stderr, "  -pointer-policy  Child elements occurring at most once held through pointers: all, optional-only or none (default: optional-only, all before -compat 4)\n")
+		fmt.Fprintf(os.Stderr, "  -mixed-style   Representation of mixed content with child elements: text or nodes (default: text)\n")
+		fmt.Fprintf(os.Stderr, "  -sequence-style  Representation of elements occurring at several places of a sequence: merged or positional (default: merged)\n")
+		fmt.Fprintf(os.Stderr, "  -compat   Compatibility level to pin the field planning and naming to, so newer versions generate the same API (default: %d)\n", CompatLatest)
+		fmt.Fprintf(os.Stderr, "  -occurrences  Also generate Occurrences() min/max metadata for child element fields (go format)\n")
+		fmt.Fprintf(os.Stderr, "  -content-regexp  Also generate the content models as regular expressions with MatchContent (go format)\n")
+		fmt.Fprintf(os.Stderr, "  -explain-decisions  Annotate every generated field with the rule that produced it (go format)\n")
+		fmt.Fprintf(os.Stderr, "  -violation-hooks  Report enumeration and content model violations to an injectable ViolationRecorder (go format)\n")
+		fmt.Fprintf(os.Stderr, "  -case-insensitive  Match element and attribute names case-insensitively when decoding, marshaling the DTD spelling (go format)\n")
+		fmt.Fprintf(os.Stderr, "  -parents-index  Also generate ParentsOf, listing the elements allowed to contain each element (go format)\n")
+		fmt.Fprintf(os.Stderr, "  -no-xml-tags  Generate plain structs without xml tags, XMLName fields or encoding/xml (go format)\n")
+		fmt.Fprintf(os.Stderr, "  -tinygo   Generate hand-rolled XML decoders and encoders instead of encoding/xml (go format)\n")
+		fmt.Fprintf(os.Stderr, "  -sample   Reduce the model to the elements and attributes used by this sample XML document\n")
+		fmt.Fprintf(os.Stderr, "  -only     Regenerate only the structs of these comma separated elements in the existing -output file (go format)\n")
+		fmt.Fprintf(os.Stderr, "  -prune    Drop unused entities and attributes no generated type can hold, reporting what was pruned\n")
+		fmt.Fprintf(os.Stderr, "  -strict   Fail on conflicting declarations and declarations that break the XML 1.0 DTD grammar\n")
+		fmt.Fprintf(os.Stderr, "  -duplicate-elements  Which declaration of an element declared more than once is used: first, last or error (default: first)\n")
+		fmt.Fprintf(os.Stderr, "  -stream   Report declarations without collecting the model, bounding memory use (events format)\n")
+		fmt.Fprintf(os.Stderr, "  -debug-parse  Log every declaration recognized, entity expansion and skipped construct with its position to a file, or - for stderr\n")
+		fmt.Fprintf(os.Stderr, "  -inline-wrappers  Lift single-child wrapper elements into their only parent (go format)\n")
+		fmt.Fprintf(os.Stderr, "  -parse-helpers  Also generate ParseX helpers for the document roots (go format)\n")
+		fmt.Fprintf(os.Stderr, "  -decode-into  Also generate DecodeInto, applying documents to existing structs as partial updates (go format)\n")
+		fmt.Fprintf(os.Stderr, "  -charset  Decode legacy charsets such as ISO-8859-1 in the ParseX helpers (go format)\n")
+		fmt.Fprintf(os.Stderr, "  -doc      Also write a doc.go with package documentation summarizing the schema next to -output (go format)\n")
+		fmt.Fprintf(os.Stderr, "  -manifest Also write a JSON manifest of the generated files with their hashes, the schema fingerprint, tool version and options\n")
+		fmt.Fprintf(os.Stderr, "  -normalize-attrs  Normalize attribute values by their declared type in the ParseX helpers or -tinygo decoders (go format)\n")
+		fmt.Fprintf(os.Stderr, "  -id-index  Also generate IDIndex with IDs and FindByID on the document roots (go format)\n")
+		fmt.Fprintf(os.Stderr, "  -id-attrs  Comma separated further attributes (name or element@name) to index as IDs (go format)\n")
+		fmt.Fprintf(os.Stderr, "  -fill-defaults  Fill in the DTD default values of absent attributes when decoding (go format)\n")
+		fmt.Fprintf(os.Stderr, "  -constructors  Also generate NewX constructors setting attributes to their DTD defaults (go format)\n")
+		fmt.Fprintf(os.Stderr, "  -validate-methods  Also generate Validate methods checking required attributes, children and choices (go format)\n")
+		fmt.Fprintf(os.Stderr, "  -validate-fixed  Reject documents giving #FIXED attributes other values when decoding (go format)\n")
+		fmt.Fprintf(os.Stderr, "  -c14n     Also generate MarshalCanonical, WriteCanonical and Canonicalize producing Canonical XML (go format)\n")
+		fmt.Fprintf(os.Stderr, "  -attr-groups  Embed attributes declared identically on at least N elements as one shared struct (go format)\n")
+		fmt.Fprintf(os.Stderr, "  -otel     Instrument the ParseX helpers with spans and metrics (go format)\n")
+		fmt.Fprintf(os.Stderr, "  -split    Also generate Split, cutting documents into standalone fragments at these comma separated elements (go format)\n")
+		fmt.Fprintf(os.Stderr, "  -max-line-length  Wrap generated comments at N columns, moving long trailing comments above their line (go format)\n")
+		fmt.Fprintf(os.Stderr, "  -root     Comma separated document roots to generate Parse helpers and WriteXML for (go format)\n")
+		fmt.Fprintf(os.Stderr, "  -config   JSON generator config; its redaction section generates Redact for sensitive fields (go format)\n")
+		fmt.Fprintf(os.Stderr, "\nSubcommands:\n")
+		fmt.Fprintf(os.Stderr, "  %s lint -input <dtd-file> [-config <json-file>]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s validate -input <dtd-file> [-workers <n>] [-json] <xml-file-or-directory>...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s report -input <dtd-file> [-output <html-file>] [-config <json-file>]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s serve -schema <name>=<dtd-file> [-schema ...] [-addr <host:port>] [-reload-interval <duration>]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s model-schema\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s registry push -registry <url> -name <name> <dtd-file>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s registry pull -registry <url> -name <name> [-fingerprint <fp>] [-output <file>]\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "\nExample:\n")
 		fmt.Fprintf(os.Stderr, "  %s -input example.dtd -output structs.go -package models\n", os.Args[0])
 		os.Exit(1)
 	}
 
+	// The event stream is written while parsing and owns stdout
+	if *stream && *format != "events" {
+		fmt.Fprintf(os.Stderr, "-stream only applies to -format events, which needs no model\n")
+		os.Exit(1)
+	}
+	switch *duplicates {
+	case DuplicateFirst, DuplicateLast, DuplicateError:
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown -duplicate-elements %q (expected first, last or error)\n", *duplicates)
+		os.Exit(1)
+	}
+	parserOptions := ParserOptions{Strict: *strict, Streaming: *stream, DuplicateElements: *duplicates}
+	if *debugParse == "-" {
+		parserOptions.Trace = os.Stderr
+	} else if *debugParse != "" {
+		trace, err := os.Create(*debugParse)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer trace.Close()
+		parserOptions.Trace = trace
+	}
+	var output OutputFS = DiskOutput{}
+
+	if *format == "events" {
+		if err := runEvents(*inputFile, *outputFile, parserOptions); err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing DTD file: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Catch a package clause that would not compile next to the output directory's files
+	// before doing any work
+	if *format == "go" {
+		name, err := resolvePackageName(*packageName, *outputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		*packageName = name
+	}
+	if *docFile && (*format != "go" || *outputFile == "" || filepath.Base(*outputFile) == docFileName) {
+		fmt.Fprintf(os.Stderr, "-doc needs go format and an -output file other than %s\n", docFileName)
+		os.Exit(1)
+	}
+	if *manifest != "" && *outputFile == "" {
+		fmt.Fprintf(os.Stderr, "-manifest needs an -output file to describe\n")
+		os.Exit(1)
+	}
+	if *only != "" && (*format != "go" || *outputFile == "") {
+		fmt.Fprintf(os.Stderr, "-only needs go format and the -output file to update\n")
+		os.Exit(1)
+	}
+	var config GeneratorConfig
+	if *configFile != "" {
+		if *format != "go" {
+			fmt.Fprintf(os.Stderr, "-config only applies to go format\n")
+			os.Exit(1)
+		}
+		loaded, err := LoadGeneratorConfig(*configFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		config = loaded
+	}
+
 	// Parse the DTD file
 	fmt.Printf("Parsing DTD file: %s\n", *inputFile)
-	parser := NewDTDParser()
+	parser := NewDTDParser(parserOptions)
 	result, err := parser.ParseFile(*inputFile)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error parsing DTD file: %v\n", err)
 		os.Exit(1)
 	}
 
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+
 	if len(result.Elements) == 0 {
 		fmt.Printf("No elements found in DTD file\n")
 		return
 	}
 
+	if *sample != "" {
+		file, err := os.Open(*sample)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening sample document: %v\n", err)
+			os.Exit(1)
+		}
+		report, err := result.Subset(file)
+		file.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error subsetting by sample: %v\n", err)
+			os.Exit(1)
+		}
+		for _, line := range report {
+			fmt.Fprintf(os.Stderr, "subset: %s\n", line)
+		}
+	}
+
+	if *prune {
+		for _, line := range result.Prune() {
+			fmt.Fprintf(os.Stderr, "prune: %s\n", line)
+		}
+	}
+
 	fmt.Printf("Found %d elements in DTD file\n", len(result.Elements))
 	for _, name := range result.Order {
 		fmt.Printf("  - %s\n", name)
 	}
 
-	// Generate Go structs
-	generator := NewStructGenerator(*packageName, result.Elements, result.Order)
-	structCode := generator.GenerateStructs()
+	// Generate code in the requested format
+	options := GeneratorOptions{
+		GenericDecoder: *generic,
+		AnyStyle:       *anyStyle,
+		InlineWrappers: *inline,
+		EnumStyle:      *enumStyle,
+		OptionalEnums:  *optEnums,
+		Occurrences:    *occurrences,
+		ContentRegexp:  *contentRe,
+		ParentsIndex:   *parents,
+		ViolationHooks: *violations,
+		Explain:        *explain,
+		FoldCase:       *foldCase,
+		NoXMLTags:      *noXMLTags,
+		TinyGo:         *tinyGo,
+		ParseHelpers:   *parse,
+		DecodeInto:     *decodeInto,
+		Instrument:     *otel,
+		CharsetReader:  *charset,
+		NormalizeAttrs: *normalize,
+		IDIndex:        *idIndex,
+		IDAttributes:   splitOnly(*idAttrs),
+		FillDefaults:   *defaults,
+		Constructors:   *newFuncs,
+		Validation:     *validators,
+		ValidateFixed:  *fixedCheck,
+		Canonical:      *canonical,
+		AttrGroups:     *attrGroups,
+		EmptyStyle:     *emptyStyle,
+		Redactions:     config.Redaction,
+		Transliterate:  config.Transliteration,
+		Initialisms:    config.Initialisms,
+		Types:          config.Types,
+		Split:          splitOnly(*split),
+		MaxLineLength:  *maxLine,
+		ChoiceStyle:    *choiceStyle,
+		GroupStyle:     *groupStyle,
+		PointerPolicy:  *pointers,
+		MixedStyle:     *mixedStyle,
+		SequenceStyle:  *sequence,
+		Compat:         *compat,
+		Roots:          splitOnly(*roots),
+	}
+	switch options.AnyStyle {
+	case AnyStyleInnerXML, AnyStyleElements, AnyStyleUnion:
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown -any-style %q (expected innerxml, elements or union)\n", options.AnyStyle)
+		os.Exit(1)
+	}
+	switch options.EmptyStyle {
+	case EmptyStyleStruct, EmptyStyleBool:
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown -empty-style %q (expected struct or bool)\n", options.EmptyStyle)
+		os.Exit(1)
+	}
+	switch options.ChoiceStyle {
+	case ChoiceStyleFields, ChoiceStyleInterface:
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown -choice-style %q (expected fields or interface)\n", options.ChoiceStyle)
+		os.Exit(1)
+	}
+	switch options.GroupStyle {
+	case GroupStyleFlat, GroupStyleAnonymous, GroupStyleNamed:
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown -group-style %q (expected flat, anonymous or named)\n", options.GroupStyle)
+		os.Exit(1)
+	}
+	switch options.PointerPolicy {
+	case "", PointerPolicyAll, PointerPolicyOptionalOnly, PointerPolicyNone:
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown -pointer-policy %q (expected all, optional-only or none)\n", options.PointerPolicy)
+		os.Exit(1)
+	}
+	if options.Compat < 1 || options.Compat > CompatLatest {
+		fmt.Fprintf(os.Stderr, "Unknown -compat %d (expected a level from 1 up to the latest, %d)\n", options.Compat, CompatLatest)
+		os.Exit(1)
+	}
+	switch options.MixedStyle {
+	case MixedStyleText, MixedStyleNodes:
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown -mixed-style %q (expected text or nodes)\n", options.MixedStyle)
+		os.Exit(1)
+	}
+	switch options.SequenceStyle {
+	case SequenceStyleMerged, SequenceStylePositional:
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown -sequence-style %q (expected merged or positional)\n", options.SequenceStyle)
+		os.Exit(1)
+	}
+	if options.NoXMLTags && (options.GenericDecoder || options.ParseHelpers || options.DecodeInto || options.Instrument || options.CharsetReader || options.NormalizeAttrs || len(options.Roots) > 0) {
+		fmt.Fprintf(os.Stderr, "-no-xml-tags cannot be combined with the XML decoding helpers (-generic, -parse-helpers, -decode-into, -otel, -charset, -normalize-attrs, -root)\n")
+		os.Exit(1)
+	}
+	if options.MaxLineLength < 0 {
+		fmt.Fprintf(os.Stderr, "-max-line-length must not be negative\n")
+		os.Exit(1)
+	}
+	if len(options.IDAttributes) > 0 && !options.IDIndex {
+		fmt.Fprintf(os.Stderr, "-id-attrs only applies to -id-index\n")
+		os.Exit(1)
+	}
+	if options.FillDefaults && (options.NoXMLTags || options.TinyGo) {
+		fmt.Fprintf(os.Stderr, "-fill-defaults generates UnmarshalXML methods and cannot be combined with -no-xml-tags or -tinygo\n")
+		os.Exit(1)
+	}
+	if options.ValidateFixed && (options.NoXMLTags || options.TinyGo) {
+		fmt.Fprintf(os.Stderr, "-validate-fixed generates UnmarshalXML methods and cannot be combined with -no-xml-tags or -tinygo\n")
+		os.Exit(1)
+	}
+	if options.ChoiceStyle == ChoiceStyleInterface && options.TinyGo {
+		fmt.Fprintf(os.Stderr, "-choice-style interface needs the encoding/xml codecs and cannot be combined with -tinygo\n")
+		os.Exit(1)
+	}
+	if options.GroupStyle != GroupStyleFlat && options.TinyGo {
+		fmt.Fprintf(os.Stderr, "-group-style %s needs the encoding/xml codecs and cannot be combined with -tinygo\n", options.GroupStyle)
+		os.Exit(1)
+	}
+	if options.MixedStyle == MixedStyleNodes && options.TinyGo {
+		fmt.Fprintf(os.Stderr, "-mixed-style nodes needs the encoding/xml codecs and cannot be combined with -tinygo\n")
+		os.Exit(1)
+	}
+	if options.SequenceStyle == SequenceStylePositional && options.TinyGo {
+		fmt.Fprintf(os.Stderr, "-sequence-style positional needs the encoding/xml codecs and cannot be combined with -tinygo\n")
+		os.Exit(1)
+	}
+	if options.Canonical && (options.NoXMLTags || options.TinyGo) {
+		fmt.Fprintf(os.Stderr, "-c14n canonicalizes the encoding/xml output and cannot be combined with -no-xml-tags or -tinygo\n")
+		os.Exit(1)
+	}
+	if options.FoldCase && (options.NoXMLTags || options.TinyGo) {
+		fmt.Fprintf(os.Stderr, "-case-insensitive needs the encoding/xml decoders and cannot be combined with -no-xml-tags or -tinygo\n")
+		os.Exit(1)
+	}
+	if options.TinyGo && (options.GenericDecoder || options.ParseHelpers || options.DecodeInto || options.Instrument || options.CharsetReader ||
+		options.InlineWrappers || options.Occurrences || options.ContentRegexp || options.AnyStyle != AnyStyleInnerXML) {
+		fmt.Fprintf(os.Stderr, "-tinygo cannot be combined with -generic, -parse-helpers, -decode-into, -otel, -charset, -inline-wrappers, -occurrences, -content-regexp or -any-style\n")
+		os.Exit(1)
+	}
+	switch options.EnumStyle {
+	case EnumStyleString, EnumStyleInt, EnumStyleTyped:
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown -enum-style %q (expected string, int or typed)\n", options.EnumStyle)
+		os.Exit(1)
+	}
+	switch options.OptionalEnums {
+	case OptionalEnumZero, OptionalEnumUnset, OptionalEnumPointer:
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown -optional-enums %q (expected zero, unset or pointer)\n", options.OptionalEnums)
+		os.Exit(1)
+	}
+	if options.ViolationHooks && options.EnumStyle != EnumStyleInt && !options.ContentRegexp && !options.ValidateFixed {
+		fmt.Fprintf(os.Stderr, "-violation-hooks needs checks to report: -enum-style int, -content-regexp and/or -validate-fixed\n")
+		os.Exit(1)
+	}
+	if options.OptionalEnums != OptionalEnumZero && options.EnumStyle != EnumStyleInt {
+		fmt.Fprintf(os.Stderr, "-optional-enums only applies to -enum-style int\n")
+		os.Exit(1)
+	}
+	if options.AttrGroups == 1 || options.AttrGroups < 0 {
+		fmt.Fprintf(os.Stderr, "-attr-groups needs at least 2 elements to share a group, or 0 to disable\n")
+		os.Exit(1)
+	}
+	if len(options.Split) > 0 && (options.NoXMLTags || options.TinyGo) {
+		fmt.Fprintf(os.Stderr, "-split reads documents with encoding/xml and cannot be combined with -no-xml-tags or -tinygo\n")
+		os.Exit(1)
+	}
+	if err := CheckSplitElements(result, options.Split); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := CheckRedactions(result, options.Redactions); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(options.Types) > 0 && options.TinyGo {
+		fmt.Fprintf(os.Stderr, "the types section of -config needs the encoding/xml codecs and cannot be combined with -tinygo\n")
+		os.Exit(1)
+	}
+	if err := CheckTypeMappings(result, options.Types, options.Redactions); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(options.Roots) > 0 {
+		if _, err := NewStructGenerator(*packageName, result.Elements, result.Order, options).structNamesOf(options.Roots); err != nil {
+			fmt.Fprintf(os.Stderr, "Error selecting -root elements: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	run := &Generation{
+		Result:      result,
+		Format:      *format,
+		PackageName: *packageName,
+		Output:      *outputFile,
+		Options:     options,
+		Doc:         *docFile,
+		Manifest:    *manifest,
+		Input:       *inputFile,
+		Sources:     parserOptions.FS,
+		Flags:       generationFlags(),
+	}
+	if *only != "" {
+		run.Only = splitOnly(*only)
+		existing, err := os.ReadFile(*outputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading output file to update: %v\n", err)
+			os.Exit(1)
+		}
+		run.Existing = string(existing)
+	}
+	files, err := run.generate()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating code: %v\n", err)
+		os.Exit(1)
+	}
+	for _, line := range run.Diagnostics {
+		fmt.Fprintln(os.Stderr, line)
+	}
 
 	// Output the generated code
 	if *outputFile == "" {
 		// Output to stdout
 		fmt.Println("\n" + strings.Repeat("=", 50))
-		fmt.Println("Generated Go Structs:")
+		fmt.Printf("%s:\n", files[0].Title)
 		fmt.Println(strings.Repeat("=", 50))
-		fmt.Print(structCode)
-	} else {
-		// Output to file
-		err := writeToFile(*outputFile, structCode)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing to output file: %v\n", err)
+		fmt.Print(files[0].Content)
+		return
+	}
+	for _, file := range files {
+		if err := output.WriteFile(file.Name, file.Content); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", file.Name, err)
 			os.Exit(1)
 		}
-		fmt.Printf("Generated Go structs written to: %s\n", *outputFile)
+		fmt.Printf("%s written to: %s\n", file.Title, file.Name)
+	}
+}
+
+// generationFlags returns the flags set on the command line that shape the generated
+// code, as they would be passed again
+func generationFlags() []string {
+	var flags []string
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "input", "output", "package", "doc", "manifest":
+			return
+		}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			if f.Value.String() == "true" {
+				flags = append(flags, "-"+f.Name)
+			}
+			return
+		}
+		flags = append(flags, "-"+f.Name+" "+f.Value.String())
+	})
+	return flags
+}
+
+// generateCode runs the output backend for the given format and returns the code with a
+// human readable title and the backend's reports, such as renamed names, for the user
+func generateCode(format, packageName, outputFile string, options GeneratorOptions, result *ParseResult) (code, title string, diagnostics []string, err error) {
+	switch format {
+	case "go":
+		generator := NewStructGenerator(packageName, result.Elements, result.Order, options)
+		code := generator.GenerateStructs()
+		name := outputFile
+		if name == "" {
+			name = "generated.go"
+		}
+		if err := checkCode(name, code); err != nil {
+			return "", "", nil, err
+		}
+		for _, line := range generator.attributeGroupReport() {
+			diagnostics = append(diagnostics, "attr-groups: "+line)
+		}
+		for _, line := range generator.renameReport() {
+			diagnostics = append(diagnostics, "renamed: "+line)
+		}
+		for _, lossy := range generator.lossyConversions() {
+			diagnostics = append(diagnostics, fmt.Sprintf("lossy: <%s> %s: %s", lossy.Element, lossy.Kind, lossy.Detail))
+		}
+		return code, "Go Structs", diagnostics, nil
+	case "python":
+		generator := NewPythonGenerator(result.Elements, result.Order)
+		return generator.GenerateDataclasses(), "Python Dataclasses", nil, nil
+	case "java":
+		// Java requires the public class to match the file name
+		className := "Schema"
+		if outputFile != "" {
+			className = strings.TrimSuffix(filepath.Base(outputFile), filepath.Ext(outputFile))
+		}
+		if !isJavaIdentifier(className) {
+			suggestion := NewStructGenerator("", nil, nil, GeneratorOptions{}).toPascalCase(className)
+			if !isJavaIdentifier(suggestion) {
+				suggestion = "Schema"
+			}
+			return "", "", nil, fmt.Errorf("the Java class is named after the output file, and %q is not a Java identifier; name the output %s.java instead", className, suggestion)
+		}
+		generator := NewJavaGenerator(packageName, className, result.Elements, result.Order)
+		return generator.GenerateClasses(), "Java Classes", nil, nil
+	case "csharp":
+		generator := NewCSharpGenerator(packageName, result.Elements, result.Order)
+		return generator.GenerateClasses(), "C# Classes", nil, nil
+	case "avro":
+		generator := NewAvroGenerator(packageName, result.Elements, result.Order)
+		schema, err := generator.GenerateSchema()
+		return schema, "Avro Schema", nil, err
+	case "parquet":
+		generator := NewParquetGenerator(result.Elements, result.Order)
+		schema, report := generator.GenerateSchema()
+		for _, line := range report {
+			diagnostics = append(diagnostics, "parquet: "+line)
+		}
+		return schema, "Parquet Schema", diagnostics, nil
+	case "mock":
+		generator := NewMockGenerator(packageName, result.Elements, result.Order)
+		return generator.GenerateServer(), "Mock Server", nil, nil
+	case "ent":
+		generator := NewEntGenerator(packageName, result.Elements, result.Order)
+		return generator.GenerateSchemas(), "ent Schemas", nil, nil
+	case "json":
+		model, err := result.ModelJSON()
+		return model, "Model JSON", nil, err
+	default:
+		return "", "", nil, fmt.Errorf("unknown output format %q", format)
 	}
 }
 
-// writeToFile writes content to the specified file
-func writeToFile(filename, content string) error {
+// writeToFile writes content to the specified file. The content goes to a temporary file
+// in the same directory that is renamed over the destination once complete, so a failed or
+// interrupted run leaves the previous file untouched rather than half written. Go files
+// are checked to parse before they replace the destination. Long paths are prefixed to
+// lift the Windows MAX_PATH limit.
+func writeToFile(filename, content string) (err error) {
+	filename = longPath(filename)
+
 	// Create directory if it doesn't exist
 	dir := filepath.Dir(filename)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	// Write content to file
-	file, err := os.Create(filename)
+	if filepath.Ext(filename) == ".go" {
+		if _, err := parser.ParseFile(token.NewFileSet(), filename, content, parser.AllErrors); err != nil {
+			return fmt.Errorf("generated code does not parse, leaving %s unchanged: %w", filename, err)
+		}
+	}
+
+	file, err := os.CreateTemp(dir, "."+filepath.Base(filename)+".*.tmp")
 	if err != nil {
 		return fmt.Errorf("failed to create file: %w", err)
 	}
-	defer file.Close()
+	defer func() {
+		if err != nil {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
 
-	_, err = file.WriteString(content)
-	if err != nil {
-		return fmt.Errorf("failed to write content: %w", err)
+	// Keep the permissions of the file being replaced
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(filename); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := file.Chmod(mode); err != nil {
+		return fmt.Errorf("failed to set file permissions: %w", err)
 	}
 
+	if _, err := file.WriteString(content); err != nil {
+		return fmt.Errorf("failed to write content: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to write content: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write content: %w", err)
+	}
+	if err := os.Rename(file.Name(), filename); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", filename, err)
+	}
 	return nil
 }
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+)
+
+// generationManifest records what a run generated and from what, for build systems that
+// keep provenance of generated code such as SLSA attestations
+type generationManifest struct {
+	Tool    manifestTool   `json:"tool"`
+	Schema  manifestSchema `json:"schema"`
+	Format  string         `json:"format"`
+	Package string         `json:"package,omitempty"` // Go package of the generated code
+	Compat  int            `json:"compat,omitempty"`  // Compatibility level of the generated Go API
+	Options []string       `json:"options"`           // Generation options as given on the command line
+	Files   []manifestFile `json:"files"`
+}
+
+// manifestTool identifies the dtd-to-go build that generated the files
+type manifestTool struct {
+	Name     string `json:"name"`
+	Version  string `json:"version"`            // Module version, "(devel)" for local builds
+	Revision string `json:"revision,omitempty"` // VCS revision the binary was built from, if known
+	Modified bool   `json:"modified,omitempty"` // The binary was built from a modified working tree
+}
+
+// manifestSchema identifies the DTD the files were generated from
+type manifestSchema struct {
+	Input       string         `json:"input"`
+	Fingerprint string         `json:"fingerprint"` // Fingerprint of the input file, as the schema registry computes it
+	Sources     []manifestFile `json:"sources"`     // The input and every file it includes
+}
+
+// manifestFile is a file with its content digest
+type manifestFile struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+// newManifestTool describes the running binary from its embedded build information
+func newManifestTool() manifestTool {
+	tool := manifestTool{Name: "dtd-to-go", Version: "(devel)"}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return tool
+	}
+	if info.Main.Version != "" {
+		tool.Version = info.Main.Version
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			tool.Revision = setting.Value
+		case "vcs.modified":
+			tool.Modified = setting.Value == "true"
+		}
+	}
+	return tool
+}
+
+// sha256Hex returns the hex encoded SHA-256 digest of content
+func sha256Hex(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// newGenerationManifest builds the manifest of a run from its parsed schema, read again
+// from its sources, and the contents of the files it generated, by path
+func newGenerationManifest(run *Generation, written []manifestFile) (*generationManifest, error) {
+	manifest := &generationManifest{
+		Tool:    newManifestTool(),
+		Format:  run.Format,
+		Options: run.Flags,
+		Files:   written,
+	}
+	if run.Format == "go" {
+		manifest.Package = run.PackageName
+		manifest.Compat = run.Options.Compat
+	}
+	if manifest.Options == nil {
+		manifest.Options = []string{}
+	}
+
+	manifest.Schema.Input = run.Input
+	seen := make(map[string]bool)
+	for _, path := range run.Result.Files {
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		content, err := readSource(run.Sources, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash schema source: %w", err)
+		}
+		if len(manifest.Schema.Sources) == 0 {
+			manifest.Schema.Fingerprint = Fingerprint(content)
+		}
+		manifest.Schema.Sources = append(manifest.Schema.Sources, manifestFile{Path: path, SHA256: sha256Hex(content)})
+	}
+	return manifest, nil
+}
+
+// encode returns the manifest as indented JSON
+func (manifest *generationManifest) encode() (string, error) {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import "sync"
+
+// memoCache remembers the results of a pure function of a name. It is safe for
+// concurrent use, so generators can share one across goroutines.
+type memoCache[V any] struct {
+	mu     sync.Mutex
+	values map[string]V
+}
+
+// get returns the cached result for key, computing and storing it on first use
+func (c *memoCache[V]) get(key string, compute func(string) V) V {
+	c.mu.Lock()
+	value, ok := c.values[key]
+	c.mu.Unlock()
+	if ok {
+		return value
+	}
+
+	// Computed outside the lock: compute may consult other caches, and a duplicate
+	// computation of the same pure result is harmless
+	value = compute(key)
+
+	c.mu.Lock()
+	if c.values == nil {
+		c.values = make(map[string]V)
+	}
+	c.values[key] = value
+	c.mu.Unlock()
+	return value
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Representations of mixed content such as (#PCDATA | em | strong)*, selectable with
+// GeneratorOptions.MixedStyle
+const (
+	MixedStyleText  = "text"  // The text in one Text field, dropping the child elements
+	MixedStyleNodes = "nodes" // A list of nodes holding the text and child elements in document order
+)
+
+// mixedChildren returns the child elements mixed content allows, in declaration order, or
+// nil for other content models
+func mixedChildren(element *DTDElement) []string {
+	model, err := ParseContentModel(element.Content)
+	if err != nil || model.Kind != ContentMixed {
+		return nil
+	}
+	return model.ElementNames()
+}
+
+// holdsMixedNodes reports whether an element's struct holds its mixed content as nodes,
+// which needs a struct even for elements without attributes
+func (g *StructGenerator) holdsMixedNodes(name string) bool {
+	element, exists := g.elements[name]
+	return exists && g.options.MixedStyle == MixedStyleNodes && len(mixedChildren(element)) > 0
+}
+
+// mixedNodeName returns the type of the nodes of an element's mixed content
+func (g *StructGenerator) mixedNodeName(name string) string {
+	taken := make(map[string]bool)
+	for _, other := range g.elementOrder {
+		if _, exists := g.elements[other]; exists && !g.isSimpleElement(other) {
+			taken[g.toGoStructName(other)] = true
+		}
+	}
+	for _, enum := range g.enumTypes() {
+		taken[enum.Name] = true
+	}
+	nodeName := g.toGoStructName(name) + "Node"
+	for taken[nodeName] {
+		nodeName += "Node"
+	}
+	return nodeName
+}
+
+// mixedNodesField returns the field of an element's struct holding its mixed content as
+// nodes, in place of the Text field
+func (g *StructGenerator) mixedNodesField(element *DTDElement) goField {
+	fieldName := "Nodes"
+	for _, attr := range element.Attributes {
+		if g.toGoFieldName(attr.Name) == fieldName {
+			fieldName = "MixedNodes"
+		}
+	}
+	return goField{Name: fieldName, Type: "[]" + g.mixedNodeName(element.Name), Tag: ",any",
+		Explain: fmt.Sprintf("from %s -> nodes in document order because -mixed-style nodes", element.Content)}
+}
+
+// mixedNodeFields returns the fields of the type of the nodes of an element's mixed content
+// after Text: one per child element, holding a single occurrence
+func (g *StructGenerator) mixedNodeFields(element *DTDElement) []goField {
+	var fields []goField
+	for _, child := range mixedChildren(element) {
+		fieldType := "*" + g.toGoStructName(child)
+		switch {
+		case g.isPresenceElement(child):
+			fieldType = "*Presence"
+		case g.isSimpleElement(child):
+			fieldType = "*" + g.textType(child)
+		}
+		fieldName := g.toGoFieldName(child)
+		if fieldName == "Text" {
+			fieldName = "TextElement"
+		}
+		fields = append(fields, goField{Name: fieldName, Type: fieldType, Element: child})
+	}
+	return fields
+}
+
+// mixedDispatch returns the code making a method call, such as "indexIDs(index)", on the
+// elements generated as structs among the nodes of mixed content, for the generated
+// methods that walk the elements inside a struct. It reports false for fields that do not
+// hold nodes.
+func (g *StructGenerator) mixedDispatch(element *DTDElement, field goField, structs map[string]bool, call string) (string, bool) {
+	if !g.holdsMixedNodes(element.Name) || field.Type != g.mixedNodesField(element).Type {
+		return "", false
+	}
+	var builder strings.Builder
+	for _, inner := range g.mixedNodeFields(element) {
+		if structs[inner.Type[1:]] {
+			builder.WriteString(fmt.Sprintf("\t\tif v.%s[i].%s != nil {\n", field.Name, inner.Name))
+			builder.WriteString(fmt.Sprintf("\t\t\tv.%s[i].%s.%s\n", field.Name, inner.Name, call))
+			builder.WriteString("\t\t}\n")
+		}
+	}
+	if builder.Len() == 0 {
+		return "", true
+	}
+	return fmt.Sprintf("\tfor i := range v.%s {\n%s\t}\n", field.Name, builder.String()), true
+}
+
+// mixedDecoderDecl returns the statement of a generated UnmarshalXML method of a struct
+// holding nodes that makes it decode from a decoder appending the text to the nodes, so
+// the text and the child elements, which encoding/xml appends itself, keep their order
+func (g *StructGenerator) mixedDecoderDecl(name string) string {
+	if !g.holdsMixedNodes(name) {
+		return ""
+	}
+	field := g.mixedNodesField(g.elements[name])
+	return fmt.Sprintf("\td = mixedDecoder(d, start, func(text string) { v.%s = append(v.%s, %s{Text: text}) })\n",
+		field.Name, field.Name, g.mixedNodeName(name))
+}
+
+// mixedRuntime reads the text directly inside an element with mixed content as it passes
+// the tokens of the element on to encoding/xml
+const mixedRuntime = `
+// mixedReader replays pending and then the rest of an element from d, handing the text
+// directly inside the element, between its child elements, to text as it is read
+type mixedReader struct {
+	d       *xml.Decoder
+	pending []xml.Token
+	depth   int
+	text    func(string)
+}
+
+func (r *mixedReader) Token() (xml.Token, error) {
+	var tok xml.Token
+	var err error
+	if len(r.pending) > 0 {
+		tok, r.pending = r.pending[0], r.pending[1:]
+	} else {
+		tok, err = r.d.Token()
+	}
+	switch t := tok.(type) {
+	case xml.StartElement:
+		r.depth++
+	case xml.EndElement:
+		r.depth--
+	case xml.CharData:
+		if r.depth == 1 {
+			r.text(string(t))
+		}
+	}
+	return tok, err
+}
+
+// mixedDecoder returns a decoder reading the content of the element opened by start from
+// d, which hands the text directly inside the element to text in document order
+func mixedDecoder(d *xml.Decoder, start xml.StartElement, text func(string)) *xml.Decoder {
+	mixed := xml.NewTokenDecoder(&mixedReader{d: d, pending: []xml.Token{start}, text: text})
+	mixed.Token() // Reads start, so the end of the element matches it
+	return mixed
+}
+`
+
+// generateMixedNodes generates the node types of mixed content held as nodes and, unless
+// NoXMLTags, their codecs and the UnmarshalXML methods of the structs holding them
+func (g *StructGenerator) generateMixedNodes() string {
+	var builder strings.Builder
+
+	used := false
+	for _, name := range g.elementOrder {
+		if !g.holdsMixedNodes(name) {
+			continue
+		}
+		used = true
+		element := g.elements[name]
+		nodeName := g.mixedNodeName(name)
+		fields := g.mixedNodeFields(element)
+
+		builder.WriteString(fmt.Sprintf("\n// %s is a node of the content %s of <%s>: text, or\n", nodeName, strings.TrimSpace(element.Content), name))
+		builder.WriteString("// the one child element whose field is set\n")
+		builder.WriteString(fmt.Sprintf("type %s struct {\n", nodeName))
+		builder.WriteString("\tText string\n")
+		for _, field := range fields {
+			builder.WriteString(fmt.Sprintf("\t%s %s\n", field.Name, field.Type))
+		}
+		builder.WriteString("}\n")
+
+		if g.options.NoXMLTags {
+			continue
+		}
+
+		builder.WriteString("\n// UnmarshalXML decodes the element into the field for its name, skipping unknown elements\n")
+		builder.WriteString(fmt.Sprintf("func (n *%s) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {\n", nodeName))
+		builder.WriteString("\tswitch start.Name.Local {\n")
+		for _, field := range fields {
+			builder.WriteString(fmt.Sprintf("\tcase %q:\n", localName(field.Element)))
+			builder.WriteString(fmt.Sprintf("\t\tn.%s = new(%s)\n", field.Name, field.Type[1:]))
+			builder.WriteString(fmt.Sprintf("\t\treturn d.DecodeElement(n.%s, &start)\n", field.Name))
+		}
+		builder.WriteString("\t}\n")
+		builder.WriteString("\treturn d.Skip()\n")
+		builder.WriteString("}\n")
+
+		builder.WriteString("\n// MarshalXML encodes the element the node holds, or else its text\n")
+		builder.WriteString(fmt.Sprintf("func (n %s) MarshalXML(e *xml.Encoder, start xml.StartElement) error {\n", nodeName))
+		builder.WriteString("\tswitch {\n")
+		for _, field := range fields {
+			space, local := g.xmlName(field.Element)
+			elementName := fmt.Sprintf("xml.Name{Local: %q}", local)
+			if space != "" {
+				elementName = fmt.Sprintf("xml.Name{Space: %q, Local: %q}", space, local)
+			}
+			builder.WriteString(fmt.Sprintf("\tcase n.%s != nil:\n", field.Name))
+			builder.WriteString(fmt.Sprintf("\t\treturn e.EncodeElement(n.%s, xml.StartElement{Name: %s})\n", field.Name, elementName))
+		}
+		builder.WriteString("\t}\n")
+		builder.WriteString("\treturn e.EncodeToken(xml.CharData(n.Text))\n")
+		builder.WriteString("}\n")
+
+		// The case folding, default filling and #FIXED checking UnmarshalXML methods read
+		// the text themselves
+		if g.options.FoldCase || g.fillsDefaults(name) || g.checksFixed(name) {
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("\n// UnmarshalXML decodes <%s>, keeping the order of its text and child elements\n", name))
+		builder.WriteString(fmt.Sprintf("func (v *%s) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {\n", g.toGoStructName(name)))
+		builder.WriteString(g.plainDecl(name))
+		builder.WriteString(g.decodeReturn(name, "d.DecodeElement("+g.decodeTarget(name)+", &start)"))
+		builder.WriteString("}\n")
+	}
+	if used && !g.options.NoXMLTags {
+		builder.WriteString(mixedRuntime)
+	}
+
+	return builder.String()
+}
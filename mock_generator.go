@@ -0,0 +1,535 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// noFiniteDocument is the height of content that cannot be completed, such as an element
+// whose every content model alternative requires the element again
+const noFiniteDocument = -1
+
+// MockGenerator generates a Go package serving random documents valid against a DTD over
+// HTTP, so integration tests of feed consumers can run without partner sandboxes. The
+// package holds the declarations it needs as data and does not use the generated structs.
+type MockGenerator struct {
+	packageName  string
+	elements     map[string]*DTDElement
+	elementOrder []string
+	heights      map[string]int // Depth of the smallest valid document of each element
+}
+
+// NewMockGenerator creates a new mock server generator
+func NewMockGenerator(packageName string, elements map[string]*DTDElement, elementOrder []string) *MockGenerator {
+	g := &MockGenerator{
+		packageName:  packageName,
+		elements:     elements,
+		elementOrder: elementOrder,
+		heights:      make(map[string]int),
+	}
+	g.computeHeights()
+	return g
+}
+
+// computeHeights finds the depth of the smallest valid document of every element, taking
+// the shortest alternative of every choice and leaving out optional particles. Heights
+// only shrink from noFiniteDocument, so repeating until none changes terminates.
+func (g *MockGenerator) computeHeights() {
+	models := make(map[string]*ContentModel)
+	for _, name := range g.elementOrder {
+		g.heights[name] = noFiniteDocument
+		if model, err := ParseContentModel(g.elements[name].Content); err == nil {
+			models[name] = model
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, name := range g.elementOrder {
+			height := 1
+			if model := models[name]; model != nil && model.Kind == ContentChildren {
+				height = g.particleHeight(model.Root)
+				if height != noFiniteDocument {
+					height++
+				}
+			}
+			if height != noFiniteDocument && (g.heights[name] == noFiniteDocument || height < g.heights[name]) {
+				g.heights[name] = height
+				changed = true
+			}
+		}
+	}
+}
+
+// particleHeight returns the depth of the smallest content a particle matches when it
+// occurs, ignoring its own occurrence indicator
+func (g *MockGenerator) particleHeight(c *ContentParticle) int {
+	switch c.Kind {
+	case ParticleElement:
+		if height, declared := g.heights[c.Name]; declared {
+			return height
+		}
+		return 1 // Undeclared elements are generated empty
+	case ParticleSequence:
+		height := 0
+		for _, child := range c.Children {
+			childHeight := g.occursHeight(child)
+			if childHeight == noFiniteDocument {
+				return noFiniteDocument
+			}
+			height = max(height, childHeight)
+		}
+		return height
+	default:
+		height := noFiniteDocument
+		for _, child := range c.Children {
+			if childHeight := g.occursHeight(child); childHeight != noFiniteDocument && (height == noFiniteDocument || childHeight < height) {
+				height = childHeight
+			}
+		}
+		return height
+	}
+}
+
+// occursHeight returns the depth of the smallest content a particle matches, which is
+// none when it is optional
+func (g *MockGenerator) occursHeight(c *ContentParticle) int {
+	if c.Indicator == '?' || c.Indicator == '*' {
+		return 0
+	}
+	return g.particleHeight(c)
+}
+
+// GenerateServer generates the Go source of the mock server package
+func (g *MockGenerator) GenerateServer() string {
+	var builder strings.Builder
+	builder.WriteString("// Code generated from a DTD by dtd-to-go. DO NOT EDIT.\n\n")
+	builder.WriteString(fmt.Sprintf("package %s\n\n", g.packageName))
+	builder.WriteString("import (\n")
+	for _, path := range []string{"bytes", "encoding/xml", "fmt", "math/rand", "net/http", "net/http/httptest", "strconv", "sync/atomic"} {
+		builder.WriteString(fmt.Sprintf("\t%q\n", path))
+	}
+	builder.WriteString(")\n")
+
+	roots := (&ParseResult{Elements: g.elements, Order: g.elementOrder}).Graph().Roots()
+	builder.WriteString("\n// Roots are the elements no other element contains, which Handler serves by default\n")
+	builder.WriteString("var Roots = []string{")
+	for i, root := range roots {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(fmt.Sprintf("%q", root))
+	}
+	builder.WriteString("}\n")
+
+	builder.WriteString("\n// mockDeclarations holds the declared elements\n")
+	builder.WriteString("var mockDeclarations = []*mockElement{\n")
+	for _, name := range g.elementOrder {
+		element := g.elements[name]
+		builder.WriteString("\t{")
+		parts := []string{fmt.Sprintf("Name: %q", name)}
+		if len(element.Attributes) > 0 {
+			var attrs []string
+			for _, attr := range element.Attributes {
+				attrs = append(attrs, mockAttrLiteral(attr))
+			}
+			parts = append(parts, fmt.Sprintf("Attrs: []mockAttr{%s}", strings.Join(attrs, ", ")))
+		}
+		model, err := ParseContentModel(element.Content)
+		switch {
+		case err != nil || model.Kind == ContentAny:
+			parts = append(parts, `Content: "ANY"`)
+		case model.Kind == ContentEmpty:
+			parts = append(parts, `Content: "EMPTY"`)
+		case model.Kind == ContentMixed:
+			parts = append(parts, `Content: "mixed"`)
+			if names := model.ElementNames(); len(names) > 0 {
+				parts = append(parts, fmt.Sprintf("Mixed: %s", goStringSlice(names)))
+			}
+		default:
+			parts = append(parts, `Content: "children"`, "Model: &"+g.particleLiteral(model.Root))
+		}
+		parts = append(parts, fmt.Sprintf("Height: %d", g.heights[name]))
+		builder.WriteString(strings.Join(parts, ", "))
+		builder.WriteString("},\n")
+	}
+	builder.WriteString("}\n")
+
+	builder.WriteString(mockRuntime)
+	return builder.String()
+}
+
+// particleLiteral returns the composite literal of a content particle for the mock server,
+// without its type
+func (g *MockGenerator) particleLiteral(c *ContentParticle) string {
+	parts := []string{fmt.Sprintf("Kind: %q", rune(mockParticleKind(c)))}
+	if c.Kind == ParticleElement {
+		parts = append(parts, fmt.Sprintf("Name: %q", c.Name))
+	}
+	if c.Indicator != 0 {
+		parts = append(parts, fmt.Sprintf("Indicator: %q", rune(c.Indicator)))
+	}
+	parts = append(parts, fmt.Sprintf("Height: %d", g.particleHeight(c)))
+	if c.Kind == ParticleChoice {
+		shortest := 0
+		for i, child := range c.Children {
+			height, best := g.occursHeight(child), g.occursHeight(c.Children[shortest])
+			if height != noFiniteDocument && (best == noFiniteDocument || height < best) {
+				shortest = i
+			}
+		}
+		parts = append(parts, fmt.Sprintf("Shortest: %d", shortest))
+	}
+	if len(c.Children) > 0 {
+		var children []string
+		for _, child := range c.Children {
+			children = append(children, strings.TrimPrefix(g.particleLiteral(child), "mockParticle"))
+		}
+		parts = append(parts, fmt.Sprintf("Children: []*mockParticle{%s}", strings.Join(children, ", ")))
+	}
+	return "mockParticle{" + strings.Join(parts, ", ") + "}"
+}
+
+// mockParticleKind returns the character the mock server marks a particle kind with
+func mockParticleKind(c *ContentParticle) byte {
+	switch c.Kind {
+	case ParticleElement:
+		return 'e'
+	case ParticleSequence:
+		return ','
+	default:
+		return '|'
+	}
+}
+
+// mockAttrLiteral returns the Go expression of an attribute declaration for the mock server
+func mockAttrLiteral(attr DTDAttribute) string {
+	parts := []string{fmt.Sprintf("Name: %q", attr.Name)}
+	if len(attr.Values) > 0 {
+		parts = append(parts, "Values: "+goStringSlice(attr.Values))
+	} else {
+		parts = append(parts, fmt.Sprintf("Type: %q", strings.ToUpper(attr.Type)))
+	}
+	if attr.DefaultValue != "" && !strings.HasPrefix(attr.DefaultValue, "#") {
+		parts = append(parts, fmt.Sprintf("Default: %q", attr.DefaultValue))
+	}
+	if attr.Required {
+		parts = append(parts, "Required: true")
+	}
+	if attr.Fixed {
+		parts = append(parts, "Fixed: true")
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// goStringSlice returns the Go expression of a string slice
+func goStringSlice(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = fmt.Sprintf("%q", value)
+	}
+	return "[]string{" + strings.Join(quoted, ", ") + "}"
+}
+
+// mockRuntime generates and serves the documents from the declarations
+const mockRuntime = `
+// mockDepth is the nesting depth past which documents are completed with the smallest
+// content their elements allow, so recursive content models end
+const mockDepth = 8
+
+// mockWords are the words of generated text and attribute values
+var mockWords = []string{"alpha", "bravo", "charlie", "delta", "echo", "foxtrot", "golf", "hotel", "india", "juliett"}
+
+// mockParticle is a particle of a content model
+type mockParticle struct {
+	Kind      rune // 'e' for an element, ',' for a sequence and '|' for a choice
+	Name      string
+	Indicator rune // 0, '?', '*' or '+'
+	Height    int  // Depth of the smallest content the particle matches, -1 if there is none
+	Shortest  int  // Child of a choice matching the smallest content
+	Children  []*mockParticle
+}
+
+// mockAttr is a declared attribute
+type mockAttr struct {
+	Name     string
+	Type     string   // Declared type, such as CDATA, ID or NMTOKENS
+	Values   []string // Values of an enumerated or NOTATION type, which has no Type
+	Default  string
+	Required bool
+	Fixed    bool
+}
+
+// mockElement is a declared element
+type mockElement struct {
+	Name    string
+	Attrs   []mockAttr
+	Content string   // "EMPTY", "ANY", "mixed" or "children"
+	Mixed   []string // Child elements of mixed content
+	Model   *mockParticle
+	Height  int // Depth of the smallest valid element, -1 if it has none
+}
+
+// mockElements holds mockDeclarations by name
+var mockElements = func() map[string]*mockElement {
+	elements := make(map[string]*mockElement)
+	for _, element := range mockDeclarations {
+		elements[element.Name] = element
+	}
+	return elements
+}()
+
+// mockNode is a generated element
+type mockNode struct {
+	name    string
+	attrs   []*mockValue
+	content []any // Text and *mockNode children
+}
+
+// mockValue is a generated attribute
+type mockValue struct {
+	name  string
+	value string
+	omit  bool // An optional reference left out as the document has no IDs
+}
+
+// mockReference is an IDREF or IDREFS attribute, set once all IDs are known
+type mockReference struct {
+	attr     *mockValue
+	tokens   int
+	required bool
+}
+
+// mocker generates one document
+type mocker struct {
+	rng  *rand.Rand
+	ids  []string
+	refs []mockReference
+}
+
+// Document returns a random document valid against the DTD with root as its document
+// element. Documents deeper than a few levels are completed with the smallest content the
+// elements allow. References are drawn from the IDs the document holds; a required
+// IDREF in a document without IDs cannot be valid.
+func Document(root string, rng *rand.Rand) ([]byte, error) {
+	element, declared := mockElements[root]
+	if !declared {
+		return nil, fmt.Errorf("element <%s> is not declared", root)
+	}
+	if element.Height < 0 {
+		return nil, fmt.Errorf("element <%s> has no finite valid content", root)
+	}
+	m := &mocker{rng: rng}
+	node := m.element(root, 0)
+	for _, ref := range m.refs {
+		switch {
+		case len(m.ids) > 0:
+			ref.attr.value = m.ids[m.rng.Intn(len(m.ids))]
+			for i := 1; i < ref.tokens; i++ {
+				ref.attr.value += " " + m.ids[m.rng.Intn(len(m.ids))]
+			}
+		case ref.required:
+			ref.attr.value = "unresolved"
+		default:
+			ref.attr.omit = true
+		}
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	node.write(&buf)
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// element generates an element at a nesting depth
+func (m *mocker) element(name string, depth int) *mockNode {
+	node := &mockNode{name: name}
+	element, declared := mockElements[name]
+	if !declared {
+		return node // A valid document cannot hold it, so it is left empty
+	}
+	for _, attr := range element.Attrs {
+		if value := m.attribute(attr); value != nil {
+			node.attrs = append(node.attrs, value)
+		}
+	}
+	small := depth >= mockDepth
+	switch element.Content {
+	case "ANY", "mixed":
+		node.content = append(node.content, m.text())
+		if small {
+			break
+		}
+		var children []string
+		for _, child := range element.Mixed {
+			if mockFinite(child) {
+				children = append(children, child)
+			}
+		}
+		for n := m.rng.Intn(4); n > 0 && len(children) > 0; n-- {
+			node.content = append(node.content, m.element(children[m.rng.Intn(len(children))], depth+1), m.text())
+		}
+	case "children":
+		m.particle(node, element.Model, depth)
+	}
+	return node
+}
+
+// particle appends the children a particle of an element's content model matches
+func (m *mocker) particle(node *mockNode, p *mockParticle, depth int) {
+	small := depth >= mockDepth
+	n := 1
+	switch {
+	case p.Height < 0:
+		n = 0 // Only optional particles can match no finite content
+	case p.Indicator == '?' && (small || m.rng.Intn(2) == 0):
+		n = 0
+	case p.Indicator == '*' && small:
+		n = 0
+	case p.Indicator == '*':
+		n = m.rng.Intn(3)
+	case p.Indicator == '+' && !small:
+		n = 1 + m.rng.Intn(2)
+	}
+	for ; n > 0; n-- {
+		switch p.Kind {
+		case 'e':
+			node.content = append(node.content, m.element(p.Name, depth+1))
+		case ',':
+			for _, child := range p.Children {
+				m.particle(node, child, depth)
+			}
+		case '|':
+			child := p.Children[p.Shortest]
+			if !small {
+				var finite []*mockParticle
+				for _, c := range p.Children {
+					if c.Height >= 0 || c.Indicator == '?' || c.Indicator == '*' {
+						finite = append(finite, c)
+					}
+				}
+				child = finite[m.rng.Intn(len(finite))]
+			}
+			m.particle(node, child, depth)
+		}
+	}
+}
+
+// mockFinite reports whether an element has finite valid content
+func mockFinite(name string) bool {
+	element, declared := mockElements[name]
+	return !declared || element.Height >= 0
+}
+
+// attribute generates an attribute, or nil to leave an optional one out
+func (m *mocker) attribute(attr mockAttr) *mockValue {
+	value := &mockValue{name: attr.Name}
+	switch {
+	case attr.Fixed:
+		value.value = attr.Default // Always given, as namespace declarations are #FIXED
+		return value
+	case !attr.Required && m.rng.Intn(2) == 0:
+		return nil
+	case attr.Default != "" && m.rng.Intn(2) == 0:
+		value.value = attr.Default
+	case len(attr.Values) > 0:
+		value.value = attr.Values[m.rng.Intn(len(attr.Values))]
+	case attr.Type == "ID":
+		value.value = "id" + strconv.Itoa(len(m.ids)+1)
+		m.ids = append(m.ids, value.value)
+	case attr.Type == "IDREF":
+		m.refs = append(m.refs, mockReference{attr: value, tokens: 1, required: attr.Required})
+	case attr.Type == "IDREFS":
+		m.refs = append(m.refs, mockReference{attr: value, tokens: 1 + m.rng.Intn(2), required: attr.Required})
+	case attr.Type == "NMTOKENS" || attr.Type == "ENTITIES":
+		value.value = m.word() + " " + m.word()
+	case attr.Type == "NMTOKEN" || attr.Type == "ENTITY":
+		value.value = m.word()
+	default:
+		value.value = m.text()
+	}
+	return value
+}
+
+// word returns a random word
+func (m *mocker) word() string {
+	return mockWords[m.rng.Intn(len(mockWords))]
+}
+
+// text returns a few random words
+func (m *mocker) text() string {
+	text := m.word()
+	for n := m.rng.Intn(4); n > 0; n-- {
+		text += " " + m.word()
+	}
+	return text
+}
+
+// write writes the element as XML
+func (n *mockNode) write(buf *bytes.Buffer) {
+	buf.WriteString("<" + n.name)
+	for _, attr := range n.attrs {
+		if attr.omit {
+			continue
+		}
+		buf.WriteString(" " + attr.name + "=\"")
+		xml.EscapeText(buf, []byte(attr.value))
+		buf.WriteString("\"")
+	}
+	if len(n.content) == 0 {
+		buf.WriteString("/>")
+		return
+	}
+	buf.WriteString(">")
+	for _, content := range n.content {
+		switch c := content.(type) {
+		case string:
+			xml.EscapeText(buf, []byte(c))
+		case *mockNode:
+			c.write(buf)
+		}
+	}
+	buf.WriteString("</" + n.name + ">")
+}
+
+// Handler serves a new random document at each endpoint, mapping URL patterns such as
+// "GET /orders" to the root element of the documents served there, or every root at
+// "/<root>" when endpoints is nil. The n-th request is generated from seed+n, and a seed
+// query parameter replays a document.
+func Handler(endpoints map[string]string, seed int64) http.Handler {
+	if endpoints == nil {
+		endpoints = make(map[string]string)
+		for _, root := range Roots {
+			endpoints["/"+root] = root
+		}
+	}
+	var requests atomic.Int64
+	mux := http.NewServeMux()
+	for pattern, root := range endpoints {
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			documentSeed := seed + requests.Add(1)
+			if s := r.URL.Query().Get("seed"); s != "" {
+				parsed, err := strconv.ParseInt(s, 10, 64)
+				if err != nil {
+					http.Error(w, "seed must be an integer", http.StatusBadRequest)
+					return
+				}
+				documentSeed = parsed
+			}
+			document, err := Document(root, rand.New(rand.NewSource(documentSeed)))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/xml")
+			w.Header().Set("X-Mock-Seed", strconv.FormatInt(documentSeed, 10))
+			w.Write(document)
+		})
+	}
+	return mux
+}
+
+// NewServer starts a test server with Handler(endpoints, seed); close it when done
+func NewServer(endpoints map[string]string, seed int64) *httptest.Server {
+	return httptest.NewServer(Handler(endpoints, seed))
+}
+`
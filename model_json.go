@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// modelVersion is the version of the -format json model dump. Adding optional fields
+// keeps it; removing, renaming or retyping a field bumps it, along with modelSchemaID.
+const modelVersion = 1
+
+// modelSchemaID identifies the JSON Schema describing this version of the model dump
+const modelSchemaID = "urn:dtd-to-go:model:v1"
+
+// jsonModel is the -format json dump of a parsed DTD
+type jsonModel struct {
+	Schema   string        `json:"$schema"`
+	Version  int           `json:"version"`
+	Elements []jsonElement `json:"elements"` // In declaration order
+	Entities []jsonEntity  `json:"entities"` // Sorted by name, parameter entities first
+
+	Notations []jsonNotation `json:"notations,omitempty"` // Sorted by name
+}
+
+// jsonElement is one element declaration of the model dump
+type jsonElement struct {
+	Name       string          `json:"name"`
+	Content    string          `json:"content"`
+	Attributes []jsonAttribute `json:"attributes"`
+	Deprecated string          `json:"deprecated,omitempty"`
+	Position   Position        `json:"position"`
+}
+
+// jsonAttribute is one attribute of an element in the model dump
+type jsonAttribute struct {
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`
+	Default    string   `json:"default,omitempty"`
+	Required   bool     `json:"required"`
+	Fixed      bool     `json:"fixed,omitempty"` // Default is the only value allowed
+	Values     []string `json:"values,omitempty"`
+	Deprecated string   `json:"deprecated,omitempty"`
+	Position   Position `json:"position"`
+}
+
+// jsonEntity is one entity declaration of the model dump
+type jsonEntity struct {
+	Name     string   `json:"name"`
+	General  bool     `json:"general"`
+	Value    string   `json:"value,omitempty"`
+	SystemID string   `json:"systemId,omitempty"`
+	PublicID string   `json:"publicId,omitempty"`
+	Position Position `json:"position"`
+}
+
+// jsonNotation is one notation declaration of the model dump
+type jsonNotation struct {
+	Name     string   `json:"name"`
+	SystemID string   `json:"systemId,omitempty"`
+	PublicID string   `json:"publicId,omitempty"`
+	Position Position `json:"position"`
+}
+
+// ModelJSON returns the parsed model as indented JSON conforming to ModelSchema
+func (r *ParseResult) ModelJSON() (string, error) {
+	model := jsonModel{
+		Schema:   modelSchemaID,
+		Version:  modelVersion,
+		Elements: make([]jsonElement, 0, len(r.Order)),
+		Entities: make([]jsonEntity, 0, len(r.Entities)+len(r.General)),
+	}
+
+	for _, name := range r.Order {
+		element, exists := r.Elements[name]
+		if !exists {
+			continue
+		}
+		dumped := jsonElement{
+			Name:       element.Name,
+			Content:    element.Content,
+			Attributes: make([]jsonAttribute, 0, len(element.Attributes)),
+			Deprecated: element.Deprecated,
+			Position:   element.Position,
+		}
+		for _, attr := range element.Attributes {
+			dumped.Attributes = append(dumped.Attributes, jsonAttribute{
+				Name:       attr.Name,
+				Type:       attr.Type,
+				Default:    attr.DefaultValue,
+				Required:   attr.Required,
+				Fixed:      attr.Fixed,
+				Values:     attr.Values,
+				Deprecated: attr.Deprecated,
+				Position:   attr.Position,
+			})
+		}
+		model.Elements = append(model.Elements, dumped)
+	}
+
+	for _, entities := range []map[string]*DTDEntity{r.Entities, r.General} {
+		names := make([]string, 0, len(entities))
+		for name := range entities {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			entity := entities[name]
+			model.Entities = append(model.Entities, jsonEntity{
+				Name:     entity.Name,
+				General:  entity.General,
+				Value:    entity.Value,
+				SystemID: entity.SystemID,
+				PublicID: entity.PublicID,
+				Position: entity.Position,
+			})
+		}
+	}
+
+	names := make([]string, 0, len(r.Notations))
+	for name := range r.Notations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		notation := r.Notations[name]
+		model.Notations = append(model.Notations, jsonNotation{
+			Name:     notation.Name,
+			SystemID: notation.SystemID,
+			PublicID: notation.PublicID,
+			Position: notation.Position,
+		})
+	}
+
+	data, err := json.MarshalIndent(model, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode model: %w", err)
+	}
+	return string(data) + "\n", nil
+}
+
+// ModelSchema is the JSON Schema (draft 2020-12) of the -format json model dump
+const ModelSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$id": "` + modelSchemaID + `",
+  "title": "dtd-to-go model",
+  "description": "Elements, attributes and entities of a DTD as parsed by dtd-to-go -format json. Version 1; new optional properties may be added without a version change.",
+  "type": "object",
+  "required": ["$schema", "version", "elements", "entities"],
+  "properties": {
+    "$schema": {"const": "` + modelSchemaID + `"},
+    "version": {"const": 1},
+    "elements": {
+      "description": "Element declarations in declaration order",
+      "type": "array",
+      "items": {"$ref": "#/$defs/element"}
+    },
+    "entities": {
+      "description": "Parameter entities sorted by name, then general entities sorted by name",
+      "type": "array",
+      "items": {"$ref": "#/$defs/entity"}
+    },
+    "notations": {
+      "description": "Notation declarations sorted by name; absent when the DTD declares none",
+      "type": "array",
+      "items": {"$ref": "#/$defs/notation"}
+    }
+  },
+  "$defs": {
+    "position": {
+      "type": "object",
+      "required": ["file", "line"],
+      "properties": {
+        "file": {"type": "string"},
+        "line": {"type": "integer", "minimum": 1}
+      }
+    },
+    "element": {
+      "type": "object",
+      "required": ["name", "content", "attributes", "position"],
+      "properties": {
+        "name": {"type": "string"},
+        "content": {"description": "Content model as declared, e.g. (a, b*), EMPTY or ANY", "type": "string"},
+        "attributes": {"type": "array", "items": {"$ref": "#/$defs/attribute"}},
+        "deprecated": {"description": "Reason from a @deprecated comment", "type": "string"},
+        "position": {"$ref": "#/$defs/position"}
+      }
+    },
+    "attribute": {
+      "type": "object",
+      "required": ["name", "type", "required", "position"],
+      "properties": {
+        "name": {"type": "string"},
+        "type": {"description": "Declared type such as CDATA or ID; enumerations have type string and their values in values, NOTATION attributes type NOTATION and their notations in values", "type": "string"},
+        "default": {"description": "Default value with entity and character references expanded", "type": "string"},
+        "required": {"type": "boolean"},
+        "fixed": {"description": "Declared #FIXED: the default is the only value allowed", "type": "boolean"},
+        "values": {"type": "array", "items": {"type": "string"}},
+        "deprecated": {"type": "string"},
+        "position": {"$ref": "#/$defs/position"}
+      }
+    },
+    "entity": {
+      "type": "object",
+      "required": ["name", "general", "position"],
+      "properties": {
+        "name": {"type": "string"},
+        "general": {"description": "Declared without %", "type": "boolean"},
+        "value": {"description": "Replacement text of an internal entity", "type": "string"},
+        "systemId": {"description": "System identifier of an external entity", "type": "string"},
+        "publicId": {"type": "string"},
+        "position": {"$ref": "#/$defs/position"}
+      }
+    },
+    "notation": {
+      "type": "object",
+      "required": ["name", "position"],
+      "properties": {
+        "name": {"type": "string"},
+        "systemId": {"type": "string"},
+        "publicId": {"type": "string"},
+        "position": {"$ref": "#/$defs/position"}
+      }
+    }
+  }
+}
+`
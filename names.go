@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// identifierWords splits a name into the words of a Go identifier. Letters and digits make
+// up the words and every other rune separates them, such as the - of line-item or the
+// middle dot of a·b, except combining marks, which are dropped from the word they are in.
+func identifierWords(name string) []string {
+	var words []string
+	var word strings.Builder
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+		case unicode.IsMark(r):
+		default:
+			if word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
+			}
+		}
+	}
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// validIdentifier returns an exported identifier formed of words as a valid one: Go
+// identifiers cannot start with a digit, so an X goes before one, as in X2nd
+func validIdentifier(name string) string {
+	if name != "" && unicode.IsDigit([]rune(name)[0]) {
+		return "X" + name
+	}
+	return name
+}
+
+// identifierIssue returns why a DTD name cannot be written as a Go identifier with only its
+// separators -, _, : and . dropped, or "" if it can
+func identifierIssue(name string) string {
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("-_:.", r) {
+			return fmt.Sprintf("%q cannot be part of a Go identifier", r)
+		}
+	}
+	if words := identifierWords(name); len(words) > 0 && unicode.IsDigit([]rune(words[0])[0]) {
+		return "Go identifiers cannot start with a digit"
+	}
+	if len(identifierWords(name)) == 0 {
+		return "it has no letters or digits"
+	}
+	return ""
+}
+
+// structRename is an element whose struct is renamed, as an element declared before it
+// forms the same Go name, such as lineItem after line-item
+type structRename struct {
+	Element string
+	Name    string // Name the struct would have had
+	Renamed string
+	TakenBy string // Element whose struct has Name
+}
+
+// structRenames returns the struct names of the elements whose names collide, by element.
+// The first element declared keeps the name and the others take the first free numbered
+// name after it in the order of declaration, such as LineItem2, so the names only change
+// when the DTD does.
+func (g *StructGenerator) structRenames() map[string]structRename {
+	g.renamesOnce.Do(func() {
+		g.renames = make(map[string]structRename)
+		owners := make(map[string]string) // Go name to the element that keeps it
+		var declared []string
+		for _, name := range g.elementOrder {
+			if _, exists := g.elements[name]; exists {
+				declared = append(declared, name)
+			}
+		}
+		for _, name := range declared {
+			if _, taken := owners[g.baseStructName(name)]; !taken {
+				owners[g.baseStructName(name)] = name
+			}
+		}
+		for _, name := range declared {
+			base := g.baseStructName(name)
+			if owners[base] == name {
+				continue
+			}
+			renamed := base
+			for n := 2; owners[renamed] != ""; n++ {
+				renamed = fmt.Sprintf("%s%d", base, n)
+			}
+			owners[renamed] = name
+			g.renames[name] = structRename{Element: name, Name: base, Renamed: renamed, TakenBy: owners[base]}
+		}
+	})
+	return g.renames
+}
+
+// baseStructName returns the Go struct name an element name forms on its own
+func (g *StructGenerator) baseStructName(name string) string {
+	return goStructName(g.initialismCase(g.identifierName(name)))
+}
+
+// uniqueFieldNames renames the fields of a struct whose Go names collide, such as those of
+// the attribute title and the child <title>, or of the children line-item and lineItem.
+// The names in reserved, such as XMLName and Text, are kept by the fields the generator
+// adds; every other field keeps its name unless an earlier field has it, and takes the
+// first free numbered name after it otherwise, such as Title2.
+func uniqueFieldNames(fields []goField, reserved ...string) []goField {
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = field.Name
+	}
+	for i, renamed := range uniqueNames(names, reserved...) {
+		field := fields[i]
+		if renamed == field.Name {
+			continue
+		}
+		fields[i].Renamed = field.Name
+		fields[i].Name = renamed
+		if field.Explain != "" {
+			fields[i].Explain = fmt.Sprintf("%s, renamed from %s, which another field has", field.Explain, field.Name)
+		}
+	}
+	return fields
+}
+
+// uniqueNames numbers apart the names of a struct's, class's or record's members the way
+// uniqueFieldNames does: the names in reserved are kept by members added after them, and
+// every other name is kept by the first member having it
+func uniqueNames(names []string, reserved ...string) []string {
+	taken := make(map[string]bool)
+	for _, name := range reserved {
+		taken[name] = true
+	}
+	for _, name := range names {
+		taken[name] = true
+	}
+
+	unique := make([]string, len(names))
+	owners := make(map[string]bool) // Names kept by a member
+	for i, name := range names {
+		unique[i] = name
+		if !owners[name] && !containsString(reserved, name) {
+			owners[name] = true
+			continue
+		}
+		for n := 2; taken[unique[i]]; n++ {
+			unique[i] = fmt.Sprintf("%s%d", name, n)
+		}
+		taken[unique[i]] = true
+	}
+	return unique
+}
+
+// attributeFieldName returns the name of the field of an element's struct holding one of
+// its attributes, which uniqueFieldNames may have renamed
+func (g *StructGenerator) attributeFieldName(element *DTDElement, attr DTDAttribute) string {
+	for i, declared := range element.Attributes {
+		if declared.Name == attr.Name {
+			return g.structFields(element)[i].Name
+		}
+	}
+	return g.toGoFieldName(attr.Name)
+}
+
+// renameReport describes for stderr the names that are not valid Go identifiers as they
+// are, and the structs and fields renamed because their Go names collide
+func (g *StructGenerator) renameReport() []string {
+	var report []string
+	for _, name := range g.elementOrder {
+		element, exists := g.elements[name]
+		if !exists {
+			continue
+		}
+		if issue := identifierIssue(name); issue != "" {
+			report = append(report, fmt.Sprintf("<%s>: named %s in Go, as %s", name, g.toGoStructName(name), issue))
+		}
+		for _, attr := range element.Attributes {
+			if issue := identifierIssue(attr.Name); issue != "" && !g.isSimpleElement(name) {
+				report = append(report, fmt.Sprintf("<%s>: field %s for the attribute %s, as %s", name, g.attributeFieldName(element, attr), attr.Name, issue))
+			}
+		}
+	}
+	for _, name := range g.elementOrder {
+		if rename, ok := g.structRenames()[name]; ok {
+			report = append(report, fmt.Sprintf("<%s>: type %s, as <%s> is %s", name, rename.Renamed, rename.TakenBy, rename.Name))
+		}
+	}
+	for _, name := range g.elementOrder {
+		element, exists := g.elements[name]
+		if !exists || g.isSimpleElement(name) || g.isInlined(name) {
+			continue
+		}
+		fields := g.structFields(element)
+		for i, field := range fields {
+			if field.Renamed == "" {
+				continue
+			}
+			source := fmt.Sprintf("child <%s>", field.Element)
+			if i < len(element.Attributes) {
+				source = "attribute " + element.Attributes[i].Name
+			}
+			report = append(report, fmt.Sprintf("<%s>: field %s for the %s, as another field is %s", name, field.Name, source, field.Renamed))
+		}
+	}
+	return report
+}
@@ -0,0 +1,68 @@
+package main
+
+import "strings"
+
+// splitQualifiedName splits a qualified name such as xhtml:body into its namespace prefix
+// and local part. Names without a prefix have an empty prefix.
+func splitQualifiedName(name string) (prefix, local string) {
+	if prefix, local, ok := strings.Cut(name, ":"); ok && prefix != "" && local != "" {
+		return prefix, local
+	}
+	return "", name
+}
+
+// localName returns the local part of a qualified name, which encoding/xml reports as
+// xml.Name.Local
+func localName(name string) string {
+	_, local := splitQualifiedName(name)
+	return local
+}
+
+// namespaceURIs returns the namespaces the DTD binds prefixes to, taken from the defaults
+// of xmlns:prefix attributes such as xmlns:xlink CDATA "http://www.w3.org/1999/xlink".
+// The first binding of a prefix is used; xml is always bound.
+func (g *StructGenerator) namespaceURIs() map[string]string {
+	g.namespacesOnce.Do(func() {
+		g.namespaces = map[string]string{"xml": xmlNamespace}
+		for _, name := range g.elementOrder {
+			element, exists := g.elements[name]
+			if !exists {
+				continue
+			}
+			for _, attr := range element.Attributes {
+				prefix, local := splitQualifiedName(attr.Name)
+				if _, bound := g.namespaces[local]; prefix == "xmlns" && !bound && attr.DefaultValue != "" {
+					g.namespaces[local] = attr.DefaultValue
+				}
+			}
+		}
+	})
+	return g.namespaces
+}
+
+// xmlName returns the namespace and local name encoding/xml decodes a qualified name to.
+// Names whose prefix the DTD does not bind get no namespace, so they match in any.
+func (g *StructGenerator) xmlName(name string) (space, local string) {
+	prefix, local := splitQualifiedName(name)
+	switch {
+	case prefix == "":
+		return "", name
+	case prefix == "xmlns":
+		return prefix, local
+	}
+	return g.namespaceURIs()[prefix], local
+}
+
+// xmlTagName returns the name of an element or attribute in an xml struct tag, "URI local"
+// for a prefix the DTD binds. Namespace declarations keep their xmlns: name, so they are
+// marshaled as written, and the -tinygo codecs match qualified names as they are.
+func (g *StructGenerator) xmlTagName(name string) string {
+	space, local := g.xmlName(name)
+	switch {
+	case g.options.TinyGo, space == "xmlns":
+		return name
+	case space != "":
+		return space + " " + local
+	}
+	return local
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// attrNormalizationRuntime implements the attribute-value normalization of XML 1.0
+// section 3.3.3, which encoding/xml does not perform
+const attrNormalizationRuntime = `
+// normalizeCDATA replaces each whitespace character in an attribute value with a space
+func normalizeCDATA(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' {
+			return ' '
+		}
+		return r
+	}, value)
+}
+
+// normalizeTokens normalizes a tokenized attribute value: whitespace becomes spaces,
+// leading and trailing spaces are dropped and runs of spaces collapse to one
+func normalizeTokens(value string) string {
+	return strings.Join(strings.FieldsFunc(normalizeCDATA(value), func(r rune) bool { return r == ' ' }), " ")
+}
+`
+
+// attrNormalizerType applies the normalization to the start elements read by the
+// encoding/xml based Parse helpers
+const attrNormalizerType = `
+// attrNormalizer normalizes the attribute values of the start elements read from r
+type attrNormalizer struct {
+	r xml.TokenReader
+}
+
+func (n *attrNormalizer) Token() (xml.Token, error) {
+	tok, err := n.r.Token()
+	if start, ok := tok.(xml.StartElement); ok {
+		attrs := make([]xml.Attr, len(start.Attr))
+		for i, attr := range start.Attr {
+			attr.Value = normalizeAttr(start.Name.Local, attr.Name.Local, attr.Value)
+			attrs[i] = attr
+		}
+		start.Attr = attrs
+		tok = start
+	}
+	return tok, err
+}
+`
+
+// isTokenizedAttributeType reports whether values of a DTD attribute type are
+// normalized as tokens rather than as CDATA
+func isTokenizedAttributeType(dtdType string) bool {
+	return strings.ToUpper(strings.TrimSpace(dtdType)) != "CDATA"
+}
+
+// generateAttrNormalization generates the normalization helpers and, for the
+// encoding/xml based Parse helpers, normalizeAttr with the token reader applying it
+func (g *StructGenerator) generateAttrNormalization() string {
+	var builder strings.Builder
+
+	builder.WriteString(attrNormalizationRuntime)
+	if g.options.TinyGo {
+		// The hand-rolled decoders know each attribute's type and call the helpers directly
+		return builder.String()
+	}
+
+	var tokenized []string
+	for _, name := range g.elementOrder {
+		element, exists := g.elements[name]
+		if !exists {
+			continue
+		}
+		for _, attr := range element.Attributes {
+			if isTokenizedAttributeType(attr.Type) {
+				tokenized = append(tokenized, localName(name)+" "+localName(attr.Name))
+			}
+		}
+	}
+
+	builder.WriteString("\n// normalizeAttr normalizes the value of an attribute according to its declared type\n")
+	builder.WriteString("func normalizeAttr(element, name, value string) string {\n")
+	if len(tokenized) > 0 {
+		builder.WriteString("\tswitch element + \" \" + name {\n")
+		builder.WriteString("\tcase ")
+		for i, key := range tokenized {
+			if i > 0 {
+				builder.WriteString(",\n\t\t")
+			}
+			builder.WriteString(fmt.Sprintf("%q", key))
+		}
+		builder.WriteString(":\n")
+		builder.WriteString("\t\treturn normalizeTokens(value)\n")
+		builder.WriteString("\t}\n")
+	}
+	builder.WriteString("\treturn normalizeCDATA(value)\n")
+	builder.WriteString("}\n")
+
+	builder.WriteString(attrNormalizerType)
+
+	return builder.String()
+}
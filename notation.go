@@ -0,0 +1,61 @@
+package main
+
+import (
+	"regexp"
+)
+
+// DTDNotation represents a <!NOTATION> declaration, naming a format that unparsed
+// entities and NOTATION attributes refer to
+type DTDNotation struct {
+	Name     string
+	SystemID string
+	PublicID string
+	Position Position // Where the notation was declared
+}
+
+// notationPattern matches <!NOTATION png SYSTEM "image/png"> and
+// <!NOTATION gif PUBLIC "-//CompuServe//NOTATION GIF//EN" ["gif.exe"]>, with the system
+// identifier of a SYSTEM notation in group 2 and the identifiers of a PUBLIC one in
+// groups 3 and 4
+var notationPattern = regexp.MustCompile(`<!NOTATION\s+([\w\p{L}\p{M}.:\x{B7}-]+)\s+(?:SYSTEM\s+("[^"]*"|'[^']*')|PUBLIC\s+("[^"]*"|'[^']*')(?:\s+("[^"]*"|'[^']*'))?)\s*>`)
+
+// notationAttributeType is the DTD type of attributes naming a notation, e.g.
+// format NOTATION (png | gif) #REQUIRED. Their notations are kept in DTDAttribute.Values.
+const notationAttributeType = "NOTATION"
+
+// parseNotation parses a NOTATION declaration; the first declaration of a name is kept
+func (p *DTDParser) parseNotation(line string) {
+	matches := notationPattern.FindStringSubmatch(line)
+	if matches == nil {
+		p.fail("malformed notation declaration")
+		return
+	}
+
+	notation := &DTDNotation{Name: matches[1], SystemID: unquote(matches[2]), Position: p.position}
+	if matches[3] != "" {
+		notation.PublicID, notation.SystemID = unquote(matches[3]), unquote(matches[4])
+	}
+	if _, exists := p.notations[notation.Name]; exists {
+		p.warn("notation %s redeclared; keeping the first declaration", notation.Name)
+		return
+	}
+	p.trace(p.position, "declared notation %s", notation.Name)
+	p.notations[notation.Name] = notation
+	p.emit("notation", notation.Name, notationEvent{SystemID: notation.SystemID, PublicID: notation.PublicID})
+}
+
+// checkNotations reports NOTATION attributes naming notations the DTD does not declare
+func (p *DTDParser) checkNotations() {
+	for _, name := range p.elementOrder {
+		for _, attr := range p.attributes[name] {
+			if attr.Type != notationAttributeType {
+				continue
+			}
+			for _, value := range attr.Values {
+				if _, exists := p.notations[value]; !exists {
+					p.warnAt(attr.Position, "attribute %q of <%s> names undeclared notation %s", attr.Name, name, value)
+				}
+			}
+		}
+	}
+}
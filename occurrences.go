@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// occurrenceType is the metadata type returned by the generated Occurrences methods
+const occurrenceType = `
+// Occurrence is the allowed number of occurrences of a child element
+type Occurrence struct {
+	Min int
+	Max int // Unbounded for children that can repeat without limit
+}
+
+// Unbounded is the Max of children that can repeat without limit
+const Unbounded = -1
+`
+
+// generateOccurrences generates an Occurrences method for every struct with child element
+// fields, reporting the min/max occurrences the DTD allows for each field
+func (g *StructGenerator) generateOccurrences() string {
+	var builder strings.Builder
+
+	builder.WriteString(occurrenceType)
+
+	for _, name := range g.elementOrder {
+		element, exists := g.elements[name]
+		if !exists || g.isSimpleElement(name) || g.isInlined(name) {
+			continue
+		}
+
+		var entries []string
+		interfaces := g.choiceInterfaces(element)
+		for _, field := range g.structFields(element) {
+			if field.Occurs == nil {
+				continue
+			}
+			if choice := choiceInterfaceOf(interfaces, field.Element); choice != nil {
+				// The interface field holds one of the alternatives, or none if they are optional
+				if field.Element == choice.Elements[0] {
+					entries = append(entries, fmt.Sprintf("\t\t%q: {Min: %d, Max: 1},\n", choice.Field, choice.minOccurs()))
+				}
+				continue
+			}
+			max := fmt.Sprint(field.Occurs.Max)
+			if field.Occurs.Max == Unbounded {
+				max = "Unbounded"
+			}
+			entries = append(entries, fmt.Sprintf("\t\t%q: {Min: %d, Max: %s},\n", field.Name, field.Occurs.Min, max))
+		}
+		if len(entries) == 0 {
+			continue
+		}
+
+		structName := g.toGoStructName(name)
+		builder.WriteString("\n// Occurrences returns the allowed occurrences of each child element field, keyed by field name\n")
+		builder.WriteString(fmt.Sprintf("func (%s) Occurrences() map[string]Occurrence {\n", structName))
+		builder.WriteString("\treturn map[string]Occurrence{\n")
+		for _, entry := range entries {
+			builder.WriteString(entry)
+		}
+		builder.WriteString("\t}\n")
+		builder.WriteString("}\n")
+	}
+
+	return builder.String()
+}
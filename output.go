@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"sync"
+	"testing/fstest"
+)
+
+// OutputFS receives the files a run generates, named as on the command line
+type OutputFS interface {
+	WriteFile(name, content string) error
+}
+
+// DiskOutput writes generated files to the operating system's file system, replacing each
+// destination atomically as writeToFile does
+type DiskOutput struct{}
+
+// WriteFile writes one generated file
+func (DiskOutput) WriteFile(name, content string) error {
+	return writeToFile(name, content)
+}
+
+// MemoryFS keeps generated files in memory, for the serve mode and tests. It
+// is an fs.FS too, so files written to it can be read back or parsed with ParserOptions.FS.
+// It is safe for concurrent use.
+type MemoryFS struct {
+	mu    sync.RWMutex
+	files fstest.MapFS
+}
+
+// NewMemoryFS returns a MemoryFS holding files, which may be nil
+func NewMemoryFS(files map[string]string) *MemoryFS {
+	m := &MemoryFS{files: make(fstest.MapFS)}
+	for name, content := range files {
+		m.files[name] = &fstest.MapFile{Data: []byte(content), Mode: 0644}
+	}
+	return m
+}
+
+// WriteFile stores a file, replacing any previous content. Names must be valid fs.FS
+// names, such as "models/structs.go".
+func (m *MemoryFS) WriteFile(name, content string) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[name] = &fstest.MapFile{Data: []byte(content), Mode: 0644}
+	return nil
+}
+
+// Open opens a stored file, implementing fs.FS
+func (m *MemoryFS) Open(name string) (fs.File, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.files.Open(name)
+}
+
+// readSource reads a schema file from fsys, or from the operating system if fsys is nil
+func readSource(fsys fs.FS, name string) ([]byte, error) {
+	if fsys == nil {
+		return os.ReadFile(name)
+	}
+	return fs.ReadFile(fsys, name)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// autoPackage is the -package value that infers the package name from the output directory
+const autoPackage = "auto"
+
+// resolvePackageName checks the package name of generated Go code against the Go files
+// already in the output directory. With -package auto the name is taken from those
+// files, or derived from the directory name when there are none; output to stdout
+// uses the current directory. A name given that is not an identifier, such as a keyword,
+// is rejected.
+func resolvePackageName(packageName, outputFile string) (string, error) {
+	if packageName != autoPackage && (!token.IsIdentifier(packageName) || packageName == "_") {
+		return "", fmt.Errorf("-package %s is not a valid Go package name", packageName)
+	}
+	if outputFile == "" && packageName != autoPackage {
+		return packageName, nil // Nothing is written next to other files
+	}
+	dir := "."
+	if outputFile != "" {
+		dir = filepath.Dir(outputFile)
+	}
+
+	existing, err := siblingPackage(dir, outputFile)
+	if err != nil {
+		return "", err
+	}
+
+	if packageName == autoPackage {
+		if existing != "" {
+			return existing, nil
+		}
+		return packageNameFromDir(dir)
+	}
+
+	if existing != "" && existing != packageName {
+		return "", fmt.Errorf("%s already holds package %s, not %s (use -package %s or -package auto)", dir, existing, packageName, existing)
+	}
+	return packageName, nil
+}
+
+// siblingPackage returns the package clause of the non-test Go files in dir other than
+// the output file itself, or "" when there are none
+func siblingPackage(dir, outputFile string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read output directory: %w", err)
+	}
+
+	fset := token.NewFileSet()
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(dir, name)
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if outputFile != "" && filepath.Clean(path) == filepath.Clean(outputFile) {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, path, nil, parser.PackageClauseOnly)
+		if err != nil {
+			return "", fmt.Errorf("failed to read package clause: %w", err)
+		}
+		return file.Name.Name, nil
+	}
+	return "", nil
+}
+
+// packageNameFromDir derives a package name from a directory such as "models/xml-model"
+// ("xmlmodel"), skipping major version suffixes such as "v2"
+func packageNameFromDir(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve output directory: %w", err)
+	}
+
+	base := filepath.Base(abs)
+	if isMajorVersion(base) {
+		base = filepath.Base(filepath.Dir(abs))
+	}
+
+	var name strings.Builder
+	for _, c := range strings.ToLower(base) {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' {
+			name.WriteRune(c)
+		}
+	}
+	if name.Len() == 0 || unicode.IsDigit([]rune(name.String())[0]) || token.IsKeyword(name.String()) {
+		return "", fmt.Errorf("cannot infer a package name from directory %q; pass -package", base)
+	}
+	return name.String(), nil
+}
+
+// isMajorVersion reports whether a path element is a module major version suffix such as "v2"
+func isMajorVersion(elem string) bool {
+	if len(elem) < 2 || elem[0] != 'v' {
+		return false
+	}
+	for _, c := range elem[1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
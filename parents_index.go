@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// generateParentsIndex generates ParentsOf, which lists the elements whose content
+// models allow a given child element. Elements with ANY content are parents of every
+// declared element.
+func (g *StructGenerator) generateParentsIndex() string {
+	var builder strings.Builder
+
+	var anyParents []string
+	for _, name := range g.elementOrder {
+		if element, exists := g.elements[name]; exists && strings.TrimSpace(element.Content) == "ANY" {
+			anyParents = append(anyParents, name)
+		}
+	}
+
+	result := &ParseResult{Elements: g.elements, Order: g.elementOrder}
+	graph := result.Graph()
+
+	builder.WriteString("\n// ParentsOf returns the elements whose content models allow name as a child, in\n")
+	builder.WriteString("// declaration order, e.g. for \"element not allowed here\" messages. It returns nil for\n")
+	builder.WriteString("// elements that cannot appear inside another element.\n")
+	builder.WriteString("func ParentsOf(name string) []string {\n")
+	builder.WriteString("\tswitch name {\n")
+	for _, name := range graph.Nodes() {
+		parents := graph.Parents(name)
+		if _, declared := g.elements[name]; declared {
+			parents = mergeParents(g.elementOrder, parents, anyParents)
+		}
+		if len(parents) == 0 {
+			continue
+		}
+		quoted := make([]string, len(parents))
+		for i, parent := range parents {
+			quoted[i] = fmt.Sprintf("%q", parent)
+		}
+		builder.WriteString(fmt.Sprintf("\tcase %q:\n", name))
+		builder.WriteString(fmt.Sprintf("\t\treturn []string{%s}\n", strings.Join(quoted, ", ")))
+	}
+	builder.WriteString("\t}\n")
+	builder.WriteString("\treturn nil\n")
+	builder.WriteString("}\n")
+
+	return builder.String()
+}
+
+// mergeParents returns the union of two parent lists in declaration order
+func mergeParents(order []string, a, b []string) []string {
+	wanted := make(map[string]bool)
+	for _, name := range append(append([]string(nil), a...), b...) {
+		wanted[name] = true
+	}
+
+	var merged []string
+	for _, name := range order {
+		if wanted[name] {
+			merged = append(merged, name)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var parquetInvalidNameChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// parquetColumn is a single column of a flattened table
+type parquetColumn struct {
+	Repetition string // required, optional or repeated
+	Type       string
+	Name       string
+}
+
+// parquetTable is a flattened table derived from one element
+type parquetTable struct {
+	Name    string
+	Columns []parquetColumn
+}
+
+// ParquetGenerator generates Parquet message schemas from DTD elements. Singular complex
+// children are flattened into their parent's columns, while repeated complex children are
+// exploded into child tables linked back to the parent row.
+type ParquetGenerator struct {
+	elements     map[string]*DTDElement
+	elementOrder []string
+	models       map[string]*ElementModel
+	tables       []*parquetTable
+	exploded     map[string]*parquetTable
+	report       []string
+}
+
+// NewParquetGenerator creates a new Parquet schema generator
+func NewParquetGenerator(elements map[string]*DTDElement, elementOrder []string) *ParquetGenerator {
+	return &ParquetGenerator{
+		elements:     elements,
+		elementOrder: elementOrder,
+	}
+}
+
+// GenerateSchema generates the Parquet schema text together with a report describing which
+// nested structures were flattened or exploded into child tables
+func (g *ParquetGenerator) GenerateSchema() (string, []string) {
+	g.models = make(map[string]*ElementModel)
+	g.exploded = make(map[string]*parquetTable)
+	g.tables = nil
+	g.report = nil
+
+	models := BuildElementModels(g.elements, g.elementOrder)
+	referenced := make(map[string]bool)
+	for _, model := range models {
+		g.models[model.Name] = model
+		for _, child := range model.Children {
+			referenced[child.Name] = true
+		}
+	}
+
+	for _, model := range models {
+		if !referenced[model.Name] {
+			g.tableFor(model, "")
+		}
+	}
+	if len(g.tables) == 0 && len(models) > 0 {
+		g.tableFor(models[0], "")
+	}
+
+	var builder strings.Builder
+	for i, table := range g.tables {
+		if i > 0 {
+			builder.WriteString("\n")
+		}
+		builder.WriteString(fmt.Sprintf("message %s {\n", table.Name))
+		for _, column := range table.Columns {
+			builder.WriteString(fmt.Sprintf("  %s %s %s;\n", column.Repetition, column.Type, column.Name))
+		}
+		builder.WriteString("}\n")
+	}
+
+	return builder.String(), g.report
+}
+
+// tableFor returns the table for an element, creating it on first use. parent is the
+// element whose rows the new table's rows hang off ("" for root tables).
+func (g *ParquetGenerator) tableFor(model *ElementModel, parent string) *parquetTable {
+	if table, exists := g.exploded[model.Name]; exists {
+		return table
+	}
+
+	table := &parquetTable{Name: g.toColumnName(model.Name)}
+	g.exploded[model.Name] = table
+	g.tables = append(g.tables, table)
+
+	table.Columns = append(table.Columns, parquetColumn{"required", "int64", "_row_id"})
+	if parent != "" {
+		table.Columns = append(table.Columns,
+			parquetColumn{"required", "int64", "_parent_row_id"},
+			parquetColumn{"required", "binary", "_parent_element (STRING)"})
+	}
+
+	g.addColumns(table, model, "", false, map[string]bool{model.Name: true})
+
+	return table
+}
+
+// addColumns adds the columns of model to table, prefixing names for flattened children.
+// optional marks columns that sit below an optional flattened child.
+func (g *ParquetGenerator) addColumns(table *parquetTable, model *ElementModel, prefix string, optional bool, ancestors map[string]bool) {
+	// Columns whose names collide, such as those of the attribute title and the child
+	// <title>, are numbered apart, as a message cannot hold two columns of one name
+	var reserved []string
+	if model.AnyContent {
+		reserved = append(reserved, "content")
+	}
+	if model.HasText {
+		reserved = append(reserved, "text")
+	}
+	names := model.memberNames(g.toColumnName, reserved...)
+	prefix = g.toColumnName(prefix)
+
+	for i, attr := range model.Attributes {
+		name := prefix + names[i]
+		if isListAttributeType(attr.Type) {
+			table.Columns = append(table.Columns, parquetColumn{"repeated", "binary", name + " (STRING)"})
+			continue
+		}
+
+		repetition := "optional"
+		if attr.Required && !optional {
+			repetition = "required"
+		}
+		table.Columns = append(table.Columns, parquetColumn{repetition, "binary", name + " (STRING)"})
+	}
+
+	for i, child := range model.Children {
+		name := prefix + names[len(model.Attributes)+i]
+		childModel, complex := g.models[child.Name]
+
+		switch {
+		case !complex && child.Repeated:
+			table.Columns = append(table.Columns, parquetColumn{"repeated", "binary", name + " (STRING)"})
+		case !complex:
+			table.Columns = append(table.Columns, parquetColumn{"optional", "binary", name + " (STRING)"})
+		case child.Repeated || ancestors[child.Name]:
+			// Repeated (or recursive) structures cannot be flattened into a single row
+			childTable := g.tableFor(childModel, model.Name)
+			g.report = append(g.report, fmt.Sprintf("exploded <%s> inside <%s> into child table %s", child.Name, model.Name, childTable.Name))
+		default:
+			g.report = append(g.report, fmt.Sprintf("flattened <%s> into table %s as %s_*", child.Name, table.Name, name))
+			ancestors[child.Name] = true
+			g.addColumns(table, childModel, name+"_", true, ancestors)
+			delete(ancestors, child.Name)
+		}
+	}
+
+	if model.AnyContent {
+		table.Columns = append(table.Columns, parquetColumn{"optional", "binary", prefix + "content (STRING)"})
+	}
+
+	if model.HasText {
+		table.Columns = append(table.Columns, parquetColumn{"optional", "binary", prefix + "text (STRING)"})
+	}
+}
+
+// toColumnName converts a DTD name to a Parquet column name
+func (g *ParquetGenerator) toColumnName(name string) string {
+	return parquetInvalidNameChars.ReplaceAllString(name, "_")
+}
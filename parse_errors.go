@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseError describes a declaration the parser could not make sense of and skipped
+type ParseError struct {
+	Position    Position // File and line the declaration starts on
+	Column      int      // Column of the declaration's first character, counted in runes from 1
+	Declaration string   // Text of the declaration as assembled from its rows
+	Message     string
+}
+
+// Error formats the error as file:line:column: message, followed by the declaration
+func (e *ParseError) Error() string {
+	if e.Declaration == "" {
+		return fmt.Sprintf("%s:%d: %s", e.Position, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s: %s", e.Position, e.Column, e.Message, e.Declaration)
+}
+
+// ParseErrors is returned by ParseFile with every declaration it had to skip, in the
+// order they appear
+type ParseErrors []*ParseError
+
+// Error lists the errors, one per line after the first
+func (e ParseErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = "\n  " + err.Error()
+	}
+	return fmt.Sprintf("%d declarations could not be parsed:%s", len(e), strings.Join(lines, ""))
+}
+
+// Unwrap returns the errors, so errors.As finds the first *ParseError
+func (e ParseErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// fail records an error for the declaration being parsed
+func (p *DTDParser) fail(format string, args ...any) {
+	p.failAt(p.position, p.column, p.declaration, format, args...)
+}
+
+// failAt records an error for a declaration at a given position
+func (p *DTDParser) failAt(position Position, column int, declaration, format string, args ...any) {
+	declaration = strings.Join(strings.Fields(deprecationMarkerPattern.ReplaceAllString(declaration, "")), " ")
+	p.errors = append(p.errors, &ParseError{
+		Position:    position,
+		Column:      column,
+		Declaration: declaration,
+		Message:     fmt.Sprintf(format, args...),
+	})
+	p.trace(position, "error: %s", fmt.Sprintf(format, args...))
+}
+
+// failUnterminated records an error for text that ended without the > of a declaration
+func (p *DTDParser) failUnterminated(position Position, column int, text string) {
+	if strings.HasPrefix(text, "<!") {
+		p.failAt(position, column, text, "declaration is not terminated by >")
+	} else {
+		p.failAt(position, column, text, "text outside a declaration")
+	}
+}
+
+// rowOffset returns the byte offset on a raw input line, at or after from, where the text
+// of a declaration row found on it starts. Comments and conditional section markup have
+// been stripped from the row, so it is located by its first word; from if that is not
+// found.
+func rowOffset(raw string, from int, row string) int {
+	if from >= len(raw) {
+		return len(raw)
+	}
+	word := row
+	if end := strings.IndexAny(row, " \t"); end >= 0 {
+		word = row[:end]
+	}
+	start := strings.Index(raw[from:], word)
+	if start < 0 {
+		return from
+	}
+	return from + start
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// decodeOptionsRuntime declares the DecodeOption plumbing shared by the Parse helpers
+const decodeOptionsRuntime = `
+// DecodeOption configures the Parse helpers
+type DecodeOption func(*decodeOptions)
+
+// WithCharsetReader sets the xml.Decoder CharsetReader used to read documents that are
+// not UTF-8, such as ISO-8859-1 feeds
+func WithCharsetReader(fn func(charset string, input io.Reader) (io.Reader, error)) DecodeOption {
+	return func(o *decodeOptions) { o.charsetReader = fn }
+}
+`
+
+// instrumentationRuntime lets callers plug an OpenTelemetry tracer and meter into the
+// Parse helpers without the generated package depending on the OpenTelemetry SDK
+const instrumentationRuntime = `
+// Tracer starts decode spans; adapt an OpenTelemetry trace.Tracer to it
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is the part of an OpenTelemetry span used by the Parse helpers
+type Span interface {
+	SetAttribute(key string, value any)
+	RecordError(err error)
+	End()
+}
+
+// Metrics receives decode measurements; adapt OpenTelemetry instruments to it
+type Metrics interface {
+	RecordDocumentSize(ctx context.Context, root string, bytes int64)
+	RecordElementCount(ctx context.Context, root, element string, count int64)
+	RecordDecodeFailure(ctx context.Context, root string, err error)
+}
+
+// WithContext sets the context decode spans and measurements are recorded under
+func WithContext(ctx context.Context) DecodeOption {
+	return func(o *decodeOptions) { o.ctx = ctx }
+}
+
+// WithTracer wraps every decode in a span started by t
+func WithTracer(t Tracer) DecodeOption {
+	return func(o *decodeOptions) { o.tracer = t }
+}
+
+// WithMetrics records document size, element counts and decode failures to m
+func WithMetrics(m Metrics) DecodeOption {
+	return func(o *decodeOptions) { o.metrics = m }
+}
+
+// countingReader counts the bytes read from the document
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// elementCounter counts the start elements passing through a decoder
+type elementCounter struct {
+	d      xml.TokenReader
+	total  int64
+	counts map[string]int64
+}
+
+func (c *elementCounter) Token() (xml.Token, error) {
+	tok, err := c.d.Token()
+	if start, ok := tok.(xml.StartElement); ok {
+		c.total++
+		c.counts[start.Name.Local]++
+	}
+	return tok, err
+}
+
+// decodeInstrumented decodes v inside a span and reports the decode measurements
+func (o *decodeOptions) decodeInstrumented(r io.Reader, root string, v any) error {
+	ctx := o.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var span Span
+	if o.tracer != nil {
+		ctx, span = o.tracer.Start(ctx, "Parse "+root)
+		defer span.End()
+	}
+
+	size := &countingReader{r: r}
+	elements := &elementCounter{d: o.tokenReader(size), counts: make(map[string]int64)}
+	err := xml.NewTokenDecoder(elements).Decode(v)
+
+	if span != nil {
+		span.SetAttribute("xml.root", root)
+		span.SetAttribute("xml.document.size", size.n)
+		span.SetAttribute("xml.element.count", elements.total)
+		if err != nil {
+			span.RecordError(err)
+		}
+	}
+	if o.metrics != nil {
+		o.metrics.RecordDocumentSize(ctx, root, size.n)
+		for name, count := range elements.counts {
+			o.metrics.RecordElementCount(ctx, root, name, count)
+		}
+		if err != nil {
+			o.metrics.RecordDecodeFailure(ctx, root, err)
+		}
+	}
+	return err
+}
+`
+
+// charsetPackage provides the legacy charset decoders wired in by -charset
+const charsetPackage = "golang.org/x/net/html/charset"
+
+// usesParseHelpers reports whether ParseX helpers are generated
+func (g *StructGenerator) usesParseHelpers() bool {
+	return g.options.ParseHelpers || g.options.DecodeInto || g.options.Instrument || g.options.CharsetReader ||
+		((g.options.NormalizeAttrs || len(g.options.Roots) > 0) && !g.options.TinyGo)
+}
+
+// documentRoots returns the elements that get a Parse helper: those selected with -root,
+// or else the generated structs no other element references, or the first generated
+// struct when every element is nested
+func (g *StructGenerator) documentRoots() []string {
+	if len(g.options.Roots) > 0 {
+		return append([]string(nil), g.options.Roots...)
+	}
+	var generated, roots []string
+	for _, name := range g.elementOrder {
+		if _, exists := g.elements[name]; !exists || g.isSimpleElement(name) || g.isInlined(name) {
+			continue
+		}
+		generated = append(generated, name)
+
+		isRoot := true
+		for _, parent := range g.parentsOf(name) {
+			if parent != name {
+				isRoot = false
+				break
+			}
+		}
+		if isRoot {
+			roots = append(roots, name)
+		}
+	}
+
+	if len(roots) == 0 && len(generated) > 0 {
+		roots = generated[:1]
+	}
+	return roots
+}
+
+// generateParseHelpers generates a ParseX function for every document root together
+// with the DecodeOption settings they accept
+func (g *StructGenerator) generateParseHelpers() string {
+	var builder strings.Builder
+
+	builder.WriteString(decodeOptionsRuntime)
+
+	builder.WriteString("\n// decodeOptions holds the settings applied by DecodeOption values\n")
+	builder.WriteString("type decodeOptions struct {\n")
+	builder.WriteString("\tcharsetReader func(charset string, input io.Reader) (io.Reader, error)\n")
+	if g.options.Instrument {
+		builder.WriteString("\tctx           context.Context\n")
+		builder.WriteString("\ttracer        Tracer\n")
+		builder.WriteString("\tmetrics       Metrics\n")
+	}
+	builder.WriteString("}\n")
+
+	if g.options.Instrument {
+		builder.WriteString(instrumentationRuntime)
+	}
+
+	builder.WriteString("\n// newDecoder creates the xml.Decoder configured by the options\n")
+	builder.WriteString("func (o *decodeOptions) newDecoder(r io.Reader) *xml.Decoder {\n")
+	builder.WriteString("\td := xml.NewDecoder(r)\n")
+	builder.WriteString("\td.CharsetReader = o.charsetReader\n")
+	builder.WriteString("\treturn d\n")
+	builder.WriteString("}\n")
+
+	if g.options.Instrument || g.options.NormalizeAttrs {
+		builder.WriteString("\n// tokenReader returns the token stream documents are decoded from\n")
+		builder.WriteString("func (o *decodeOptions) tokenReader(r io.Reader) xml.TokenReader {\n")
+		if g.options.NormalizeAttrs {
+			builder.WriteString("\treturn &attrNormalizer{r: o.newDecoder(r)}\n")
+		} else {
+			builder.WriteString("\treturn o.newDecoder(r)\n")
+		}
+		builder.WriteString("}\n")
+	}
+
+	builder.WriteString("\n// decodeDocument decodes the document read from r into v\n")
+	builder.WriteString("func decodeDocument(r io.Reader, root string, v any, opts []DecodeOption) error {\n")
+	if g.options.CharsetReader {
+		// Legacy encodings are understood unless a caller overrides the reader
+		builder.WriteString(fmt.Sprintf("\toptions := decodeOptions{charsetReader: %s.NewReaderLabel}\n", g.imports.name(charsetPackage)))
+	} else {
+		builder.WriteString("\tvar options decodeOptions\n")
+	}
+	builder.WriteString("\tfor _, opt := range opts {\n")
+	builder.WriteString("\t\topt(&options)\n")
+	builder.WriteString("\t}\n")
+	if g.options.Instrument {
+		builder.WriteString("\tif options.tracer != nil || options.metrics != nil {\n")
+		builder.WriteString("\t\treturn options.decodeInstrumented(r, root, v)\n")
+		builder.WriteString("\t}\n")
+	}
+	if g.options.NormalizeAttrs {
+		builder.WriteString("\treturn xml.NewTokenDecoder(options.tokenReader(r)).Decode(v)\n")
+	} else {
+		builder.WriteString("\treturn options.newDecoder(r).Decode(v)\n")
+	}
+	builder.WriteString("}\n")
+
+	for _, name := range g.documentRoots() {
+		structName := g.toGoStructName(name)
+		builder.WriteString(fmt.Sprintf("\n// Parse%s decodes a <%s> document\n", structName, name))
+		builder.WriteString(fmt.Sprintf("func Parse%s(r io.Reader, opts ...DecodeOption) (*%s, error) {\n", structName, structName))
+		builder.WriteString(fmt.Sprintf("\tv := &%s{}\n", structName))
+		builder.WriteString(fmt.Sprintf("\tif err := decodeDocument(r, %q, v, opts); err != nil {\n", name))
+		builder.WriteString("\t\treturn nil, err\n")
+		builder.WriteString("\t}\n")
+		builder.WriteString("\treturn v, nil\n")
+		builder.WriteString("}\n")
+
+		if len(g.options.Roots) == 0 {
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("\n// WriteXML writes the <%s> document to w\n", name))
+		builder.WriteString(fmt.Sprintf("func (v *%s) WriteXML(w io.Writer) error {\n", structName))
+		if g.usesSelfClosingMarshal() {
+			builder.WriteString("\tdata, err := Marshal(v)\n")
+		} else {
+			builder.WriteString("\tdata, err := xml.Marshal(v)\n")
+		}
+		builder.WriteString("\tif err != nil {\n")
+		builder.WriteString("\t\treturn err\n")
+		builder.WriteString("\t}\n")
+		builder.WriteString("\t_, err = w.Write(data)\n")
+		builder.WriteString("\treturn err\n")
+		builder.WriteString("}\n")
+	}
+
+	return builder.String()
+}
+
+// rootTypes returns the Go types of the generated structs a document with the given root
+// can hold, the root's own included, in declaration order
+func (g *StructGenerator) rootTypes(root string) []string {
+	reachable := map[string]bool{root: true}
+	queue := []string{root}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		element, exists := g.elements[name]
+		if !exists {
+			continue
+		}
+		for _, child := range contentReferences(element.Content) {
+			if !reachable[child] {
+				reachable[child] = true
+				queue = append(queue, child)
+			}
+		}
+	}
+
+	var types []string
+	for _, name := range g.elementOrder {
+		if _, exists := g.elements[name]; exists && reachable[name] && !g.isSimpleElement(name) && !g.isInlined(name) {
+			types = append(types, g.toGoStructName(name))
+		}
+	}
+	return types
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// trace logs a decision of the parser at a position to ParserOptions.Trace, as one
+// file:line: message line
+func (p *DTDParser) trace(position Position, format string, args ...any) {
+	if p.options.Trace == nil {
+		return
+	}
+	fmt.Fprintf(p.options.Trace, "%s: %s\n", position, fmt.Sprintf(format, args...))
+}
+
+// traceShort abbreviates text such as a comment for the trace, on one line
+func traceShort(text string) string {
+	const limit = 60
+	text = strings.Join(strings.Fields(text), " ")
+	if len([]rune(text)) > limit {
+		text = string([]rune(text)[:limit]) + "..."
+	}
+	return fmt.Sprintf("%q", text)
+}
+
+// traceUnused logs, once parsing is done, the declarations that leave nothing in the
+// result although they parsed: attribute lists of elements that are never declared, and
+// external parameter entities that are never referenced, so the files they name are not
+// read. These are the usual reasons for an element or attribute missing from the output.
+func (p *DTDParser) traceUnused() {
+	if p.options.Trace == nil {
+		return
+	}
+	var undeclared []string
+	for name := range p.attributes {
+		if _, exists := p.elements[name]; !exists {
+			undeclared = append(undeclared, name)
+		}
+	}
+	sort.Strings(undeclared)
+	for _, name := range undeclared {
+		attrs := p.attributes[name]
+		p.trace(attrs[0].Position, "dropped the %d attribute(s) declared for <%s>: no <!ELEMENT %s> is declared", len(attrs), name, name)
+	}
+
+	var unreferenced []*DTDEntity
+	for _, entity := range p.entities {
+		if entity.SystemID != "" && entity.References == 0 {
+			unreferenced = append(unreferenced, entity)
+		}
+	}
+	sort.Slice(unreferenced, func(i, j int) bool {
+		a, b := unreferenced[i].Position, unreferenced[j].Position
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	for _, entity := range unreferenced {
+		p.trace(entity.Position, "external parameter entity %%%s; is never referenced, so %s is not read", entity.Name, entity.SystemID)
+	}
+}
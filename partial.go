@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// splitOnly returns the distinct element names of a comma separated -only list
+func splitOnly(list string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// structNamesOf returns the struct type names generated for the named elements, failing
+// for elements that are not declared or have no struct of their own
+func (g *StructGenerator) structNamesOf(names []string) ([]string, error) {
+	var structNames []string
+	for _, name := range names {
+		if _, exists := g.elements[name]; !exists {
+			return nil, &GenerateError{Element: name, Err: ErrUndeclaredElement}
+		}
+		if g.isSimpleElement(name) || g.isInlined(name) {
+			return nil, &GenerateError{Element: name, Err: ErrNotStruct}
+		}
+		structNames = append(structNames, g.toGoStructName(name))
+	}
+	return structNames, nil
+}
+
+// typeDeclSpan is the byte range of a type declaration, including its doc comment
+type typeDeclSpan struct {
+	start, end int
+}
+
+// typeDecls returns the spans of the single type declarations in a Go source file
+func typeDecls(src string) (map[string]typeDeclSpan, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	spans := make(map[string]typeDeclSpan)
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE || len(gen.Specs) != 1 {
+			continue
+		}
+		start := gen.Pos()
+		if gen.Doc != nil {
+			start = gen.Doc.Pos()
+		}
+		name := gen.Specs[0].(*ast.TypeSpec).Name.Name
+		spans[name] = typeDeclSpan{start: fset.Position(start).Offset, end: fset.Position(gen.End()).Offset}
+	}
+	return spans, nil
+}
+
+// replaceTypeDecls returns existing with the declarations of the named types, doc
+// comments included, replaced by their declarations in generated. Everything else in
+// existing is kept as it is; types existing does not declare yet are appended.
+func replaceTypeDecls(existing, generated string, typeNames []string) (string, error) {
+	current, err := typeDecls(existing)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse existing output: %w", err)
+	}
+	fresh, err := typeDecls(generated)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse generated code: %w", err)
+	}
+
+	type replacement struct {
+		span typeDeclSpan
+		code string
+	}
+	var replacements []replacement
+	var appended strings.Builder
+	for _, name := range typeNames {
+		span, ok := fresh[name]
+		if !ok {
+			return "", fmt.Errorf("generated code does not declare type %s", name)
+		}
+		code := generated[span.start:span.end]
+		if old, ok := current[name]; ok {
+			replacements = append(replacements, replacement{span: old, code: code})
+		} else {
+			appended.WriteString("\n" + code + "\n")
+		}
+	}
+
+	// Replace from the end of the file so earlier offsets stay valid
+	sort.Slice(replacements, func(i, j int) bool {
+		return replacements[i].span.start > replacements[j].span.start
+	})
+	result := existing
+	for _, r := range replacements {
+		result = result[:r.span.start] + r.code + result[r.span.end:]
+	}
+	return result + appended.String(), nil
+}
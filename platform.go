@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// scanLines is a bufio.SplitFunc like bufio.ScanLines that also ends lines at a lone \r,
+// so DTDs saved with \r\n, \r or \n line endings parse alike and report the same lines
+func scanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		switch {
+		case data[i] == '\n':
+			return i + 1, data[:i], nil
+		case i+1 < len(data) && data[i+1] == '\n':
+			return i + 2, data[:i], nil
+		case i+1 < len(data) || atEOF:
+			return i + 1, data[:i], nil
+		}
+		// A \r at the end of the buffer may be followed by \n
+		return 0, nil, nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// maxWindowsPath is the length from which Windows refuses paths without the \\?\ prefix;
+// directories are limited to 248 characters, leaving room for an 8.3 file name
+const maxWindowsPath = 248
+
+// longPath returns a path to filename that Windows accepts however long it is, by making
+// it absolute and adding the \\?\ prefix that lifts the MAX_PATH limit. Other paths, and
+// all paths on other platforms, are returned unchanged.
+func longPath(filename string) string {
+	if runtime.GOOS != "windows" || len(filename) < maxWindowsPath || strings.HasPrefix(filename, `\\?\`) {
+		return filename
+	}
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return filename
+	}
+	if strings.HasPrefix(path, `\\`) {
+		return `\\?\UNC\` + path[2:]
+	}
+	return `\\?\` + path
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// entityReferencePattern matches parameter entity references such as %address.model;
+var entityReferencePattern = regexp.MustCompile(`%([\w\p{L}\p{M}.:\x{B7}-]+);`)
+
+// Prune removes what no output backend uses from the parsed model: parameter entities that
+// are never referenced. Every element with attributes gets a type holding them, so
+// attributes are kept. It returns a description of everything removed.
+func (r *ParseResult) Prune() []string {
+	var report []string
+
+	// Count references from content models and from other entities' values
+	referenced := make(map[string]bool)
+	for name, entity := range r.Entities {
+		if entity.References > 0 {
+			referenced[name] = true
+		}
+	}
+	var texts []string
+	for _, element := range r.Elements {
+		texts = append(texts, element.Content)
+	}
+	for _, entity := range r.Entities {
+		texts = append(texts, entity.Value)
+	}
+	for _, text := range texts {
+		for _, match := range entityReferencePattern.FindAllStringSubmatch(text, -1) {
+			referenced[match[1]] = true
+		}
+	}
+
+	var unused []*DTDEntity
+	for name, entity := range r.Entities {
+		if !referenced[name] {
+			unused = append(unused, entity)
+		}
+	}
+	sort.Slice(unused, func(i, j int) bool {
+		return unused[i].Position.Line < unused[j].Position.Line
+	})
+	for _, entity := range unused {
+		delete(r.Entities, entity.Name)
+		report = append(report, fmt.Sprintf("%s: removed unused entity %%%s;", entity.Position, entity.Name))
+	}
+
+	return report
+}
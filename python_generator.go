@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// pythonKeywords lists Python reserved words that cannot be used as field names
+var pythonKeywords = map[string]bool{
+	"False": true, "None": true, "True": true, "and": true, "as": true, "assert": true,
+	"async": true, "await": true, "break": true, "class": true, "continue": true,
+	"def": true, "del": true, "elif": true, "else": true, "except": true, "finally": true,
+	"for": true, "from": true, "global": true, "if": true, "import": true, "in": true,
+	"is": true, "lambda": true, "nonlocal": true, "not": true, "or": true, "pass": true,
+	"raise": true, "return": true, "try": true, "while": true, "with": true, "yield": true,
+}
+
+// PythonGenerator generates Python dataclasses from DTD elements
+type PythonGenerator struct {
+	elements     map[string]*DTDElement
+	elementOrder []string
+	names        *StructGenerator // Reused for class naming so all backends agree
+}
+
+// NewPythonGenerator creates a new Python dataclass generator
+func NewPythonGenerator(elements map[string]*DTDElement, elementOrder []string) *PythonGenerator {
+	return &PythonGenerator{
+		elements:     elements,
+		elementOrder: elementOrder,
+		names:        NewStructGenerator("", elements, elementOrder, GeneratorOptions{}),
+	}
+}
+
+// GenerateDataclasses generates Python dataclass code for all elements
+func (g *PythonGenerator) GenerateDataclasses() string {
+	var builder strings.Builder
+
+	builder.WriteString("\"\"\"Dataclasses generated from a DTD by dtd-to-go.\n\n")
+	builder.WriteString("Field metadata follows the xsdata conventions (name/type/required) so the\n")
+	builder.WriteString("classes can be bound to XML directly or inspected by other tooling.\n")
+	builder.WriteString("\"\"\"\n\n")
+	builder.WriteString("from __future__ import annotations\n\n")
+	builder.WriteString("from dataclasses import dataclass, field\n")
+	builder.WriteString("from typing import List, Optional\n")
+
+	for _, model := range BuildElementModels(g.elements, g.elementOrder) {
+		builder.WriteString("\n\n")
+		builder.WriteString(g.generateDataclass(model))
+	}
+
+	return builder.String()
+}
+
+// generateDataclass generates a Python dataclass for a single element
+func (g *PythonGenerator) generateDataclass(model *ElementModel) string {
+	var builder strings.Builder
+
+	className := g.names.toGoStructName(model.Name)
+
+	builder.WriteString("@dataclass\n")
+	builder.WriteString(fmt.Sprintf("class %s:\n", className))
+	builder.WriteString(fmt.Sprintf("    \"\"\"Represents the <%s> element.\"\"\"\n\n", model.Name))
+	builder.WriteString("    class Meta:\n")
+	builder.WriteString(fmt.Sprintf("        name = %q\n", model.Name))
+
+	var fields []string
+
+	// Fields whose names collide, such as those of the attribute title and the child
+	// <title>, are numbered apart as in the Go structs, as a later dataclass field of the
+	// same name would replace the earlier one
+	var reserved []string
+	if model.AnyContent {
+		reserved = append(reserved, "content")
+	}
+	if model.HasText {
+		reserved = append(reserved, "text")
+	}
+	names := model.memberNames(g.toPythonFieldName, reserved...)
+
+	for i, attr := range model.Attributes {
+		metadata := fmt.Sprintf(`{"name": %q, "type": "Attribute"`, attr.Name)
+		if attr.Required {
+			metadata += `, "required": True`
+		}
+		metadata += "}"
+
+		pyType := "Optional[str]"
+		if isListAttributeType(attr.Type) {
+			pyType = "List[str]"
+			fields = append(fields, fmt.Sprintf("%s: %s = field(default_factory=list, metadata=%s)", names[i], pyType, metadata))
+			continue
+		}
+
+		defaultValue := "None"
+		if attr.DefaultValue != "" {
+			defaultValue = fmt.Sprintf("%q", attr.DefaultValue)
+		}
+		fields = append(fields, fmt.Sprintf("%s: %s = field(default=%s, metadata=%s)", names[i], pyType, defaultValue, metadata))
+	}
+
+	for i, child := range model.Children {
+		elemType := "str"
+		if !isSimpleElement(g.elements, child.Name) {
+			elemType = g.names.toGoStructName(child.Name)
+		}
+		metadata := fmt.Sprintf(`{"name": %q, "type": "Element"}`, child.Name)
+		fieldName := names[len(model.Attributes)+i]
+
+		if child.Repeated {
+			fields = append(fields, fmt.Sprintf("%s: List[%s] = field(default_factory=list, metadata=%s)", fieldName, elemType, metadata))
+		} else {
+			fields = append(fields, fmt.Sprintf("%s: Optional[%s] = field(default=None, metadata=%s)", fieldName, elemType, metadata))
+		}
+	}
+
+	if model.AnyContent {
+		fields = append(fields, `content: List[object] = field(default_factory=list, metadata={"type": "Wildcard"})`)
+	}
+
+	if model.HasText {
+		fields = append(fields, `text: Optional[str] = field(default=None, metadata={"type": "Text"})`)
+	}
+
+	if len(fields) > 0 {
+		builder.WriteString("\n")
+	}
+	for _, f := range fields {
+		builder.WriteString(fmt.Sprintf("    %s\n", f))
+	}
+
+	return builder.String()
+}
+
+// toPythonFieldName converts a DTD element/attribute name to a snake_case Python field name
+func (g *PythonGenerator) toPythonFieldName(name string) string {
+	var result strings.Builder
+
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsMark(r):
+			result.WriteRune('_') // Separators such as - and :, and runes identifiers cannot hold
+		case unicode.IsUpper(r):
+			// Start a new word on a lower-to-upper transition (modTime -> mod_time)
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])) {
+				result.WriteRune('_')
+			}
+			result.WriteRune(unicode.ToLower(r))
+		default:
+			result.WriteRune(r)
+		}
+	}
+
+	fieldName := result.String()
+	if fieldName == "" {
+		fieldName = "field"
+	}
+	if unicode.IsDigit([]rune(fieldName)[0]) {
+		fieldName = "x" + fieldName // Identifiers cannot start with a digit
+	}
+	if pythonKeywords[fieldName] {
+		fieldName += "_"
+	}
+
+	return fieldName
+}
@@ -0,0 +1,445 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Ways Redact replaces a sensitive value, selected per field in the redaction section of
+// the generator config
+const (
+	RedactBlank = "blank" // Replace the value by the empty string
+	RedactHash  = "hash"  // Replace the value by a digest, so equal values stay equal
+)
+
+// GeneratorConfig holds the generation settings too structured for flags. It is read from
+// JSON with -config:
+//
+//	{"redaction": [{"field": "phone", "action": "hash"}, {"field": "vendor@email"}],
+//	 "transliteration": {"ä": "ae", "ö": "oe", "ü": "ue"},
+//	 "initialisms": ["SKU", "EAN"],
+//	 "types": [{"field": "price", "type": "float64"}, {"field": "listing@date-listed", "type": "time.Time", "layout": "2006-01-02"}]}
+type GeneratorConfig struct {
+	Redaction       []RedactionRule `json:"redaction"`       // Sensitive elements and attributes Redact replaces
+	Transliteration Transliteration `json:"transliteration"` // Replacements in names when forming Go identifiers
+	Initialisms     []string        `json:"initialisms"`     // Words kept in upper case in Go identifiers, besides the common ones
+	Types           []TypeMapping   `json:"types"`           // Go types of elements' text and attributes other than string
+}
+
+// RedactionRule names a sensitive field: an element for its text, element@attribute for
+// an attribute of one element, or @attribute for the attribute on every element that
+// declares it
+type RedactionRule struct {
+	Field  string `json:"field"`
+	Action string `json:"action"` // RedactBlank (the default) or RedactHash
+}
+
+// target splits the field into its element and attribute
+func (r RedactionRule) target() (element, attribute string) {
+	return fieldTarget(r.Field)
+}
+
+// fieldTarget splits a field of the generator config into its element and attribute;
+// element is empty for @attribute and attribute is empty for an element's text
+func fieldTarget(field string) (element, attribute string) {
+	if i := strings.Index(field, "@"); i >= 0 {
+		return field[:i], field[i+1:]
+	}
+	return field, ""
+}
+
+// validFieldTarget reports whether a field of the generator config is element,
+// element@attribute or @attribute
+func validFieldTarget(field string) bool {
+	element, attribute := fieldTarget(field)
+	return (element != "" || attribute != "") && strings.Count(field, "@") <= 1 && (!strings.Contains(field, "@") || attribute != "")
+}
+
+// LoadGeneratorConfig reads a generator configuration file, rejecting unknown fields,
+// malformed redaction rules and type mappings, and transliterations or initialisms that
+// cannot form identifiers. Whether the rules and mappings fit the schema is checked by
+// CheckRedactions and CheckTypeMappings once it is parsed.
+func LoadGeneratorConfig(filename string) (GeneratorConfig, error) {
+	var config GeneratorConfig
+	file, err := os.Open(filename)
+	if err != nil {
+		return config, fmt.Errorf("failed to open generator config: %w", err)
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return config, fmt.Errorf("failed to parse generator config %s: %w", filename, err)
+	}
+
+	seen := make(map[string]bool)
+	for i, rule := range config.Redaction {
+		if !validFieldTarget(rule.Field) {
+			return config, fmt.Errorf("generator config %s: redaction field %q is not element, element@attribute or @attribute", filename, rule.Field)
+		}
+		switch rule.Action {
+		case "":
+			config.Redaction[i].Action = RedactBlank
+		case RedactBlank, RedactHash:
+		default:
+			return config, fmt.Errorf("generator config %s: unknown redaction action %q for %s (expected blank or hash)", filename, rule.Action, rule.Field)
+		}
+		if seen[rule.Field] {
+			return config, fmt.Errorf("generator config %s: redaction field %q is listed twice", filename, rule.Field)
+		}
+		seen[rule.Field] = true
+	}
+	if err := config.Transliteration.check(); err != nil {
+		return config, fmt.Errorf("generator config %s: %w", filename, err)
+	}
+	if err := checkInitialisms(config.Initialisms); err != nil {
+		return config, fmt.Errorf("generator config %s: %w", filename, err)
+	}
+	if err := checkTypeMappings(config.Types); err != nil {
+		return config, fmt.Errorf("generator config %s: %w", filename, err)
+	}
+	return config, nil
+}
+
+// CheckRedactions reports the redaction rules that do not fit the schema or whose
+// replacement would make documents invalid: text of elements that cannot hold text,
+// undeclared attributes, enumerated and ENTITY attributes, blanked tokenized attributes
+// that must have a value, and ID or IDREF attributes hashed while others are not, which
+// would leave references dangling
+func CheckRedactions(result *ParseResult, rules []RedactionRule) error {
+	covered := make(map[string]string) // element@attribute to the field of the rule covering it
+	references := false                // Some ID, IDREF or IDREFS attribute is redacted
+	for _, rule := range rules {
+		name, attribute := rule.target()
+		if attribute == "" {
+			element, exists := result.Elements[name]
+			if !exists {
+				return fmt.Errorf("redaction %s: %w", rule.Field, &GenerateError{Element: name, Err: ErrUndeclaredElement})
+			}
+			if !strings.Contains(element.Content, "#PCDATA") {
+				return fmt.Errorf("redaction %s: <%s> has no text to redact (content %s)", rule.Field, name, element.Content)
+			}
+			continue
+		}
+
+		found := false
+		for _, elementName := range result.Order {
+			element, exists := result.Elements[elementName]
+			if !exists || (name != "" && name != elementName) {
+				continue
+			}
+			for _, attr := range element.Attributes {
+				if attr.Name != attribute {
+					continue
+				}
+				found = true
+				key := elementName + "@" + attribute
+				if other, ok := covered[key]; ok {
+					return fmt.Errorf("redaction %s: attribute %s of <%s> is already covered by %s", rule.Field, attribute, elementName, other)
+				}
+				covered[key] = rule.Field
+				if err := checkRedactedAttribute(rule, element, attr); err != nil {
+					return err
+				}
+				if isReferenceAttributeType(attr.Type) {
+					references = true
+				}
+			}
+		}
+		if !found {
+			if name != "" {
+				return fmt.Errorf("redaction %s: attribute %s of <%s> is not declared", rule.Field, attribute, name)
+			}
+			return fmt.Errorf("redaction %s: no element declares attribute %s", rule.Field, attribute)
+		}
+	}
+
+	// Hashing maps an ID and the references to it to the same name only when all are hashed
+	if references {
+		for _, name := range result.Order {
+			element, exists := result.Elements[name]
+			if !exists {
+				continue
+			}
+			for _, attr := range element.Attributes {
+				if isReferenceAttributeType(attr.Type) && covered[name+"@"+attr.Name] == "" {
+					return fmt.Errorf("redaction: %s attribute %s of <%s> must be hashed too, or the redacted IDs and references no longer match", attr.Type, attr.Name, name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkRedactedAttribute reports an attribute whose values the rule cannot replace while
+// keeping documents valid
+func checkRedactedAttribute(rule RedactionRule, element *DTDElement, attr DTDAttribute) error {
+	attrType := strings.ToUpper(attr.Type)
+	switch {
+	case attr.Fixed:
+		return fmt.Errorf("redaction %s: attribute %s of <%s> is #FIXED %q, so no replacement is a valid value", rule.Field, attr.Name, element.Name, attr.DefaultValue)
+	case len(attr.Values) > 0:
+		return fmt.Errorf("redaction %s: attribute %s of <%s> is enumerated, so no replacement is a valid value", rule.Field, attr.Name, element.Name)
+	case attrType == "ENTITY" || attrType == "ENTITIES":
+		return fmt.Errorf("redaction %s: attribute %s of <%s> must name declared entities, so no replacement is a valid value", rule.Field, attr.Name, element.Name)
+	case rule.Action == RedactBlank && isReferenceAttributeType(attrType):
+		return fmt.Errorf("redaction %s: blanking %s attribute %s of <%s> would break the references to it; use hash", rule.Field, attr.Type, attr.Name, element.Name)
+	case rule.Action == RedactBlank && attr.Required && attrType != "CDATA":
+		return fmt.Errorf("redaction %s: required %s attribute %s of <%s> cannot be blank; use hash", rule.Field, attr.Type, attr.Name, element.Name)
+	}
+	return nil
+}
+
+// isReferenceAttributeType reports whether an attribute type identifies elements or refers
+// to them
+func isReferenceAttributeType(attrType string) bool {
+	switch strings.ToUpper(attrType) {
+	case "ID", "IDREF", "IDREFS":
+		return true
+	}
+	return false
+}
+
+// redactMixedStrings returns the code redacting the text nodes of mixed content held by
+// field, and the child elements among the nodes generated as plain strings
+func (g *StructGenerator) redactMixedStrings(element *DTDElement, field goField) string {
+	var body strings.Builder
+	if redacted, ok := g.redactionField(element.Name, ""); ok {
+		body.WriteString(fmt.Sprintf("\t\tif v.%s[i].Text != \"\" {\n", field.Name))
+		body.WriteString(fmt.Sprintf("\t\t\tv.%s[i].Text = r.value(%q, v.%s[i].Text)\n", field.Name, redacted, field.Name))
+		body.WriteString("\t\t}\n")
+	}
+	for _, inner := range g.mixedNodeFields(element) {
+		redacted, ok := g.redactionField(inner.Element, "")
+		if !ok || inner.Type != "*string" {
+			continue
+		}
+		value := fmt.Sprintf("v.%s[i].%s", field.Name, inner.Name)
+		body.WriteString(fmt.Sprintf("\t\tif %s != nil {\n", value))
+		body.WriteString(fmt.Sprintf("\t\t\t*%s = r.value(%q, *%s)\n", value, redacted, value))
+		body.WriteString("\t\t}\n")
+	}
+	if body.Len() == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\tfor i := range v.%s {\n%s\t}\n", field.Name, body.String())
+}
+
+// redactGroupStrings returns the code redacting the elements generated as plain strings
+// in every repetition of a group
+func (g *StructGenerator) redactGroupStrings(group *contentGroup) string {
+	var builder strings.Builder
+	for _, inner := range group.Fields {
+		redacted, ok := g.redactionField(inner.Element, "")
+		if !ok {
+			continue
+		}
+		value := fmt.Sprintf("v.%s[i].%s", group.Field.Name, inner.Name)
+		switch inner.Type {
+		case "*string":
+			builder.WriteString(fmt.Sprintf("\t\tif %s != nil {\n", value))
+			builder.WriteString(fmt.Sprintf("\t\t\t*%s = r.value(%q, *%s)\n", value, redacted, value))
+			builder.WriteString("\t\t}\n")
+		case "[]string":
+			builder.WriteString(fmt.Sprintf("\t\tfor j := range %s {\n", value))
+			builder.WriteString(fmt.Sprintf("\t\t\t%s[j] = r.value(%q, %s[j])\n", value, redacted, value))
+			builder.WriteString("\t\t}\n")
+		}
+	}
+	if builder.Len() == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\tfor i := range v.%s {\n%s\t}\n", group.Field.Name, builder.String())
+}
+
+// redactionField returns the field of the rule redacting an attribute of element, or
+// with an empty attribute the element's text, if one does
+func (g *StructGenerator) redactionField(element, attribute string) (string, bool) {
+	for _, rule := range g.options.Redactions {
+		name, attr := rule.target()
+		if attr == attribute && (name == element || (name == "" && attribute != "")) {
+			return rule.Field, true
+		}
+	}
+	return "", false
+}
+
+// redactionRuntime holds the field selection and the replacements of the generated Redact
+const redactionRuntime = `
+// redactor maps the fields being redacted to the replacement applied to them
+type redactor map[string]string
+
+// value returns the redacted form of a value of field; values of other fields and empty
+// values are kept
+func (r redactor) value(field, s string) string {
+	action, ok := r[field]
+	switch {
+	case !ok || s == "":
+		return s
+	case action == "hash":
+		return redactHash(s)
+	default:
+		return ""
+	}
+}
+
+// tokens returns the redacted form of a list value of field
+func (r redactor) tokens(field string, tokens []string) []string {
+	action, ok := r[field]
+	switch {
+	case !ok || len(tokens) == 0:
+		return tokens
+	case action == "hash":
+		hashed := make([]string, len(tokens))
+		for i, token := range tokens {
+			hashed[i] = redactHash(token)
+		}
+		return hashed
+	default:
+		return nil
+	}
+}
+
+// redactHash replaces a value by a digest that is a valid XML name, so hashed IDs and the
+// IDREFs to them still match and equal values stay equal across documents
+func redactHash(s string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(s)))
+	return "h" + hex.EncodeToString(sum[:8])
+}
+`
+
+// generateRedaction generates Redact, which blanks or hashes the sensitive elements and
+// attributes of the redaction config in a decoded document, and a redact method for
+// every struct
+func (g *StructGenerator) generateRedaction() string {
+	var builder strings.Builder
+
+	rules := append([]RedactionRule(nil), g.options.Redactions...)
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Field < rules[j].Field })
+	builder.WriteString("\n// Redactions are the sensitive elements and attributes Redact replaces, by field name:\n")
+	builder.WriteString("// an element for its text, element@attribute or @attribute on every element. \"blank\"\n")
+	builder.WriteString("// values become empty; \"hash\" values a digest, so equal values stay equal.\n")
+	builder.WriteString("var Redactions = map[string]string{\n")
+	for _, rule := range rules {
+		builder.WriteString(fmt.Sprintf("\t%q: %q,\n", rule.Field, rule.Action))
+	}
+	builder.WriteString("}\n")
+
+	structs := make(map[string]bool) // Go types of the structs that can be redacted
+	var names []string
+	for _, name := range g.elementOrder {
+		if _, exists := g.elements[name]; exists && !g.isSimpleElement(name) && !g.isInlined(name) {
+			structs[g.toGoStructName(name)] = true
+			names = append(names, name)
+		}
+	}
+
+	builder.WriteString("\n// Redact replaces the sensitive elements and attributes listed in Redactions in the\n")
+	builder.WriteString("// document held by root, a pointer to one of the element structs, in place. Without\n")
+	builder.WriteString("// fields every listed field is redacted, otherwise only the given ones. The replacements\n")
+	builder.WriteString("// keep the document valid, so redacted documents can be shared as samples.\n")
+	builder.WriteString("func Redact(root any, fields ...string) error {\n")
+	builder.WriteString("\tr := make(redactor)\n")
+	builder.WriteString("\tfor field, action := range Redactions {\n")
+	builder.WriteString("\t\tif len(fields) == 0 {\n")
+	builder.WriteString("\t\t\tr[field] = action\n")
+	builder.WriteString("\t\t}\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\tfor _, field := range fields {\n")
+	builder.WriteString("\t\taction, ok := Redactions[field]\n")
+	builder.WriteString("\t\tif !ok {\n")
+	builder.WriteString("\t\t\treturn fmt.Errorf(\"redact: %q is not a redaction field\", field)\n")
+	builder.WriteString("\t\t}\n")
+	builder.WriteString("\t\tr[field] = action\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\tswitch v := root.(type) {\n")
+	for _, name := range names {
+		builder.WriteString(fmt.Sprintf("\tcase *%s:\n", g.toGoStructName(name)))
+		builder.WriteString("\t\tv.redact(r)\n")
+	}
+	builder.WriteString("\tdefault:\n")
+	builder.WriteString("\t\treturn fmt.Errorf(\"redact: %T is not an element struct\", root)\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\treturn nil\n")
+	builder.WriteString("}\n")
+
+	builder.WriteString(redactionRuntime)
+
+	for _, name := range names {
+		element := g.elements[name]
+		structName := g.toGoStructName(name)
+		builder.WriteString(fmt.Sprintf("\n// redact redacts the sensitive fields of this <%s> and the elements inside it\n", name))
+		builder.WriteString(fmt.Sprintf("func (v *%s) redact(r redactor) {\n", structName))
+		for _, attr := range element.Attributes {
+			field, ok := g.redactionField(name, attr.Name)
+			if !ok {
+				continue
+			}
+			fieldName := g.attributeFieldName(element, attr)
+			if isListAttributeType(attr.Type) {
+				builder.WriteString(fmt.Sprintf("\tv.%s = r.tokens(%q, v.%s)\n", fieldName, field, fieldName))
+			} else {
+				builder.WriteString(fmt.Sprintf("\tv.%s = r.value(%q, v.%s)\n", fieldName, field, fieldName))
+			}
+		}
+		interfaces := g.choiceInterfaces(element)
+		groups := g.contentGroups(element)
+		for _, field := range g.structFields(element) {
+			if code, ok := g.choiceDispatch(interfaces, field, "redact(r)"); ok {
+				builder.WriteString(code)
+				continue
+			}
+			if code, ok := g.mixedDispatch(element, field, structs, "redact(r)"); ok {
+				builder.WriteString(g.redactMixedStrings(element, field))
+				builder.WriteString(code)
+				continue
+			}
+			if group := groupField(groups, field); group != nil {
+				builder.WriteString(g.redactGroupStrings(group))
+				code, _ := g.groupDispatch(groups, field, structs, "redact(r)")
+				builder.WriteString(code)
+				continue
+			}
+			switch {
+			case field.Name == "Text" && field.Occurs == nil:
+				if redacted, ok := g.redactionField(name, ""); ok {
+					builder.WriteString(fmt.Sprintf("\tv.Text = r.value(%q, v.Text)\n", redacted))
+				}
+			case field.Occurs == nil:
+			case field.Type == "string":
+				if redacted, ok := g.redactionField(field.Element, ""); ok {
+					builder.WriteString(fmt.Sprintf("\tv.%s = r.value(%q, v.%s)\n", field.Name, redacted, field.Name))
+				}
+			case field.Type == "*string":
+				if redacted, ok := g.redactionField(field.Element, ""); ok {
+					builder.WriteString(fmt.Sprintf("\tif v.%s != nil {\n", field.Name))
+					builder.WriteString(fmt.Sprintf("\t\t*v.%s = r.value(%q, *v.%s)\n", field.Name, redacted, field.Name))
+					builder.WriteString("\t}\n")
+				}
+			case field.Type == "[]string":
+				if redacted, ok := g.redactionField(field.Element, ""); ok {
+					builder.WriteString(fmt.Sprintf("\tfor i := range v.%s {\n", field.Name))
+					builder.WriteString(fmt.Sprintf("\t\tv.%s[i] = r.value(%q, v.%s[i])\n", field.Name, redacted, field.Name))
+					builder.WriteString("\t}\n")
+				}
+			case strings.HasPrefix(field.Type, "*") && structs[field.Type[1:]]:
+				builder.WriteString(fmt.Sprintf("\tif v.%s != nil {\n", field.Name))
+				builder.WriteString(fmt.Sprintf("\t\tv.%s.redact(r)\n", field.Name))
+				builder.WriteString("\t}\n")
+			case structs[field.Type]:
+				builder.WriteString(fmt.Sprintf("\tv.%s.redact(r)\n", field.Name))
+			case strings.HasPrefix(field.Type, "[]") && structs[field.Type[2:]]:
+				builder.WriteString(fmt.Sprintf("\tfor i := range v.%s {\n", field.Name))
+				builder.WriteString(fmt.Sprintf("\t\tv.%s[i].redact(r)\n", field.Name))
+				builder.WriteString("\t}\n")
+			}
+		}
+		builder.WriteString("}\n")
+	}
+
+	return builder.String()
+}
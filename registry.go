@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// fingerprintHeader carries a schema fingerprint in registry HTTP requests and responses
+const fingerprintHeader = "X-Schema-Fingerprint"
+
+// SchemaRegistry stores DTDs under a name together with their content fingerprints
+type SchemaRegistry interface {
+	// Push stores a new version of the named schema and returns its fingerprint
+	Push(name string, content []byte) (string, error)
+	// Pull retrieves the named schema at the given fingerprint, or the latest version
+	// when fingerprint is empty, and returns its content and fingerprint
+	Pull(name, fingerprint string) ([]byte, string, error)
+}
+
+// registryBackends maps registry URL schemes to backend constructors
+var registryBackends = map[string]func(u *url.URL) (SchemaRegistry, error){
+	"http":  newHTTPRegistry,
+	"https": newHTTPRegistry,
+	"file":  newDirRegistry,
+}
+
+// OpenRegistry opens the schema registry at the given URL using the backend for its scheme
+func OpenRegistry(rawURL string) (SchemaRegistry, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid registry URL: %w", err)
+	}
+
+	backend, exists := registryBackends[u.Scheme]
+	if !exists {
+		return nil, fmt.Errorf("unsupported registry scheme %q", u.Scheme)
+	}
+	return backend(u)
+}
+
+// Fingerprint returns the content fingerprint used to identify a schema version
+func Fingerprint(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// HTTPRegistry talks to a schema registry over HTTP. Schemas live at
+// {base}/schemas/{name}; specific versions at {base}/schemas/{name}/versions/{fingerprint}.
+type HTTPRegistry struct {
+	baseURL string
+	client  *http.Client
+}
+
+// newHTTPRegistry creates an HTTP registry client for the given base URL
+func newHTTPRegistry(u *url.URL) (SchemaRegistry, error) {
+	return &HTTPRegistry{
+		baseURL: strings.TrimSuffix(u.String(), "/"),
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Push uploads a schema version with a PUT request
+func (r *HTTPRegistry) Push(name string, content []byte) (string, error) {
+	fingerprint := Fingerprint(content)
+
+	req, err := http.NewRequest(http.MethodPut, r.baseURL+"/schemas/"+url.PathEscape(name), bytes.NewReader(content))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/xml-dtd")
+	req.Header.Set(fingerprintHeader, fingerprint)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to push schema: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("registry rejected schema: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	if stored := resp.Header.Get(fingerprintHeader); stored != "" && stored != fingerprint {
+		return "", fmt.Errorf("registry stored fingerprint %s, expected %s", stored, fingerprint)
+	}
+
+	return fingerprint, nil
+}
+
+// Pull downloads a schema version and verifies its fingerprint
+func (r *HTTPRegistry) Pull(name, fingerprint string) ([]byte, string, error) {
+	endpoint := r.baseURL + "/schemas/" + url.PathEscape(name)
+	if fingerprint != "" {
+		endpoint += "/versions/" + url.PathEscape(fingerprint)
+	}
+
+	resp, err := r.client.Get(endpoint)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to pull schema: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("registry returned %s for schema %q", resp.Status, name)
+	}
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	actual := Fingerprint(content)
+	if expected := resp.Header.Get(fingerprintHeader); expected != "" && expected != actual {
+		return nil, "", fmt.Errorf("schema content does not match registry fingerprint %s", expected)
+	}
+	if fingerprint != "" && fingerprint != actual {
+		return nil, "", fmt.Errorf("schema content does not match requested fingerprint %s", fingerprint)
+	}
+
+	return content, actual, nil
+}
+
+// DirRegistry is a registry kept in a local or shared directory (file:// URLs).
+// Each version is stored as {dir}/{name}/{fingerprint}.dtd with a "latest" pointer file.
+type DirRegistry struct {
+	dir string
+}
+
+// newDirRegistry creates a directory registry for a file:// URL
+func newDirRegistry(u *url.URL) (SchemaRegistry, error) {
+	dir := filepath.FromSlash(u.Path)
+	if dir == "" {
+		return nil, fmt.Errorf("file registry URL needs a path")
+	}
+	return &DirRegistry{dir: dir}, nil
+}
+
+// Push stores a schema version in the registry directory
+func (r *DirRegistry) Push(name string, content []byte) (string, error) {
+	if err := checkSchemaName(name); err != nil {
+		return "", err
+	}
+	fingerprint := Fingerprint(content)
+	schemaDir := filepath.Join(r.dir, name)
+
+	if err := os.MkdirAll(schemaDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create registry directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(schemaDir, r.versionFile(fingerprint)), content, 0644); err != nil {
+		return "", fmt.Errorf("failed to store schema: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(schemaDir, "latest"), []byte(fingerprint+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("failed to update latest version: %w", err)
+	}
+
+	return fingerprint, nil
+}
+
+// Pull reads a schema version from the registry directory
+func (r *DirRegistry) Pull(name, fingerprint string) ([]byte, string, error) {
+	if err := checkSchemaName(name); err != nil {
+		return nil, "", err
+	}
+	schemaDir := filepath.Join(r.dir, name)
+
+	if fingerprint == "" {
+		latest, err := os.ReadFile(filepath.Join(schemaDir, "latest"))
+		if err != nil {
+			return nil, "", fmt.Errorf("schema %q not found in registry: %w", name, err)
+		}
+		fingerprint = strings.TrimSpace(string(latest))
+	}
+	if err := checkSchemaName(fingerprint); err != nil {
+		return nil, "", err
+	}
+
+	content, err := os.ReadFile(filepath.Join(schemaDir, r.versionFile(fingerprint)))
+	if err != nil {
+		return nil, "", fmt.Errorf("schema %q version %s not found in registry: %w", name, fingerprint, err)
+	}
+	if actual := Fingerprint(content); actual != fingerprint {
+		return nil, "", fmt.Errorf("stored schema does not match fingerprint %s", fingerprint)
+	}
+
+	return content, fingerprint, nil
+}
+
+// checkSchemaName rejects names and fingerprints that would escape the registry directory
+func checkSchemaName(name string) error {
+	if name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid schema name %q", name)
+	}
+	return nil
+}
+
+// versionFile returns the file name used for a schema version
+func (r *DirRegistry) versionFile(fingerprint string) string {
+	return strings.ReplaceAll(fingerprint, ":", "-") + ".dtd"
+}
+
+// runRegistry implements the "registry push" and "registry pull" subcommands
+func runRegistry(args []string) int {
+	if len(args) == 0 || (args[0] != "push" && args[0] != "pull") {
+		fmt.Fprintf(os.Stderr, "Usage: %s registry push|pull [options]\n", os.Args[0])
+		return 1
+	}
+	command := args[0]
+
+	flags := flag.NewFlagSet("registry "+command, flag.ContinueOnError)
+	registryURL := flags.String("registry", os.Getenv("DTD_REGISTRY"), "Registry URL (http(s):// or file://; default: $DTD_REGISTRY)")
+	name := flags.String("name", "", "Schema name in the registry (required)")
+	fingerprint := flags.String("fingerprint", "", "Schema version to pull (default: latest)")
+	output := flags.String("output", "", "File to write the pulled schema to (default: stdout)")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 1
+	}
+
+	if *registryURL == "" || *name == "" {
+		fmt.Fprintf(os.Stderr, "registry %s: -registry and -name are required\n", command)
+		return 1
+	}
+
+	registry, err := OpenRegistry(*registryURL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening registry: %v\n", err)
+		return 1
+	}
+
+	switch command {
+	case "push":
+		if flags.NArg() != 1 {
+			fmt.Fprintf(os.Stderr, "Usage: %s registry push -registry <url> -name <name> <dtd-file>\n", os.Args[0])
+			return 1
+		}
+		content, err := os.ReadFile(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading DTD file: %v\n", err)
+			return 1
+		}
+		pushed, err := registry.Push(*name, content)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error pushing schema: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Pushed %s %s\n", *name, pushed)
+
+	case "pull":
+		content, pulled, err := registry.Pull(*name, *fingerprint)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error pulling schema: %v\n", err)
+			return 1
+		}
+		if *output == "" {
+			os.Stdout.Write(content)
+			return 0
+		}
+		if err := writeToFile(*output, string(content)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing schema: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Pulled %s %s to %s\n", *name, pulled, *output)
+	}
+
+	return 0
+}
@@ -0,0 +1,472 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// SchemaComplexity measures how hard a DTD is to map onto types, for comparing schemas
+// in bug reports without sharing them
+type SchemaComplexity struct {
+	Elements      int
+	Attributes    int
+	MaxAttributes int // Most attributes declared on one element
+	Entities      int // Parameter and general entities
+	Notations     int
+
+	Roots      int
+	Leaves     int
+	Undeclared int // Elements referenced by content models but never declared
+	Recursive  int // Recursive element groups
+	MaxDepth   int // Longest chain of nested elements from a root, Unbounded with recursion
+	MaxFanOut  int // Most distinct children of one element
+	MaxFanIn   int // Most parents of one element
+
+	Empty, Any, Mixed, Text, Children int // Elements by kind of content
+
+	MaxParticles     int // Most element names and groups in one content model
+	MaxGroupDepth    int // Deepest nesting of groups in one content model
+	Nondeterministic int // Content models XML 1.0 Appendix E rejects
+	Enumerated       int // Enumerated and NOTATION attributes
+	References       int // ID, IDREF and IDREFS attributes
+}
+
+// MeasureComplexity computes the complexity metrics of a parsed DTD
+func MeasureComplexity(result *ParseResult) SchemaComplexity {
+	graph := result.Graph()
+	c := SchemaComplexity{
+		Elements:  len(result.Elements),
+		Entities:  len(result.Entities) + len(result.General),
+		Notations: len(result.Notations),
+		Roots:     len(graph.Roots()),
+		Leaves:    len(graph.Leaves()),
+		Recursive: len(graph.Cycles()),
+	}
+
+	for _, name := range graph.Nodes() {
+		if _, exists := result.Elements[name]; !exists {
+			c.Undeclared++
+		}
+		c.MaxFanOut = max(c.MaxFanOut, len(graph.Children(name)))
+		c.MaxFanIn = max(c.MaxFanIn, len(graph.Parents(name)))
+	}
+
+	if c.Recursive > 0 {
+		c.MaxDepth = Unbounded
+	} else {
+		depths := make(map[string]int)
+		var depth func(name string) int
+		depth = func(name string) int {
+			if d, ok := depths[name]; ok {
+				return d
+			}
+			d := 1
+			for _, child := range graph.Children(name) {
+				d = max(d, depth(child)+1)
+			}
+			depths[name] = d
+			return d
+		}
+		for _, root := range graph.Roots() {
+			c.MaxDepth = max(c.MaxDepth, depth(root))
+		}
+	}
+
+	for _, name := range result.Order {
+		element, exists := result.Elements[name]
+		if !exists {
+			continue
+		}
+		c.Attributes += len(element.Attributes)
+		c.MaxAttributes = max(c.MaxAttributes, len(element.Attributes))
+		for _, attr := range element.Attributes {
+			if len(attr.Values) > 0 {
+				c.Enumerated++
+			}
+			if isReferenceAttributeType(attr.Type) {
+				c.References++
+			}
+		}
+
+		model, err := ParseContentModel(element.Content)
+		if err != nil {
+			continue
+		}
+		switch {
+		case model.Kind == ContentEmpty:
+			c.Empty++
+		case model.Kind == ContentAny:
+			c.Any++
+		case model.Kind == ContentMixed && len(model.ElementNames()) == 0:
+			c.Text++
+		case model.Kind == ContentMixed:
+			c.Mixed++
+		default:
+			c.Children++
+		}
+		if model.Root != nil {
+			particles, depth := model.Root.size()
+			c.MaxParticles = max(c.MaxParticles, particles)
+			c.MaxGroupDepth = max(c.MaxGroupDepth, depth)
+		}
+		if _, ambiguous := model.Ambiguity(); ambiguous {
+			c.Nondeterministic++
+		}
+	}
+	return c
+}
+
+// size returns the number of element names and groups in the particle and how deeply
+// its groups nest
+func (c *ContentParticle) size() (particles, depth int) {
+	particles = 1
+	for _, child := range c.Children {
+		childParticles, childDepth := child.size()
+		particles += childParticles
+		depth = max(depth, childDepth)
+	}
+	if c.Kind != ParticleElement {
+		depth++
+	}
+	return particles, depth
+}
+
+// rows lists the metrics with their labels, in reporting order
+func (c SchemaComplexity) rows() [][2]string {
+	depth := fmt.Sprint(c.MaxDepth)
+	if c.MaxDepth == Unbounded {
+		depth = "unbounded (recursive)"
+	}
+	count := func(n int) string { return fmt.Sprint(n) }
+	return [][2]string{
+		{"Elements", count(c.Elements)},
+		{"Attributes", count(c.Attributes)},
+		{"Most attributes on one element", count(c.MaxAttributes)},
+		{"Enumerated and NOTATION attributes", count(c.Enumerated)},
+		{"ID, IDREF and IDREFS attributes", count(c.References)},
+		{"Entities", count(c.Entities)},
+		{"Notations", count(c.Notations)},
+		{"Root elements", count(c.Roots)},
+		{"Leaf elements", count(c.Leaves)},
+		{"Referenced but undeclared elements", count(c.Undeclared)},
+		{"Recursive element groups", count(c.Recursive)},
+		{"Deepest element nesting", depth},
+		{"Most children of one element", count(c.MaxFanOut)},
+		{"Most parents of one element", count(c.MaxFanIn)},
+		{"EMPTY / ANY / text-only / mixed / element content", fmt.Sprintf("%d / %d / %d / %d / %d", c.Empty, c.Any, c.Text, c.Mixed, c.Children)},
+		{"Largest content model (names and groups)", count(c.MaxParticles)},
+		{"Deepest group nesting in a content model", count(c.MaxGroupDepth)},
+		{"Non-deterministic content models", count(c.Nondeterministic)},
+	}
+}
+
+// LossyConversion is a construct of a DTD that documents can use but the generated Go
+// structs cannot reproduce when marshaling a decoded document
+type LossyConversion struct {
+	Element string
+	Kind    string // Short name of the construct, such as mixed-content-order
+	Detail  string
+	Child   string // Child element occurring at several places, for split-occurrences
+}
+
+// FindLossyConversions lists the constructs whose information the generated structs drop:
+// the interleaving of text and child elements, the relative order of children that may
+// repeat in any order or occur at several places, and namespace prefixes, which
+// encoding/xml replaces by its own
+func FindLossyConversions(result *ParseResult) []LossyConversion {
+	var lossy []LossyConversion
+	for _, name := range result.Order {
+		element, exists := result.Elements[name]
+		if !exists {
+			continue
+		}
+		if strings.Contains(name, ":") {
+			lossy = append(lossy, LossyConversion{Element: name, Kind: "namespace-prefix",
+				Detail: fmt.Sprintf("encoding/xml does not keep the prefix of <%s> but declares its namespace its own way", name)})
+		}
+		for _, attr := range element.Attributes {
+			if strings.Contains(attr.Name, ":") && !strings.HasPrefix(attr.Name, "xml:") && !strings.HasPrefix(attr.Name, "xmlns") {
+				lossy = append(lossy, LossyConversion{Element: name, Kind: "namespace-prefix",
+					Detail: fmt.Sprintf("encoding/xml does not keep the prefix of attribute %s but declares its namespace its own way", attr.Name)})
+			}
+		}
+
+		model, err := ParseContentModel(element.Content)
+		if err != nil || model.Root == nil {
+			continue
+		}
+		names := model.ElementNames()
+		if model.Kind == ContentMixed {
+			if len(names) > 0 {
+				lossy = append(lossy, LossyConversion{Element: name, Kind: "mixed-content-order",
+					Detail: fmt.Sprintf("text is kept in one Text field apart from the child elements, so how %s interleaves them is lost unless -mixed-style nodes", strings.TrimSpace(element.Content))})
+			}
+			continue
+		}
+		if group := model.Root.unorderedRepetition(); group != nil {
+			detail := fmt.Sprintf("the children of %s go into one field per element, so their relative order is lost", group)
+			for _, kept := range model.repeatedGroups() {
+				if kept == group {
+					detail += " unless -group-style anonymous or named"
+				}
+			}
+			lossy = append(lossy, LossyConversion{Element: name, Kind: "repeated-group-order", Detail: detail})
+		}
+		positions := make(map[string]int)
+		model.Root.countPositions(positions)
+		members := make(map[string]int)
+		if model.Root.Kind == ParticleSequence && model.Root.Indicator != '*' && model.Root.Indicator != '+' {
+			for _, member := range model.Root.Children {
+				if member.Kind == ParticleElement {
+					members[member.Name]++
+				}
+			}
+		}
+		for _, child := range names {
+			if positions[child] > 1 {
+				detail := fmt.Sprintf("<%s> occurs at %d places in %s, all held by one field, so which place each came from is lost", child, positions[child], strings.TrimSpace(element.Content))
+				if members[child] == positions[child] {
+					detail += " unless -sequence-style positional"
+				}
+				lossy = append(lossy, LossyConversion{Element: name, Kind: "split-occurrences", Detail: detail, Child: child})
+			}
+		}
+	}
+	return lossy
+}
+
+// lossyConversions lists the constructs whose information the generated structs drop with
+// the generator's options, leaving out the ones they keep, such as mixed content held as
+// nodes. Namespace prefixes, which no option keeps, are left out as well.
+func (g *StructGenerator) lossyConversions() []LossyConversion {
+	var lossy []LossyConversion
+	for _, conversion := range FindLossyConversions(&ParseResult{Elements: g.elements, Order: g.elementOrder}) {
+		element := g.elements[conversion.Element]
+		switch conversion.Kind {
+		case "namespace-prefix":
+			continue
+		case "mixed-content-order":
+			if g.holdsMixedNodes(conversion.Element) {
+				continue
+			}
+		case "repeated-group-order":
+			model, _ := ParseContentModel(element.Content) // Parses, as it has a group
+			kept := false
+			for _, group := range g.contentGroups(element) {
+				kept = kept || group.Particle.String() == model.Root.unorderedRepetition().String()
+			}
+			if kept {
+				continue
+			}
+		case "split-occurrences":
+			if sequence := g.positionalSequence(element); sequence != nil && sequence.Split[conversion.Child] {
+				continue
+			}
+		}
+		lossy = append(lossy, conversion)
+	}
+	return lossy
+}
+
+// unorderedRepetition returns the first group that can repeat and holds more than one
+// element name, whose children can therefore come in any relative order, or nil
+func (c *ContentParticle) unorderedRepetition() *ContentParticle {
+	if c.Kind != ParticleElement && (c.Indicator == '*' || c.Indicator == '+') {
+		if len((&ContentModel{Root: c}).ElementNames()) > 1 {
+			return c
+		}
+	}
+	for _, child := range c.Children {
+		if group := child.unorderedRepetition(); group != nil {
+			return group
+		}
+	}
+	return nil
+}
+
+// countPositions counts the occurrences of each element name in the particle
+func (c *ContentParticle) countPositions(positions map[string]int) {
+	if c.Kind == ParticleElement {
+		positions[c.Name]++
+	}
+	for _, child := range c.Children {
+		child.countPositions(positions)
+	}
+}
+
+// schemaReport is what the report command packages into one HTML page
+type schemaReport struct {
+	Tool        manifestTool
+	GoVersion   string
+	Platform    string
+	Input       string
+	Fingerprint string
+	Sources     []string
+	Error       string // Why the DTD could not be parsed, if it could not
+	Metrics     [][2]string
+	Lossy       []LossyConversion
+	Warnings    []string
+	Lint        []string
+}
+
+// newSchemaReport assembles the report of a DTD. Paths are reduced to their base names,
+// so the report does not reveal the directory layout of the machine it was made on.
+func newSchemaReport(inputFile string, config LintConfig) *schemaReport {
+	report := &schemaReport{
+		Tool:      newManifestTool(),
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		Input:     filepath.Base(inputFile),
+	}
+	if content, err := os.ReadFile(inputFile); err == nil {
+		report.Fingerprint = Fingerprint(content)
+	}
+
+	result, err := NewDTDParser(ParserOptions{}).ParseFile(inputFile)
+	if err != nil {
+		report.Error = anonymizePaths(err.Error(), inputFile)
+		return report
+	}
+	for _, file := range result.Files {
+		report.Sources = append(report.Sources, filepath.Base(file))
+	}
+	report.Metrics = MeasureComplexity(result).rows()
+	report.Lossy = FindLossyConversions(result)
+	for _, warning := range result.Warnings {
+		report.Warnings = append(report.Warnings, anonymizePaths(warning.String(), result.Files...))
+	}
+	for _, name := range result.Graph().Nodes() {
+		if _, exists := result.Elements[name]; !exists {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("element <%s> is referenced but not declared", name))
+		}
+	}
+	for _, issue := range Lint(result, config) {
+		report.Lint = append(report.Lint, anonymizePaths(issue.String(), result.Files...))
+	}
+	return report
+}
+
+// anonymizePaths replaces the given file paths in a message by their base names
+func anonymizePaths(message string, paths ...string) string {
+	for _, path := range paths {
+		if base := filepath.Base(path); base != path {
+			message = strings.ReplaceAll(message, path, base)
+		}
+	}
+	return message
+}
+
+// reportTemplate renders the report as a self-contained page, without scripts or
+// external resources, so it can be attached to a bug report and opened anywhere
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>dtd-to-go report: {{.Input}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 60em; padding: 0 1em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+th { background: #f3f3f3; }
+code, pre { font-family: monospace; }
+.error { color: #a00; }
+.note { color: #666; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>dtd-to-go report: {{.Input}}</h1>
+<p class="note">Generated offline by dtd-to-go report; nothing was sent anywhere. Of the schema it
+holds only what is shown below: metrics, element names, the content models quoted and diagnostics,
+with file paths reduced to their base names.</p>
+
+<h2>Environment</h2>
+<table>
+<tr><th>dtd-to-go</th><td>{{.Tool.Version}}{{if .Tool.Revision}} ({{.Tool.Revision}}{{if .Tool.Modified}}, modified{{end}}){{end}}</td></tr>
+<tr><th>Go</th><td>{{.GoVersion}}</td></tr>
+<tr><th>Platform</th><td>{{.Platform}}</td></tr>
+<tr><th>Schema</th><td>{{.Input}}</td></tr>
+{{if .Fingerprint}}<tr><th>Fingerprint</th><td><code>{{.Fingerprint}}</code></td></tr>{{end}}
+{{if .Sources}}<tr><th>Files</th><td>{{range $i, $file := .Sources}}{{if $i}}, {{end}}{{$file}}{{end}}</td></tr>{{end}}
+</table>
+{{if .Error}}
+<h2>Parse error</h2>
+<pre class="error">{{.Error}}</pre>
+{{else}}
+<h2>Schema complexity</h2>
+<table>
+{{range .Metrics}}<tr><th>{{index . 0}}</th><td>{{index . 1}}</td></tr>
+{{end}}</table>
+
+<h2>Lossy conversions ({{len .Lossy}})</h2>
+{{if .Lossy}}<p>Documents using these constructs do not round-trip through the generated structs.</p>
+<table>
+<tr><th>Element</th><th>Construct</th><th>Detail</th></tr>
+{{range .Lossy}}<tr><td><code>{{.Element}}</code></td><td>{{.Kind}}</td><td>{{.Detail}}</td></tr>
+{{end}}</table>
+{{else}}<p>None: decoded documents marshal back with the same content.</p>{{end}}
+
+<h2>Diagnostics</h2>
+<h3>Parser warnings ({{len .Warnings}})</h3>
+{{if .Warnings}}<ul>
+{{range .Warnings}}<li><code>{{.}}</code></li>
+{{end}}</ul>{{else}}<p>None.</p>{{end}}
+<h3>Lint issues ({{len .Lint}})</h3>
+{{if .Lint}}<ul>
+{{range .Lint}}<li><code>{{.}}</code></li>
+{{end}}</ul>{{else}}<p>None.</p>{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+// runReport implements the report subcommand, which writes the complexity metrics,
+// lossy conversions and diagnostics of a DTD to one HTML file to attach to bug reports.
+// It works offline and sends nothing anywhere.
+func runReport(args []string) int {
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	inputFile := flags.String("input", "", "Path to the DTD file to report on (required)")
+	outputFile := flags.String("output", "dtd-to-go-report.html", "Path of the HTML report to write, or - for stdout")
+	configFile := flags.String("config", "", "Path to a JSON lint config disabling rules or excluding elements from them")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	if *inputFile == "" || flags.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "Usage: %s report -input <dtd-file> [-output <html-file>] [-config <json-file>]\n", os.Args[0])
+		return 1
+	}
+
+	var config LintConfig
+	if *configFile != "" {
+		var err error
+		if config, err = LoadLintConfig(*configFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+
+	report := newSchemaReport(*inputFile, config)
+	var page bytes.Buffer
+	if err := reportTemplate.Execute(&page, report); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *outputFile == "-" {
+		os.Stdout.Write(page.Bytes())
+	} else {
+		if err := os.WriteFile(*outputFile, page.Bytes(), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Wrote report to %s; review it before attaching it to a bug report\n", *outputFile)
+	}
+	if report.Error != "" {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,75 @@
+#!/bin/sh
+# Generates Go code for every DTD in testdata/ and XML document in
+# testdata/documents/ into a throwaway module and runs go vet and go build on
+# it, so broken output (duplicate fields, invalid identifiers) is caught before
+# it reaches users. go test runs TestGeneratedCodeBuilds, which does the same for a
+# handful of option sets at once; this script goes through every flag combination.
+#
+# Usage: scripts/check-generated.sh [extra dtd-to-go flags...]
+set -eu
+
+root=$(cd "$(dirname "$0")/.." && pwd)
+work=$(mktemp -d)
+trap 'rm -rf "$work"' EXIT
+
+go build -o "$work/dtd-to-go" "$root"
+
+status=0
+
+# check generates the Go code of a DTD or document with the given flags (and the script's
+# own) into a module of its own named after the variant, then vets and builds it
+check() {
+	input=$1
+	name=$2
+	variant=$3
+	shift 3
+	mod="$work/$name$(echo "$variant" | tr -c 'a-z' '_')"
+	mkdir -p "$mod"
+	printf 'module generated\n\ngo 1.24\n' > "$mod/go.mod"
+
+	if ! "$work/dtd-to-go" -input "$input" -output "$mod/generated.go" -package generated "$@" > /dev/null; then
+		echo "FAIL $name $variant: generation failed"
+		status=1
+		return
+	fi
+
+	if (cd "$mod" && go vet ./... && go build ./...); then
+		echo "ok   $name $variant"
+	else
+		echo "FAIL $name $variant"
+		status=1
+	fi
+}
+
+for dtd in "$root"/testdata/*.dtd "$root"/testdata/documents/*.xml; do
+	name=$(basename "$dtd" | tr . _)
+	for variant in "" "-generic" "-any-style elements" "-any-style union" "-inline-wrappers" "-otel" "-enum-style int" "-occurrences" "-content-regexp" "-no-xml-tags" "-tinygo" "-tinygo -enum-style int" "-normalize-attrs" "-tinygo -normalize-attrs" "-parents-index" "-enum-style int -optional-enums unset" "-enum-style int -optional-enums pointer" "-tinygo -enum-style int -optional-enums pointer" "-violation-hooks -enum-style int -content-regexp" "-explain-decisions" "-case-insensitive" "-case-insensitive -inline-wrappers -parse-helpers" "-attr-groups 2" "-c14n" "-fill-defaults -enum-style int" "-fill-defaults -case-insensitive -any-style union" "-c14n -case-insensitive -parse-helpers" "-attr-groups 2 -tinygo -explain-decisions" "-id-index" "-id-index -tinygo -attr-groups 2" "-id-index -fill-defaults -case-insensitive" "-duplicate-elements last" "-empty-style bool" "-empty-style bool -tinygo" "-empty-style bool -no-xml-tags -explain-decisions" "-empty-style bool -fill-defaults -case-insensitive -any-style union" "-decode-into" "-decode-into -otel -inline-wrappers -any-style elements" "-validate-fixed" "-validate-fixed -enum-style int -case-insensitive -fill-defaults" "-validate-fixed -violation-hooks -enum-style int -optional-enums pointer" "-max-line-length 80 -explain-decisions" "-max-line-length 100 -explain-decisions -c14n -fill-defaults" "-choice-style interface -occurrences -decode-into -id-index" "-choice-style interface -case-insensitive -validate-fixed -fill-defaults -enum-style int" "-choice-style interface -no-xml-tags -explain-decisions" "-group-style anonymous" "-group-style named -occurrences -decode-into -id-index -c14n" "-group-style named -case-insensitive -validate-fixed -fill-defaults -choice-style interface" "-group-style anonymous -no-xml-tags -explain-decisions" "-pointer-policy all" "-pointer-policy all -tinygo -empty-style bool" "-pointer-policy all -decode-into -id-index -group-style named -choice-style interface" "-pointer-policy all -inline-wrappers -case-insensitive -fill-defaults -explain-decisions" "-mixed-style nodes" "-mixed-style nodes -empty-style bool -decode-into -id-index" "-mixed-style nodes -case-insensitive -validate-fixed -fill-defaults" "-mixed-style nodes -no-xml-tags -explain-decisions" "-compat 1" "-compat 2" "-compat 3" "-enum-style typed" "-enum-style typed -tinygo -normalize-attrs" "-enum-style typed -validate-fixed -fill-defaults -case-insensitive" "-enum-style typed -attr-groups 2 -explain-decisions -no-xml-tags" "-constructors" "-constructors -enum-style int -optional-enums pointer -tinygo" "-constructors -enum-style typed -attr-groups 2 -fill-defaults" "-validate-methods" "-validate-methods -choice-style interface -group-style named -mixed-style nodes" "-validate-methods -tinygo -enum-style int -empty-style bool -pointer-policy all" "-validate-methods -inline-wrappers -attr-groups 2 -explain-decisions" "-format mock" "-sequence-style positional" "-sequence-style positional -case-insensitive -fill-defaults -validate-fixed -decode-into -id-index" "-sequence-style positional -no-xml-tags -pointer-policy all -empty-style bool -explain-decisions" "-sequence-style positional -choice-style interface -group-style named -validate-methods -occurrences -constructors" "-pointer-policy none" "-pointer-policy none -tinygo -empty-style bool" "-pointer-policy none -decode-into -id-index -group-style named -choice-style interface -validate-methods" "-pointer-policy optional-only -sequence-style positional -case-insensitive -fill-defaults -explain-decisions"; do
+		# shellcheck disable=SC2086
+		check "$dtd" "$name" "$variant" $variant "$@"
+	done
+done
+
+# DTDs with a generator config next to them, such as vendors.config.json for vendors.dtd,
+# are also generated with it
+for config in "$root"/testdata/*.config.json; do
+	dtd="${config%.config.json}.dtd"
+	name=$(basename "$dtd" | tr . _)
+	for variant in "" "-tinygo" "-no-xml-tags" "-inline-wrappers -attr-groups 2" "-pointer-policy all -tinygo"; do
+		# shellcheck disable=SC2086
+		check "$dtd" "$name" "-config $variant" -config "$config" $variant "$@"
+	done
+done
+# Flags naming the elements of a particular DTD
+check "$root/testdata/listing.dtd" listing_dtd "-split residential,rental" -split residential,rental "$@"
+check "$root/testdata/namespaces.dtd" namespaces_dtd "-split xhtml:p,xhtml:a -parse-helpers" -split xhtml:p,xhtml:a -parse-helpers "$@"
+check "$root/testdata/messages.dtd" messages_dtd "-root request,response" -root request,response "$@"
+check "$root/testdata/messages.dtd" messages_dtd "-root request,response -tinygo" -root request,response -tinygo "$@"
+check "$root/testdata/messages.dtd" messages_dtd "-root request,response -decode-into -id-index -choice-style interface" -root request,response -decode-into -id-index -choice-style interface "$@"
+
+# Type mappings, which the hand-rolled -tinygo codecs do not support
+for variant in "" "-no-xml-tags -explain-decisions" "-constructors -fill-defaults -validate-methods -pointer-policy all" "-mixed-style nodes -case-insensitive -decode-into -id-index -c14n" "-inline-wrappers -attr-groups 2 -sequence-style positional -normalize-attrs" "-pointer-policy none -mixed-style nodes"; do
+	# shellcheck disable=SC2086
+	check "$root/testdata/offers.dtd" offers_dtd "-config types $variant" -config "$root/testdata/offers.types.json" $variant "$@"
+done
+
+exit $status
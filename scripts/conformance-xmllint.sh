@@ -0,0 +1,54 @@
+#!/bin/sh
+# Checks that dtd-to-go accepts and rejects the DTDs in testdata/ the same way
+# libxml2 does, so parsing semantics track the reference implementation. A DTD
+# counts as accepted by xmllint when loading it as the external subset of a stub
+# document raises no DTD error (exit status 2); validity errors of the stub
+# itself are expected and ignored.
+#
+# Optional: skipped when xmllint is not installed.
+#
+# Usage: scripts/conformance-xmllint.sh [extra dtd-to-go flags...]
+set -eu
+
+if ! command -v xmllint > /dev/null 2>&1; then
+	echo "skip: xmllint not found"
+	exit 0
+fi
+
+root=$(cd "$(dirname "$0")/.." && pwd)
+work=$(mktemp -d)
+trap 'rm -rf "$work"' EXIT
+
+go build -o "$work/dtd-to-go" "$root"
+printf '<?xml version="1.0"?>\n<stub/>\n' > "$work/stub.xml"
+
+status=0
+for dtd in "$root"/testdata/*.dtd; do
+	name=$(basename "$dtd" .dtd)
+
+	# shellcheck disable=SC2086
+	if "$work/dtd-to-go" -input "$dtd" -output "$work/$name/out.go" -package conformance -strict "$@" > /dev/null 2>&1; then
+		ours=accept
+	else
+		ours=reject
+	fi
+
+	set +e
+	xmllint --noout --dtdvalid "$dtd" "$work/stub.xml" > /dev/null 2>&1
+	code=$?
+	set -e
+	if [ "$code" -eq 2 ]; then
+		reference=reject
+	else
+		reference=accept
+	fi
+
+	if [ "$ours" = "$reference" ]; then
+		echo "ok   $name ($ours)"
+	else
+		echo "FAIL $name: dtd-to-go would $ours, xmllint would $reference"
+		status=1
+	fi
+done
+
+exit $status
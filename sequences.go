@@ -0,0 +1,307 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Representations of elements that occur at several places of a sequence, such as note in
+// (head, note*, body, note*), selectable with GeneratorOptions.SequenceStyle
+const (
+	SequenceStyleMerged     = "merged"     // One field holding the occurrences of every place, encoded together
+	SequenceStylePositional = "positional" // A field per place, decoded and encoded in document order
+)
+
+// positionalSequence is the sequence making up an element's content model whose elements
+// occurring at several places of it get a field per place with SequenceStylePositional
+type positionalSequence struct {
+	Particle *ContentParticle
+	Fields   []goField       // Content fields of the struct in the order of the sequence
+	Places   [][]int         // Members of the sequence holding the element of each field
+	Split    map[string]bool // Elements occurring at several places
+	Child    string          // Field of the encoding/xml form holding the elements in document order
+}
+
+// positionalSequence returns the sequence of an element's struct whose elements occur at
+// several places, or nil. It applies to content models that are a sequence occurring at
+// most once, and to the elements occurring only as members of that sequence, such as
+// title in (title, (para | fig)*, title?); elements occurring at several places within its
+// groups keep one field. Structs lifted into their parent by -inline-wrappers have none.
+func (g *StructGenerator) positionalSequence(element *DTDElement) *positionalSequence {
+	if g.options.SequenceStyle != SequenceStylePositional || g.isInlined(element.Name) {
+		return nil
+	}
+	model, err := ParseContentModel(element.Content)
+	if err != nil || model.Kind != ContentChildren || model.Root == nil || model.Root.Kind != ParticleSequence ||
+		model.Root.Indicator == '*' || model.Root.Indicator == '+' {
+		return nil
+	}
+
+	positions := make(map[string]int)
+	model.Root.countPositions(positions)
+	members := make(map[string]int) // Places of each element as a member of the sequence
+	for _, member := range model.Root.Children {
+		if member.Kind == ParticleElement {
+			members[member.Name]++
+		}
+	}
+	split := make(map[string]bool)
+	for name, count := range members {
+		if count > 1 && positions[name] == count {
+			split[name] = true
+		}
+	}
+	if len(split) == 0 {
+		return nil
+	}
+
+	// The members of the sequence each element occurs in
+	places := make(map[string][]int)
+	for i, member := range model.Root.Children {
+		for _, name := range (&ContentModel{Root: member}).ElementNames() {
+			places[name] = append(places[name], i)
+		}
+	}
+
+	merged := g.parseContentModel(element.Content)
+	byElement := make(map[string]goField)
+	taken := map[string]bool{"Text": true}
+	for _, attr := range element.Attributes {
+		taken[g.toGoFieldName(attr.Name)] = true
+	}
+	for _, field := range merged {
+		byElement[field.Element] = field
+		if !split[field.Element] {
+			taken[field.Name] = true
+		}
+	}
+
+	sequence := &positionalSequence{Particle: model.Root, Split: split}
+	seen := make(map[string]int) // Places of each split element so far
+	for i, member := range model.Root.Children {
+		if member.Kind == ParticleElement && split[member.Name] {
+			seen[member.Name]++
+			field := byElement[member.Name]
+			sequence.Fields = append(sequence.Fields, g.placeField(field, member, seen[member.Name], members[member.Name], model.Root, taken))
+			sequence.Places = append(sequence.Places, []int{i})
+			continue
+		}
+		// The other elements are held by one field, in the place of their first member
+		for _, field := range merged {
+			if !split[field.Element] && places[field.Element][0] == i {
+				sequence.Fields = append(sequence.Fields, field)
+				sequence.Places = append(sequence.Places, places[field.Element])
+			}
+		}
+	}
+	// The codecs assign and encode the fields as the struct holds them, by value if
+	// PointerPolicy has it so
+	for i := range sequence.Fields {
+		sequence.Fields[i] = g.requiredValue(element, sequence.Fields[i])
+	}
+
+	sequence.Child = "Children"
+	for n := 2; taken[sequence.Child]; n++ {
+		sequence.Child = fmt.Sprintf("Children%d", n)
+	}
+	return sequence
+}
+
+// placeField returns the field holding the occurrences of an element at one of its places
+// in a sequence, from the field that would hold all of them. The first place keeps the
+// field's name and the others are numbered, such as Note2 for the second place of note.
+func (g *StructGenerator) placeField(field goField, member *ContentParticle, n, places int, sequence *ContentParticle, taken map[string]bool) goField {
+	name := field.Name
+	if n > 1 {
+		name = fmt.Sprintf("%s%d", field.Name, n)
+		for i := n + 1; taken[name]; i++ {
+			name = fmt.Sprintf("%s%d", field.Name, i)
+		}
+	}
+	taken[name] = true
+
+	occurs := &occurrence{Min: 1, Max: 1}
+	if member.Indicator == '?' || member.Indicator == '*' || sequence.Indicator == '?' {
+		occurs.Min = 0
+	}
+	if member.Indicator == '*' || member.Indicator == '+' {
+		occurs.Max = Unbounded
+	}
+
+	elementType := strings.TrimLeft(field.Type, "*[]")
+	reason := "pointer because it occurs once"
+	switch {
+	case occurs.Max == Unbounded:
+		field.Type, reason = "[]"+elementType, "slice because it may repeat without limit"
+	case elementType == "Presence":
+		field.Type, reason = elementType, "Presence because it is EMPTY (-empty-style bool)"
+	case occurs.Min == 0:
+		field.Type, reason = "*"+elementType, "pointer because it is optional"
+	default:
+		field.Type = "*" + elementType
+	}
+	field.Name = name
+	field.Tag = "-"
+	field.Occurs = occurs
+	field.Explain = fmt.Sprintf("from %s -> place %d of %d of <%s>, %s, because -sequence-style positional", sequence, n, places, field.Element, reason)
+	return field
+}
+
+// hasPositionalSequence reports whether an element's struct holds elements occurring at
+// several places of its sequence in a field per place
+func (g *StructGenerator) hasPositionalSequence(name string) bool {
+	element, exists := g.elements[name]
+	return exists && !g.isSimpleElement(name) && g.positionalSequence(element) != nil
+}
+
+// sequenceChildName returns the unexported type decoding and encoding the elements of a
+// struct with a positional sequence one at a time, in document order
+func (g *StructGenerator) sequenceChildName(name string) string {
+	return lowerFirst(g.toGoStructName(name)) + "SequenceChild"
+}
+
+// sequenceChildFields returns the fields of the type decoding and encoding the elements of
+// a struct with a positional sequence, one per element holding a single occurrence
+func (g *StructGenerator) sequenceChildFields(element *DTDElement) []goField {
+	var fields []goField
+	for _, field := range g.parseContentModel(element.Content) {
+		field.Type = "*" + strings.TrimLeft(field.Type, "*[]")
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// generatePositionalSequences generates, unless NoXMLTags, the types decoding and encoding
+// the elements of the structs with positional sequences one at a time
+func (g *StructGenerator) generatePositionalSequences() string {
+	if g.options.NoXMLTags {
+		return ""
+	}
+	var builder strings.Builder
+	for _, name := range g.elementOrder {
+		if !g.hasPositionalSequence(name) {
+			continue
+		}
+		doc := fmt.Sprintf("an element of <%s>, decoded in document order", name)
+		builder.WriteString(g.childType(g.sequenceChildName(name), doc, g.sequenceChildFields(g.elements[name])))
+	}
+	return builder.String()
+}
+
+// sequenceAssembly returns the statements of a setXMLForm method assigning the elements of
+// a struct with a positional sequence, decoded in document order, to their fields. The
+// member of the sequence the last element occurs in is tracked, so an element occurring
+// at several places goes to the first place after it that can still hold the element.
+func (g *StructGenerator) sequenceAssembly(name string, sequence *positionalSequence) string {
+	var builder strings.Builder
+
+	builder.WriteString("\tplace := -1 // The member of the sequence the last element occurs in\n")
+	builder.WriteString(fmt.Sprintf("\tfor _, c := range x.%s {\n", sequence.Child))
+	builder.WriteString("\t\tswitch {\n")
+	for _, childField := range g.sequenceChildFields(g.elements[name]) {
+		child := "c." + childField.Name
+		builder.WriteString(fmt.Sprintf("\t\tcase %s != nil:\n", child))
+		if !sequence.Split[childField.Element] {
+			for i, field := range sequence.Fields {
+				if field.Element == childField.Element {
+					builder.WriteString(placeAssignment("\t\t\t", field, child))
+					builder.WriteString(placeSwitch("\t\t\t", sequence.Places[i]))
+				}
+			}
+			continue
+		}
+
+		var targets []int // Fields of the places of the element
+		for i, field := range sequence.Fields {
+			if field.Element == childField.Element {
+				targets = append(targets, i)
+			}
+		}
+		builder.WriteString("\t\t\tswitch {\n")
+		for j, i := range targets {
+			field := sequence.Fields[i]
+			switch {
+			case j == len(targets)-1:
+				builder.WriteString("\t\t\tdefault:\n")
+			case field.Occurs.Max == Unbounded:
+				builder.WriteString(fmt.Sprintf("\t\t\tcase place <= %d:\n", sequence.Places[i][0]))
+			default:
+				builder.WriteString(fmt.Sprintf("\t\t\tcase place < %d:\n", sequence.Places[i][0]))
+			}
+			builder.WriteString(placeAssignment("\t\t\t\t", field, child))
+			builder.WriteString(fmt.Sprintf("\t\t\t\tplace = %d\n", sequence.Places[i][0]))
+		}
+		builder.WriteString("\t\t\t}\n")
+	}
+	builder.WriteString("\t\t}\n")
+	builder.WriteString("\t}\n")
+
+	return builder.String()
+}
+
+// placeSwitch returns the statements setting place to the member an element occurring in
+// the given members of the sequence is in: the first one not before the last element
+func placeSwitch(indent string, members []int) string {
+	if len(members) == 1 {
+		return fmt.Sprintf("%splace = %d\n", indent, members[0])
+	}
+	var builder strings.Builder
+	builder.WriteString(indent + "switch {\n")
+	for j, member := range members {
+		if j == len(members)-1 {
+			builder.WriteString(indent + "default:\n")
+		} else {
+			builder.WriteString(fmt.Sprintf("%scase place <= %d:\n", indent, member))
+		}
+		builder.WriteString(fmt.Sprintf("%s\tplace = %d\n", indent, member))
+	}
+	builder.WriteString(indent + "}\n")
+	return builder.String()
+}
+
+// placeAssignment returns the statement setting a field of a struct from the pointer to a
+// single occurrence of its element in child
+func placeAssignment(indent string, field goField, child string) string {
+	switch {
+	case strings.HasPrefix(field.Type, "[]"):
+		return fmt.Sprintf("%sv.%s = append(v.%s, *%s)\n", indent, field.Name, field.Name, child)
+	case strings.HasPrefix(field.Type, "*"):
+		return fmt.Sprintf("%sv.%s = %s\n", indent, field.Name, child)
+	}
+	return fmt.Sprintf("%sv.%s = *%s\n", indent, field.Name, child) // Held by value
+}
+
+// sequenceEncoding returns the statements of a MarshalXML method adding the elements of a
+// struct with a positional sequence to its encoding/xml form x in the order of the sequence
+func (g *StructGenerator) sequenceEncoding(name string, sequence *positionalSequence) string {
+	var builder strings.Builder
+
+	childName := g.sequenceChildName(name)
+	childFields := make(map[string]string)
+	for _, field := range g.sequenceChildFields(g.elements[name]) {
+		childFields[field.Element] = field.Name
+	}
+	for _, field := range sequence.Fields {
+		add := func(indent, value string) string {
+			return fmt.Sprintf("%sx.%s = append(x.%s, %s{%s: %s})\n", indent, sequence.Child, sequence.Child, childName, childFields[field.Element], value)
+		}
+		switch {
+		case strings.HasPrefix(field.Type, "[]"):
+			builder.WriteString(fmt.Sprintf("\tfor i := range v.%s {\n", field.Name))
+			builder.WriteString(add("\t\t", fmt.Sprintf("&v.%s[i]", field.Name)))
+			builder.WriteString("\t}\n")
+		case strings.HasPrefix(field.Type, "*"):
+			builder.WriteString(fmt.Sprintf("\tif v.%s != nil {\n", field.Name))
+			builder.WriteString(add("\t\t", "v."+field.Name))
+			builder.WriteString("\t}\n")
+		case field.Type == "Presence":
+			builder.WriteString(fmt.Sprintf("\tif v.%s {\n", field.Name))
+			builder.WriteString(add("\t\t", "&v."+field.Name))
+			builder.WriteString("\t}\n")
+		default:
+			builder.WriteString(add("\t", "&v."+field.Name))
+		}
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go/token"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// maxServedDocumentSize bounds the documents the schema service validates
+const maxServedDocumentSize = 32 << 20
+
+// servedSchema is one version of a schema as loaded by the schema service. It is not
+// modified once loaded; a reload replaces it.
+type servedSchema struct {
+	result      *ParseResult
+	validator   *Validator
+	fingerprint string
+	loadedAt    time.Time
+	sources     map[string]sourceStamp // Files the schema was read from
+}
+
+// sourceStamp is what a schema source looked like when it was read, to notice changes
+type sourceStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// schemaEntry is a named schema registered with the schema service
+type schemaEntry struct {
+	name      string
+	input     string
+	current   *servedSchema          // Nil until the schema loads for the first time
+	lastError error                  // Why the latest reload failed; the current version is kept
+	attempted map[string]sourceStamp // Sources of the failed reload, so it is not retried until they change
+}
+
+// SchemaService serves named schemas over HTTP: their details, documents validated
+// against them and the artifacts generated from them. Schemas are reloaded when their
+// DTD or a file it includes changes.
+type SchemaService struct {
+	mu      sync.RWMutex
+	schemas map[string]*schemaEntry
+}
+
+// NewSchemaService creates a service for the DTD files by schema name. Schemas that fail
+// to load are served with their error until a change fixes them.
+func NewSchemaService(inputs map[string]string) *SchemaService {
+	s := &SchemaService{schemas: make(map[string]*schemaEntry)}
+	for name, input := range inputs {
+		entry := &schemaEntry{name: name, input: input}
+		s.schemas[name] = entry
+		s.reload(entry)
+	}
+	return s
+}
+
+// loadSchema parses a schema and records the state of its sources
+func loadSchema(input string) (*servedSchema, error) {
+	result, err := NewDTDParser(ParserOptions{}).ParseFile(input)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(input)
+	if err != nil {
+		return nil, err
+	}
+	return &servedSchema{
+		result:      result,
+		validator:   NewValidator(result),
+		fingerprint: Fingerprint(content),
+		loadedAt:    time.Now(),
+		sources:     stampSources(result.Files),
+	}, nil
+}
+
+// stampSources records the modification time and size of files; missing files are
+// recorded with zero stamps
+func stampSources(files []string) map[string]sourceStamp {
+	stamps := make(map[string]sourceStamp, len(files))
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			stamps[file] = sourceStamp{modTime: info.ModTime(), size: info.Size()}
+		} else {
+			stamps[file] = sourceStamp{}
+		}
+	}
+	return stamps
+}
+
+// sourcesChanged reports whether any of the stamped files changed since
+func sourcesChanged(stamps map[string]sourceStamp) bool {
+	for file, stamp := range stampSources(sortedKeys(stamps)) {
+		if stamp != stamps[file] {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// reload loads a schema again, keeping the current version if that fails
+func (s *SchemaService) reload(entry *schemaEntry) {
+	loaded, err := loadSchema(entry.input)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		entry.lastError = err
+		entry.attempted = stampSources([]string{entry.input})
+		if entry.current != nil {
+			entry.attempted = stampSources(sortedKeys(entry.current.sources))
+		}
+		fmt.Fprintf(os.Stderr, "schema %s: failed to load %s: %v\n", entry.name, entry.input, err)
+		return
+	}
+	entry.current, entry.lastError, entry.attempted = loaded, nil, nil
+	fmt.Fprintf(os.Stderr, "schema %s: loaded %s (%s)\n", entry.name, entry.input, loaded.fingerprint)
+}
+
+// ReloadChanged reloads the schemas whose sources changed since they were last read
+func (s *SchemaService) ReloadChanged() {
+	s.mu.RLock()
+	var changed []*schemaEntry
+	for _, name := range sortedKeys(s.schemas) {
+		entry := s.schemas[name]
+		switch {
+		case entry.attempted != nil:
+			// Retry a failed load only once the files change again
+			if sourcesChanged(entry.attempted) {
+				changed = append(changed, entry)
+			}
+		case entry.current == nil || sourcesChanged(entry.current.sources):
+			changed = append(changed, entry)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, entry := range changed {
+		s.reload(entry)
+	}
+}
+
+// Watch reloads changed schemas every interval until ctx is done
+func (s *SchemaService) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.ReloadChanged()
+		}
+	}
+}
+
+// schemaInfo describes a schema in the responses of the schema service
+type schemaInfo struct {
+	Name        string    `json:"name"`
+	Input       string    `json:"input"`
+	Fingerprint string    `json:"fingerprint,omitempty"`
+	LoadedAt    time.Time `json:"loadedAt,omitzero"`
+	Elements    int       `json:"elements"`
+	Sources     []string  `json:"sources,omitempty"`
+	Error       string    `json:"error,omitempty"` // Why the schema, or its latest change, failed to load
+}
+
+// info describes a schema entry; the caller holds the read lock
+func (entry *schemaEntry) info() schemaInfo {
+	info := schemaInfo{Name: entry.name, Input: entry.input}
+	if entry.lastError != nil {
+		info.Error = entry.lastError.Error()
+	}
+	if entry.current != nil {
+		info.Fingerprint = entry.current.fingerprint
+		info.LoadedAt = entry.current.loadedAt
+		info.Elements = len(entry.current.result.Order)
+		info.Sources = sortedKeys(entry.current.sources)
+	}
+	return info
+}
+
+// schema returns the current version of a schema, or writes the error response if there
+// is none
+func (s *SchemaService) schema(w http.ResponseWriter, name string) (*servedSchema, bool) {
+	s.mu.RLock()
+	entry, exists := s.schemas[name]
+	var current *servedSchema
+	var lastError error
+	if exists {
+		current, lastError = entry.current, entry.lastError
+	}
+	s.mu.RUnlock()
+
+	switch {
+	case !exists:
+		http.Error(w, fmt.Sprintf("schema %q is not registered", name), http.StatusNotFound)
+		return nil, false
+	case current == nil:
+		http.Error(w, fmt.Sprintf("schema %q failed to load: %v", name, lastError), http.StatusServiceUnavailable)
+		return nil, false
+	}
+	w.Header().Set("X-Schema-Fingerprint", current.fingerprint)
+	return current, true
+}
+
+// Handler returns the HTTP API of the service:
+//
+//	GET  /schemas                           the registered schemas
+//	GET  /schemas/{name}                    one schema
+//	POST /schemas/{name}/reload             reload a schema now
+//	POST /schemas/{name}/validate           validate the XML document in the request body
+//	GET  /schemas/{name}/artifacts/{format} generated code, with ?package= for the package name
+func (s *SchemaService) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /schemas", s.handleList)
+	mux.HandleFunc("GET /schemas/{name}", s.handleInfo)
+	mux.HandleFunc("POST /schemas/{name}/reload", s.handleReload)
+	mux.HandleFunc("POST /schemas/{name}/validate", s.handleValidate)
+	mux.HandleFunc("GET /schemas/{name}/artifacts/{format}", s.handleArtifact)
+	return mux
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+// handleList lists the registered schemas
+func (s *SchemaService) handleList(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	infos := make([]schemaInfo, 0, len(s.schemas))
+	for _, name := range sortedKeys(s.schemas) {
+		infos = append(infos, s.schemas[name].info())
+	}
+	s.mu.RUnlock()
+	writeJSON(w, http.StatusOK, infos)
+}
+
+// handleInfo describes one schema
+func (s *SchemaService) handleInfo(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	entry, exists := s.schemas[r.PathValue("name")]
+	var info schemaInfo
+	if exists {
+		info = entry.info()
+	}
+	s.mu.RUnlock()
+	if !exists {
+		http.Error(w, fmt.Sprintf("schema %q is not registered", r.PathValue("name")), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+// handleReload reloads a schema, reporting its state afterwards
+func (s *SchemaService) handleReload(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	entry, exists := s.schemas[r.PathValue("name")]
+	s.mu.RUnlock()
+	if !exists {
+		http.Error(w, fmt.Sprintf("schema %q is not registered", r.PathValue("name")), http.StatusNotFound)
+		return
+	}
+	s.reload(entry)
+	s.handleInfo(w, r)
+}
+
+// handleValidate validates the document in the request body. The response is the
+// DocumentResult, with status 200 for valid documents, 422 for invalid ones and 400 for
+// documents that are not well-formed.
+func (s *SchemaService) handleValidate(w http.ResponseWriter, r *http.Request) {
+	schema, ok := s.schema(w, r.PathValue("name"))
+	if !ok {
+		return
+	}
+	document, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxServedDocumentSize))
+	if err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, fmt.Sprintf("failed to read the document: %v", err), status)
+		return
+	}
+
+	var result DocumentResult
+	result.Violations, err = schema.validator.Validate(bytes.NewReader(document))
+	status := http.StatusOK
+	switch {
+	case err != nil:
+		result.Error = err.Error()
+		status = http.StatusBadRequest
+	case len(result.Violations) > 0:
+		status = http.StatusUnprocessableEntity
+	default:
+		result.Valid = true
+	}
+	writeJSON(w, status, result)
+}
+
+// handleArtifact generates code from a schema in one of the output formats
+func (s *SchemaService) handleArtifact(w http.ResponseWriter, r *http.Request) {
+	format := r.PathValue("format")
+	if format == "events" {
+		http.Error(w, "the events format is not an artifact", http.StatusNotFound)
+		return
+	}
+	packageName := r.URL.Query().Get("package")
+	if packageName == "" {
+		packageName = "main"
+	}
+	if !token.IsIdentifier(packageName) {
+		http.Error(w, fmt.Sprintf("package %q is not a valid identifier", packageName), http.StatusBadRequest)
+		return
+	}
+
+	schema, ok := s.schema(w, r.PathValue("name"))
+	if !ok {
+		return
+	}
+	run := &Generation{Result: schema.result, Format: format, PackageName: packageName}
+	files, err := run.Generate()
+	if err != nil {
+		status := http.StatusInternalServerError
+		if strings.HasPrefix(err.Error(), "unknown output format") {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	switch format {
+	case "json", "avro":
+		w.Header().Set("Content-Type", "application/json")
+	default:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	w.Write(files[run.outputName()])
+}
+
+// schemaFlags collects repeated -schema name=file.dtd flags
+type schemaFlags map[string]string
+
+// String lists the registered schemas
+func (f schemaFlags) String() string {
+	var pairs []string
+	for _, name := range sortedKeys(f) {
+		pairs = append(pairs, name+"="+f[name])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set registers one name=file.dtd schema
+func (f schemaFlags) Set(value string) error {
+	name, input, ok := strings.Cut(value, "=")
+	if !ok || name == "" || input == "" {
+		return fmt.Errorf("expected name=file.dtd, got %q", value)
+	}
+	if _, exists := f[name]; exists {
+		return fmt.Errorf("schema %q registered twice", name)
+	}
+	f[name] = input
+	return nil
+}
+
+// runServe implements the "serve" subcommand, running the schema service until it is
+// interrupted
+func runServe(args []string) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := flags.String("addr", "localhost:8080", "Address to listen on")
+	interval := flags.Duration("reload-interval", 2*time.Second, "How often to check the schema files for changes, or 0 to never reload")
+	schemas := make(schemaFlags)
+	flags.Var(schemas, "schema", "Schema to serve as name=file.dtd (repeatable)")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	if len(schemas) == 0 || flags.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "Usage: %s serve -schema <name>=<dtd-file> [-schema ...] [-addr <host:port>] [-reload-interval <duration>]\n", os.Args[0])
+		return 1
+	}
+
+	service := NewSchemaService(schemas)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *interval > 0 {
+		go service.Watch(ctx, *interval)
+	}
+
+	server := &http.Server{Addr: *addr, Handler: service.Handler()}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+	fmt.Fprintf(os.Stderr, "Serving %d schemas on %s\n", len(schemas), *addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
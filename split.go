@@ -0,0 +1,271 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CheckSplitElements reports boundary elements whose fragments would not be valid
+// documents. A fragment keeps the elements enclosing its boundary element with only their
+// children before the first boundary element, so every element that can enclose a
+// boundary element (up to the document root, without passing another boundary element)
+// must allow the child leading to it as its last child.
+func CheckSplitElements(result *ParseResult, names []string) error {
+	graph := result.Graph()
+	boundaries := make(map[string]bool)
+	for _, name := range names {
+		if _, exists := result.Elements[name]; !exists {
+			return fmt.Errorf("-split %s: %w", name, &GenerateError{Element: name, Err: ErrUndeclaredElement})
+		}
+		boundaries[name] = true
+	}
+
+	seen := make(map[string]bool)
+	queue := append([]string(nil), names...)
+	for len(queue) > 0 {
+		child := queue[0]
+		queue = queue[1:]
+		if seen[child] {
+			continue
+		}
+		seen[child] = true
+		for _, parent := range graph.Parents(child) {
+			if boundaries[parent] {
+				// Inside a fragment nothing is split further
+				continue
+			}
+			element := result.Elements[parent]
+			model, err := ParseContentModel(element.Content)
+			if err != nil {
+				continue
+			}
+			if model.Root != nil && !model.Root.canEndWith(child) {
+				return fmt.Errorf("-split %s: %w", strings.Join(names, ","), &GenerateError{Element: parent,
+					Err: fmt.Errorf("cannot end with <%s> (content %s), so the fragments would be invalid", child, strings.TrimSpace(element.Content))})
+			}
+			queue = append(queue, parent)
+		}
+	}
+	return nil
+}
+
+// canEndWith reports whether name can be the last child of a sequence the particle matches
+func (c *ContentParticle) canEndWith(name string) bool {
+	switch c.Kind {
+	case ParticleElement:
+		return c.Name == name
+	case ParticleSequence:
+		for i := len(c.Children) - 1; i >= 0; i-- {
+			if c.Children[i].canEndWith(name) {
+				return true
+			}
+			if !c.Children[i].optional() {
+				return false
+			}
+		}
+	case ParticleChoice:
+		for _, child := range c.Children {
+			if child.canEndWith(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// optional reports whether the particle matches the empty sequence
+func (c *ContentParticle) optional() bool {
+	if c.Indicator == '?' || c.Indicator == '*' {
+		return true
+	}
+	switch c.Kind {
+	case ParticleSequence:
+		for _, child := range c.Children {
+			if !child.optional() {
+				return false
+			}
+		}
+		return true
+	case ParticleChoice:
+		for _, child := range c.Children {
+			if child.optional() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// splitRuntime cuts documents into standalone documents at the boundary elements. The
+// bytes of each boundary element are copied from the input unchanged, with the start tags
+// of the elements enclosing it before and their end tags after.
+const splitRuntime = `
+// Split cuts a document into standalone documents, one per boundary element, and passes
+// each to emit in document order with the name of its boundary element. A fragment starts
+// with the document's XML declaration and DOCTYPE and the elements enclosing its boundary
+// element, each with its children before its first boundary element (such as a feed
+// header), and ends with their end tags. Boundary elements inside a fragment are not
+// split further. The bytes are copied from the document unchanged, so the fragments keep
+// its encoding, and only one fragment is held in memory at a time, so huge documents can
+// be processed in parallel. emit may keep the fragment.
+func Split(r io.Reader, emit func(name string, fragment []byte) error) error {
+	in := &splitReader{r: bufio.NewReader(r)}
+	d := xml.NewDecoder(in)
+	d.Strict = false
+	d.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) { return input, nil }
+
+	var prolog []byte      // The XML declaration and DOCTYPE, repeated in every fragment
+	var open []*splitFrame // Elements enclosing the current position, outside fragments
+	start := int64(-1)     // Offset of the current fragment's boundary element, or -1
+	depth := 0             // Depth inside the current fragment's boundary element
+	var boundary string
+	for {
+		offset := d.InputOffset()
+		if start < 0 {
+			in.discard(keepFrom(open, offset))
+		}
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		raw := in.since(offset, d.InputOffset())
+
+		switch t := tok.(type) {
+		case xml.ProcInst:
+			if t.Target == "xml" && len(open) == 0 && start < 0 {
+				prolog = append(append(prolog, raw...), '\n')
+			}
+		case xml.Directive:
+			if bytes.HasPrefix(t, []byte("DOCTYPE")) && len(open) == 0 && start < 0 {
+				prolog = append(append(prolog, raw...), '\n')
+			}
+		case xml.StartElement:
+			switch name := splitName(t.Name); {
+			case start >= 0:
+				depth++
+			case splitElements[name]:
+				start, depth, boundary = offset, 1, name
+				for _, frame := range open {
+					frame.entered = true
+				}
+			default:
+				open = append(open, &splitFrame{name: name, start: offset, tag: append([]byte(nil), raw...)})
+			}
+		case xml.EndElement:
+			switch {
+			case start >= 0:
+				depth--
+				if depth > 0 {
+					continue
+				}
+				fragment := append([]byte(nil), prolog...)
+				for _, frame := range open {
+					fragment = append(append(append(fragment, frame.tag...), '\n'), frame.context...)
+				}
+				fragment = append(fragment, in.since(start, d.InputOffset())...)
+				for i := len(open) - 1; i >= 0; i-- {
+					fragment = append(append(append(fragment, "\n</"...), open[i].name...), '>')
+				}
+				fragment = append(fragment, '\n')
+				start = -1
+				if err := emit(boundary, fragment); err != nil {
+					return err
+				}
+			case len(open) > 0:
+				frame := open[len(open)-1]
+				open = open[:len(open)-1]
+				// Children before the parent's first boundary element go into its fragments
+				if parent := len(open) - 1; parent >= 0 && !open[parent].entered && !frame.entered {
+					open[parent].context = append(append(open[parent].context, in.since(frame.start, d.InputOffset())...), '\n')
+				}
+			}
+		}
+	}
+	if start >= 0 {
+		return fmt.Errorf("split: document ends inside <%s>", boundary)
+	}
+	return nil
+}
+
+// splitFrame is an element enclosing the current position while splitting
+type splitFrame struct {
+	name    string
+	start   int64  // Offset of the start tag
+	tag     []byte // The start tag
+	context []byte // Children before the first boundary element inside, repeated in every fragment
+	entered bool   // A boundary element was found inside
+}
+
+// keepFrom returns the offset of the earliest byte still needed when outside fragments:
+// the start of the outermost element that may become context of its parent
+func keepFrom(open []*splitFrame, offset int64) int64 {
+	for i := 1; i < len(open); i++ {
+		if !open[i-1].entered && !open[i].entered {
+			return open[i].start
+		}
+	}
+	return offset
+}
+
+// splitName returns the name of an element as written in the document
+func splitName(name xml.Name) string {
+	if name.Space != "" {
+		return name.Space + ":" + name.Local
+	}
+	return name.Local
+}
+
+// splitReader keeps the bytes the decoder reads from the document, from the start of the
+// current token or fragment on
+type splitReader struct {
+	r      *bufio.Reader
+	buf    []byte
+	offset int64 // Document offset of buf[0]
+}
+
+// Read reads from the document, keeping the bytes read
+func (s *splitReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	s.buf = append(s.buf, p[:n]...)
+	return n, err
+}
+
+// ReadByte reads a byte from the document, keeping it. xml.Decoder reads byte by byte
+// from an io.ByteReader, so its offsets match the bytes kept.
+func (s *splitReader) ReadByte() (byte, error) {
+	b, err := s.r.ReadByte()
+	if err == nil {
+		s.buf = append(s.buf, b)
+	}
+	return b, err
+}
+
+// discard drops the bytes before offset
+func (s *splitReader) discard(offset int64) {
+	s.buf = s.buf[offset-s.offset:]
+	s.offset = offset
+}
+
+// since returns the bytes from offset start up to end
+func (s *splitReader) since(start, end int64) []byte {
+	return s.buf[start-s.offset : end-s.offset]
+}
+`
+
+// generateSplitter generates Split for the boundary elements of GeneratorOptions.Split
+func (g *StructGenerator) generateSplitter() string {
+	var builder strings.Builder
+
+	builder.WriteString("\n// splitElements are the boundary elements Split cuts documents at\n")
+	builder.WriteString("var splitElements = map[string]bool{\n")
+	for _, name := range g.options.Split {
+		builder.WriteString(fmt.Sprintf("\t%q: true,\n", name))
+	}
+	builder.WriteString("}\n")
+	builder.WriteString(splitRuntime)
+
+	return builder.String()
+}
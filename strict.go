@@ -0,0 +1,81 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Names and name tokens of XML 1.0, which -strict checks declarations against
+var (
+	xmlNamePattern    = regexp.MustCompile(`^[:_\p{L}][:_\p{L}\p{Mn}\p{Mc}\p{Nd}.\x{B7}-]*$`)
+	xmlNmtokenPattern = regexp.MustCompile(`^[:_\p{L}\p{Mn}\p{Mc}\p{Nd}.\x{B7}-]+$`)
+)
+
+// attributeTypes are the attribute types of XML 1.0 other than enumerations
+var attributeTypes = map[string]bool{
+	"CDATA": true, "ID": true, "IDREF": true, "IDREFS": true, "ENTITY": true,
+	"ENTITIES": true, "NMTOKEN": true, "NMTOKENS": true,
+}
+
+// enumerationPattern matches a complete enumerated type, such as ( yes | no )
+var enumerationPattern = regexp.MustCompile(`^\(\s*[^\s|()]+(?:\s*\|\s*[^\s|()]+)*\s*\)$`)
+
+// checkName reports a declared name that is not an XML name, in strict mode
+func (p *DTDParser) checkName(kind, name string) {
+	if p.options.Strict && !xmlNamePattern.MatchString(name) {
+		p.fail("%s name %q is not a valid XML name", kind, name)
+	}
+}
+
+// checkContentModel reports a content model that does not follow the XML 1.0 grammar,
+// such as unbalanced groups, misplaced occurrence indicators or mixed content not ending
+// in )*, in strict mode. Models with unexpanded references were reported already.
+func (p *DTDParser) checkContentModel(content string) {
+	if !p.options.Strict || entityReferencePattern.MatchString(content) {
+		return
+	}
+	if _, err := ParseContentModel(content); err != nil {
+		p.fail("%v", err)
+	}
+}
+
+// checkAttributeDefinition reports an attribute definition that does not follow the XML
+// 1.0 grammar in strict mode: its name, its type (or for an enumerated type, the tokens in
+// enumeration) and its default declaration
+func (p *DTDParser) checkAttributeDefinition(elementName, name, attrType, enumeration, defaultInfo string) {
+	if !p.options.Strict {
+		return
+	}
+	p.checkName("attribute", name)
+
+	switch {
+	case enumeration != "":
+		if !enumerationPattern.MatchString(enumeration) {
+			p.fail("enumerated type %s of attribute %s of <%s> is malformed", enumeration, name, elementName)
+			break
+		}
+		token := xmlNmtokenPattern
+		if attrType == notationAttributeType {
+			token = xmlNamePattern
+		}
+		for _, value := range enumerationValues(enumeration) {
+			if !token.MatchString(value) {
+				p.fail("value %q of attribute %s of <%s> is not a valid name token", value, name, elementName)
+			}
+		}
+	case !attributeTypes[attrType]:
+		p.fail("attribute %s of <%s> has unknown type %s", name, elementName, attrType)
+	}
+
+	switch {
+	case defaultInfo == "#REQUIRED", defaultInfo == "#IMPLIED", defaultInfo == "#FIXED":
+	case isQuotedLiteral(defaultInfo):
+	default:
+		p.fail("default of attribute %s of <%s> must be #REQUIRED, #IMPLIED, #FIXED or a quoted literal, not %s", name, elementName, defaultInfo)
+	}
+}
+
+// isQuotedLiteral reports whether a default value is a literal in matching quotes
+func isQuotedLiteral(value string) bool {
+	return len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && strings.HasSuffix(value, value[:1])
+}
@@ -2,24 +2,130 @@ package main
 
 import (
 	"fmt"
-	"regexp"
 	"strings"
+	"sync"
 	"unicode"
 )
 
+// Child elements occurring at most once that are held through a pointer, nil when a
+// document leaves the child out, rather than by value, selectable with
+// GeneratorOptions.PointerPolicy
+const (
+	PointerPolicyAll          = "all"           // Every one
+	PointerPolicyOptionalOnly = "optional-only" // The optional ones; those occurring exactly once are always in a valid document
+	PointerPolicyNone         = "none"          // Only the alternatives of choices, whose pointers tell which one is set
+)
+
+// GeneratorOptions controls optional parts of the generated Go code
+type GeneratorOptions struct {
+	GenericDecoder bool            // Emit DecodeGeneric for schema-driven map decoding
+	AnyStyle       string          // Representation of ANY content (AnyStyleInnerXML, AnyStyleElements or AnyStyleUnion)
+	InlineWrappers bool            // Lift single-child wrapper elements into their only parent
+	EnumStyle      string          // Representation of enumerated attributes (EnumStyleString, EnumStyleInt or EnumStyleTyped)
+	OptionalEnums  string          // Representation of optional integer enums (OptionalEnumZero, OptionalEnumUnset or OptionalEnumPointer)
+	Occurrences    bool            // Emit per-struct Occurrences() min/max metadata for child element fields
+	ContentRegexp  bool            // Emit the content models as regular expressions with MatchContent
+	ParentsIndex   bool            // Emit ParentsOf, the elements allowed to contain each element
+	ViolationHooks bool            // Report enumeration and content model violations to a ViolationRecorder
+	Explain        bool            // Annotate every field with the rule that produced it, for generator bug reports
+	FoldCase       bool            // Match element and attribute names case-insensitively when decoding
+	NoXMLTags      bool            // Emit plain structs without xml tags, XMLName fields or encoding/xml
+	TinyGo         bool            // Emit hand-rolled XML codecs instead of encoding/xml (implies NoXMLTags)
+	ParseHelpers   bool            // Emit ParseX(r, opts...) helpers for the document roots
+	DecodeInto     bool            // Emit DecodeInto, applying documents to existing roots as partial updates (implies ParseHelpers)
+	Instrument     bool            // Let the Parse helpers report spans and metrics (implies ParseHelpers)
+	CharsetReader  bool            // Decode legacy charsets with golang.org/x/net/html/charset (implies ParseHelpers)
+	NormalizeAttrs bool            // Normalize attribute values by declared type when decoding (implies ParseHelpers unless TinyGo)
+	IDIndex        bool            // Emit IDIndex with IDs and FindByID on the document roots
+	IDAttributes   []string        // Further attributes to index as IDs, as name or element@name
+	FillDefaults   bool            // Fill in the DTD defaults of absent attributes when decoding
+	Constructors   bool            // Emit NewX constructors setting defaulted attributes to their DTD defaults
+	Validation     bool            // Emit Validate methods checking required attributes, children and choices
+	ValidateFixed  bool            // Reject documents giving #FIXED attributes other values when decoding
+	Canonical      bool            // Emit MarshalCanonical and friends producing Canonical XML (C14N)
+	AttrGroups     int             // Embed attributes shared verbatim by at least this many elements as one struct (0 disables)
+	EmptyStyle     string          // Representation of EMPTY elements without attributes (EmptyStyleStruct or EmptyStyleBool)
+	Redactions     []RedactionRule // Sensitive elements and attributes Redact blanks or hashes
+	Split          []string        // Boundary elements Split cuts documents into standalone fragments at
+	MaxLineLength  int             // Wrap comments at this many columns, moving long trailing comments above their line (0 disables)
+	ChoiceStyle    string          // Representation of exclusive choices between elements (ChoiceStyleFields or ChoiceStyleInterface)
+	GroupStyle     string          // Representation of repeated groups of elements (GroupStyleFlat, GroupStyleAnonymous or GroupStyleNamed)
+	PointerPolicy  string          // Child elements occurring at most once held through pointers (PointerPolicyAll, PointerPolicyOptionalOnly or PointerPolicyNone; "" for the default of Compat)
+	MixedStyle     string          // Representation of mixed content with child elements (MixedStyleText or MixedStyleNodes)
+	SequenceStyle  string          // Representation of elements occurring at several places of a sequence (SequenceStyleMerged or SequenceStylePositional)
+	Compat         int             // Compatibility level the field planning and naming keep to (1 to CompatLatest; 0 for CompatLatest)
+	Transliterate  Transliteration // Replacements of letters or sequences in names when forming Go identifiers, before the built-in ones
+	Initialisms    []string        // Words kept in upper case in type and field names, besides the common ones from compatibility level 3
+	Types          []TypeMapping   // Go types other than string for elements' text and attributes, such as float64 or time.Time
+	Roots          []string        // Document roots getting Parse helpers and WriteXML instead of the detected ones (implies ParseHelpers unless TinyGo)
+}
+
 // StructGenerator generates Go structs from DTD elements
 type StructGenerator struct {
 	packageName  string
 	elements     map[string]*DTDElement
 	elementOrder []string
+	options      GeneratorOptions
+
+	graph     *Graph // Element usage graph, built on first use
+	graphOnce sync.Once
+	imports   importSet // Packages imported by the generated code, set by collectImports
+
+	groups     []attributeGroup // Attribute groups embedded with AttrGroups, found on first use
+	groupsOnce sync.Once
+
+	namespaces     map[string]string // Namespace URIs by prefix, found on first use
+	namespacesOnce sync.Once
+
+	transliteration     *strings.Replacer // Applies options.Transliterate, built on first use
+	transliterationOnce sync.Once
+
+	renames     map[string]structRename // Elements whose struct names collide, found on first use
+	renamesOnce sync.Once
+
+	typed     map[string]typedField // Fields options.Types gives a type, found on first use
+	typedOnce sync.Once
+
+	// Memoized per-name results, which the generator looks up once per reference
+	simple      memoCache[bool]
+	structNames memoCache[string]
+	fieldNames  memoCache[string]
 }
 
 // NewStructGenerator creates a new struct generator
-func NewStructGenerator(packageName string, elements map[string]*DTDElement, elementOrder []string) *StructGenerator {
+func NewStructGenerator(packageName string, elements map[string]*DTDElement, elementOrder []string, options GeneratorOptions) *StructGenerator {
+	if options.TinyGo {
+		// The hand-rolled codecs replace the xml tags, so the structs are the plain ones
+		options.NoXMLTags = true
+	}
+	if options.Compat == 0 {
+		options.Compat = CompatLatest
+	}
+	// Unset styles are the CLI's defaults, so the serve mode and tests leaving them out get the same code
+	defaultStyle(&options.AnyStyle, AnyStyleInnerXML)
+	defaultStyle(&options.EnumStyle, EnumStyleString)
+	defaultStyle(&options.OptionalEnums, OptionalEnumZero)
+	defaultStyle(&options.EmptyStyle, EmptyStyleStruct)
+	defaultStyle(&options.ChoiceStyle, ChoiceStyleFields)
+	defaultStyle(&options.GroupStyle, GroupStyleFlat)
+	if options.Compat >= 4 {
+		defaultStyle(&options.PointerPolicy, PointerPolicyOptionalOnly)
+	}
+	defaultStyle(&options.PointerPolicy, PointerPolicyAll)
+	defaultStyle(&options.MixedStyle, MixedStyleText)
+	defaultStyle(&options.SequenceStyle, SequenceStyleMerged)
 	return &StructGenerator{
 		packageName:  packageName,
 		elements:     elements,
 		elementOrder: elementOrder,
+		options:      options,
+	}
+}
+
+// defaultStyle sets an unset style option to its default
+func defaultStyle(style *string, fallback string) {
+	if *style == "" {
+		*style = fallback
 	}
 }
 
@@ -27,14 +133,16 @@ func NewStructGenerator(packageName string, elements map[string]*DTDElement, ele
 func (g *StructGenerator) GenerateStructs() string {
 	var builder strings.Builder
 
-	builder.WriteString(fmt.Sprintf("package %s\n\n", g.packageName))
-	builder.WriteString("import \"encoding/xml\"\n\n")
+	// The imports are picked first, as type names of third party packages use their
+	// names, and rendered once the code is written
+	g.collectImports()
 
 	// Generate structs for each element in declaration order
 	for _, elementName := range g.elementOrder {
 		if element, exists := g.elements[elementName]; exists {
 			// Skip generating struct for simple elements (they'll be string fields)
-			if !g.isSimpleElement(elementName) {
+			// and for wrapper children lifted into their only parent
+			if !g.isSimpleElement(elementName) && !g.isInlined(elementName) {
 				structCode := g.generateStruct(element)
 				builder.WriteString(structCode)
 				builder.WriteString("\n")
@@ -42,166 +150,574 @@ func (g *StructGenerator) GenerateStructs() string {
 		}
 	}
 
-	return builder.String()
-}
+	if g.usesTokenList() {
+		builder.WriteString(tokenListType)
+	}
 
-// generateStruct generates a Go struct for a single DTD element
-func (g *StructGenerator) generateStruct(element *DTDElement) string {
-	var builder strings.Builder
+	builder.WriteString(g.generateAttributeGroups())
 
-	structName := g.toGoStructName(element.Name)
+	builder.WriteString(g.generateEnumTypes())
 
-	builder.WriteString(fmt.Sprintf("// %s represents the <%s> element\n", structName, element.Name))
-	builder.WriteString(fmt.Sprintf("type %s struct {\n", structName))
+	builder.WriteString(g.generateLayoutTypes())
 
-	// Add XML name annotation
-	builder.WriteString(fmt.Sprintf("\tXMLName xml.Name `xml:\"%s\"`\n", element.Name))
+	builder.WriteString(g.generateChoices())
 
-	// Add attributes as struct fields
-	for _, attr := range element.Attributes {
-		fieldName := g.toGoFieldName(attr.Name)
-		fieldType := g.getGoType(attr.Type)
-		xmlTag := g.getXMLTag(attr.Name, attr.Required, true)
+	builder.WriteString(g.generateChoiceInterfaces())
+
+	builder.WriteString(g.generateContentGroups())
 
-		builder.WriteString(fmt.Sprintf("\t%s %s `xml:\"%s\"`\n", fieldName, fieldType, xmlTag))
+	builder.WriteString(g.generatePositionalSequences())
+
+	builder.WriteString(g.generateXMLForms())
+
+	builder.WriteString(g.generateMixedNodes())
+
+	builder.WriteString(g.generateEmptyElements())
+
+	if g.options.Occurrences {
+		builder.WriteString(g.generateOccurrences())
 	}
 
-	// Add content fields based on element content model
-	contentFields := g.parseContentModel(element.Content)
-	for _, field := range contentFields {
-		builder.WriteString(fmt.Sprintf("\t%s\n", field))
+	if g.options.ContentRegexp {
+		builder.WriteString(g.generateContentPatterns())
 	}
 
-	// Add text content field if element can contain text
-	if g.canContainText(element.Content) {
-		builder.WriteString("\tText string `xml:\",chardata\"`\n")
+	if g.options.ParentsIndex {
+		builder.WriteString(g.generateParentsIndex())
 	}
 
-	builder.WriteString("}")
+	if g.options.ViolationHooks {
+		builder.WriteString(violationsRuntime)
+	}
+	builder.WriteString(g.generateAnyTypes())
 
-	return builder.String()
-}
+	if g.options.GenericDecoder {
+		builder.WriteString(g.generateGenericDecoder())
+	}
 
-// parseContentModel parses the DTD content model and returns Go struct fields
-func (g *StructGenerator) parseContentModel(content string) []string {
-	var fields []string
+	if g.usesParseHelpers() {
+		builder.WriteString(g.generateParseHelpers())
+	}
 
-	original := strings.TrimSpace(content)
-	// Detect group-level repetition like (a | b | c)* or (a, b)+
-	groupRepeating := false
-	if strings.HasSuffix(original, ")*") || strings.HasSuffix(original, ")+") {
-		groupRepeating = true
+	if g.options.DecodeInto {
+		builder.WriteString(g.generateDecodeInto())
 	}
 
-	// Handle different content models
-	if content == "EMPTY" {
-		return fields
+	if g.options.TinyGo {
+		builder.WriteString(g.generateTinyGoCodecs())
 	}
 
-	if content == "ANY" {
-		fields = append(fields, "Content string `xml:\",innerxml\"`")
-		return fields
+	if g.options.NormalizeAttrs {
+		builder.WriteString(g.generateAttrNormalization())
 	}
 
-	if strings.Contains(content, "#PCDATA") {
-		return fields // Text content handled separately
+	if g.options.FoldCase {
+		builder.WriteString(g.generateCaseFolding())
 	}
 
-	// Skip complex content models with entity references
-	if strings.Contains(content, "%") {
-		return fields
+	if g.options.FillDefaults {
+		builder.WriteString(g.generateAttrDefaults())
 	}
 
-	// Clean up the content model
-	// If group-level repetition, strip trailing occurrence indicator for parsing child names
-	if groupRepeating && (strings.HasSuffix(content, ")*") || strings.HasSuffix(content, ")+")) {
-		// remove trailing )* or )+
-		content = content[:len(content)-2]
-	}
-	content = strings.Trim(content, "()")
-	content = strings.TrimSpace(content)
-
-	// Handle choice (|) and sequence (,) operators
-	var elementNames []string
-
-	// Simplified parsing - extract element names
-	// Remove occurrence indicators and extract basic element names
-	parts := regexp.MustCompile(`[,|]`).Split(content, -1)
-
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		// Remove occurrence indicators
-		part = regexp.MustCompile(`[+*?]`).ReplaceAllString(part, "")
-		// Remove parentheses
-		part = strings.Trim(part, "()")
-		part = strings.TrimSpace(part)
-
-		if part != "" && !strings.Contains(part, "#PCDATA") && !strings.Contains(part, "%") {
-			// Split further if there are nested structures
-			subParts := strings.Fields(part)
-			for _, subPart := range subParts {
-				subPart = regexp.MustCompile(`[+*?(),]`).ReplaceAllString(subPart, "")
-				subPart = strings.TrimSpace(subPart)
-				if subPart != "" && !strings.Contains(subPart, "#PCDATA") {
-					elementNames = append(elementNames, subPart)
-				}
+	if g.options.Constructors {
+		builder.WriteString(g.generateConstructors())
+	}
+
+	if g.options.Validation {
+		builder.WriteString(g.generateValidation())
+	}
+
+	if len(g.fixedStructs()) > 0 {
+		builder.WriteString(g.generateFixedAttributes())
+	}
+
+	if g.options.IDIndex {
+		builder.WriteString(g.generateIDIndex())
+	}
+
+	if g.options.Canonical {
+		builder.WriteString(canonicalRuntime)
+	}
+
+	if len(g.options.Redactions) > 0 {
+		builder.WriteString(g.generateRedaction())
+	}
+
+	if len(g.options.Split) > 0 {
+		builder.WriteString(g.generateSplitter())
+	}
+
+	// An option's code may not use every package it can, such as encoding/xml and the
+	// validation's errors without any structs in a DTD of text elements
+	g.imports.keepUsed(builder.String())
+	return g.formatCode(fmt.Sprintf("package %s\n\n", g.packageName) + g.imports.render() + builder.String())
+}
+
+// tokenListType handles whitespace separated list attributes, which encoding/xml cannot
+// map onto a plain []string
+const tokenListType = `
+// TokenList is a whitespace separated attribute value such as NMTOKENS or IDREFS
+type TokenList []string
+
+// MarshalXMLAttr joins the tokens with single spaces
+func (t TokenList) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return xml.Attr{Name: name, Value: strings.Join(t, " ")}, nil
+}
+
+// UnmarshalXMLAttr splits the attribute value on whitespace
+func (t *TokenList) UnmarshalXMLAttr(attr xml.Attr) error {
+	*t = strings.Fields(attr.Value)
+	return nil
+}
+`
+
+// usesTokenList reports whether any generated struct has a list-typed attribute
+func (g *StructGenerator) usesTokenList() bool {
+	if g.options.NoXMLTags {
+		return false
+	}
+	for _, name := range g.elementOrder {
+		element, exists := g.elements[name]
+		if !exists || g.isSimpleElement(name) {
+			continue
+		}
+		for _, attr := range element.Attributes {
+			if isListAttributeType(attr.Type) {
+				return true
 			}
 		}
 	}
+	return false
+}
 
-	// Remove duplicates
-	uniqueNames := make(map[string]bool)
-	for _, name := range elementNames {
-		if !uniqueNames[name] {
-			uniqueNames[name] = true
-			fieldName := g.toGoFieldName(name)
-			structType := g.toGoStructName(name)
+// collectImports sets imports to the packages the generated code may use
+func (g *StructGenerator) collectImports() {
+	needed := make(map[string]bool)
+	if !g.options.NoXMLTags {
+		needed["encoding/xml"] = true
+	}
+	if g.options.GenericDecoder {
+		needed["io"] = true
+		needed["strings"] = true
+	}
+	if g.usesTokenList() {
+		needed["strings"] = true
+	}
+	if g.options.ContentRegexp {
+		needed["regexp"] = true
+		needed["strings"] = true
+	}
+	if g.usesIntEnums() && !g.options.TinyGo {
+		needed["fmt"] = true
+	}
+	if g.usesParseHelpers() {
+		needed["io"] = true
+	}
+	if g.options.Instrument {
+		needed["context"] = true
+	}
+	if g.options.CharsetReader {
+		needed[charsetPackage] = true
+	}
+	if g.options.NormalizeAttrs {
+		needed["strings"] = true
+	}
+	if g.options.ViolationHooks {
+		needed["sync/atomic"] = true
+	}
+	if g.options.FoldCase {
+		needed["strings"] = true
+	}
+	if g.options.IDIndex {
+		needed["strings"] = true
+	}
+	if g.usesSelfClosingMarshal() {
+		needed["bytes"] = true
+	}
+	if g.options.Validation {
+		needed["errors"] = true
+		needed["fmt"] = true
+	}
+	if g.options.ValidateFixed && len(g.fixedStructs()) > 0 {
+		needed["fmt"] = true
+		needed["strings"] = true
+	}
+	if len(g.options.Redactions) > 0 {
+		for _, path := range []string{"crypto/sha256", "encoding/hex", "fmt", "strings"} {
+			needed[path] = true
+		}
+	}
+	if len(g.options.Split) > 0 {
+		for _, path := range []string{"bufio", "bytes", "fmt", "io"} {
+			needed[path] = true
+		}
+	}
+	if g.options.Canonical {
+		for _, path := range []string{"bufio", "bytes", "io", "sort", "strings"} {
+			needed[path] = true
+		}
+	}
+	if g.options.TinyGo {
+		for _, path := range []string{"bufio", "errors", "io", "strconv", "strings", "unicode/utf8"} {
+			needed[path] = true
+		}
+	}
+	for _, path := range g.typeImports() {
+		needed[path] = true
+	}
 
-			// Determine if this should be a slice based on occurrence indicators or choice groups
-			isSlice := groupRepeating || strings.Contains(original, name+"*") || strings.Contains(original, name+"+") || strings.Contains(original, "|")
+	// importSet groups the standard library before third party packages and picks aliases
+	g.imports = importSet{}
+	for path := range needed {
+		g.imports.add(path)
+	}
+}
 
-			// Check if element is simple (just contains text)
-			if g.isSimpleElement(name) {
-				if isSlice {
-					fields = append(fields, fmt.Sprintf("%s []string `xml:\"%s,omitempty\"`", fieldName, name))
-				} else {
-					fields = append(fields, fmt.Sprintf("%s *string `xml:\"%s,omitempty\"`", fieldName, name))
+// goField is a single field of a generated struct
+type goField struct {
+	Name    string
+	Type    string
+	Tag     string      // Value of the xml struct tag
+	Occurs  *occurrence // Allowed occurrences of the child element held by a content field
+	Element string      // Child element held by a content field
+
+	Deprecated string // Reason the attribute or child element is deprecated, if it is
+	Explain    string // Rule that produced the field, written as a trailing comment by -explain-decisions
+	Renamed    string // Name the field would have had, which another field of the struct has
+}
+
+// occurrence is the allowed number of occurrences of a child element
+type occurrence struct {
+	Min, Max int // Max is Unbounded for children that can repeat without limit
+}
+
+// String renders the field as it appears in the struct body
+func (f goField) String() string {
+	if f.Tag == "" {
+		return fmt.Sprintf("%s %s", f.Name, f.Type)
+	}
+	return fmt.Sprintf("%s %s `xml:\"%s\"`", f.Name, f.Type, f.Tag)
+}
+
+// generateStruct generates a Go struct for a single DTD element
+func (g *StructGenerator) generateStruct(element *DTDElement) string {
+	var builder strings.Builder
+
+	structName := g.toGoStructName(element.Name)
+
+	if rename, ok := g.structRenames()[element.Name]; ok {
+		builder.WriteString(fmt.Sprintf("// %s represents the <%s> element, numbered as %s represents <%s>\n", structName, element.Name, rename.Name, rename.TakenBy))
+	} else {
+		builder.WriteString(fmt.Sprintf("// %s represents the <%s> element\n", structName, element.Name))
+	}
+	if element.Deprecated != "" {
+		builder.WriteString(fmt.Sprintf("//\n// Deprecated: %s\n", element.Deprecated))
+	}
+	builder.WriteString(g.structType(element, structName, false))
+
+	return builder.String()
+}
+
+// structType generates the type declaration of an element's struct under the given name.
+// As the encoding/xml form of the struct, the alternatives of its interface choices are
+// held by a field each rather than one field of the interface type, the elements of its
+// repeated groups by one ",any" field decoding them in document order, and so are all its
+// elements with a positional sequence.
+func (g *StructGenerator) structType(element *DTDElement, typeName string, form bool) string {
+	var builder strings.Builder
+
+	builder.WriteString(fmt.Sprintf("type %s struct {\n", typeName))
+
+	// Add XML name annotation
+	if !g.options.NoXMLTags {
+		builder.WriteString(fmt.Sprintf("\tXMLName xml.Name `xml:\"%s\"`", g.xmlTagName(element.Name)))
+		if g.options.Explain {
+			builder.WriteString(fmt.Sprintf(" // from <!ELEMENT %s>", element.Name))
+		}
+		builder.WriteString("\n")
+	}
+
+	choices := g.choiceGroups(element)
+	interfaces := g.choiceInterfaces(element)
+	groups := g.contentGroups(element)
+	sequence := g.positionalSequence(element)
+	embedded := make(map[string]bool)
+	for i, field := range g.structFields(element) {
+		// The leading fields are the element's attributes, some of which may be shared
+		if i < len(element.Attributes) {
+			if group := g.attributeGroupOf(element, element.Attributes[i]); group != nil {
+				if !embedded[group.Name] {
+					embedded[group.Name] = true
+					builder.WriteString(fmt.Sprintf("\t%s", group.Name))
+					if g.options.Explain {
+						builder.WriteString(fmt.Sprintf(" // from ATTLIST <%s> -> embedded because -attr-groups %d", element.Name, g.options.AttrGroups))
+					}
+					builder.WriteString("\n")
+				}
+				continue
+			}
+		}
+		if g.options.NoXMLTags {
+			field.Tag = ""
+		}
+		if form && sequence != nil && field.Occurs != nil {
+			if field.Name == sequence.Fields[0].Name {
+				builder.WriteString(fmt.Sprintf("\t%s []%s `xml:\",any\"`\n", sequence.Child, g.sequenceChildName(element.Name)))
+			}
+			continue
+		}
+		if group := groupField(groups, field); form && group != nil {
+			builder.WriteString(fmt.Sprintf("\t%s []%s `xml:\",any\"`\n", field.Name, g.groupChildName(element.Name)))
+			continue
+		}
+		if choice := choiceInterfaceOf(interfaces, field.Element); !form && field.Element != "" && choice != nil {
+			if field.Element == choice.Elements[0] {
+				holder := goField{Name: choice.Field, Type: choice.Name, Tag: "-"}
+				if g.options.NoXMLTags {
+					holder.Tag = ""
 				}
-			} else {
-				if isSlice {
-					fields = append(fields, fmt.Sprintf("%s []%s `xml:\"%s,omitempty\"`", fieldName, structType, name))
+				builder.WriteString(fmt.Sprintf("\t// %s holds the alternative of the choice %s, a %s\n", choice.Field, choice.Group.Particle, choice.alternativeList()))
+				if g.options.Explain {
+					builder.WriteString(fmt.Sprintf("\t%s // from %s -> interface because -choice-style interface\n", holder, element.Content))
 				} else {
-					fields = append(fields, fmt.Sprintf("%s *%s `xml:\"%s,omitempty\"`", fieldName, structType, name))
+					builder.WriteString(fmt.Sprintf("\t%s\n", holder))
 				}
 			}
+			continue
+		}
+		for j, choice := range choices {
+			if choiceInterfaceOf(interfaces, field.Element) != nil {
+				break
+			}
+			if field.Element != "" && field.Element == choice.Alternatives[0][0] {
+				builder.WriteString(fmt.Sprintf("\t// One alternative of the choice %s; see %s\n", choice.Particle, g.choiceMethod(element, j)))
+			}
+		}
+		if field.Deprecated != "" {
+			builder.WriteString(fmt.Sprintf("\t// Deprecated: %s\n", field.Deprecated))
+		}
+		if g.options.Explain && field.Explain != "" {
+			builder.WriteString(fmt.Sprintf("\t%s // %s\n", field, field.Explain))
+		} else {
+			builder.WriteString(fmt.Sprintf("\t%s\n", field))
 		}
 	}
 
-	return fields
+	builder.WriteString("}")
+
+	return builder.String()
 }
 
-// isSimpleElement determines if an element should be treated as a simple string field
-func (g *StructGenerator) isSimpleElement(elementName string) bool {
-	element, exists := g.elements[elementName]
-	if !exists {
-		return true // Unknown elements treated as simple
+// structFields returns the attribute, content and text fields of an element's struct
+func (g *StructGenerator) structFields(element *DTDElement) []goField {
+	return g.fieldsOf(element, true)
+}
+
+// fieldsOf returns the fields of an element's struct. With values, the children that occur
+// at most once are held by value as PointerPolicy has it; the fields lifted from a wrapper
+// are not, as the wrapper itself may be absent.
+func (g *StructGenerator) fieldsOf(element *DTDElement, values bool) []goField {
+	var fields []goField
+
+	// Add attributes as struct fields
+	for _, attr := range element.Attributes {
+		fields = append(fields, g.attributeField(element, attr))
 	}
 
-	content := strings.TrimSpace(element.Content)
+	// Add content fields based on element content model
+	if wrapped, ok := g.inlinedChild(element); ok {
+		// The single child's fields are lifted into this struct under a path tag
+		taken := make(map[string]bool)
+		for _, field := range fields {
+			taken[field.Name] = true
+		}
+		for _, field := range g.fieldsOf(g.elements[wrapped], false) {
+			field.Tag = wrapped + ">" + field.Tag
+			field.Explain = fmt.Sprintf("lifted from <%s> by -inline-wrappers; %s", wrapped, field.Explain)
+			if taken[field.Name] {
+				field.Name = g.toGoStructName(wrapped) + field.Name
+			}
+			taken[field.Name] = true
+			fields = append(fields, field)
+		}
+	} else if sequence := g.positionalSequence(element); sequence != nil {
+		fields = append(fields, sequence.Fields...)
+	} else if groups := g.contentGroups(element); len(groups) > 0 {
+		// The elements of each repeated group are held by the group's field, in place of
+		// the first of them
+		for _, field := range g.parseContentModel(element.Content) {
+			group := contentGroupOf(groups, field.Element)
+			switch {
+			case group == nil:
+				fields = append(fields, field)
+			case group.Fields[0].Element == field.Element:
+				fields = append(fields, group.Field)
+			}
+		}
+	} else {
+		fields = append(fields, g.parseContentModel(element.Content)...)
+	}
+	if values {
+		for i := len(element.Attributes); i < len(fields); i++ {
+			fields[i] = g.requiredValue(element, fields[i])
+		}
+	}
 
-	// Elements that are explicitly simple
-	if content == "( #PCDATA )" || content == "#PCDATA" || content == "EMPTY" {
-		return true
+	// Add text content field if element can contain text
+	var text []goField
+	if g.holdsMixedNodes(element.Name) {
+		text = append(text, g.mixedNodesField(element))
+	} else if g.canContainText(element.Content) {
+		field := goField{Name: "Text", Type: g.textType(element.Name), Tag: ",chardata",
+			Explain: fmt.Sprintf("from %s -> text because the model allows #PCDATA", element.Content)}
+		if field.Type != "string" {
+			field.Explain += fmt.Sprintf("; %s by the types section of the generator config", field.Type)
+		}
+		text = append(text, field)
 	}
 
-	// Elements with no attributes and simple content model
-	if len(element.Attributes) == 0 && (content == "( #PCDATA )" || strings.Contains(content, "#PCDATA")) {
-		return true
+	// The fields the generator adds keep their names; the others are renamed on collisions
+	reserved := []string{"XMLName"}
+	for _, field := range text {
+		reserved = append(reserved, field.Name)
 	}
+	return append(uniqueFieldNames(fields, reserved...), text...)
+}
+
+// requiredValue returns a content field of an element's struct holding its child by value
+// if the child occurs at most once and PointerPolicy holds it by value: plain strings, and
+// structs that do not hold the element itself by value again, which Go cannot express
+func (g *StructGenerator) requiredValue(element *DTDElement, field goField) goField {
+	if field.Element == "" || field.Occurs == nil || field.Occurs.Max != 1 || !strings.HasPrefix(field.Type, "*") ||
+		!g.byValue(element, field.Element, field.Occurs.Min) {
+		return field
+	}
+	if !g.isSimpleElement(field.Element) && g.holdsByValue(field.Element, element.Name, make(map[string]bool)) {
+		return field
+	}
+	field.Type = field.Type[1:]
+	field.Tag = strings.TrimSuffix(field.Tag, ",omitempty")
+	field.Explain = strings.Replace(field.Explain, "pointer because it occurs once", "value because it occurs once and -pointer-policy "+g.options.PointerPolicy, 1)
+	field.Explain = strings.Replace(field.Explain, "pointer because it is optional", "value, the zero value when left out, because -pointer-policy none", 1)
+	return field
+}
+
+// byValue reports whether PointerPolicy holds a child of an element occurring at most
+// once, and at least min times, by value. PointerPolicyNone keeps the pointers of the
+// alternatives of choices, which tell the one set apart from the others.
+func (g *StructGenerator) byValue(element *DTDElement, child string, min int) bool {
+	switch g.options.PointerPolicy {
+	case PointerPolicyOptionalOnly:
+		return min >= 1
+	case PointerPolicyNone:
+		return choiceOf(g.choiceGroups(element), child) == nil
+	}
+	return false
+}
 
+// holdsByValue reports whether the struct of element from holds the struct of element to
+// by value, directly or through the structs it holds by value
+func (g *StructGenerator) holdsByValue(from, to string, seen map[string]bool) bool {
+	element, exists := g.elements[from]
+	if !exists || seen[from] {
+		return false
+	}
+	seen[from] = true
+	for _, child := range contentChildren(element.Content) {
+		if child.Max != 1 || !g.byValue(element, child.Name, child.Min) || g.isSimpleElement(child.Name) {
+			continue
+		}
+		if child.Name == to || g.holdsByValue(child.Name, to, seen) {
+			return true
+		}
+	}
 	return false
 }
 
+// attributeField returns the struct field holding an attribute of an element
+func (g *StructGenerator) attributeField(element *DTDElement, attr DTDAttribute) goField {
+	fieldType := g.getGoType(attr.Type)
+	if enum := g.enumFieldType(element, attr); enum != "" {
+		fieldType = enum
+	}
+	if typed, ok := g.typedAttribute(element, attr); ok {
+		// Absent optional attributes are told apart from zero values such as 0 or false
+		fieldType = g.typedType(typed)
+		if !attr.Required {
+			fieldType = "*" + fieldType
+		}
+	}
+	return goField{
+		Name:       g.toGoFieldName(attr.Name),
+		Type:       fieldType,
+		Tag:        g.getXMLTag(attr.Name, attr.Required, true),
+		Deprecated: attr.Deprecated,
+		Explain:    g.explainAttribute(element, attr, fieldType),
+	}
+}
+
+// parseContentModel returns the Go struct fields for the child elements of a content model
+func (g *StructGenerator) parseContentModel(content string) []goField {
+	var fields []goField
+
+	if content == "ANY" {
+		fields := g.anyContentField()
+		for i := range fields {
+			fields[i].Explain = "from ANY -> -any-style " + g.options.AnyStyle
+		}
+		return fields
+	}
+
+	for _, child := range contentChildren(content) {
+		name := child.Name
+		fieldType := g.toGoStructName(name)
+
+		// Check if element is simple (just contains text)
+		presence := g.isPresenceElement(name)
+		switch {
+		case presence:
+			fieldType = "Presence"
+		case g.isSimpleElement(name):
+			fieldType = g.textType(name)
+		}
+
+		if child.Repeated {
+			fieldType = "[]" + fieldType
+		} else if !presence {
+			fieldType = "*" + fieldType
+		}
+
+		field := goField{
+			Name:    g.toGoFieldName(name),
+			Type:    fieldType,
+			Tag:     g.xmlTagName(name) + ",omitempty",
+			Element: name,
+		}
+		field.Explain = fmt.Sprintf("from %s -> %s", content, child.Reason)
+		if presence {
+			field.Explain += fmt.Sprintf("; Presence because <%s> is EMPTY (-empty-style bool)", name)
+		} else if g.isSimpleElement(name) && g.textType(name) != "string" {
+			field.Explain += fmt.Sprintf("; %s because <%s> is generated as a plain string, typed by the types section of the generator config", g.textType(name), name)
+		} else if g.isSimpleElement(name) {
+			field.Explain += fmt.Sprintf("; string because <%s> is generated as a plain string", name)
+		}
+		field.Occurs = &occurrence{Min: child.Min, Max: child.Max}
+		if child, exists := g.elements[name]; exists {
+			field.Deprecated = child.Deprecated
+		}
+		fields = append(fields, field)
+	}
+
+	return fields
+}
+
+// isSimpleElement determines if an element should be treated as a simple string field
+func (g *StructGenerator) isSimpleElement(elementName string) bool {
+	return g.simple.get(elementName, func(name string) bool {
+		return (isSimpleElement(g.elements, name) && !g.holdsMixedNodes(name)) || g.isPresenceElement(name)
+	})
+}
+
 // canContainText determines if an element can contain text content
 func (g *StructGenerator) canContainText(content string) bool {
 	return strings.Contains(content, "#PCDATA")
@@ -209,19 +725,23 @@ func (g *StructGenerator) canContainText(content string) bool {
 
 // toGoStructName converts DTD element name to Go struct name
 func (g *StructGenerator) toGoStructName(name string) string {
-	// Convert to PascalCase
-	words := strings.FieldsFunc(name, func(c rune) bool {
-		return c == '-' || c == '_'
+	return g.structNames.get(name, func(name string) string {
+		if rename, ok := g.structRenames()[name]; ok {
+			return rename.Renamed
+		}
+		return g.baseStructName(name)
 	})
+}
 
+// goStructName converts DTD element name to Go struct name
+func goStructName(name string) string {
+	// Convert to PascalCase
 	var result strings.Builder
-	for _, word := range words {
-		if len(word) > 0 {
-			result.WriteString(strings.Title(word))
-		}
+	for _, word := range identifierWords(name) {
+		result.WriteString(strings.Title(word))
 	}
 
-	structName := result.String()
+	structName := validIdentifier(result.String())
 	if structName == "" {
 		structName = "Element"
 	}
@@ -231,22 +751,24 @@ func (g *StructGenerator) toGoStructName(name string) string {
 
 // toGoFieldName converts DTD element/attribute name to Go field name
 func (g *StructGenerator) toGoFieldName(name string) string {
-	// Convert to PascalCase for field names
-	words := strings.FieldsFunc(name, func(c rune) bool {
-		return c == '-' || c == '_'
+	return g.fieldNames.get(name, func(name string) string {
+		return goFieldName(g.initialismCase(g.identifierName(name)))
 	})
+}
 
+// goFieldName converts DTD element/attribute name to Go field name
+func goFieldName(name string) string {
+	// Convert to PascalCase for field names, so xml:lang becomes XmlLang (XMLLang once
+	// initialismCase has upper cased xml)
 	var result strings.Builder
-	for _, word := range words {
-		if len(word) > 0 {
-			// Capitalize first letter, keep rest as is
-			runes := []rune(word)
-			runes[0] = unicode.ToUpper(runes[0])
-			result.WriteString(string(runes))
-		}
+	for _, word := range identifierWords(name) {
+		// Capitalize first letter, keep rest as is
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		result.WriteString(string(runes))
 	}
 
-	fieldName := result.String()
+	fieldName := validIdentifier(result.String())
 	if fieldName == "" {
 		fieldName = "Field"
 	}
@@ -278,19 +800,61 @@ func (g *StructGenerator) getGoType(dtdType string) string {
 	switch strings.ToUpper(dtdType) {
 	case "CDATA", "ID", "IDREF", "NMTOKEN":
 		return "string"
-	case "IDREFS", "NMTOKENS":
-		return "[]string"
+	case "IDREFS", "NMTOKENS", "ENTITIES":
+		if g.options.NoXMLTags {
+			return "[]string"
+		}
+		return "TokenList"
 	default:
 		// For enumerated types or unknown types, default to string
 		return "string"
 	}
 }
 
-// getXMLTag generates the XML tag for struct fields
+// explainAttribute describes how an attribute declaration became a field of type fieldType
+func (g *StructGenerator) explainAttribute(element *DTDElement, attr DTDAttribute, fieldType string) string {
+	declaration := attr.Name + " " + attr.DeclaredType()
+	if attr.Fixed {
+		declaration += " #FIXED"
+	}
+	if attr.DefaultValue != "" {
+		declaration += fmt.Sprintf(" %q", attr.DefaultValue)
+	}
+	if attr.Required {
+		declaration += " #REQUIRED"
+	}
+
+	var decision string
+	switch {
+	case g.typeMapping(element.Name, attr.Name) != nil:
+		decision = fieldType + " by the types section of the generator config"
+	case strings.HasPrefix(fieldType, "*"):
+		decision = "pointer to the -enum-style int type because -optional-enums pointer"
+	case g.enumTypeName(element, attr) != "" && attr.Type == notationAttributeType:
+		decision = "enum type because NOTATION"
+	case g.isStringEnum(element, attr):
+		decision = "string enum type because -enum-style typed"
+	case g.enumTypeName(element, attr) != "":
+		decision = "enum type because -enum-style int"
+	case len(attr.Values) > 0:
+		decision = "string because -enum-style string"
+	case isListAttributeType(attr.Type):
+		decision = fmt.Sprintf("%s because %s is a list type", fieldType, attr.Type)
+	default:
+		decision = "string attr"
+	}
+	if !attr.Required {
+		decision += "; omitempty because not #REQUIRED"
+	}
+	return fmt.Sprintf("from ATTLIST <%s> %s -> %s", element.Name, declaration, decision)
+}
+
+// getXMLTag generates the XML tag for struct fields. encoding/xml reports prefixed
+// attributes, such as xml:id, in their namespace; see xmlTagName.
 func (g *StructGenerator) getXMLTag(name string, required bool, isAttribute bool) string {
-	tag := name
+	tag := g.xmlTagName(name)
 	if isAttribute {
-		tag = name + ",attr"
+		tag += ",attr"
 	}
 	if !required {
 		tag += ",omitempty"
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os/exec"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// parseDTD parses the DTD text dtd
+func parseDTD(tb testing.TB, dtd string) *ParseResult {
+	tb.Helper()
+	result, err := NewDTDParser(ParserOptions{FS: fstest.MapFS{"schema.dtd": {Data: []byte(dtd)}}}).ParseFile("schema.dtd")
+	if err != nil {
+		tb.Fatalf("parsing %q: %v", dtd, err)
+	}
+	return result
+}
+
+// generateGo generates the Go code of a parsed DTD with the given options
+func generateGo(t *testing.T, result *ParseResult, options GeneratorOptions) string {
+	t.Helper()
+	files, err := (&Generation{Result: result, Format: "go", PackageName: "schema", Options: options}).Generate()
+	if err != nil {
+		t.Fatalf("generating: %v", err)
+	}
+	return string(files["schema.go"])
+}
+
+// structFields parses code and returns the fields of the struct type name, each as its
+// name, type and tag, such as "Text string `xml:\",chardata\"`"
+func structFields(t *testing.T, code, name string) []string {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "schema.go", code, 0)
+	if err != nil {
+		t.Fatalf("parsing the generated code: %v", err)
+	}
+	var fields []string
+	found := false
+	ast.Inspect(file, func(node ast.Node) bool {
+		spec, ok := node.(*ast.TypeSpec)
+		if !ok || spec.Name.Name != name {
+			return true
+		}
+		found = true
+		structType, ok := spec.Type.(*ast.StructType)
+		if !ok {
+			t.Fatalf("type %s is not a struct", name)
+		}
+		for _, field := range structType.Fields.List {
+			for _, ident := range field.Names {
+				fields = append(fields, ident.Name+" "+exprString(field.Type)+" "+field.Tag.Value)
+			}
+		}
+		return false
+	})
+	if !found {
+		t.Fatalf("no type %s in the generated code:\n%s", name, code)
+	}
+	return fields
+}
+
+// exprString formats the type expressions generated structs use
+func exprString(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return expr.Name
+	case *ast.SelectorExpr:
+		return exprString(expr.X) + "." + expr.Sel.Name
+	case *ast.StarExpr:
+		return "*" + exprString(expr.X)
+	case *ast.ArrayType:
+		return "[]" + exprString(expr.Elt)
+	}
+	return "?"
+}
+
+// TestTextWithAttributes checks that an element of text with attributes, the money and
+// measurement pattern of measurements.dtd, gets a struct with the attributes next to a
+// chardata field rather than a plain string, and that it round-trips through encoding/xml
+func TestTextWithAttributes(t *testing.T) {
+	result, err := NewDTDParser(ParserOptions{}).ParseFile("testdata/measurements.dtd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := generateGo(t, result, GeneratorOptions{})
+	want := []string{
+		"XMLName xml.Name `xml:\"price\"`",
+		"Currency string `xml:\"currency,attr\"`",
+		"Vat string `xml:\"vat,attr,omitempty\"`",
+		"Text string `xml:\",chardata\"`",
+	}
+	if got := structFields(t, code, "Price"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Price fields:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	goCommand := lookGo(t)
+	module := t.TempDir()
+	writeFiles(t, module, map[string][]byte{
+		"go.mod":           []byte(generatedModule),
+		"schema/schema.go": []byte(code),
+		"main.go": []byte(`package main
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"generated/schema"
+)
+
+func main() {
+	var price schema.Price
+	if err := xml.Unmarshal([]byte(` + "`" + priceDocument + "`" + `), &price); err != nil {
+		panic(err)
+	}
+	fmt.Printf("%q %q %q\n", price.Currency, price.Vat, price.Text)
+	data, err := xml.Marshal(price)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(string(data))
+}
+`),
+	})
+	command := exec.Command(goCommand, "run", ".")
+	command.Dir = module
+	output, err := command.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go run: %v\n%s", err, output)
+	}
+	if want := "\"EUR\" \"\" \"9.99\"\n" + priceDocument + "\n"; string(output) != want {
+		t.Errorf("round trip of %s:\n%s\nwant:\n%s", priceDocument, output, want)
+	}
+}
+
+// priceDocument is the <price> TestTextWithAttributes round-trips
+const priceDocument = `<price currency="EUR">9.99</price>`
+
+// TestDecodeIntoWithoutStructs checks that DecodeInto is left out, rather than generated
+// over an empty set of types, when no document root is a struct
+func TestDecodeIntoWithoutStructs(t *testing.T) {
+	decodeInto := func(dtd string) string {
+		result := parseDTD(t, dtd)
+		return NewStructGenerator("schema", result.Elements, result.Order, GeneratorOptions{DecodeInto: true}).generateDecodeInto()
+	}
+	if code := decodeInto(`<!ELEMENT note (#PCDATA)>`); code != "" {
+		t.Errorf("DecodeInto generated without a struct:\n%s", code)
+	}
+	if code := decodeInto(`<!ELEMENT notes (note*)> <!ELEMENT note (#PCDATA)>`); !strings.Contains(code, "func DecodeInto[T interface{ *Notes }]") {
+		t.Errorf("DecodeInto not generated for <notes>:\n%s", code)
+	}
+}
+
+// largeSchema parses a synthetic DTD of n elements, each with a sequence of references
+// to the 14 elements after it and a few attributes, for the benchmarks
+func largeSchema(b *testing.B, n int) *ParseResult {
+	b.Helper()
+	var dtd strings.Builder
+	for i := 0; i < n; i++ {
+		var children []string
+		for j := 1; j <= 14 && i+j < n; j++ {
+			children = append(children, fmt.Sprintf("item-%d?", i+j))
+		}
+		if len(children) == 0 {
+			fmt.Fprintf(&dtd, "<!ELEMENT item-%d (#PCDATA)>\n", i)
+		} else {
+			fmt.Fprintf(&dtd, "<!ELEMENT item-%d (%s)>\n", i, strings.Join(children, ", "))
+		}
+		fmt.Fprintf(&dtd, "<!ATTLIST item-%d item-id ID #IMPLIED kind (a | b) \"a\" xml:lang CDATA #IMPLIED>\n", i)
+	}
+	return parseDTD(b, dtd.String())
+}
+
+// BenchmarkGenerateStructs generates the structs of a schema of thousands of elements with
+// tens of thousands of references between them
+func BenchmarkGenerateStructs(b *testing.B) {
+	result := largeSchema(b, 3000)
+	b.ResetTimer()
+	for range b.N {
+		NewStructGenerator("schema", result.Elements, result.Order, GeneratorOptions{}).GenerateStructs()
+	}
+}
+
+// BenchmarkNameLookups looks up, for every reference of the same schema, whether the
+// element is simple and its struct and field names, as generating a field does
+func BenchmarkNameLookups(b *testing.B) {
+	result := largeSchema(b, 3000)
+	g := NewStructGenerator("schema", result.Elements, result.Order, GeneratorOptions{})
+	var references []string
+	for i := range result.Order {
+		for j := 1; j <= 14 && i+j < len(result.Order); j++ {
+			references = append(references, result.Order[i+j])
+		}
+	}
+	b.ResetTimer()
+	for range b.N {
+		for _, name := range references {
+			g.isSimpleElement(name)
+			g.toGoStructName(name)
+			g.toGoFieldName(name)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// sampleUsage records the elements and attributes a sample document exercises
+type sampleUsage struct {
+	root       string
+	elements   map[string]bool
+	attributes map[string]map[string]bool // Element name to attribute names
+}
+
+// readSampleUsage scans a sample XML document for the element and attribute names it uses.
+// Names keep their prefix (xml:lang), as DTDs declare them.
+func readSampleUsage(sample io.Reader) (*sampleUsage, error) {
+	usage := &sampleUsage{
+		elements:   make(map[string]bool),
+		attributes: make(map[string]map[string]bool),
+	}
+
+	d := xml.NewDecoder(sample)
+	d.Strict = false // Samples may use entities declared only in the DTD
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sample document: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		name := qualifiedName(start.Name)
+		if usage.root == "" {
+			usage.root = name
+		}
+		usage.elements[name] = true
+		for _, attr := range start.Attr {
+			if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+				continue
+			}
+			if usage.attributes[name] == nil {
+				usage.attributes[name] = make(map[string]bool)
+			}
+			usage.attributes[name][qualifiedName(attr.Name)] = true
+		}
+	}
+
+	if usage.root == "" {
+		return nil, fmt.Errorf("sample document has no root element")
+	}
+	return usage, nil
+}
+
+// qualifiedName returns a raw token name as written, e.g. "xml:lang"
+func qualifiedName(name xml.Name) string {
+	if name.Space != "" {
+		return name.Space + ":" + name.Local
+	}
+	return name.Local
+}
+
+// Subset reduces the model to the elements and attributes a sample document exercises,
+// plus the elements leading from a document root down to the sample's root element when
+// the sample is a fragment. Content models are rewritten without the dropped children.
+// It returns a description of everything removed.
+func (r *ParseResult) Subset(sample io.Reader) ([]string, error) {
+	usage, err := readSampleUsage(sample)
+	if err != nil {
+		return nil, err
+	}
+	if _, exists := r.Elements[usage.root]; !exists {
+		return nil, fmt.Errorf("sample root %w", &GenerateError{Element: usage.root, Err: ErrUndeclaredElement})
+	}
+
+	keep := make(map[string]bool)
+	for name := range usage.elements {
+		if _, exists := r.Elements[name]; exists {
+			keep[name] = true
+		}
+	}
+	for _, name := range r.Graph().pathFromRoot(usage.root) {
+		keep[name] = true
+	}
+
+	var report, dropped, order []string
+	for _, name := range r.Order {
+		if !keep[name] {
+			dropped = append(dropped, name)
+			delete(r.Elements, name)
+			continue
+		}
+		order = append(order, name)
+	}
+	r.Order = order
+	if len(dropped) > 0 {
+		report = append(report, fmt.Sprintf("removed %d elements the sample does not use: %s", len(dropped), strings.Join(dropped, ", ")))
+	}
+
+	for _, name := range r.Order {
+		element := r.Elements[name]
+
+		var attributes []DTDAttribute
+		var unused []string
+		for _, attr := range element.Attributes {
+			if usage.attributes[name][attr.Name] {
+				attributes = append(attributes, attr)
+			} else {
+				unused = append(unused, attr.Name)
+			}
+		}
+		if len(unused) > 0 {
+			element.Attributes = attributes
+			report = append(report, fmt.Sprintf("%s: removed attributes of <%s> the sample does not use: %s",
+				element.Position, name, strings.Join(unused, ", ")))
+		}
+
+		// Content models with unexpanded entity references are left as they are
+		model, err := ParseContentModel(element.Content)
+		if err != nil || model.Root == nil {
+			continue
+		}
+		if reduced := model.without(keep); reduced.String() != model.String() {
+			element.Content = reduced.String()
+		}
+	}
+
+	return report, nil
+}
+
+// pathFromRoot returns the elements on a shortest path from an element no other element
+// references down to name, or nil when name is itself such an element
+func (g *Graph) pathFromRoot(name string) []string {
+	roots := make(map[string]bool)
+	for _, root := range g.Roots() {
+		roots[root] = true
+	}
+	if roots[name] {
+		return nil
+	}
+
+	// Walk up through the parents breadth first until a root is reached
+	via := map[string]string{name: ""}
+	queue := []string{name}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if roots[current] {
+			var path []string
+			for step := current; step != ""; step = via[step] {
+				path = append(path, step)
+			}
+			return path
+		}
+		for _, parent := range g.Parents(current) {
+			if _, seen := via[parent]; !seen {
+				via[parent] = current
+				queue = append(queue, parent)
+			}
+		}
+	}
+	return nil
+}
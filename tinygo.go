@@ -0,0 +1,743 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tinyXMLRuntime is a small non-validating XML reader and the escaping helpers used by
+// the hand-rolled codecs of -tinygo output, which must not pull in encoding/xml
+const tinyXMLRuntime = `
+// xmlTokenKind classifies the tokens returned by xmlReader
+type xmlTokenKind int
+
+const (
+	xmlStartToken xmlTokenKind = iota
+	xmlEndToken
+	xmlTextToken
+)
+
+// xmlAttr is an attribute of a start tag
+type xmlAttr struct {
+	Name  string
+	Value string
+}
+
+// xmlToken is a start tag, end tag or run of character data
+type xmlToken struct {
+	Kind        xmlTokenKind
+	Name        string
+	Attrs       []xmlAttr
+	Text        string
+	SelfClosing bool
+}
+
+// xmlReader tokenizes a document, skipping declarations, comments and processing instructions
+type xmlReader struct {
+	r *bufio.Reader
+}
+
+var errUnexpectedEOF = errors.New("xml: unexpected end of document")
+
+// next returns the next token
+func (x *xmlReader) next() (xmlToken, error) {
+	for {
+		c, err := x.r.ReadByte()
+		if err != nil {
+			return xmlToken{}, err
+		}
+		if c != '<' {
+			x.r.UnreadByte()
+			text, err := x.readUntil('<')
+			if err != nil && err != io.EOF {
+				return xmlToken{}, err
+			}
+			if err == nil {
+				x.r.UnreadByte()
+			}
+			return xmlToken{Kind: xmlTextToken, Text: unescapeXML(text)}, nil
+		}
+
+		c, err = x.r.ReadByte()
+		if err != nil {
+			return xmlToken{}, errUnexpectedEOF
+		}
+		switch c {
+		case '?':
+			if err := x.skipPast("?>"); err != nil {
+				return xmlToken{}, err
+			}
+		case '!':
+			token, ok, err := x.readMarkup()
+			if err != nil || ok {
+				return token, err
+			}
+		case '/':
+			name, err := x.readUntil('>')
+			if err != nil {
+				return xmlToken{}, errUnexpectedEOF
+			}
+			return xmlToken{Kind: xmlEndToken, Name: strings.TrimSpace(name)}, nil
+		default:
+			x.r.UnreadByte()
+			return x.readStart()
+		}
+	}
+}
+
+// readMarkup handles "<!": comments and DOCTYPE declarations are skipped, CDATA
+// sections are returned as text
+func (x *xmlReader) readMarkup() (xmlToken, bool, error) {
+	head, err := x.r.Peek(2)
+	if err == nil && string(head) == "--" {
+		return xmlToken{}, false, x.skipPast("-->")
+	}
+	head, err = x.r.Peek(7)
+	if err == nil && string(head) == "[CDATA[" {
+		x.r.Discard(7)
+		var text strings.Builder
+		for !strings.HasSuffix(text.String(), "]]>") {
+			c, err := x.r.ReadByte()
+			if err != nil {
+				return xmlToken{}, false, errUnexpectedEOF
+			}
+			text.WriteByte(c)
+		}
+		return xmlToken{Kind: xmlTextToken, Text: strings.TrimSuffix(text.String(), "]]>")}, true, nil
+	}
+
+	// <!DOCTYPE ...> with an optional [internal subset]
+	depth := 0
+	for {
+		c, err := x.r.ReadByte()
+		if err != nil {
+			return xmlToken{}, false, errUnexpectedEOF
+		}
+		switch {
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case c == '>' && depth <= 0:
+			return xmlToken{}, false, nil
+		}
+	}
+}
+
+// readStart reads a start tag after its "<"
+func (x *xmlReader) readStart() (xmlToken, error) {
+	token := xmlToken{Kind: xmlStartToken}
+	name, err := x.readName()
+	if err != nil {
+		return token, err
+	}
+	token.Name = name
+
+	for {
+		c, err := x.skipSpace()
+		if err != nil {
+			return token, errUnexpectedEOF
+		}
+		switch c {
+		case '>':
+			return token, nil
+		case '/':
+			if c, err := x.r.ReadByte(); err != nil || c != '>' {
+				return token, errors.New("xml: expected /> in <" + token.Name + ">")
+			}
+			token.SelfClosing = true
+			return token, nil
+		}
+
+		x.r.UnreadByte()
+		attrName, err := x.readName()
+		if err != nil {
+			return token, err
+		}
+		if c, err := x.skipSpace(); err != nil || c != '=' {
+			return token, errors.New("xml: expected = after attribute " + attrName)
+		}
+		quote, err := x.skipSpace()
+		if err != nil || (quote != '"' && quote != '\'') {
+			return token, errors.New("xml: unquoted value of attribute " + attrName)
+		}
+		value, err := x.readUntil(quote)
+		if err != nil {
+			return token, errUnexpectedEOF
+		}
+		token.Attrs = append(token.Attrs, xmlAttr{Name: attrName, Value: unescapeXML(value)})
+	}
+}
+
+// readName reads a tag or attribute name
+func (x *xmlReader) readName() (string, error) {
+	var name strings.Builder
+	for {
+		c, err := x.r.ReadByte()
+		if err != nil {
+			return "", errUnexpectedEOF
+		}
+		if c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '=' || c == '>' || c == '/' {
+			x.r.UnreadByte()
+			if name.Len() == 0 {
+				return "", errors.New("xml: expected a name")
+			}
+			return name.String(), nil
+		}
+		name.WriteByte(c)
+	}
+}
+
+// skipSpace skips whitespace and returns the next byte
+func (x *xmlReader) skipSpace() (byte, error) {
+	for {
+		c, err := x.r.ReadByte()
+		if err != nil || (c != ' ' && c != '\t' && c != '\r' && c != '\n') {
+			return c, err
+		}
+	}
+}
+
+// readUntil reads up to and including delim and returns the text before it
+func (x *xmlReader) readUntil(delim byte) (string, error) {
+	text, err := x.r.ReadString(delim)
+	if err != nil {
+		return text, err
+	}
+	return text[:len(text)-1], nil
+}
+
+// skipPast discards input up to and including end
+func (x *xmlReader) skipPast(end string) error {
+	var window []byte
+	for {
+		c, err := x.r.ReadByte()
+		if err != nil {
+			return errUnexpectedEOF
+		}
+		window = append(window, c)
+		if len(window) > len(end) {
+			window = window[1:]
+		}
+		if string(window) == end {
+			return nil
+		}
+	}
+}
+
+// text returns the character data directly inside the element opened by start
+func (x *xmlReader) text(start xmlToken) (string, error) {
+	if start.SelfClosing {
+		return "", nil
+	}
+	var text strings.Builder
+	for {
+		token, err := x.next()
+		if err != nil {
+			return "", unexpectedEOF(err)
+		}
+		switch token.Kind {
+		case xmlTextToken:
+			text.WriteString(token.Text)
+		case xmlStartToken:
+			if err := x.skip(token); err != nil {
+				return "", err
+			}
+		case xmlEndToken:
+			return text.String(), nil
+		}
+	}
+}
+
+// skip discards the element opened by start
+func (x *xmlReader) skip(start xmlToken) error {
+	_, err := x.raw(start)
+	return err
+}
+
+// raw returns the element opened by start re-encoded as XML
+func (x *xmlReader) raw(start xmlToken) (string, error) {
+	b := appendStartTag(nil, start)
+	if start.SelfClosing {
+		return string(b), nil
+	}
+	for {
+		token, err := x.next()
+		if err != nil {
+			return "", unexpectedEOF(err)
+		}
+		switch token.Kind {
+		case xmlTextToken:
+			b = appendEscaped(b, token.Text)
+		case xmlStartToken:
+			inner, err := x.raw(token)
+			if err != nil {
+				return "", err
+			}
+			b = append(b, inner...)
+		case xmlEndToken:
+			b = append(b, "</"+start.Name+">"...)
+			return string(b), nil
+		}
+	}
+}
+
+// readRoot returns the document's root start tag
+func (x *xmlReader) readRoot(name string) (xmlToken, error) {
+	for {
+		token, err := x.next()
+		if err != nil {
+			return token, unexpectedEOF(err)
+		}
+		if token.Kind != xmlStartToken {
+			continue
+		}
+		if token.Name != name {
+			return token, errors.New("xml: expected root element <" + name + ">, found <" + token.Name + ">")
+		}
+		return token, nil
+	}
+}
+
+// unexpectedEOF turns io.EOF inside an element into a syntax error
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return errUnexpectedEOF
+	}
+	return err
+}
+
+// unescapeXML replaces the predefined and numeric character references in s
+func unescapeXML(s string) string {
+	if !strings.Contains(s, "&") {
+		return s
+	}
+	var b []byte
+	for len(s) > 0 {
+		amp := strings.IndexByte(s, '&')
+		semi := strings.IndexByte(s, ';')
+		if amp < 0 || semi < amp {
+			b = append(b, s...)
+			break
+		}
+		b = append(b, s[:amp]...)
+		entity := s[amp+1 : semi]
+		s = s[semi+1:]
+		switch entity {
+		case "lt":
+			b = append(b, '<')
+		case "gt":
+			b = append(b, '>')
+		case "amp":
+			b = append(b, '&')
+		case "apos":
+			b = append(b, '\'')
+		case "quot":
+			b = append(b, '"')
+		default:
+			if r, ok := parseCharRef(entity); ok {
+				b = utf8.AppendRune(b, r)
+			} else {
+				b = append(b, "&"+entity+";"...)
+			}
+		}
+	}
+	return string(b)
+}
+
+// parseCharRef decodes the body of a character reference such as #38 or #x26
+func parseCharRef(entity string) (rune, bool) {
+	if !strings.HasPrefix(entity, "#") {
+		return 0, false
+	}
+	base, digits := 10, entity[1:]
+	if strings.HasPrefix(digits, "x") {
+		base, digits = 16, digits[1:]
+	}
+	n, err := strconv.ParseUint(digits, base, 32)
+	if err != nil {
+		return 0, false
+	}
+	return rune(n), true
+}
+
+// appendEscaped appends s with the characters that are special in XML escaped
+func appendEscaped(b []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '<':
+			b = append(b, "&lt;"...)
+		case '>':
+			b = append(b, "&gt;"...)
+		case '&':
+			b = append(b, "&amp;"...)
+		case '"':
+			b = append(b, "&quot;"...)
+		default:
+			b = append(b, s[i])
+		}
+	}
+	return b
+}
+
+// appendStartTag appends a start tag with its attributes
+func appendStartTag(b []byte, token xmlToken) []byte {
+	b = append(b, '<')
+	b = append(b, token.Name...)
+	for _, attr := range token.Attrs {
+		b = appendAttr(b, attr.Name, attr.Value)
+	}
+	if token.SelfClosing {
+		return append(b, "/>"...)
+	}
+	return append(b, '>')
+}
+
+// appendAttr appends name="value"
+func appendAttr(b []byte, name, value string) []byte {
+	b = append(b, ' ')
+	b = append(b, name...)
+	b = append(b, '=', '"')
+	b = appendEscaped(b, value)
+	return append(b, '"')
+}
+
+// appendTextElement appends an element holding only text
+func appendTextElement(b []byte, name, text string) []byte {
+	b = append(b, '<')
+	b = append(b, name...)
+	b = append(b, '>')
+	b = appendEscaped(b, text)
+	b = append(b, "</"...)
+	b = append(b, name...)
+	return append(b, '>')
+}
+`
+
+// codecField is a struct field with the XML mapping the hand-rolled codecs implement
+type codecField struct {
+	goField
+	XMLName   string
+	Attr      bool
+	Text      bool // ,chardata
+	Inner     bool // ,innerxml
+	OmitEmpty bool
+	Enum      bool   // Attribute typed as a generated integer enum
+	Strings   bool   // Attribute typed as a generated string enum
+	Tokenized bool   // Attribute whose declared type is not CDATA
+	Fixed     string // Go string expression of the value of a #FIXED attribute, always written
+}
+
+// codecFields classifies the fields of an element's struct by their xml tags
+func (g *StructGenerator) codecFields(element *DTDElement) []codecField {
+	enums := make(map[string]bool) // Whether each enum type is a string one
+	for _, enum := range g.enumTypes() {
+		enums[enum.Name] = enum.Strings
+	}
+
+	tokenized := make(map[string]bool)
+	for _, attr := range element.Attributes {
+		tokenized[attr.Name] = isTokenizedAttributeType(attr.Type)
+	}
+	fixed := make(map[string]string)
+	for _, attr := range g.fixedAttributes(element) {
+		fixed[attr.Attr.Name] = attr.stringValue()
+	}
+
+	var fields []codecField
+	for _, field := range g.structFields(element) {
+		parts := strings.Split(field.Tag, ",")
+		stringEnum, enum := enums[strings.TrimPrefix(field.Type, "*")]
+		codec := codecField{goField: field, XMLName: parts[0], Enum: enum && !stringEnum, Strings: stringEnum}
+		for _, option := range parts[1:] {
+			switch option {
+			case "attr":
+				codec.Attr = true
+				codec.Tokenized = tokenized[codec.XMLName]
+				codec.Fixed = fixed[codec.XMLName]
+			case "chardata":
+				codec.Text = true
+			case "innerxml", "any":
+				codec.Inner = true
+			case "omitempty":
+				codec.OmitEmpty = true
+			}
+		}
+		fields = append(fields, codec)
+	}
+	return fields
+}
+
+// generateTinyGoCodecs generates hand-rolled decoders and encoders for every struct,
+// plus ParseX and WriteXML for the document roots, without reflection or maps
+func (g *StructGenerator) generateTinyGoCodecs() string {
+	var builder strings.Builder
+
+	for _, name := range g.elementOrder {
+		element, exists := g.elements[name]
+		if !exists || g.isSimpleElement(name) {
+			continue
+		}
+		fields := g.codecFields(element)
+		builder.WriteString(g.generateTinyDecoder(element, fields))
+		builder.WriteString(g.generateTinyEncoder(element, fields))
+	}
+
+	for _, name := range g.documentRoots() {
+		structName := g.toGoStructName(name)
+		builder.WriteString(fmt.Sprintf("\n// Parse%s decodes a <%s> document\n", structName, name))
+		builder.WriteString(fmt.Sprintf("func Parse%s(r io.Reader) (*%s, error) {\n", structName, structName))
+		builder.WriteString("\tx := &xmlReader{r: bufio.NewReader(r)}\n")
+		builder.WriteString(fmt.Sprintf("\tstart, err := x.readRoot(%q)\n", name))
+		builder.WriteString("\tif err != nil {\n")
+		builder.WriteString("\t\treturn nil, err\n")
+		builder.WriteString("\t}\n")
+		builder.WriteString(fmt.Sprintf("\tv := &%s{}\n", structName))
+		builder.WriteString("\tif err := v.decodeXML(x, start); err != nil {\n")
+		builder.WriteString("\t\treturn nil, err\n")
+		builder.WriteString("\t}\n")
+		builder.WriteString("\treturn v, nil\n")
+		builder.WriteString("}\n")
+
+		builder.WriteString(fmt.Sprintf("\n// WriteXML writes the <%s> document to w\n", name))
+		builder.WriteString(fmt.Sprintf("func (v *%s) WriteXML(w io.Writer) error {\n", structName))
+		builder.WriteString("\t_, err := w.Write(v.AppendXML(nil))\n")
+		builder.WriteString("\treturn err\n")
+		builder.WriteString("}\n")
+	}
+
+	builder.WriteString(tinyXMLRuntime)
+
+	return builder.String()
+}
+
+// generateTinyDecoder generates the decodeXML method of an element's struct
+func (g *StructGenerator) generateTinyDecoder(element *DTDElement, fields []codecField) string {
+	var builder strings.Builder
+	structName := g.toGoStructName(element.Name)
+
+	builder.WriteString(fmt.Sprintf("\n// decodeXML decodes the <%s> element opened by start\n", element.Name))
+	builder.WriteString(fmt.Sprintf("func (v *%s) decodeXML(x *xmlReader, start xmlToken) error {\n", structName))
+
+	var attrs, children []codecField
+	var text, inner *codecField
+	for i := range fields {
+		switch field := &fields[i]; {
+		case field.Attr:
+			attrs = append(attrs, *field)
+		case field.Text:
+			text = field
+		case field.Inner:
+			inner = field
+		default:
+			children = append(children, *field)
+		}
+	}
+
+	if len(attrs) > 0 {
+		builder.WriteString("\tfor _, attr := range start.Attrs {\n")
+		builder.WriteString("\t\tswitch attr.Name {\n")
+		for _, field := range attrs {
+			builder.WriteString(fmt.Sprintf("\t\tcase %q:\n", field.XMLName))
+			value := "attr.Value"
+			if g.options.NormalizeAttrs && field.Tokenized {
+				value = "normalizeTokens(attr.Value)"
+			} else if g.options.NormalizeAttrs {
+				value = "normalizeCDATA(attr.Value)"
+			}
+			switch {
+			case field.Enum:
+				builder.WriteString(fmt.Sprintf("\t\t\tparsed, err := Parse%s(%s)\n", strings.TrimPrefix(field.Type, "*"), value))
+				builder.WriteString("\t\t\tif err != nil {\n")
+				if g.options.ViolationHooks {
+					builder.WriteString(fmt.Sprintf("\t\t\t\trecordViolation(%q, ViolationEnumeration)\n", element.Name))
+				}
+				builder.WriteString("\t\t\t\treturn err\n")
+				builder.WriteString("\t\t\t}\n")
+				if strings.HasPrefix(field.Type, "*") {
+					builder.WriteString(fmt.Sprintf("\t\t\tv.%s = &parsed\n", field.Name))
+				} else {
+					builder.WriteString(fmt.Sprintf("\t\t\tv.%s = parsed\n", field.Name))
+				}
+			case field.Type == "[]string":
+				builder.WriteString(fmt.Sprintf("\t\t\tv.%s = strings.Fields(attr.Value)\n", field.Name))
+			case field.Strings:
+				builder.WriteString(fmt.Sprintf("\t\t\tv.%s = %s(%s)\n", field.Name, field.Type, value))
+			default:
+				builder.WriteString(fmt.Sprintf("\t\t\tv.%s = %s\n", field.Name, value))
+			}
+		}
+		builder.WriteString("\t\t}\n")
+		builder.WriteString("\t}\n")
+	}
+
+	builder.WriteString("\tif start.SelfClosing {\n")
+	builder.WriteString("\t\treturn nil\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\tfor {\n")
+	builder.WriteString("\t\ttoken, err := x.next()\n")
+	builder.WriteString("\t\tif err != nil {\n")
+	builder.WriteString("\t\t\treturn unexpectedEOF(err)\n")
+	builder.WriteString("\t\t}\n")
+	builder.WriteString("\t\tswitch token.Kind {\n")
+
+	builder.WriteString("\t\tcase xmlStartToken:\n")
+	if inner != nil {
+		builder.WriteString("\t\t\traw, err := x.raw(token)\n")
+		builder.WriteString("\t\t\tif err != nil {\n")
+		builder.WriteString("\t\t\t\treturn err\n")
+		builder.WriteString("\t\t\t}\n")
+		builder.WriteString(fmt.Sprintf("\t\t\tv.%s += raw\n", inner.Name))
+	} else {
+		builder.WriteString("\t\t\tswitch token.Name {\n")
+		for _, field := range children {
+			builder.WriteString(fmt.Sprintf("\t\t\tcase %q:\n", field.XMLName))
+			elementType := strings.TrimLeft(field.Type, "[]*")
+			if elementType == "Presence" {
+				builder.WriteString("\t\t\t\tif err := x.skip(token); err != nil {\n")
+				builder.WriteString("\t\t\t\t\treturn err\n")
+				builder.WriteString("\t\t\t\t}\n")
+				if strings.HasPrefix(field.Type, "[]") {
+					builder.WriteString(fmt.Sprintf("\t\t\t\tv.%s = append(v.%s, true)\n", field.Name, field.Name))
+				} else {
+					builder.WriteString(fmt.Sprintf("\t\t\t\tv.%s = true\n", field.Name))
+				}
+				continue
+			}
+			if elementType == "string" {
+				builder.WriteString("\t\t\t\tchild, err := x.text(token)\n")
+			} else {
+				builder.WriteString(fmt.Sprintf("\t\t\t\tchild := %s{}\n", elementType))
+				builder.WriteString("\t\t\t\terr := child.decodeXML(x, token)\n")
+			}
+			builder.WriteString("\t\t\t\tif err != nil {\n")
+			builder.WriteString("\t\t\t\t\treturn err\n")
+			builder.WriteString("\t\t\t\t}\n")
+			switch {
+			case strings.HasPrefix(field.Type, "[]"):
+				builder.WriteString(fmt.Sprintf("\t\t\t\tv.%s = append(v.%s, child)\n", field.Name, field.Name))
+			case strings.HasPrefix(field.Type, "*"):
+				builder.WriteString(fmt.Sprintf("\t\t\t\tv.%s = &child\n", field.Name))
+			default:
+				builder.WriteString(fmt.Sprintf("\t\t\t\tv.%s = child\n", field.Name))
+			}
+		}
+		builder.WriteString("\t\t\tdefault:\n")
+		builder.WriteString("\t\t\t\tif err := x.skip(token); err != nil {\n")
+		builder.WriteString("\t\t\t\t\treturn err\n")
+		builder.WriteString("\t\t\t\t}\n")
+		builder.WriteString("\t\t\t}\n")
+	}
+
+	if text != nil || inner != nil {
+		builder.WriteString("\t\tcase xmlTextToken:\n")
+		if inner != nil {
+			builder.WriteString(fmt.Sprintf("\t\t\tv.%s += string(appendEscaped(nil, token.Text))\n", inner.Name))
+		}
+		if text != nil {
+			builder.WriteString(fmt.Sprintf("\t\t\tv.%s += token.Text\n", text.Name))
+		}
+	}
+
+	builder.WriteString("\t\tcase xmlEndToken:\n")
+	builder.WriteString("\t\t\treturn nil\n")
+	builder.WriteString("\t\t}\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("}\n")
+
+	return builder.String()
+}
+
+// generateTinyEncoder generates the AppendXML method of an element's struct
+func (g *StructGenerator) generateTinyEncoder(element *DTDElement, fields []codecField) string {
+	var builder strings.Builder
+	structName := g.toGoStructName(element.Name)
+
+	builder.WriteString(fmt.Sprintf("\n// AppendXML appends the <%s> element to b\n", element.Name))
+	builder.WriteString(fmt.Sprintf("func (v *%s) AppendXML(b []byte) []byte {\n", structName))
+	builder.WriteString(fmt.Sprintf("\tb = append(b, \"<%s\"...)\n", element.Name))
+
+	for _, field := range fields {
+		if !field.Attr {
+			continue
+		}
+		value := "v." + field.Name
+		switch {
+		case field.Fixed != "":
+			builder.WriteString(fmt.Sprintf("\tb = appendAttr(b, %q, %s)\n", field.XMLName, field.Fixed))
+			continue
+		case field.Enum && strings.HasPrefix(field.Type, "*"):
+			builder.WriteString(fmt.Sprintf("\tif v.%s != nil {\n", field.Name))
+			builder.WriteString(fmt.Sprintf("\t\tb = appendAttr(b, %q, v.%s.String())\n", field.XMLName, field.Name))
+			builder.WriteString("\t}\n")
+			continue
+		case field.Enum:
+			builder.WriteString(fmt.Sprintf("\tif v.%s != 0 {\n", field.Name))
+			builder.WriteString(fmt.Sprintf("\t\tb = appendAttr(b, %q, v.%s.String())\n", field.XMLName, field.Name))
+			builder.WriteString("\t}\n")
+			continue
+		case field.Type == "[]string":
+			value = fmt.Sprintf("strings.Join(v.%s, \" \")", field.Name)
+		case field.Strings:
+			value = fmt.Sprintf("string(v.%s)", field.Name)
+		}
+		if field.OmitEmpty {
+			builder.WriteString(fmt.Sprintf("\tif len(v.%s) > 0 {\n", field.Name))
+			builder.WriteString(fmt.Sprintf("\t\tb = appendAttr(b, %q, %s)\n", field.XMLName, value))
+			builder.WriteString("\t}\n")
+		} else {
+			builder.WriteString(fmt.Sprintf("\tb = appendAttr(b, %q, %s)\n", field.XMLName, value))
+		}
+	}
+	if g.isEmptyElement(element.Name) {
+		builder.WriteString("\treturn append(b, \"/>\"...)\n")
+		builder.WriteString("}\n")
+		return builder.String()
+	}
+	builder.WriteString("\tb = append(b, '>')\n")
+
+	for _, field := range fields {
+		switch {
+		case field.Attr:
+		case field.Text:
+			builder.WriteString(fmt.Sprintf("\tb = appendEscaped(b, v.%s)\n", field.Name))
+		case field.Inner:
+			builder.WriteString(fmt.Sprintf("\tb = append(b, v.%s...)\n", field.Name))
+		case field.Type == "*string":
+			builder.WriteString(fmt.Sprintf("\tif v.%s != nil {\n", field.Name))
+			builder.WriteString(fmt.Sprintf("\t\tb = appendTextElement(b, %q, *v.%s)\n", field.XMLName, field.Name))
+			builder.WriteString("\t}\n")
+		case field.Type == "string":
+			builder.WriteString(fmt.Sprintf("\tb = appendTextElement(b, %q, v.%s)\n", field.XMLName, field.Name))
+		case field.Type == "[]string":
+			builder.WriteString(fmt.Sprintf("\tfor _, child := range v.%s {\n", field.Name))
+			builder.WriteString(fmt.Sprintf("\t\tb = appendTextElement(b, %q, child)\n", field.XMLName))
+			builder.WriteString("\t}\n")
+		case field.Type == "Presence":
+			builder.WriteString(fmt.Sprintf("\tif v.%s {\n", field.Name))
+			builder.WriteString(fmt.Sprintf("\t\tb = append(b, \"<%s/>\"...)\n", field.XMLName))
+			builder.WriteString("\t}\n")
+		case field.Type == "[]Presence":
+			builder.WriteString(fmt.Sprintf("\tfor _, present := range v.%s {\n", field.Name))
+			builder.WriteString("\t\tif present {\n")
+			builder.WriteString(fmt.Sprintf("\t\t\tb = append(b, \"<%s/>\"...)\n", field.XMLName))
+			builder.WriteString("\t\t}\n")
+			builder.WriteString("\t}\n")
+		case strings.HasPrefix(field.Type, "[]"):
+			builder.WriteString(fmt.Sprintf("\tfor i := range v.%s {\n", field.Name))
+			builder.WriteString(fmt.Sprintf("\t\tb = v.%s[i].AppendXML(b)\n", field.Name))
+			builder.WriteString("\t}\n")
+		case strings.HasPrefix(field.Type, "*"):
+			builder.WriteString(fmt.Sprintf("\tif v.%s != nil {\n", field.Name))
+			builder.WriteString(fmt.Sprintf("\t\tb = v.%s.AppendXML(b)\n", field.Name))
+			builder.WriteString("\t}\n")
+		default:
+			builder.WriteString(fmt.Sprintf("\tb = v.%s.AppendXML(b)\n", field.Name))
+		}
+	}
+
+	builder.WriteString(fmt.Sprintf("\treturn append(b, \"</%s>\"...)\n", element.Name))
+	builder.WriteString("}\n")
+
+	return builder.String()
+}
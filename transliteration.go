@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Transliteration maps letters, or sequences of them, in DTD names to the ASCII that
+// replaces them in Go identifiers, such as "ü" to "ue"
+type Transliteration map[string]string
+
+// latinTransliterations replaces the letters of Latin-1 and Latin Extended-A outside ASCII,
+// grouped by their replacement, so names such as résumé or Straße form the ASCII Go
+// identifiers Resume and Strasse
+var latinTransliterations = map[string]string{
+	"ÀÁÂÃÄÅĀĂĄ": "A", "àáâãäåāăą": "a", "Æ": "AE", "æ": "ae",
+	"ÇĆĈĊČ": "C", "çćĉċč": "c",
+	"ÐĎĐ": "D", "ðďđ": "d",
+	"ÈÉÊËĒĔĖĘĚ": "E", "èéêëēĕėęě": "e",
+	"ĜĞĠĢ": "G", "ĝğġģ": "g",
+	"ĤĦ": "H", "ĥħ": "h",
+	"ÌÍÎÏĨĪĬĮİ": "I", "ìíîïĩīĭįı": "i", "Ĳ": "IJ", "ĳ": "ij",
+	"Ĵ": "J", "ĵ": "j",
+	"Ķ": "K", "ķĸ": "k",
+	"ĹĻĽĿŁ": "L", "ĺļľŀł": "l",
+	"ÑŃŅŇ": "N", "ñńņňŉ": "n", "Ŋ": "NG", "ŋ": "ng",
+	"ÒÓÔÕÖØŌŎŐ": "O", "òóôõöøōŏő": "o", "Œ": "OE", "œ": "oe",
+	"ŔŖŘ": "R", "ŕŗř": "r",
+	"ŚŜŞŠ": "S", "śŝşšſ": "s", "ß": "ss",
+	"ŢŤŦ": "T", "ţťŧ": "t", "Þ": "TH", "þ": "th",
+	"ÙÚÛÜŨŪŬŮŰŲ": "U", "ùúûüũūŭůűų": "u",
+	"Ŵ": "W", "ŵ": "w",
+	"ÝŶŸ": "Y", "ýÿŷ": "y",
+	"ŹŻŽ": "Z", "źżž": "z",
+}
+
+// latinTransliterationTable is latinTransliterations by letter
+var latinTransliterationTable = func() map[rune]string {
+	table := make(map[rune]string)
+	for letters, replacement := range latinTransliterations {
+		for _, letter := range letters {
+			table[letter] = replacement
+		}
+	}
+	return table
+}()
+
+// identifierName returns a DTD name with its letters replaced for forming Go identifiers:
+// first by the configured Transliterate, longest match first, and from compatibility
+// level 2 on the remaining accented Latin letters by their ASCII transliteration. Other
+// letters are kept, as Go accepts them.
+func (g *StructGenerator) identifierName(name string) string {
+	if len(g.options.Transliterate) > 0 {
+		name = g.transliterator().Replace(name)
+	}
+	if g.options.Compat < 2 {
+		return name
+	}
+
+	var result strings.Builder
+	for _, r := range name {
+		if replacement, ok := latinTransliterationTable[r]; ok {
+			result.WriteString(replacement)
+		} else {
+			result.WriteRune(r)
+		}
+	}
+	return result.String()
+}
+
+// transliterator returns the replacer applying the configured Transliterate
+func (g *StructGenerator) transliterator() *strings.Replacer {
+	g.transliterationOnce.Do(func() {
+		// strings.Replacer tries the pairs in order, so longer sequences go first
+		var from []string
+		for sequence := range g.options.Transliterate {
+			from = append(from, sequence)
+		}
+		sort.Slice(from, func(i, j int) bool {
+			if len(from[i]) != len(from[j]) {
+				return len(from[i]) > len(from[j])
+			}
+			return from[i] < from[j]
+		})
+		var pairs []string
+		for _, sequence := range from {
+			pairs = append(pairs, sequence, g.options.Transliterate[sequence])
+		}
+		g.transliteration = strings.NewReplacer(pairs...)
+	})
+	return g.transliteration
+}
+
+// check rejects a transliteration that could not form Go identifiers: empty sequences, and
+// replacements other than ASCII letters, digits and _
+func (transliteration Transliteration) check() error {
+	for sequence, replacement := range transliteration {
+		if sequence == "" {
+			return fmt.Errorf("transliteration of an empty sequence")
+		}
+		for _, r := range replacement {
+			if r >= unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_') {
+				return fmt.Errorf("transliteration of %q to %q: replacements must be ASCII letters, digits or _", sequence, replacement)
+			}
+		}
+	}
+	return nil
+}
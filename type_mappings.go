@@ -0,0 +1,396 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// mappedBasicTypes are the Go types besides string the types section of the generator
+// config can give a field, with their bit sizes, which encoding/xml parses and formats
+// itself
+var mappedBasicTypes = map[string]int{
+	"bool":    0,
+	"int":     0,
+	"int8":    8,
+	"int16":   16,
+	"int32":   32,
+	"int64":   64,
+	"uint":    0,
+	"uint8":   8,
+	"uint16":  16,
+	"uint32":  32,
+	"uint64":  64,
+	"float32": 32,
+	"float64": 64,
+}
+
+// timeType is the qualified type a layout applies to
+const timeType = "time.Time"
+
+// TypeMapping gives the Go type of an element's text or an attribute in place of string,
+// such as float64 for a price. The field is named as for redaction.
+type TypeMapping struct {
+	Field  string `json:"field"`
+	Type   string `json:"type"`   // A basic type such as float64, or a qualified one such as time.Time
+	Layout string `json:"layout"` // Layout of time.Time values as for time.Parse, RFC 3339 if empty
+}
+
+// target splits the field into its element and attribute, as for RedactionRule
+func (m TypeMapping) target() (element, attribute string) {
+	return fieldTarget(m.Field)
+}
+
+// qualified splits a qualified type such as github.com/shopspring/decimal.Decimal into its
+// import path and type name, reporting false for the basic types
+func (m TypeMapping) qualified() (path, name string, ok bool) {
+	i := strings.LastIndex(m.Type, ".")
+	if i <= 0 || i < strings.LastIndex(m.Type, "/") {
+		return "", "", false
+	}
+	return m.Type[:i], m.Type[i+1:], true
+}
+
+// checkTypeMappings reports the type mappings of a generator config that are malformed:
+// fields that are not element, element@attribute or @attribute, listed twice, types that
+// are neither basic nor qualified, and layouts for types other than time.Time
+func checkTypeMappings(mappings []TypeMapping) error {
+	seen := make(map[string]bool)
+	for _, mapping := range mappings {
+		if !validFieldTarget(mapping.Field) {
+			return fmt.Errorf("types field %q is not element, element@attribute or @attribute", mapping.Field)
+		}
+		if seen[mapping.Field] {
+			return fmt.Errorf("types field %q is listed twice", mapping.Field)
+		}
+		seen[mapping.Field] = true
+		if _, basic := mappedBasicTypes[mapping.Type]; !basic {
+			path, name, ok := mapping.qualified()
+			if !ok || strings.ContainsAny(path, " \t\"") || !token.IsIdentifier(name) || !token.IsExported(name) {
+				return fmt.Errorf("unknown type %q for %s (expected a basic type such as float64 or int, or a qualified type such as time.Time)", mapping.Type, mapping.Field)
+			}
+		}
+		if mapping.Layout != "" && mapping.Type != timeType {
+			return fmt.Errorf("types %s: a layout only applies to time.Time, not %s", mapping.Field, mapping.Type)
+		}
+	}
+	return nil
+}
+
+// CheckTypeMappings reports the type mappings that do not fit the schema: elements that
+// do not hold text only, undeclared attributes, attributes whose values are tokens the
+// generator handles itself, such as enumerated, ID and list attributes, #FIXED attributes,
+// defaults the type cannot hold, and fields that are also redacted, which needs strings
+func CheckTypeMappings(result *ParseResult, mappings []TypeMapping, redactions []RedactionRule) error {
+	redacted := make(map[string]string) // element or element@attribute to the redaction field
+	for _, rule := range redactions {
+		name, attribute := rule.target()
+		if attribute == "" {
+			redacted[name] = rule.Field
+			continue
+		}
+		for _, elementName := range result.Order {
+			if element, exists := result.Elements[elementName]; exists && (name == "" || name == elementName) {
+				for _, attr := range element.Attributes {
+					if attr.Name == attribute {
+						redacted[elementName+"@"+attribute] = rule.Field
+					}
+				}
+			}
+		}
+	}
+
+	covered := make(map[string]string) // element@attribute to the field of the mapping typing it
+	for _, mapping := range mappings {
+		name, attribute := mapping.target()
+		if attribute == "" {
+			element, exists := result.Elements[name]
+			if !exists {
+				return fmt.Errorf("types %s: %w", mapping.Field, &GenerateError{Element: name, Err: ErrUndeclaredElement})
+			}
+			if !isTextOnly(element) {
+				return fmt.Errorf("types %s: <%s> does not hold text only (content %s)", mapping.Field, name, element.Content)
+			}
+			if rule, ok := redacted[name]; ok {
+				return fmt.Errorf("types %s: <%s> is redacted by %s, which needs its text to be a string", mapping.Field, name, rule)
+			}
+			continue
+		}
+
+		found := false
+		for _, elementName := range result.Order {
+			element, exists := result.Elements[elementName]
+			if !exists || (name != "" && name != elementName) {
+				continue
+			}
+			for _, attr := range element.Attributes {
+				if attr.Name != attribute {
+					continue
+				}
+				found = true
+				key := elementName + "@" + attribute
+				if other, ok := covered[key]; ok {
+					return fmt.Errorf("types %s: attribute %s of <%s> is already typed by %s", mapping.Field, attribute, elementName, other)
+				}
+				covered[key] = mapping.Field
+				if rule, ok := redacted[key]; ok {
+					return fmt.Errorf("types %s: attribute %s of <%s> is redacted by %s, which needs it to be a string", mapping.Field, attribute, elementName, rule)
+				}
+				if err := checkTypedAttribute(mapping, element, attr); err != nil {
+					return err
+				}
+			}
+		}
+		if !found {
+			if name != "" {
+				return fmt.Errorf("types %s: attribute %s of <%s> is not declared", mapping.Field, attribute, name)
+			}
+			return fmt.Errorf("types %s: no element declares attribute %s", mapping.Field, attribute)
+		}
+	}
+	return nil
+}
+
+// checkTypedAttribute reports an attribute that cannot take the type of a mapping
+func checkTypedAttribute(mapping TypeMapping, element *DTDElement, attr DTDAttribute) error {
+	attrType := strings.ToUpper(attr.Type)
+	switch {
+	case len(attr.Values) > 0:
+		return fmt.Errorf("types %s: attribute %s of <%s> is enumerated, so it gets an enum type or a string", mapping.Field, attr.Name, element.Name)
+	case attrType != "CDATA" && attrType != "NMTOKEN":
+		return fmt.Errorf("types %s: attribute %s of <%s> is %s; only CDATA and NMTOKEN attributes can be typed", mapping.Field, attr.Name, element.Name, attr.Type)
+	case attr.Name == xmlIDAttribute:
+		return fmt.Errorf("types %s: attribute %s of <%s> is an ID, which must be a string", mapping.Field, attr.Name, element.Name)
+	case attr.Fixed:
+		return fmt.Errorf("types %s: attribute %s of <%s> is #FIXED %q, so it is kept as declared", mapping.Field, attr.Name, element.Name, attr.DefaultValue)
+	}
+	if attr.DefaultValue == "" || attr.Required || strings.HasPrefix(attr.DefaultValue, "#") {
+		return nil // No default to fill in
+	}
+	if _, basic := mappedBasicTypes[mapping.Type]; !basic {
+		return fmt.Errorf("types %s: attribute %s of <%s> has the default %q, which only basic types can be given", mapping.Field, attr.Name, element.Name, attr.DefaultValue)
+	}
+	if _, err := typedLiteral(mapping.Type, attr.DefaultValue); err != nil {
+		return fmt.Errorf("types %s: the default %q of attribute %s of <%s> is not a %s: %w", mapping.Field, attr.DefaultValue, attr.Name, element.Name, mapping.Type, err)
+	}
+	return nil
+}
+
+// isTextOnly reports whether an element's content model is (#PCDATA), without children
+func isTextOnly(element *DTDElement) bool {
+	model, err := ParseContentModel(element.Content)
+	return err == nil && model.Kind == ContentMixed && (model.Root == nil || len(model.Root.Children) == 0)
+}
+
+// typedLiteral returns the Go expression of a value of a basic type as encoding/xml parses
+// it, such as float64(0.5), or an error if the value is not one
+func typedLiteral(basicType, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	bits := mappedBasicTypes[basicType]
+	if bits == 0 {
+		bits = 64
+	}
+	switch {
+	case basicType == "bool":
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatBool(parsed), nil
+	case strings.HasPrefix(basicType, "int"):
+		parsed, err := strconv.ParseInt(value, 10, bits)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s(%d)", basicType, parsed), nil
+	case strings.HasPrefix(basicType, "uint"):
+		parsed, err := strconv.ParseUint(value, 10, bits)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s(%d)", basicType, parsed), nil
+	}
+	parsed, err := strconv.ParseFloat(value, bits)
+	if err != nil {
+		return "", err
+	}
+	if math.IsInf(parsed, 0) || math.IsNaN(parsed) {
+		return "", fmt.Errorf("%s has no Go literal", value)
+	}
+	return fmt.Sprintf("%s(%s)", basicType, strconv.FormatFloat(parsed, 'g', -1, bits)), nil
+}
+
+// typedField is an element's text or an attribute the types section of the generator
+// config gives a type
+type typedField struct {
+	Mapping *TypeMapping
+	Layout  string // Name of the type formatting and parsing the time in Mapping.Layout, if it has one
+	Used    bool   // A generated field holds it, so the package of its type is imported
+	Doc     string // What the field holds, for the doc comment of the layout type
+}
+
+// typedFields returns the fields the types section of the generator config gives a type,
+// by element for its text and by element@attribute. Each field with a layout gets a type
+// of its own, named like the enum types: after the struct and field, or after the element
+// for the text of elements generated as plain strings.
+func (g *StructGenerator) typedFields() map[string]typedField {
+	g.typedOnce.Do(func() {
+		g.typed = make(map[string]typedField)
+		if len(g.options.Types) == 0 {
+			return
+		}
+		taken := make(map[string]bool)
+		for _, name := range g.elementOrder {
+			if _, exists := g.elements[name]; exists && !g.isSimpleElement(name) {
+				taken[g.toGoStructName(name)] = true
+			}
+		}
+		for _, enum := range g.enumTypes() {
+			taken[enum.Name] = true
+		}
+		add := func(key, layout, doc string, mapping *TypeMapping, used bool) {
+			field := typedField{Mapping: mapping, Used: used, Doc: doc}
+			if mapping.Layout != "" {
+				field.Layout = layout
+				for n := 2; taken[field.Layout]; n++ {
+					field.Layout = fmt.Sprintf("%s%d", layout, n)
+				}
+				taken[field.Layout] = true
+			}
+			g.typed[key] = field
+		}
+
+		for _, name := range g.elementOrder {
+			element, exists := g.elements[name]
+			if !exists {
+				continue
+			}
+			if mapping := g.typeMapping(name, ""); mapping != nil {
+				if g.isSimpleElement(name) {
+					used := false
+					for _, parent := range g.parentsOf(name) {
+						if _, exists := g.elements[parent]; exists && !g.isSimpleElement(parent) {
+							used = true
+						}
+					}
+					add(name, g.toGoStructName(name), fmt.Sprintf("the text of <%s>", name), mapping, used)
+				} else {
+					add(name, g.toGoStructName(name)+"Text", fmt.Sprintf("the text of <%s>", name), mapping, true)
+				}
+			}
+			if g.isSimpleElement(name) {
+				continue
+			}
+			for _, attr := range element.Attributes {
+				if mapping := g.typeMapping(name, attr.Name); mapping != nil {
+					add(name+"@"+attr.Name, g.toGoStructName(name)+g.toGoFieldName(attr.Name),
+						fmt.Sprintf("the attribute %s of <%s>", attr.Name, name), mapping, true)
+				}
+			}
+		}
+	})
+	return g.typed
+}
+
+// typeMapping returns the mapping of the types section of the generator config for an
+// element's text, with attribute "", or for one of its attributes, or nil
+func (g *StructGenerator) typeMapping(element, attribute string) *TypeMapping {
+	for i, mapping := range g.options.Types {
+		name, attr := mapping.target()
+		if attr == attribute && (name == element || (name == "" && attribute != "")) {
+			return &g.options.Types[i]
+		}
+	}
+	return nil
+}
+
+// typedType returns the Go type of a typed field: the type formatting its layout, the
+// basic type or the qualified type as the generated code refers to it
+func (g *StructGenerator) typedType(field typedField) string {
+	if field.Layout != "" {
+		return field.Layout
+	}
+	if path, name, ok := field.Mapping.qualified(); ok {
+		return g.imports.name(path) + "." + name
+	}
+	return field.Mapping.Type
+}
+
+// textType returns the Go type of an element's text: string unless the types section of
+// the generator config gives it another
+func (g *StructGenerator) textType(name string) string {
+	if field, ok := g.typedFields()[name]; ok {
+		return g.typedType(field)
+	}
+	return "string"
+}
+
+// typedAttribute returns the typed field of an attribute, if the types section of the
+// generator config gives it a type
+func (g *StructGenerator) typedAttribute(element *DTDElement, attr DTDAttribute) (typedField, bool) {
+	field, ok := g.typedFields()[element.Name+"@"+attr.Name]
+	return field, ok
+}
+
+// typeImports returns the packages the typed fields need: those of the qualified types
+// held by generated fields, and time and strings for the layout types
+func (g *StructGenerator) typeImports() []string {
+	var paths []string
+	for _, field := range g.typedFields() {
+		if field.Layout != "" {
+			paths = append(paths, "strings", "time")
+		} else if path, _, ok := field.Mapping.qualified(); ok && field.Used {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// generateLayoutTypes generates the types of the typed fields with a layout, which embed
+// time.Time and format and parse it in that layout
+func (g *StructGenerator) generateLayoutTypes() string {
+	var builder strings.Builder
+
+	var layouts []typedField
+	for _, name := range g.elementOrder {
+		element, exists := g.elements[name]
+		if !exists {
+			continue
+		}
+		if field, ok := g.typedFields()[name]; ok && field.Layout != "" {
+			layouts = append(layouts, field)
+		}
+		for _, attr := range element.Attributes {
+			if field, ok := g.typedAttribute(element, attr); ok && field.Layout != "" {
+				layouts = append(layouts, field)
+			}
+		}
+	}
+
+	for _, field := range layouts {
+		layout := field.Mapping.Layout
+		builder.WriteString(fmt.Sprintf("\n// %s is the time in %s, written in the layout %s\n", field.Layout, field.Doc, layout))
+		builder.WriteString(fmt.Sprintf("type %s struct {\n", field.Layout))
+		builder.WriteString("\ttime.Time\n")
+		builder.WriteString("}\n")
+
+		builder.WriteString(fmt.Sprintf("\n// MarshalText formats the time in the layout %s\n", layout))
+		builder.WriteString(fmt.Sprintf("func (t %s) MarshalText() ([]byte, error) {\n", field.Layout))
+		builder.WriteString(fmt.Sprintf("\treturn []byte(t.Format(%q)), nil\n", layout))
+		builder.WriteString("}\n")
+
+		builder.WriteString(fmt.Sprintf("\n// UnmarshalText parses the time in the layout %s\n", layout))
+		builder.WriteString(fmt.Sprintf("func (t *%s) UnmarshalText(text []byte) error {\n", field.Layout))
+		builder.WriteString(fmt.Sprintf("\tparsed, err := time.Parse(%q, strings.TrimSpace(string(text)))\n", layout))
+		builder.WriteString("\tif err != nil {\n")
+		builder.WriteString("\t\treturn err\n")
+		builder.WriteString("\t}\n")
+		builder.WriteString("\tt.Time = parsed\n")
+		builder.WriteString("\treturn nil\n")
+		builder.WriteString("}\n")
+	}
+
+	return builder.String()
+}
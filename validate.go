@@ -0,0 +1,394 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// validateChunkSize is the number of documents a validation worker takes at a time, so
+// batches of many small feed documents are not dominated by handing them out
+const validateChunkSize = 16
+
+// DocumentResult is the outcome of validating one document
+type DocumentResult struct {
+	File       string   `json:"file,omitempty"`
+	Valid      bool     `json:"valid"`
+	Violations []string `json:"violations,omitempty"` // Each prefixed with the line it was found on
+	Error      string   `json:"error,omitempty"`      // Set when the document could not be read or is not well-formed
+}
+
+// ValidationReport aggregates the results of validating a batch of documents
+type ValidationReport struct {
+	Documents  []DocumentResult `json:"documents"`
+	Valid      int              `json:"valid"`
+	Invalid    int              `json:"invalid"`
+	Unreadable int              `json:"unreadable"`
+	Violations int              `json:"violations"`
+}
+
+// Validator checks XML documents against the declarations of a parsed DTD. It is safe for
+// concurrent use.
+type Validator struct {
+	result   *ParseResult
+	models   map[string]*ContentModel
+	patterns map[string]*regexp.Regexp // Content models over child element names, see ContentModel.Regexp
+	entities map[string]string         // General entities documents may reference
+}
+
+// NewValidator prepares the content models of a DTD for validating documents. Elements
+// whose content model cannot be parsed accept any children.
+func NewValidator(result *ParseResult) *Validator {
+	v := &Validator{
+		result:   result,
+		models:   make(map[string]*ContentModel),
+		patterns: make(map[string]*regexp.Regexp),
+		entities: make(map[string]string),
+	}
+	for name, element := range result.Elements {
+		model, err := ParseContentModel(element.Content)
+		if err != nil {
+			continue
+		}
+		v.models[name] = model
+		v.patterns[name] = regexp.MustCompile(model.Regexp())
+	}
+	for name, entity := range result.General {
+		// External entities are not fetched; their references are accepted as empty
+		v.entities[name] = entity.Value
+	}
+	return v
+}
+
+// documentIDs tracks the ID attribute values of a document and its IDREF and IDREFS
+// values, which may point at IDs further on and are checked at the end of the document
+type documentIDs struct {
+	ids        map[string]bool
+	references []func(ids map[string]bool)
+}
+
+// validationFrame tracks an open element while validating
+type validationFrame struct {
+	name     string
+	line     int
+	children []string
+}
+
+// Validate checks one document: every element and attribute must be declared, required
+// attributes present, enumerated values respected, ID values unique, IDREF and IDREFS
+// values the ID of an element of the document and the children of every element allowed
+// by its content model. It returns the violations found, or an error if the document is
+// not well-formed.
+func (v *Validator) Validate(document io.Reader) ([]string, error) {
+	d := xml.NewDecoder(document)
+	d.Entity = v.entities
+	var violations []string
+	report := func(line int, format string, args ...any) {
+		violations = append(violations, fmt.Sprintf("line %d: ", line)+fmt.Sprintf(format, args...))
+	}
+
+	var stack []*validationFrame
+	rootSeen := false
+	ids := &documentIDs{ids: make(map[string]bool)}
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return violations, err
+		}
+		line, _ := d.InputPos()
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := qualifiedName(t.Name)
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, name)
+			} else if rootSeen {
+				return violations, fmt.Errorf("line %d: document has more than one root element", line)
+			}
+			rootSeen = true
+			stack = append(stack, &validationFrame{name: name, line: line})
+
+			element, declared := v.result.Elements[name]
+			if !declared {
+				report(line, "element <%s> is not declared", name)
+				continue
+			}
+			v.validateAttributes(element, t.Attr, ids, func(format string, args ...any) {
+				report(line, format, args...)
+			})
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return violations, fmt.Errorf("line %d: unexpected end element </%s>", line, qualifiedName(t.Name))
+			}
+			frame := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if pattern, ok := v.patterns[frame.name]; ok && !pattern.MatchString(childSequence(frame.children)) {
+				report(frame.line, "children of <%s> (%s) do not match its content model %s",
+					frame.name, strings.Join(frame.children, ", "), v.models[frame.name])
+			}
+		case xml.CharData:
+			if len(stack) == 0 || strings.TrimSpace(string(t)) == "" {
+				continue
+			}
+			name := stack[len(stack)-1].name
+			if model, ok := v.models[name]; ok && (model.Kind == ContentEmpty || model.Kind == ContentChildren) {
+				report(line, "element <%s> has text, but its content model %s does not allow it", name, model)
+			}
+		}
+	}
+	if !rootSeen {
+		return violations, fmt.Errorf("document has no root element")
+	}
+	if len(stack) > 0 {
+		return violations, fmt.Errorf("element <%s> is not closed", stack[len(stack)-1].name)
+	}
+	for _, check := range ids.references {
+		check(ids.ids)
+	}
+	return violations, nil
+}
+
+// validateAttributes checks the attributes of an element's start tag against its ATTLIST,
+// recording the ID values and references in ids
+func (v *Validator) validateAttributes(element *DTDElement, attrs []xml.Attr, ids *documentIDs, report func(format string, args ...any)) {
+	present := make(map[string]bool)
+	for _, attr := range attrs {
+		if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+			continue
+		}
+		name := qualifiedName(attr.Name)
+		present[name] = true
+
+		var declared *DTDAttribute
+		for i := range element.Attributes {
+			if element.Attributes[i].Name == name {
+				declared = &element.Attributes[i]
+				break
+			}
+		}
+		switch {
+		case declared == nil:
+			report("attribute %s of <%s> is not declared", name, element.Name)
+		case declared.Fixed && !fixedValueMatches(*declared, attr.Value):
+			report("attribute %s of <%s> is %q, but it is #FIXED %q", name, element.Name, attr.Value, declared.DefaultValue)
+		case len(declared.Values) > 0 && !strings.HasPrefix(declared.Type, "NOTATION") && !containsString(declared.Values, strings.TrimSpace(attr.Value)):
+			report("attribute %s of <%s> is %q, not one of %s", name, element.Name, attr.Value, strings.Join(declared.Values, ", "))
+		}
+		if declared == nil {
+			continue
+		}
+		switch strings.ToUpper(declared.Type) {
+		case "ID":
+			id := strings.TrimSpace(attr.Value)
+			if ids.ids[id] {
+				report("ID %q of <%s> is already the ID of another element", id, element.Name)
+			}
+			ids.ids[id] = true
+		case "IDREF", "IDREFS":
+			for _, reference := range strings.Fields(attr.Value) {
+				ids.references = append(ids.references, func(ids map[string]bool) {
+					if !ids[reference] {
+						report("attribute %s of <%s> refers to %q, which is not the ID of any element", name, element.Name, reference)
+					}
+				})
+			}
+		}
+	}
+	for _, attr := range element.Attributes {
+		if attr.Required && !present[attr.Name] {
+			report("required attribute %s of <%s> is missing", attr.Name, element.Name)
+		}
+	}
+}
+
+// fixedValueMatches reports whether value is the fixed value of a #FIXED attribute,
+// comparing with whitespace collapsed for declared types other than CDATA
+func fixedValueMatches(attr DTDAttribute, value string) bool {
+	if isTokenizedAttributeType(attr.Type) {
+		return strings.Join(strings.Fields(value), " ") == strings.Join(strings.Fields(attr.DefaultValue), " ")
+	}
+	return value == attr.DefaultValue
+}
+
+// childSequence formats child element names as ContentModel.Regexp patterns match them
+func childSequence(children []string) string {
+	var sequence strings.Builder
+	for _, child := range children {
+		sequence.WriteString(child)
+		sequence.WriteByte(',')
+	}
+	return sequence.String()
+}
+
+// containsString reports whether values holds value
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateFiles validates documents concurrently on the given number of workers, which
+// take the files in chunks, and reports the results in the order of files
+func (v *Validator) ValidateFiles(files []string, workers int) ValidationReport {
+	results := make([]DocumentResult, len(files))
+	chunks := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for start := range chunks {
+				for j := start; j < start+validateChunkSize && j < len(files); j++ {
+					results[j] = v.validateFile(files[j])
+				}
+			}
+		}()
+	}
+	for start := 0; start < len(files); start += validateChunkSize {
+		chunks <- start
+	}
+	close(chunks)
+	wg.Wait()
+
+	report := ValidationReport{Documents: results}
+	for _, result := range results {
+		switch {
+		case result.Error != "":
+			report.Unreadable++
+		case result.Valid:
+			report.Valid++
+		default:
+			report.Invalid++
+		}
+		report.Violations += len(result.Violations)
+	}
+	return report
+}
+
+// validateFile validates the document stored in a file
+func (v *Validator) validateFile(path string) DocumentResult {
+	result := DocumentResult{File: path}
+	file, err := os.Open(path)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer file.Close()
+
+	result.Violations, err = v.Validate(file)
+	if err != nil {
+		result.Error = err.Error()
+	}
+	result.Valid = err == nil && len(result.Violations) == 0
+	return result
+}
+
+// documentFiles expands the arguments of the validate subcommand into document files:
+// files as given, directories to the .xml files below them in lexical order
+func documentFiles(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		err = filepath.WalkDir(arg, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() && strings.EqualFold(filepath.Ext(path), ".xml") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// runValidate implements the "validate" subcommand. It exits with status 1 when a
+// document is invalid or cannot be read, and 2 when the DTD or arguments are unusable.
+func runValidate(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	inputFile := flags.String("input", "", "Path to the DTD to validate against (required)")
+	workers := flags.Int("workers", runtime.GOMAXPROCS(0), "Number of documents validated concurrently")
+	jsonReport := flags.Bool("json", false, "Write the report as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *inputFile == "" || flags.NArg() == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: %s validate -input <dtd-file> [-workers <n>] [-json] <xml-file-or-directory>...\n", os.Args[0])
+		return 2
+	}
+	if *workers < 1 {
+		fmt.Fprintf(os.Stderr, "-workers must be at least 1\n")
+		return 2
+	}
+
+	result, err := NewDTDParser(ParserOptions{}).ParseFile(*inputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing DTD file: %v\n", err)
+		return 2
+	}
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	files, err := documentFiles(flags.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	report := NewValidator(result).ValidateFiles(files, *workers)
+	if *jsonReport {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		fmt.Println(string(data))
+	} else {
+		for _, document := range report.Documents {
+			switch {
+			case document.Error != "":
+				fmt.Printf("ERROR %s: %s\n", document.File, document.Error)
+			case document.Valid:
+				fmt.Printf("PASS  %s\n", document.File)
+			default:
+				fmt.Printf("FAIL  %s: %d violations\n", document.File, len(document.Violations))
+			}
+			for _, violation := range document.Violations {
+				fmt.Printf("      %s\n", violation)
+			}
+		}
+		fmt.Printf("%d documents: %d valid, %d invalid, %d unreadable, %d violations\n",
+			len(report.Documents), report.Valid, report.Invalid, report.Unreadable, report.Violations)
+	}
+
+	if report.Invalid > 0 || report.Unreadable > 0 {
+		return 1
+	}
+	return 0
+}
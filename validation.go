@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// validationRuntime collects the violations the generated Validate methods find
+const validationRuntime = `
+// validationReport collects the violations Validate finds in a document
+type validationReport []error
+
+// addf records a violation
+func (r *validationReport) addf(format string, args ...any) {
+	*r = append(*r, fmt.Errorf(format, args...))
+}
+
+// alternativesSet returns how many alternatives of a choice are set
+func alternativesSet(set ...bool) int {
+	n := 0
+	for _, s := range set {
+		if s {
+			n++
+		}
+	}
+	return n
+}
+`
+
+// requires reports whether every sequence of elements the particle matches contains a
+// match of target, so target may not be left out. Particles are compared by their text,
+// which is unique for the exclusive choices.
+func (c *ContentParticle) requires(target *ContentParticle) bool {
+	if c.String() == target.String() {
+		return !c.optional()
+	}
+	if c.Indicator == '?' || c.Indicator == '*' || c.Kind != ParticleSequence {
+		return false
+	}
+	for _, child := range c.Children {
+		if child.requires(target) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateMethod returns the name of the Validate method of an element's struct, avoiding
+// the names of its fields
+func (g *StructGenerator) validateMethod(element *DTDElement) string {
+	method := "Validate"
+	for _, field := range g.structFields(element) {
+		if field.Name == method {
+			method += "Element"
+		}
+	}
+	return method
+}
+
+// fieldSet returns the Go condition that a child element field of the struct in v holds
+// an element, or "" for fields held by value, which always do
+func fieldSet(v string, field goField) string {
+	switch {
+	case strings.HasPrefix(field.Type, "[]"):
+		return fmt.Sprintf("len(%s.%s) > 0", v, field.Name)
+	case strings.HasPrefix(field.Type, "*"):
+		return fmt.Sprintf("%s.%s != nil", v, field.Name)
+	case field.Type == "Presence":
+		return fmt.Sprintf("bool(%s.%s)", v, field.Name)
+	}
+	return ""
+}
+
+// fieldUnset returns the negation of fieldSet
+func fieldUnset(v string, field goField) string {
+	switch {
+	case strings.HasPrefix(field.Type, "[]"):
+		return fmt.Sprintf("len(%s.%s) == 0", v, field.Name)
+	case strings.HasPrefix(field.Type, "*"):
+		return fmt.Sprintf("%s.%s == nil", v, field.Name)
+	case field.Type == "Presence":
+		return fmt.Sprintf("!%s.%s", v, field.Name)
+	}
+	return ""
+}
+
+// requiredAttributeCheck returns the statement reporting a #REQUIRED attribute of the
+// struct in v that is not set, or "" for fields that cannot tell
+func (g *StructGenerator) requiredAttributeCheck(element *DTDElement, attr DTDAttribute, field goField) string {
+	var unset string
+	switch {
+	case field.Type == "string" || g.isStringEnum(element, attr):
+		unset = fmt.Sprintf("v.%s == \"\"", field.Name)
+	case field.Type == "TokenList" || field.Type == "[]string":
+		unset = fmt.Sprintf("len(v.%s) == 0", field.Name)
+	case strings.HasPrefix(field.Type, "*"):
+		unset = fmt.Sprintf("v.%s == nil", field.Name)
+	case g.enumTypeName(element, attr) != "":
+		unset = fmt.Sprintf("v.%s == 0", field.Name) // The zero value of integer enums means absent
+	default:
+		return ""
+	}
+	return fmt.Sprintf("\tif %s {\n\t\treport.addf(\"<%s>: attribute %s is #REQUIRED but not set\")\n\t}\n", unset, element.Name, attr.Name)
+}
+
+// generateValidation generates a Validate method for every struct, checking the struct and
+// the elements inside it against the DTD, and the unexported validate methods doing so
+func (g *StructGenerator) generateValidation() string {
+	var builder strings.Builder
+
+	builder.WriteString(validationRuntime)
+
+	structs := make(map[string]bool) // Go types of the structs that can be validated
+	for _, name := range g.elementOrder {
+		if _, exists := g.elements[name]; exists && !g.isSimpleElement(name) && !g.isInlined(name) {
+			structs[g.toGoStructName(name)] = true
+		}
+	}
+
+	for _, name := range g.elementOrder {
+		element, exists := g.elements[name]
+		if !exists || !structs[g.toGoStructName(name)] {
+			continue
+		}
+		structName := g.toGoStructName(name)
+		fields := g.structFields(element)
+
+		builder.WriteString(fmt.Sprintf("\n// %s checks this <%s> and the elements inside it against the DTD: #REQUIRED\n", g.validateMethod(element), name))
+		builder.WriteString("// attributes are set, required children are present and occur as often as they must, and\n")
+		builder.WriteString("// at most one alternative of each choice is set. It returns every violation found, joined.\n")
+		builder.WriteString(fmt.Sprintf("func (v *%s) %s() error {\n", structName, g.validateMethod(element)))
+		builder.WriteString("\tvar report validationReport\n")
+		builder.WriteString("\tv.validate(&report)\n")
+		builder.WriteString("\treturn errors.Join(report...)\n")
+		builder.WriteString("}\n")
+
+		builder.WriteString(fmt.Sprintf("\n// validate adds the violations of this <%s> and the elements inside it to report\n", name))
+		builder.WriteString(fmt.Sprintf("func (v *%s) validate(report *validationReport) {\n", structName))
+		for i, attr := range element.Attributes {
+			if attr.Required {
+				builder.WriteString(g.requiredAttributeCheck(element, attr, fields[i]))
+			}
+		}
+
+		interfaces := g.choiceInterfaces(element)
+		groups := g.contentGroups(element)
+		choices := g.choiceGroups(element)
+		model, _ := ParseContentModel(element.Content) // Parses, as the struct has choices
+		byElement := make(map[string]goField)
+		for _, field := range fields[len(element.Attributes):] {
+			if field.Occurs != nil {
+				byElement[field.Element] = field
+			}
+		}
+
+		// Children that must occur, and as often as they must
+		for _, field := range fields[len(element.Attributes):] {
+			if field.Occurs == nil || choiceInterfaceOf(interfaces, field.Element) != nil || groupField(groups, field) != nil || choiceOf(choices, field.Element) != nil {
+				continue
+			}
+			repeated := strings.HasPrefix(field.Type, "[]")
+			switch {
+			case field.Occurs.Min == 1 && fieldSet("v", field) != "":
+				builder.WriteString(fmt.Sprintf("\tif %s {\n", fieldUnset("v", field)))
+				builder.WriteString(fmt.Sprintf("\t\treport.addf(\"<%s>: child <%s> is required but missing\")\n", name, field.Element))
+				builder.WriteString("\t}\n")
+			case field.Occurs.Min > 1 && repeated:
+				builder.WriteString(fmt.Sprintf("\tif len(v.%s) < %d {\n", field.Name, field.Occurs.Min))
+				builder.WriteString(fmt.Sprintf("\t\treport.addf(\"<%s>: has %%d <%s>, but at least %d are required\", len(v.%s))\n", name, field.Element, field.Occurs.Min, field.Name))
+				builder.WriteString("\t}\n")
+			}
+			if repeated && field.Occurs.Max != Unbounded {
+				builder.WriteString(fmt.Sprintf("\tif len(v.%s) > %d {\n", field.Name, field.Occurs.Max))
+				builder.WriteString(fmt.Sprintf("\t\treport.addf(\"<%s>: has %%d <%s>, but at most %d are allowed\", len(v.%s))\n", name, field.Element, field.Occurs.Max, field.Name))
+				builder.WriteString("\t}\n")
+			}
+		}
+
+		// Choices, whose alternatives exclude each other
+		for _, choice := range interfaces {
+			if model.Root.requires(choice.Group.Particle) {
+				builder.WriteString(fmt.Sprintf("\tif v.%s == nil {\n", choice.Field))
+				builder.WriteString(fmt.Sprintf("\t\treport.addf(\"<%s>: holds none of the alternatives of %s\")\n", name, choice.Group.Particle))
+				builder.WriteString("\t}\n")
+			}
+		}
+		for _, choice := range choices {
+			if choiceInterfaceOf(interfaces, choice.Alternatives[0][0]) != nil {
+				continue
+			}
+			var set []string
+			for _, alternative := range choice.Alternatives {
+				var present []string
+				for _, child := range alternative {
+					if field, ok := byElement[child]; ok && fieldSet("v", field) != "" {
+						present = append(present, fieldSet("v", field))
+					}
+				}
+				if len(present) > 0 {
+					set = append(set, strings.Join(present, " || "))
+				}
+			}
+			if len(set) != len(choice.Alternatives) {
+				continue // An alternative is held by value, so it always looks set
+			}
+			tooMany := fmt.Sprintf("\t\treport.addf(\"<%s>: holds %%d alternatives of %s, but only one is allowed\", n)\n", name, choice.Particle)
+			if !model.Root.requires(choice.Particle) {
+				builder.WriteString(fmt.Sprintf("\tif n := alternativesSet(%s); n > 1 {\n", strings.Join(set, ", ")))
+				builder.WriteString(tooMany)
+				builder.WriteString("\t}\n")
+				continue
+			}
+			builder.WriteString(fmt.Sprintf("\tswitch n := alternativesSet(%s); {\n", strings.Join(set, ", ")))
+			builder.WriteString("\tcase n > 1:\n")
+			builder.WriteString(tooMany)
+			builder.WriteString("\tcase n == 0:\n")
+			builder.WriteString(fmt.Sprintf("\t\treport.addf(\"<%s>: holds none of the alternatives of %s\")\n", name, choice.Particle))
+			builder.WriteString("\t}\n")
+		}
+
+		// The elements inside
+		for _, field := range fields[len(element.Attributes):] {
+			if code, ok := g.mixedDispatch(element, field, structs, "validate(report)"); ok {
+				builder.WriteString(code)
+				continue
+			}
+			if field.Occurs == nil {
+				continue
+			}
+			if code, ok := g.choiceDispatch(interfaces, field, "validate(report)"); ok {
+				builder.WriteString(code)
+				continue
+			}
+			if code, ok := g.groupDispatch(groups, field, structs, "validate(report)"); ok {
+				builder.WriteString(code)
+				continue
+			}
+			switch {
+			case strings.HasPrefix(field.Type, "*") && structs[field.Type[1:]]:
+				builder.WriteString(fmt.Sprintf("\tif v.%s != nil {\n", field.Name))
+				builder.WriteString(fmt.Sprintf("\t\tv.%s.validate(report)\n", field.Name))
+				builder.WriteString("\t}\n")
+			case structs[field.Type]:
+				builder.WriteString(fmt.Sprintf("\tv.%s.validate(report)\n", field.Name))
+			case strings.HasPrefix(field.Type, "[]") && structs[field.Type[2:]]:
+				builder.WriteString(fmt.Sprintf("\tfor i := range v.%s {\n", field.Name))
+				builder.WriteString(fmt.Sprintf("\t\tv.%s[i].validate(report)\n", field.Name))
+				builder.WriteString("\t}\n")
+			}
+		}
+		builder.WriteString("}\n")
+	}
+
+	return builder.String()
+}
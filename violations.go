@@ -0,0 +1,36 @@
+package main
+
+// violationsRuntime lets applications observe the constraint violations detected by the
+// generated code from one place instead of wrapping every decode and check
+const violationsRuntime = `
+// Rules reported to a ViolationRecorder
+const (
+	ViolationEnumeration  = "enumeration"   // An attribute value outside its enumeration
+	ViolationContentModel = "content-model" // Child elements not allowed by the content model
+	ViolationFixed        = "fixed"         // A #FIXED attribute with another value
+)
+
+// ViolationRecorder receives every constraint violation the generated decoders and checks
+// detect, labeled by element and rule, e.g. to count them in Prometheus
+type ViolationRecorder interface {
+	RecordViolation(element, rule string)
+}
+
+// violationRecorder holds the installed recorder, wrapped so atomic.Value accepts nil
+var violationRecorder atomic.Value
+
+type violationRecorderHolder struct{ r ViolationRecorder }
+
+// SetViolationRecorder installs r for all decoders and checks in this package; nil
+// removes it. It is safe to call while documents are being decoded.
+func SetViolationRecorder(r ViolationRecorder) {
+	violationRecorder.Store(violationRecorderHolder{r})
+}
+
+// recordViolation reports a violation to the installed recorder, if any
+func recordViolation(element, rule string) {
+	if holder, ok := violationRecorder.Load().(violationRecorderHolder); ok && holder.r != nil {
+		holder.r.RecordViolation(element, rule)
+	}
+}
+`
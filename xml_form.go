@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// hasXMLForm reports whether an element's struct is decoded and encoded through an
+// unexported encoding/xml form: structs holding interface choices, whose alternatives the
+// form holds in a field each, and repeated groups or positional sequences, whose elements
+// it holds in document order
+func (g *StructGenerator) hasXMLForm(name string) bool {
+	return !g.options.NoXMLTags && (g.holdsChoiceInterfaces(name) || g.hasContentGroups(name) || g.hasPositionalSequence(name))
+}
+
+// xmlFormName returns the unexported struct type that is the encoding/xml form of a struct
+func (g *StructGenerator) xmlFormName(name string) string {
+	return lowerFirst(g.toGoStructName(name)) + "XML"
+}
+
+// generateXMLForms generates the encoding/xml form of every struct that has one with the
+// methods converting to and from it, and the MarshalXML and UnmarshalXML methods using them
+func (g *StructGenerator) generateXMLForms() string {
+	var builder strings.Builder
+
+	for _, name := range g.elementOrder {
+		if !g.hasXMLForm(name) {
+			continue
+		}
+		element := g.elements[name]
+		interfaces := g.choiceInterfaces(element)
+		groups := g.contentGroups(element)
+		sequence := g.positionalSequence(element)
+		structName := g.toGoStructName(name)
+		formName := g.xmlFormName(name)
+
+		var holds []string
+		if len(interfaces) > 0 {
+			holds = append(holds, "each alternative of its choices in a field")
+		}
+		if len(groups) > 0 {
+			holds = append(holds, "the elements of its repeated groups in document order")
+		}
+		if sequence != nil {
+			holds = append(holds, "its elements in document order")
+		}
+		builder.WriteString(fmt.Sprintf("\n// %s is the encoding/xml form of %s, holding %s\n", formName, structName, strings.Join(holds, " and ")))
+		builder.WriteString(g.structType(element, formName, true))
+		builder.WriteString("\n")
+
+		// The fields both forms share
+		shared := []string{"XMLName"}
+		embedded := make(map[string]bool)
+		for i, field := range g.structFields(element) {
+			if i < len(element.Attributes) {
+				if group := g.attributeGroupOf(element, element.Attributes[i]); group != nil {
+					if !embedded[group.Name] {
+						embedded[group.Name] = true
+						shared = append(shared, group.Name)
+					}
+					continue
+				}
+			}
+			if (field.Element == "" || choiceInterfaceOf(interfaces, field.Element) == nil) && groupField(groups, field) == nil && (sequence == nil || field.Occurs == nil) {
+				shared = append(shared, field.Name)
+			}
+		}
+
+		unset := "its choices and groups"
+		if sequence != nil {
+			unset = "its elements"
+		}
+		builder.WriteString(fmt.Sprintf("\n// xmlForm returns the %s as its encoding/xml form, leaving %s unset\n", structName, unset))
+		builder.WriteString(fmt.Sprintf("func (v *%s) xmlForm() %s {\n", structName, formName))
+		builder.WriteString(fmt.Sprintf("\treturn %s{\n", formName))
+		for _, field := range shared {
+			builder.WriteString(fmt.Sprintf("\t\t%s: v.%s,\n", field, field))
+		}
+		builder.WriteString("\t}\n")
+		builder.WriteString("}\n")
+
+		builder.WriteString(fmt.Sprintf("\n// setXMLForm sets the %s from its encoding/xml form.", structName))
+		if len(interfaces) > 0 {
+			builder.WriteString(" A choice none of whose alternatives\n// is set keeps the alternative it holds.")
+		}
+		if len(groups) > 0 {
+			builder.WriteString(" The elements of a repeated group\n// start a new repetition when they cannot follow the elements before them in the last one.")
+		}
+		if sequence != nil {
+			builder.WriteString(" An element occurring at several places\n// of its sequence goes to the first place after the elements before it that can hold it.")
+		}
+		builder.WriteString("\n")
+		builder.WriteString(fmt.Sprintf("func (v *%s) setXMLForm(x *%s) {\n", structName, formName))
+		for _, field := range shared {
+			builder.WriteString(fmt.Sprintf("\tv.%s = x.%s\n", field, field))
+		}
+		fields := make(map[string]goField)
+		for _, field := range g.parseContentModel(element.Content) {
+			fields[field.Element] = field
+		}
+		for _, choice := range interfaces {
+			builder.WriteString("\tswitch {\n")
+			for _, child := range choice.Elements {
+				builder.WriteString(fmt.Sprintf("\tcase x.%s != nil:\n", fields[child].Name))
+				builder.WriteString(fmt.Sprintf("\t\tv.%s = x.%s\n", choice.Field, fields[child].Name))
+			}
+			builder.WriteString("\t}\n")
+		}
+		if len(groups) > 0 {
+			builder.WriteString(g.groupAssembly(groups))
+		}
+		if sequence != nil {
+			builder.WriteString(g.sequenceAssembly(name, sequence))
+		}
+		builder.WriteString("}\n")
+
+		builder.WriteString(fmt.Sprintf("\n// MarshalXML encodes <%s>", name))
+		var writes []string
+		if len(interfaces) > 0 {
+			writes = append(writes, "the alternative each choice holds")
+		}
+		if len(groups) > 0 {
+			writes = append(writes, "the repetitions of each group")
+		}
+		if sequence != nil {
+			writes = append(writes, "the elements at each place of its sequence")
+		}
+		builder.WriteString(fmt.Sprintf(" with %s in its place\n", strings.Join(writes, " and ")))
+		builder.WriteString(fmt.Sprintf("func (v %s) MarshalXML(e *xml.Encoder, start xml.StartElement) error {\n", structName))
+		builder.WriteString("\tx := v.xmlForm()\n")
+		for _, choice := range interfaces {
+			builder.WriteString(fmt.Sprintf("\tswitch c := v.%s.(type) {\n", choice.Field))
+			for i, child := range choice.Elements {
+				builder.WriteString(fmt.Sprintf("\tcase *%s:\n", choice.Types[i]))
+				builder.WriteString(fmt.Sprintf("\t\tx.%s = c\n", fields[child].Name))
+			}
+			builder.WriteString("\t}\n")
+		}
+		childName := g.groupChildName(name)
+		for _, group := range groups {
+			builder.WriteString(fmt.Sprintf("\tfor i := range v.%s {\n", group.Field.Name))
+			builder.WriteString(fmt.Sprintf("\t\trepetition := &v.%s[i]\n", group.Field.Name))
+			for _, field := range group.Fields {
+				switch {
+				case strings.HasPrefix(field.Type, "[]"):
+					builder.WriteString(fmt.Sprintf("\t\tfor j := range repetition.%s {\n", field.Name))
+					builder.WriteString(fmt.Sprintf("\t\t\tx.%s = append(x.%s, %s{%s: &repetition.%s[j]})\n", group.Field.Name, group.Field.Name, childName, field.Name, field.Name))
+					builder.WriteString("\t\t}\n")
+				case field.Type == "Presence":
+					builder.WriteString(fmt.Sprintf("\t\tif repetition.%s {\n", field.Name))
+					builder.WriteString(fmt.Sprintf("\t\t\tx.%s = append(x.%s, %s{%s: &repetition.%s})\n", group.Field.Name, group.Field.Name, childName, field.Name, field.Name))
+					builder.WriteString("\t\t}\n")
+				default:
+					builder.WriteString(fmt.Sprintf("\t\tif repetition.%s != nil {\n", field.Name))
+					builder.WriteString(fmt.Sprintf("\t\t\tx.%s = append(x.%s, %s{%s: repetition.%s})\n", group.Field.Name, group.Field.Name, childName, field.Name, field.Name))
+					builder.WriteString("\t\t}\n")
+				}
+			}
+			builder.WriteString("\t}\n")
+		}
+		if sequence != nil {
+			builder.WriteString(g.sequenceEncoding(name, sequence))
+		}
+		builder.WriteString(g.fixedAssignments(name, "x"))
+		builder.WriteString("\treturn e.Encode(x) // Named by the XMLName field, as start is not for document roots\n")
+		builder.WriteString("}\n")
+
+		// The case folding, default filling and #FIXED checking UnmarshalXML methods decode
+		// through the encoding/xml form themselves
+		if g.options.FoldCase || g.fillsDefaults(name) || g.checksFixed(name) {
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("\n// UnmarshalXML decodes <%s> through its encoding/xml form\n", name))
+		builder.WriteString(fmt.Sprintf("func (v *%s) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {\n", structName))
+		builder.WriteString(g.plainDecl(name))
+		builder.WriteString(g.decodeReturn(name, "d.DecodeElement("+g.decodeTarget(name)+", &start)"))
+		builder.WriteString("}\n")
+	}
+
+	return builder.String()
+}
+
+// groupAssembly returns the statements of a setXMLForm method appending the elements of
+// the repeated groups, decoded in document order, to the repetitions of the groups
+func (g *StructGenerator) groupAssembly(groups []contentGroup) string {
+	var builder strings.Builder
+
+	// Groups whose elements all start a repetition, such as choices, need no last element
+	tracked := make(map[string]bool)
+	for _, group := range groups {
+		for _, before := range group.Preceding {
+			tracked[group.Field.Name] = tracked[group.Field.Name] || len(before) > 0
+		}
+		if tracked[group.Field.Name] {
+			builder.WriteString(fmt.Sprintf("\tvar prev%s string // The last element of the group, \"\" before the first\n", group.Field.Name))
+		}
+	}
+	// encoding/xml decodes every element no other field matches into the first ",any" field
+	builder.WriteString(fmt.Sprintf("\tfor _, c := range x.%s {\n", groups[0].Field.Name))
+	builder.WriteString("\t\tswitch {\n")
+	for _, group := range groups {
+		repetitions := "v." + group.Field.Name
+		for _, field := range group.Fields {
+			builder.WriteString(fmt.Sprintf("\t\tcase c.%s != nil:\n", field.Name))
+			var conditions []string
+			for _, before := range group.Preceding[field.Element] {
+				conditions = append(conditions, fmt.Sprintf("prev%s != %q", group.Field.Name, before))
+			}
+			if len(conditions) == 0 {
+				builder.WriteString(fmt.Sprintf("\t\t\t%s = appendRepetition(%s)\n", repetitions, repetitions))
+			} else {
+				builder.WriteString(fmt.Sprintf("\t\t\tif %s {\n", strings.Join(conditions, " && ")))
+				builder.WriteString(fmt.Sprintf("\t\t\t\t%s = appendRepetition(%s)\n", repetitions, repetitions))
+				builder.WriteString("\t\t\t}\n")
+			}
+			last := fmt.Sprintf("%s[len(%s)-1].%s", repetitions, repetitions, field.Name)
+			switch {
+			case strings.HasPrefix(field.Type, "[]"):
+				builder.WriteString(fmt.Sprintf("\t\t\t%s = append(%s, *c.%s)\n", last, last, field.Name))
+			case field.Type == "Presence":
+				builder.WriteString(fmt.Sprintf("\t\t\t%s = *c.%s\n", last, field.Name))
+			default:
+				builder.WriteString(fmt.Sprintf("\t\t\t%s = c.%s\n", last, field.Name))
+			}
+			if tracked[group.Field.Name] {
+				builder.WriteString(fmt.Sprintf("\t\t\tprev%s = %q\n", group.Field.Name, field.Element))
+			}
+		}
+	}
+	builder.WriteString("\t\t}\n")
+	builder.WriteString("\t}\n")
+
+	return builder.String()
+}
+
+// plainDecl returns the statements starting a generated UnmarshalXML method of an element's
+// struct that declare what it decodes into: a plain type without the method, or the
+// encoding/xml form of a struct that has one. Structs holding mixed content as nodes
+// decode from a decoder reading their text as well.
+func (g *StructGenerator) plainDecl(name string) string {
+	if g.hasXMLForm(name) {
+		return "\tx := v.xmlForm()\n" + g.mixedDecoderDecl(name)
+	}
+	return fmt.Sprintf("\ttype plain %s // Without this method, so decoding does not recurse\n", g.toGoStructName(name)) + g.mixedDecoderDecl(name)
+}
+
+// decodeTarget returns the expression a generated UnmarshalXML method decodes into, as
+// declared by plainDecl
+func (g *StructGenerator) decodeTarget(name string) string {
+	if g.hasXMLForm(name) {
+		return "&x"
+	}
+	return "(*plain)(v)"
+}
+
+// decodeReturn returns the statements ending a generated UnmarshalXML method with the
+// given decoding call, setting the struct from its encoding/xml form if it has one
+func (g *StructGenerator) decodeReturn(name, call string) string {
+	if g.hasXMLForm(name) {
+		return fmt.Sprintf("\terr := %s\n\tv.setXMLForm(&x)\n\treturn err\n", call)
+	}
+	return fmt.Sprintf("\treturn %s\n", call)
+}